@@ -0,0 +1,735 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"crovlune/plaxt/lib/adminauth"
+	"crovlune/plaxt/lib/store"
+
+	"github.com/gorilla/mux"
+)
+
+// adminAccounts holds the ADMIN_ACCOUNTS logins. Without accounts /admin
+// stays open and every request acts as an admin.
+var adminAccounts *adminauth.Accounts
+
+// adminLoginGuard audits admin logins and locks out IPs that keep failing.
+var adminLoginGuard = adminauth.NewGuard()
+
+// adminRouteRoles overrides the role adminRequiredRole derives from the
+// HTTP method, keyed by "METHOD route-template".
+var adminRouteRoles = map[string]adminauth.Role{
+	// Dropping a single queued scrobble is queue maintenance, not deletion
+	"DELETE /admin/api/queue/user/{id}/events/{event_id}": adminauth.RoleOperator,
+	// Login history reveals who administers plaxt and from where
+	"GET /admin/api/auth/attempts": adminauth.RoleAdmin,
+	// Every account manages its own second factor
+	"POST /admin/api/me/totp":         adminauth.RoleViewer,
+	"POST /admin/api/me/totp/confirm": adminauth.RoleViewer,
+	"POST /admin/api/me/totp/verify":  adminauth.RoleViewer,
+	"DELETE /admin/api/me/totp":       adminauth.RoleViewer,
+}
+
+// adminPublicRoutes are admin routes reachable without logging in: the
+// ones the public onboarding wizard calls and the SSO login flow.
+var adminPublicRoutes = map[string]bool{
+	"GET /admin/api/family-groups/{id}": true,
+	"GET /admin/login":                  true,
+	"GET /admin/oidc/callback":          true,
+	"POST /admin/logout":                true,
+}
+
+// adminTOTPExemptRoutes are reachable with a password alone so an account
+// can enroll in or pass its second factor.
+var adminTOTPExemptRoutes = map[string]bool{
+	"GET /admin/2fa":                  true,
+	"GET /admin/api/me":               true,
+	"GET /admin/api/me/totp":          true,
+	"POST /admin/api/me/totp":         true,
+	"POST /admin/api/me/totp/confirm": true,
+	"POST /admin/api/me/totp/verify":  true,
+}
+
+// adminRequireTOTP makes every admin account enroll in two-factor
+// authentication before it can use the dashboard (ADMIN_REQUIRE_TOTP).
+var adminRequireTOTP bool
+
+// adminTOTPGuard locks out accounts that keep entering wrong codes. It is
+// keyed by account rather than IP because the password is already known.
+var adminTOTPGuard = adminauth.NewGuard()
+
+// adminMFAKey signs the cookie that records a passed second factor. It is
+// regenerated on start, so a restart asks for a fresh code.
+var adminMFAKey = mustSessionKey()
+
+// adminOIDC logs admins in through an OpenID Connect provider
+// (ADMIN_OIDC_ISSUER). adminOIDCRedirectURL overrides the callback URL
+// derived from the request.
+var (
+	adminOIDC            *adminauth.OIDCProvider
+	adminOIDCRedirectURL string
+)
+
+// adminProxyAuth trusts the user header of an authenticating reverse proxy
+// (ADMIN_PROXY_USER_HEADER).
+var adminProxyAuth *adminauth.ProxyAuth
+
+// adminSessionKey signs SSO session cookies. Like adminMFAKey it is
+// regenerated on start, so a restart sends SSO users back to the provider.
+var adminSessionKey = mustSessionKey()
+
+const (
+	adminMFACookie       = "plaxt_admin_mfa"
+	adminSessionCookie   = "plaxt_admin_session"
+	adminOIDCStateCookie = "plaxt_admin_oidc"
+	adminSessionLifetime = 12 * time.Hour
+	adminTOTPIssuer      = "plaxt"
+)
+
+func mustSessionKey() adminauth.SessionKey {
+	key, err := adminauth.NewSessionKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+type adminPrincipalKey struct{}
+
+type peerAddrKey struct{}
+
+// rememberPeerAddr records the address of the connecting client before
+// handlers.ProxyHeaders replaces RemoteAddr with the forwarded one, so proxy
+// auth headers are only trusted from the proxy itself.
+func rememberPeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerAddrKey{}, webhookSourceIP(r))))
+	})
+}
+
+// peerIP returns the address rememberPeerAddr recorded, falling back to the
+// request's RemoteAddr.
+func peerIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return ip
+	}
+	return webhookSourceIP(r)
+}
+
+// adminPrincipal is the account an admin request acts as.
+type adminPrincipal struct {
+	Username string         `json:"username,omitempty"`
+	Role     adminauth.Role `json:"role"`
+	// SSO is set for accounts signed in through the OIDC provider.
+	SSO bool `json:"sso,omitempty"`
+	// Proxy is set for accounts an authenticating reverse proxy vouched for.
+	Proxy bool `json:"proxy,omitempty"`
+}
+
+// adminPrincipalFrom returns the principal adminAuthMiddleware attached to
+// ctx. Requests that did not pass through it act as an admin, matching an
+// install without ADMIN_ACCOUNTS.
+func adminPrincipalFrom(ctx context.Context) adminPrincipal {
+	if p, ok := ctx.Value(adminPrincipalKey{}).(adminPrincipal); ok {
+		return p
+	}
+	return adminPrincipal{Role: adminauth.RoleAdmin}
+}
+
+// adminRouteKey names the matched route as "METHOD template", falling back
+// to the raw path when no route matched.
+func adminRouteKey(r *http.Request) string {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			path = tpl
+		}
+	}
+	return r.Method + " " + path
+}
+
+// adminRequiredRole returns the role needed for r: reads need viewer,
+// deletes need admin and every other change needs operator.
+func adminRequiredRole(r *http.Request) adminauth.Role {
+	if role, ok := adminRouteRoles[adminRouteKey(r)]; ok {
+		return role
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return adminauth.RoleViewer
+	case http.MethodDelete:
+		return adminauth.RoleAdmin
+	}
+	return adminauth.RoleOperator
+}
+
+func isAdminPath(path string) bool {
+	return path == "/admin" || strings.HasPrefix(path, "/admin/")
+}
+
+// adminAuthEnabled reports whether /admin needs a login at all.
+func adminAuthEnabled() bool {
+	return adminAccounts.Enabled() || adminOIDC != nil || adminProxyAuth != nil
+}
+
+// adminAuthMiddleware authenticates /admin requests with a trusted proxy
+// header, an SSO session cookie or HTTP basic auth against adminAccounts,
+// and rejects actions the account's role does not allow.
+func adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !adminAuthEnabled() {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminPrincipalKey{}, adminPrincipal{Role: adminauth.RoleAdmin})))
+			return
+		}
+		if username, role, ok := adminProxyAuth.Identify(r.Header, peerIP(r)); ok {
+			if role == adminauth.RoleNone {
+				slog.Warn("admin proxy login denied; no group maps to a role", "admin_user", username, "remote", webhookSourceIP(r))
+				writeJSONError(w, http.StatusForbidden, "your account is not in a group allowed to administer plaxt")
+				return
+			}
+			if adminLoginGuard.Succeed(webhookSourceIP(r), username, time.Now()) {
+				slog.Info("admin login", "admin_user", username, "role", role.String(), "remote", webhookSourceIP(r), "method", "proxy")
+			}
+			// The proxy is responsible for second factors
+			authorizeAdminRequest(w, r, next, adminPrincipal{Username: username, Role: role, Proxy: true})
+			return
+		}
+		if principal, ok := adminSSOPrincipal(r); ok {
+			// The identity provider is responsible for second factors
+			authorizeAdminRequest(w, r, next, principal)
+			return
+		}
+
+		username, password, hasAuth := r.BasicAuth()
+		ip, now := webhookSourceIP(r), time.Now()
+		if hasAuth {
+			if wait := adminLoginGuard.LockedFor(ip, now); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeJSONError(w, http.StatusTooManyRequests, "too many failed logins; try again later")
+				return
+			}
+		}
+		account, ok := adminAccounts.Authenticate(username, password)
+		if !ok {
+			if !hasAuth && adminPublicRoutes[adminRouteKey(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if hasAuth {
+				if lock := adminLoginGuard.Fail(ip, username, now); lock > 0 {
+					slog.Warn("admin login failed; locking out client", "admin_user", username, "remote", ip, "lockout", lock.String())
+				} else {
+					slog.Warn("admin login failed", "admin_user", username, "remote", ip)
+				}
+			}
+			if adminOIDC != nil && !hasAuth {
+				if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/admin/api/") {
+					http.Redirect(w, r, "/admin/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+					return
+				}
+				if !adminAccounts.Enabled() {
+					writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required", "login_url": "/admin/login"})
+					return
+				}
+			}
+			if adminAccounts.Enabled() {
+				w.Header().Set("WWW-Authenticate", `Basic realm="plaxt admin", charset="UTF-8"`)
+			}
+			writeJSONError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if adminLoginGuard.Succeed(ip, account.Username, now) {
+			slog.Info("admin login", "admin_user", account.Username, "role", account.Role.String(), "remote", ip)
+		}
+		if !adminTOTPExemptRoutes[adminRouteKey(r)] && !checkAdminSecondFactor(w, r, account.Username) {
+			return
+		}
+		authorizeAdminRequest(w, r, next, adminPrincipal{Username: account.Username, Role: account.Role})
+	})
+}
+
+// authorizeAdminRequest serves r as principal if its role allows the action.
+func authorizeAdminRequest(w http.ResponseWriter, r *http.Request, next http.Handler, principal adminPrincipal) {
+	ctx := context.WithValue(r.Context(), adminPrincipalKey{}, principal)
+	annotateRequestLog(ctx, "admin_user", principal.Username)
+	if required := adminRequiredRole(r); !principal.Role.Allows(required) {
+		slog.Warn("admin action denied", "admin_user", principal.Username, "role", principal.Role.String(), "required", required.String(), "route", adminRouteKey(r))
+		writeJSONError(w, http.StatusForbidden, "requires the "+required.String()+" role")
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// adminSessionResponse tells the dashboard who is signed in and what the
+// account may do, so it can hide actions the server would reject.
+type adminSessionResponse struct {
+	adminPrincipal
+	AuthEnabled bool `json:"auth_enabled"`
+	CanOperate  bool `json:"can_operate"`
+	CanAdmin    bool `json:"can_admin"`
+	// TOTPEnabled and TOTPVerified describe the account's second factor.
+	TOTPEnabled  bool `json:"totp_enabled,omitempty"`
+	TOTPVerified bool `json:"totp_verified,omitempty"`
+	TOTPRequired bool `json:"totp_required,omitempty"`
+}
+
+func getAdminSession(w http.ResponseWriter, r *http.Request) {
+	p := adminPrincipalFrom(r.Context())
+	resp := adminSessionResponse{
+		adminPrincipal: p,
+		AuthEnabled:    adminAuthEnabled(),
+		CanOperate:     p.Role.Allows(adminauth.RoleOperator),
+		CanAdmin:       p.Role.Allows(adminauth.RoleAdmin),
+	}
+	if p.Username != "" && !p.SSO && !p.Proxy {
+		if enrollment, err := loadAdminTOTP(r.Context(), p.Username); err == nil && enrollment != nil {
+			resp.TOTPEnabled = enrollment.Confirmed
+			resp.TOTPVerified = enrollment.Confirmed && hasAdminMFACookie(r, p.Username)
+		}
+		resp.TOTPRequired = adminRequireTOTP
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// adminLoginAuditResponse lists recent admin logins and clients with failed
+// logins.
+type adminLoginAuditResponse struct {
+	AuthEnabled bool                `json:"auth_enabled"`
+	Attempts    []adminauth.Attempt `json:"attempts"`
+	Lockouts    []adminauth.Lockout `json:"lockouts"`
+}
+
+func getAdminLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, adminLoginAuditResponse{
+		AuthEnabled: adminAuthEnabled(),
+		Attempts:    adminLoginGuard.Attempts(),
+		Lockouts:    adminLoginGuard.Lockouts(time.Now()),
+	})
+}
+
+// loadAdminTOTP returns the account's enrollment, or nil if it has none.
+func loadAdminTOTP(ctx context.Context, username string) (*store.AdminTOTP, error) {
+	if storage == nil {
+		return nil, nil
+	}
+	enrollment, err := storage.GetAdminTOTP(ctx, username)
+	if errors.Is(err, store.ErrAdminTOTPNotFound) {
+		return nil, nil
+	}
+	return enrollment, err
+}
+
+func hasAdminMFACookie(r *http.Request, username string) bool {
+	cookie, err := r.Cookie(adminMFACookie)
+	return err == nil && adminMFAKey.Check(cookie.Value, username, time.Now())
+}
+
+func setAdminMFACookie(w http.ResponseWriter, r *http.Request, username string) {
+	expires := time.Now().Add(adminSessionLifetime)
+	setAdminCookie(w, r, adminMFACookie, adminMFAKey.Issue(username, expires), expires)
+}
+
+// checkAdminSecondFactor lets the request through if the account passed its
+// second factor, or has none and ADMIN_REQUIRE_TOTP is off. Otherwise pages
+// redirect to /admin/2fa and API calls get a 401 the dashboard recognises.
+func checkAdminSecondFactor(w http.ResponseWriter, r *http.Request, username string) bool {
+	enrollment, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		slog.Error("admin two-factor lookup failed", "admin_user", username, "error", err)
+		writeJSONError(w, http.StatusServiceUnavailable, "two-factor status unavailable")
+		return false
+	}
+	confirmed := enrollment != nil && enrollment.Confirmed
+	if confirmed && hasAdminMFACookie(r, username) {
+		return true
+	}
+	if !confirmed && !adminRequireTOTP {
+		return true
+	}
+	if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/admin/api/") {
+		http.Redirect(w, r, "/admin/2fa?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return false
+	}
+	if confirmed {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "two-factor code required", "totp_required": true})
+	} else {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "two-factor enrollment required", "totp_enroll_required": true})
+	}
+	return false
+}
+
+func renderAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, "admin-2fa.html", nil)
+}
+
+// adminTOTPStatusResponse describes the signed-in account's second factor.
+type adminTOTPStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Pending           bool `json:"pending"`
+	Verified          bool `json:"verified"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// adminTOTPAccount returns the signed-in account name, or writes an error if
+// the dashboard has no accounts to enroll.
+func adminTOTPAccount(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p := adminPrincipalFrom(r.Context())
+	if p.SSO || p.Proxy {
+		writeJSONError(w, http.StatusConflict, "two-factor authentication is managed by your identity provider")
+		return "", false
+	}
+	username := p.Username
+	if username == "" {
+		writeJSONError(w, http.StatusConflict, "two-factor authentication needs ADMIN_ACCOUNTS")
+		return "", false
+	}
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return "", false
+	}
+	return username, true
+}
+
+func getAdminTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	username, ok := adminTOTPAccount(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+	resp := adminTOTPStatusResponse{Required: adminRequireTOTP}
+	if enrollment != nil {
+		resp.Enabled = enrollment.Confirmed
+		resp.Pending = !enrollment.Confirmed
+		resp.Verified = enrollment.Confirmed && hasAdminMFACookie(r, username)
+		resp.RecoveryCodesLeft = len(enrollment.RecoveryCodes)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// beginAdminTOTPEnrollment issues a new secret for the account. It stays
+// pending until a code from it is confirmed, and cannot replace a confirmed
+// enrollment: that needs a code (DELETE /admin/api/me/totp) or an admin.
+func beginAdminTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	username, ok := adminTOTPAccount(w, r)
+	if !ok {
+		return
+	}
+	existing, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+	if existing != nil && existing.Confirmed {
+		writeJSONError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	secret, err := adminauth.NewTOTPSecret()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to generate secret")
+		return
+	}
+	if err := storage.PutAdminTOTP(r.Context(), &store.AdminTOTP{Username: username, Secret: secret}); err != nil {
+		slog.Error("admin two-factor enrollment failed", "admin_user", username, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to save enrollment")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_url": adminauth.TOTPURL(adminTOTPIssuer, username, secret),
+	})
+}
+
+type adminTOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// checkAdminTOTPCode verifies a code (or, if allowRecovery, a recovery code)
+// for the enrollment and persists the consumed step or code. It writes the
+// error response itself and counts failures towards a lockout.
+func checkAdminTOTPCode(w http.ResponseWriter, r *http.Request, enrollment *store.AdminTOTP, allowRecovery bool) bool {
+	now := time.Now()
+	username := enrollment.Username
+	if wait := adminTOTPGuard.LockedFor(username, now); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeJSONError(w, http.StatusTooManyRequests, "too many wrong codes; try again later")
+		return false
+	}
+	var req adminTOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		writeJSONError(w, http.StatusBadRequest, "code is required")
+		return false
+	}
+	usedRecovery := false
+	if step, ok := adminauth.VerifyTOTP(enrollment.Secret, req.Code, now, enrollment.LastStep); ok {
+		enrollment.LastStep = step
+	} else if rest, ok := adminauth.UseRecoveryCode(enrollment.RecoveryCodes, req.Code); ok && allowRecovery {
+		enrollment.RecoveryCodes = rest
+		usedRecovery = true
+	} else {
+		ip := webhookSourceIP(r)
+		if lock := adminTOTPGuard.Fail(username, username, now); lock > 0 {
+			slog.Warn("admin two-factor code rejected; locking out account", "admin_user", username, "remote", ip, "lockout", lock.String())
+		} else {
+			slog.Warn("admin two-factor code rejected", "admin_user", username, "remote", ip)
+		}
+		writeJSONError(w, http.StatusUnauthorized, "invalid code")
+		return false
+	}
+	adminTOTPGuard.Succeed(username, username, now)
+	enrollment.UpdatedAt = now.UTC()
+	if err := storage.PutAdminTOTP(r.Context(), enrollment); err != nil {
+		slog.Error("admin two-factor update failed", "admin_user", username, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to save enrollment")
+		return false
+	}
+	if usedRecovery {
+		slog.Warn("admin recovery code used", "admin_user", username, "recovery_codes_left", len(enrollment.RecoveryCodes))
+	}
+	return true
+}
+
+// confirmAdminTOTPEnrollment enables the pending secret once the account
+// proves its app produces valid codes, and returns the recovery codes. They
+// are only stored hashed, so this is the one time they are shown.
+func confirmAdminTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	username, ok := adminTOTPAccount(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+	if enrollment == nil {
+		writeJSONError(w, http.StatusNotFound, "no pending enrollment")
+		return
+	}
+	if enrollment.Confirmed {
+		writeJSONError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	codes, hashes, err := adminauth.NewRecoveryCodes()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to generate recovery codes")
+		return
+	}
+	enrollment.Confirmed = true
+	enrollment.RecoveryCodes = hashes
+	if !checkAdminTOTPCode(w, r, enrollment, false) {
+		return
+	}
+	setAdminMFACookie(w, r, username)
+	slog.Info("admin two-factor enabled", "admin_user", username)
+	writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// verifyAdminTOTP accepts a code or recovery code and marks the browser as
+// having passed the second factor.
+func verifyAdminTOTP(w http.ResponseWriter, r *http.Request) {
+	username, ok := adminTOTPAccount(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+	if enrollment == nil || !enrollment.Confirmed {
+		writeJSONError(w, http.StatusConflict, "two-factor authentication is not enabled")
+		return
+	}
+	if !checkAdminTOTPCode(w, r, enrollment, true) {
+		return
+	}
+	setAdminMFACookie(w, r, username)
+	writeJSON(w, http.StatusOK, map[string]int{"recovery_codes_left": len(enrollment.RecoveryCodes)})
+}
+
+// disableAdminTOTP removes the account's own second factor after checking a
+// current code, so a stolen password alone cannot turn it off.
+func disableAdminTOTP(w http.ResponseWriter, r *http.Request) {
+	username, ok := adminTOTPAccount(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := loadAdminTOTP(r.Context(), username)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to load two-factor status")
+		return
+	}
+	if enrollment == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if enrollment.Confirmed && !checkAdminTOTPCode(w, r, enrollment, true) {
+		return
+	}
+	if err := storage.DeleteAdminTOTP(r.Context(), username); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to remove enrollment")
+		return
+	}
+	slog.Warn("admin two-factor disabled", "admin_user", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetAdminAccountTOTP lets an admin remove another account's second factor,
+// e.g. after a lost phone and recovery codes.
+func resetAdminAccountTOTP(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	username := strings.ToLower(strings.TrimSpace(mux.Vars(r)["username"]))
+	if err := storage.DeleteAdminTOTP(r.Context(), username); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to remove enrollment")
+		return
+	}
+	slog.Warn("admin two-factor reset", "admin_user", adminPrincipalFrom(r.Context()).Username, "target", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAdminCookie sets an /admin cookie, or clears it when value is empty.
+// SameSite=Lax (not Strict) so the cookies arrive on the redirect back from
+// the identity provider.
+func setAdminCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/admin",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.URL.Scheme == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// adminSSOPrincipal returns the account of a valid SSO session cookie.
+func adminSSOPrincipal(r *http.Request) (adminPrincipal, bool) {
+	if adminOIDC == nil {
+		return adminPrincipal{}, false
+	}
+	cookie, err := r.Cookie(adminSessionCookie)
+	if err != nil {
+		return adminPrincipal{}, false
+	}
+	subject, ok := adminSessionKey.Subject(cookie.Value, time.Now())
+	if !ok {
+		return adminPrincipal{}, false
+	}
+	roleName, username, ok := strings.Cut(subject, ":")
+	role, err := adminauth.ParseRole(roleName)
+	if !ok || err != nil || username == "" {
+		return adminPrincipal{}, false
+	}
+	return adminPrincipal{Username: username, Role: role, SSO: true}, true
+}
+
+// adminLoginNext returns where to go after logging in, accepting only local
+// /admin paths so the login cannot be used as an open redirect.
+func adminLoginNext(next string) string {
+	if next == "/admin" || strings.HasPrefix(next, "/admin/") || strings.HasPrefix(next, "/admin?") {
+		return next
+	}
+	return "/admin"
+}
+
+// startAdminLogin sends the browser to the OIDC provider. The state is also
+// kept in a cookie so a callback is only accepted by the browser that
+// started the login.
+func startAdminLogin(w http.ResponseWriter, r *http.Request) {
+	if adminOIDC == nil {
+		writeJSONError(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+	redirectURL := adminOIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = SelfRoot(r) + "/admin/oidc/callback"
+	}
+	authURL, state, err := adminOIDC.Begin(r.Context(), redirectURL, adminLoginNext(r.URL.Query().Get("next")))
+	if err != nil {
+		slog.Error("admin sso login failed to start", "error", err)
+		http.Error(w, "single sign-on is unavailable", http.StatusBadGateway)
+		return
+	}
+	setAdminCookie(w, r, adminOIDCStateCookie, state, time.Now().Add(10*time.Minute))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// finishAdminLogin handles the provider's redirect back to plaxt.
+func finishAdminLogin(w http.ResponseWriter, r *http.Request) {
+	if adminOIDC == nil {
+		writeJSONError(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+	ip, now := webhookSourceIP(r), time.Now()
+	if wait := adminLoginGuard.LockedFor(ip, now); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "too many failed logins; try again later", http.StatusTooManyRequests)
+		return
+	}
+	query := r.URL.Query()
+	setAdminCookie(w, r, adminOIDCStateCookie, "", time.Time{})
+	if e := query.Get("error"); e != "" {
+		slog.Warn("admin sso login refused by provider", "error", e, "description", query.Get("error_description"), "remote", ip)
+		http.Error(w, "login was cancelled or refused by the identity provider", http.StatusUnauthorized)
+		return
+	}
+	state := query.Get("state")
+	if cookie, err := r.Cookie(adminOIDCStateCookie); err != nil || state == "" || cookie.Value != state {
+		http.Error(w, "login expired or was started in another browser; try again", http.StatusBadRequest)
+		return
+	}
+	identity, next, err := adminOIDC.Finish(r.Context(), state, query.Get("code"))
+	if err != nil {
+		username := ""
+		if identity != nil {
+			username = identity.Username
+		}
+		adminLoginGuard.Fail(ip, username, now)
+		if errors.Is(err, adminauth.ErrOIDCNoRole) {
+			slog.Warn("admin sso login denied; no group maps to a role", "admin_user", username, "groups", identity.Groups, "remote", ip)
+			http.Error(w, "your account is not in a group allowed to administer plaxt", http.StatusForbidden)
+			return
+		}
+		slog.Warn("admin sso login failed", "error", err, "remote", ip)
+		http.Error(w, "single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+	if adminLoginGuard.Succeed(ip, identity.Username, now) {
+		slog.Info("admin login", "admin_user", identity.Username, "role", identity.Role.String(), "remote", ip, "method", "sso")
+	}
+	expires := now.Add(adminSessionLifetime)
+	setAdminCookie(w, r, adminSessionCookie, adminSessionKey.Issue(identity.Role.String()+":"+identity.Username, expires), expires)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// adminLogout ends the SSO session and forgets a passed second factor.
+func adminLogout(w http.ResponseWriter, r *http.Request) {
+	setAdminCookie(w, r, adminSessionCookie, "", time.Time{})
+	setAdminCookie(w, r, adminMFACookie, "", time.Time{})
+	w.WriteHeader(http.StatusNoContent)
+}
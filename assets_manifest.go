@@ -1,38 +1,61 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
+	"io/fs"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 )
 
+// assetManifest maps asset keys such as "js/index.js" to the fingerprinted
+// bundles `npm run build` lists in a manifest inside fsys.
 type assetManifest struct {
+	fsys     fs.FS
 	path     string
 	entries  map[string]string
 	mu       sync.RWMutex
 	isLoaded bool
 	modTime  time.Time
+
+	// Assets missing from the manifest are served from their source with a
+	// ?v= content hash, so browsers still pick up changes when `npm run
+	// build` has not been run.
+	versionsMu sync.Mutex
+	versions   map[string]assetVersion
+}
+
+// assetVersion caches a source file's content hash until it changes.
+type assetVersion struct {
+	modTime time.Time
+	size    int64
+	hash    string
 }
 
-func newAssetManifest(manifestPath string) *assetManifest {
+// newAssetManifest reads manifestPath from fsys; asset keys are relative to
+// the root of fsys.
+func newAssetManifest(fsys fs.FS, manifestPath string) *assetManifest {
 	m := &assetManifest{
-		path: manifestPath,
+		fsys:     fsys,
+		path:     manifestPath,
+		versions: make(map[string]assetVersion),
 	}
-	if err := m.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+	if err := m.reload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		slog.Error("failed to load asset manifest", "path", manifestPath, "error", err)
 	}
 	return m
 }
 
 func (m *assetManifest) reload() error {
-	info, err := os.Stat(m.path)
+	info, err := fs.Stat(m.fsys, m.path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, fs.ErrNotExist) {
 			m.mu.Lock()
 			m.entries = nil
 			m.isLoaded = false
@@ -43,7 +66,7 @@ func (m *assetManifest) reload() error {
 		return err
 	}
 
-	data, err := os.ReadFile(m.path)
+	data, err := fs.ReadFile(m.fsys, m.path)
 	if err != nil {
 		return err
 	}
@@ -67,15 +90,45 @@ func (m *assetManifest) pathFor(key string) string {
 	m.ensureLatest()
 
 	m.mu.RLock()
-	defer m.mu.RUnlock()
+	rel, ok := m.entries[normalizeAssetKey(key)]
+	loaded := m.isLoaded
+	m.mu.RUnlock()
 
-	if !m.isLoaded {
-		return prefix + normalizeAssetKey(key)
-	}
-	if rel, ok := m.entries[normalizeAssetKey(key)]; ok && rel != "" {
+	if loaded && ok && rel != "" {
 		return prefix + filepath.ToSlash(rel)
 	}
-	return prefix + normalizeAssetKey(key)
+	return m.fallbackPath(normalizeAssetKey(key))
+}
+
+// fallbackPath returns the unbuilt source path, cache-busted with a hash of
+// its contents when the file can be read.
+func (m *assetManifest) fallbackPath(key string) string {
+	path := "/static/" + key
+	if !fs.ValidPath(key) {
+		return path
+	}
+	info, err := fs.Stat(m.fsys, key)
+	if err != nil || info.IsDir() {
+		return path
+	}
+
+	m.versionsMu.Lock()
+	defer m.versionsMu.Unlock()
+	if v, ok := m.versions[key]; ok && v.modTime.Equal(info.ModTime()) && v.size == info.Size() {
+		return path + "?v=" + v.hash
+	}
+	f, err := m.fsys.Open(key)
+	if err != nil {
+		return path
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return path
+	}
+	v := assetVersion{modTime: info.ModTime(), size: info.Size(), hash: hex.EncodeToString(h.Sum(nil))[:12]}
+	m.versions[key] = v
+	return path + "?v=" + v.hash
 }
 
 func normalizeAssetKey(key string) string {
@@ -88,9 +141,9 @@ func (m *assetManifest) ensureLatest() {
 	if m == nil {
 		return
 	}
-	info, err := os.Stat(m.path)
+	info, err := fs.Stat(m.fsys, m.path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, fs.ErrNotExist) {
 			m.mu.Lock()
 			m.entries = nil
 			m.isLoaded = false
@@ -104,7 +157,7 @@ func (m *assetManifest) ensureLatest() {
 	needsReload := !m.isLoaded || info.ModTime().After(m.modTime)
 	m.mu.RUnlock()
 	if needsReload {
-		if err := m.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := m.reload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			slog.Error("failed to refresh asset manifest", "path", m.path, "error", err)
 		}
 	}
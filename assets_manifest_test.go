@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetManifestFallsBackToCacheBustedSources(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "js"), 0o755))
+	src := filepath.Join(root, "js", "index.js")
+	assert.NoError(t, os.WriteFile(src, []byte("console.log(1)"), 0o644))
+	m := newAssetManifest(os.DirFS(root), "dist/manifest.json")
+
+	first := m.pathFor("js/index.js")
+	assert.True(t, strings.HasPrefix(first, "/static/js/index.js?v="), first)
+	assert.Equal(t, first, m.pathFor("/static/js/index.js"), "the hash is stable")
+	assert.Equal(t, "/static/js/missing.js", m.pathFor("js/missing.js"), "missing files get no version")
+
+	assert.NoError(t, os.WriteFile(src, []byte("console.log(22)"), 0o644))
+	later := time.Now().Add(time.Second)
+	assert.NoError(t, os.Chtimes(src, later, later))
+	assert.NotEqual(t, first, m.pathFor("js/index.js"), "edits change the version")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "dist"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "dist", "manifest.json"), []byte(`{"js/index.js":"dist/js/index-abc.js"}`), 0o644))
+	assert.Equal(t, "/static/dist/js/index-abc.js", m.pathFor("js/index.js"))
+	assert.True(t, strings.HasPrefix(m.pathFor("css/wizard.css"), "/static/css/wizard.css"), "keys missing from the manifest still resolve")
+}
@@ -6,8 +6,16 @@ const path = require('path');
 const crypto = require('crypto');
 
 const isDev = process.argv.includes('--dev');
-const staticDir = 'static';
-const distDir = 'static/dist';
+// Source maps are always written in dev builds; --sourcemap adds them to
+// minified builds too.
+const sourcemaps = isDev || process.argv.includes('--sourcemap');
+const isWatch = process.argv.includes('--watch');
+// Font subsetting needs the optional subset-font package:
+//   npm install --no-save subset-font
+const subsetFonts = process.argv.includes('--subset-fonts');
+// Resolved from this file so the build works from any working directory
+const staticDir = path.relative(process.cwd(), path.join(__dirname, 'static')) || '.';
+const distDir = path.join(staticDir, 'dist');
 
 // Ensure dist directory exists
 if (!fs.existsSync(distDir)) {
@@ -24,117 +32,265 @@ const assets = [
   { source: 'js/admin.js', kind: 'js' },
   { source: 'js/family-admin.js', kind: 'js' },
   { source: 'js/index.js', kind: 'js' },
-  { source: 'js/queue.js', kind: 'js' }
+  { source: 'js/queue.js', kind: 'js' },
+  { source: 'js/admin-2fa.js', kind: 'js' }
 ];
 
+// Every SVG in static/img is fingerprinted and also folded into
+// img/sprite.svg as a <symbol> named after the file, for
+// <svg><use href="{{ assetPath "img/sprite.svg" }}#home"></use></svg>.
+const svgDir = 'img';
+// Fonts in static/fonts are fingerprinted and, with --subset-fonts, cut down
+// to the characters used by the templates and scripts.
+const fontDir = 'fonts';
+const fontExts = ['.woff2', '.woff', '.ttf', '.otf'];
+
+function fingerprint(content) {
+  return crypto.createHash('sha256').update(content).digest('hex').substring(0, 12);
+}
+
+// writeAsset writes content under dist with a content hash in its name and
+// records it in the manifest. A source map, when given, is written next to it
+// and linked with the comment style of the asset's language.
+function writeAsset(manifest, written, source, content, map) {
+  const ext = path.extname(source);
+  const base = path.basename(source, ext);
+  const outDir = path.join(distDir, path.dirname(source));
+  if (!fs.existsSync(outDir)) {
+    fs.mkdirSync(outDir, { recursive: true });
+  }
+
+  const hash = fingerprint(content);
+  const outName = `${base}-${hash}${ext}`;
+  const outPath = path.join(outDir, outName);
+
+  if (map) {
+    const mapName = `${outName}.map`;
+    const parsed = JSON.parse(Buffer.from(map).toString('utf8'));
+    parsed.file = outName;
+    fs.writeFileSync(path.join(outDir, mapName), JSON.stringify(parsed));
+    written.add(path.join(outDir, mapName));
+    const link = ext === '.css' ? `\n/*# sourceMappingURL=${mapName} */\n` : `\n//# sourceMappingURL=${mapName}\n`;
+    content = Buffer.concat([Buffer.from(content), Buffer.from(link, 'utf8')]);
+  }
+
+  fs.writeFileSync(outPath, content);
+  written.add(outPath);
+
+  const key = source.replace(/\\/g, '/');
+  const rel = path.join('dist', path.dirname(source), outName).replace(/\\/g, '/');
+  manifest[key] = rel;
+  console.log(`built ${source} -> ${rel}${map ? ' (+map)' : ''}`);
+}
+
+async function buildScriptOrStyle(asset) {
+  const srcPath = path.join(staticDir, asset.source);
+  const ext = path.extname(asset.source);
+  const outDir = path.join(distDir, path.dirname(asset.source));
+
+  if (!fs.existsSync(srcPath)) {
+    throw new Error(`Source file not found: ${srcPath}`);
+  }
+
+  // Special handling for common.js - it's a collection of global functions
+  if (asset.source === 'js/common.js') {
+    let content = fs.readFileSync(srcPath, 'utf8');
+    if (!isDev) {
+      // Simple minification for common.js to preserve global functions
+      content = content
+        .replace(/\/\*[\s\S]*?\*\//g, '') // Remove block comments
+        .replace(/\/\/.*$/gm, '') // Remove line comments
+        .replace(/\s+/g, ' ') // Collapse whitespace
+        .trim();
+    }
+    return { content: Buffer.from(content, 'utf8') };
+  }
+
+  const options = {
+    entryPoints: [srcPath],
+    outdir: outDir,
+    minify: !isDev,
+    bundle: false,
+    // External maps get no sourceMappingURL comment; writeAsset adds one
+    // pointing at the fingerprinted name
+    sourcemap: sourcemaps ? 'external' : false,
+    sourcesContent: true,
+    target: 'es2015',
+    format: 'iife',
+    write: false,
+    minifyIdentifiers: false,
+    minifySyntax: !isDev,
+    minifyWhitespace: !isDev
+  };
+  if (asset.kind === 'css') {
+    options.outExtension = { '.css': '.css' };
+  } else {
+    options.outExtension = { '.js': '.js' };
+    // Preserve function names and avoid aggressive optimizations
+    options.keepNames = true;
+  }
+
+  const result = await esbuild.build(options);
+  const files = result.outputFiles || [];
+  const code = files.find(f => f.path.endsWith(ext));
+  const map = files.find(f => f.path.endsWith('.map'));
+  if (!code) {
+    throw new Error(`esbuild produced no output for ${asset.source}`);
+  }
+  return { content: code.contents, map: map ? map.contents : null };
+}
+
+function minifySvg(svg) {
+  return svg
+    .replace(/<\?xml[\s\S]*?\?>/g, '')
+    .replace(/<!--[\s\S]*?-->/g, '')
+    .replace(/>\s+</g, '><')
+    .replace(/\s+/g, ' ')
+    .trim();
+}
+
+// spriteSymbol turns one SVG document into a <symbol> keeping its viewBox.
+function spriteSymbol(id, svg) {
+  const open = svg.match(/<svg\b([^>]*)>/i);
+  const close = svg.lastIndexOf('</svg>');
+  if (!open || close < 0) {
+    throw new Error(`img/${id}.svg is not an <svg> document`);
+  }
+  const viewBox = open[1].match(/viewBox="([^"]*)"/i);
+  const inner = svg.slice(open.index + open[0].length, close);
+  return `<symbol id="${id}"${viewBox ? ` viewBox="${viewBox[1]}"` : ''}>${inner}</symbol>`;
+}
+
+function listFiles(dir, exts) {
+  const full = path.join(staticDir, dir);
+  if (!fs.existsSync(full)) {
+    return [];
+  }
+  return fs.readdirSync(full)
+    .filter(name => exts.includes(path.extname(name).toLowerCase()))
+    .sort();
+}
+
+function buildSvgs(manifest, written) {
+  const symbols = [];
+  for (const name of listFiles(svgDir, ['.svg'])) {
+    const source = `${svgDir}/${name}`;
+    const svg = minifySvg(fs.readFileSync(path.join(staticDir, source), 'utf8'));
+    writeAsset(manifest, written, source, Buffer.from(svg, 'utf8'));
+    symbols.push(spriteSymbol(path.basename(name, '.svg'), svg));
+  }
+  if (symbols.length > 0) {
+    const sprite = `<svg xmlns="http://www.w3.org/2000/svg" style="display:none">${symbols.join('')}</svg>`;
+    writeAsset(manifest, written, `${svgDir}/sprite.svg`, Buffer.from(sprite, 'utf8'));
+  }
+}
+
+// usedCharacters collects every character the pages and scripts could render.
+function usedCharacters() {
+  const chars = new Set();
+  const sources = listFiles('.', ['.html']).concat(listFiles('js', ['.js']).map(name => `js/${name}`));
+  for (const source of sources) {
+    for (const ch of fs.readFileSync(path.join(staticDir, source), 'utf8')) {
+      chars.add(ch);
+    }
+  }
+  return Array.from(chars).join('');
+}
+
+async function buildFonts(manifest, written) {
+  const fonts = listFiles(fontDir, fontExts);
+  if (fonts.length === 0) {
+    return;
+  }
+  let subsetFont = null;
+  let text = '';
+  if (subsetFonts) {
+    try {
+      subsetFont = require('subset-font');
+      text = usedCharacters();
+    } catch (error) {
+      console.warn('subset-font is not installed; copying fonts unchanged (npm install --no-save subset-font)');
+    }
+  }
+  for (const name of fonts) {
+    const source = `${fontDir}/${name}`;
+    let content = fs.readFileSync(path.join(staticDir, source));
+    if (subsetFont) {
+      const ext = path.extname(name).toLowerCase();
+      const targetFormat = ext === '.woff2' ? 'woff2' : ext === '.woff' ? 'woff' : 'sfnt';
+      const before = content.length;
+      content = await subsetFont(content, text, { targetFormat });
+      console.log(`subset ${source}: ${before} -> ${content.length} bytes`);
+    }
+    writeAsset(manifest, written, source, content);
+  }
+}
+
+// pruneDist removes fingerprinted files left behind by earlier builds, which
+// otherwise pile up quickly in watch mode.
+function pruneDist(dir, written) {
+  for (const entry of fs.readdirSync(dir, { withFileTypes: true })) {
+    const full = path.join(dir, entry.name);
+    if (entry.isDirectory()) {
+      pruneDist(full, written);
+    } else if (!written.has(full) && full !== path.join(distDir, 'manifest.json')) {
+      fs.unlinkSync(full);
+    }
+  }
+}
+
 async function buildAssets() {
   const manifest = {};
-  
+  const written = new Set();
+
   for (const asset of assets) {
-    const srcPath = path.join(staticDir, asset.source);
-    
-    if (!fs.existsSync(srcPath)) {
-      console.error(`Source file not found: ${srcPath}`);
-      process.exit(1);
-    }
-    
-    const ext = path.extname(asset.source);
-    const base = path.basename(asset.source, ext);
-    const outDir = path.join(distDir, path.dirname(asset.source));
-    
-    // Ensure output directory exists
-    if (!fs.existsSync(outDir)) {
-      fs.mkdirSync(outDir, { recursive: true });
-    }
-    
     try {
-      let result;
-      
-      if (asset.kind === 'css') {
-        // Build CSS with esbuild
-        result = await esbuild.build({
-          entryPoints: [srcPath],
-          outdir: outDir,
-          minify: !isDev,
-          bundle: false,
-          sourcemap: isDev,
-          target: 'es2015',
-          format: 'iife',
-          outExtension: { '.css': '.css' },
-          write: false,
-          // CSS-specific options
-          minifyIdentifiers: false,
-          minifySyntax: !isDev,
-          minifyWhitespace: !isDev
-        });
-      } else if (asset.kind === 'js') {
-        // Special handling for common.js - it's a collection of global functions
-        if (asset.source === 'js/common.js') {
-          let content = fs.readFileSync(srcPath, 'utf8');
-          if (!isDev) {
-            // Simple minification for common.js to preserve global functions
-            content = content
-              .replace(/\/\*[\s\S]*?\*\//g, '') // Remove block comments
-              .replace(/\/\/.*$/gm, '') // Remove line comments
-              .replace(/\s+/g, ' ') // Collapse whitespace
-              .trim();
-          }
-          result = { outputFiles: [{ contents: Buffer.from(content, 'utf8') }] };
-        } else {
-          // For other JavaScript files, use esbuild for proper minification
-          result = await esbuild.build({
-            entryPoints: [srcPath],
-            outdir: outDir,
-            minify: !isDev,
-            bundle: false,
-            sourcemap: isDev,
-            target: 'es2015',
-            format: 'iife',
-            outExtension: { '.js': '.js' },
-            write: false,
-            // Preserve function names and avoid aggressive optimizations
-            keepNames: true,
-            // Preserve string literals and identifiers
-            minifyIdentifiers: false,
-            minifySyntax: !isDev,
-            minifyWhitespace: !isDev
-          });
-        }
-      }
-      
-      if (result && result.outputFiles && result.outputFiles.length > 0) {
-        const outputFile = result.outputFiles[0];
-        const content = outputFile.contents;
-        
-        // Generate hash for fingerprinting
-        const hash = crypto.createHash('sha256').update(content).digest('hex').substring(0, 12);
-        const outName = `${base}-${hash}${ext}`;
-        const outPath = path.join(outDir, outName);
-        
-        // Write the file
-        fs.writeFileSync(outPath, content);
-        
-        // Add to manifest
-        const key = asset.source.replace(/\\/g, '/');
-        const rel = path.join('dist', path.dirname(asset.source), outName).replace(/\\/g, '/');
-        manifest[key] = rel;
-        
-        console.log(`built ${asset.source} -> ${rel}`);
-      }
+      const { content, map } = await buildScriptOrStyle(asset);
+      writeAsset(manifest, written, asset.source, content, map);
     } catch (error) {
-      console.error(`Error building ${asset.source}:`, error);
-      process.exit(1);
+      throw new Error(`Error building ${asset.source}: ${error.message}`);
     }
   }
-  
+  buildSvgs(manifest, written);
+  await buildFonts(manifest, written);
+
   // Write manifest
   const manifestPath = path.join(distDir, 'manifest.json');
   fs.writeFileSync(manifestPath, JSON.stringify(manifest, null, 2));
+  pruneDist(distDir, written);
   console.log(`wrote manifest to ${manifestPath}`);
 }
 
+// watch rebuilds on every change under the source directories. The server
+// reloads the manifest when it changes, so a browser refresh is enough.
+function watch() {
+  let timer = null;
+  let running = Promise.resolve();
+  const rebuild = () => {
+    clearTimeout(timer);
+    timer = setTimeout(() => {
+      running = running.then(() => buildAssets().catch(error => console.error(error.message)));
+    }, 100);
+  };
+  for (const dir of ['css', 'js', svgDir, fontDir]) {
+    const full = path.join(staticDir, dir);
+    if (fs.existsSync(full)) {
+      fs.watch(full, rebuild);
+    }
+  }
+  console.log('watching static/ for changes');
+}
+
 // Run the build
-buildAssets().catch(error => {
-  console.error('Build failed:', error);
+buildAssets().then(() => {
+  if (isWatch) {
+    watch();
+  }
+}).catch(error => {
+  console.error('Build failed:', error.message);
+  if (isWatch) {
+    watch();
+    return;
+  }
   process.exit(1);
 });
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/provider"
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/trakt"
+	"crovlune/plaxt/lib/trakt/trakttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/singleflight"
+)
+
+// useMockTrakt wires the package globals to s (a fresh memory store when nil)
+// and a Trakt client talking to a trakttest server, restoring them when the
+// test ends.
+func useMockTrakt(t *testing.T, s store.Store) (*trakttest.Server, store.Store) {
+	t.Helper()
+	prevStorage, prevTrakt, prevProviders := storage, traktSrv, providers
+	prevApiSf, prevCache, prevLog, prevTracker := apiSf, webhookCache, queueEventLog, drainStateTracker
+	prevStates := authStates
+	t.Cleanup(func() {
+		storage, traktSrv, providers = prevStorage, prevTrakt, prevProviders
+		apiSf, webhookCache, queueEventLog, drainStateTracker = prevApiSf, prevCache, prevLog, prevTracker
+		authStates = prevStates
+	})
+
+	srv := trakttest.NewServer(t)
+	if s == nil {
+		s = store.NewMemoryStore()
+	}
+	storage = s
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+	traktSrv.SetBaseURL(srv.URL)
+	queueEventLog = store.NewQueueEventLog(100)
+	traktSrv.SetQueueEventLog(queueEventLog)
+	providers = provider.NewRegistry(traktSrv)
+	apiSf = &singleflight.Group{}
+	webhookCache = newWebhookDedupeCache()
+	drainStateTracker = NewDrainStateTracker()
+	authStates = newAuthStateStore()
+	return srv, storage
+}
+
+func movieWebhook(event, plexUser string, viewOffset int) []byte {
+	payload, _ := json.Marshal(map[string]any{
+		"event":   event,
+		"owner":   true,
+		"Account": map[string]any{"id": 1, "title": plexUser},
+		"Server":  map[string]any{"title": "Plex", "uuid": "server-1"},
+		"Player":  map[string]any{"title": "TV", "uuid": "player-1"},
+		"Metadata": map[string]any{
+			"librarySectionType": "movie",
+			"type":               "movie",
+			"ratingKey":          "42",
+			"title":              "Heat",
+			"year":               1995,
+			"duration":           1000,
+			"viewOffset":         viewOffset,
+			"Guid":               []map[string]string{{"id": "tmdb://949"}},
+		},
+	})
+	return payload
+}
+
+// onPlayer moves a movieWebhook payload to another Plex player.
+func onPlayer(payload []byte, playerUUID string) []byte {
+	var hook map[string]any
+	_ = json.Unmarshal(payload, &hook)
+	hook["Player"] = map[string]any{"title": playerUUID, "uuid": playerUUID}
+	out, _ := json.Marshal(hook)
+	return out
+}
+
+func postWebhook(t *testing.T, id string, payload []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api?id="+url.QueryEscape(id), bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "plaxt.test"
+	rr := httptest.NewRecorder()
+	api(rr, req)
+	return rr
+}
+
+// backdateSession shifts the stored playback session for movieWebhook's
+// player and item back by d, as if that much real time had passed since its
+// last event.
+func backdateSession(t *testing.T, s store.Store, d time.Duration) {
+	t.Helper()
+	session, err := s.GetPlaybackSession(context.Background(), "player-1", "42")
+	require.NoError(t, err)
+	session.StartedAt = session.StartedAt.Add(-d)
+	session.UpdatedAt = session.UpdatedAt.Add(-d)
+	require.NoError(t, s.PutPlaybackSession(context.Background(), session))
+}
+
+func TestIntegrationAuthorizeOnboardsUserAgainstTrakt(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	stateToken := createStateToken(authState{Mode: "onboarding", Username: "alice"})
+
+	req := httptest.NewRequest(http.MethodGet, "/authorize?state="+url.QueryEscape(stateToken)+"&code=alice-trakt", nil)
+	req.Host = "plaxt.test"
+	rr := httptest.NewRecorder()
+	authorize(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "success", location.Query().Get("result"))
+
+	user := s.GetUser(location.Query().Get("id"))
+	require.NotNil(t, user)
+	tokens := srv.Requests(trakttest.RouteToken)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "alice-trakt", user.TraktDisplayName, "display name comes from /users/settings")
+	assert.Equal(t, srv.Requests(trakttest.RouteUserSettings)[0].Token(), user.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(trakttest.TokenLifetime), user.TokenExpiry, time.Minute)
+}
+
+func TestIntegrationWebhookScrobblesAndQueuesDuringOutage(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-alice", "refresh-alice", trakttest.User{Username: "alice"})
+	user := store.NewUser("alice", "access-alice", "refresh-alice", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+
+	rr := postWebhook(t, user.ID, movieWebhook("media.play", "alice", 100))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	started := srv.Requests(trakttest.RouteScrobbleStart)
+	require.Len(t, started, 1)
+	assert.Equal(t, "access-alice", started[0].Token())
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(started[0].Body, &body))
+	assert.EqualValues(t, 10, body["progress"])
+
+	backdateSession(t, s, time.Second)
+	srv.FailNext(trakttest.RouteScrobbleStop, http.StatusServiceUnavailable, 1)
+	rr = postWebhook(t, user.ID, movieWebhook("media.stop", "alice", 950))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	queued, err := s.DequeueScrobbles(context.Background(), user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, queued, 1, "the scrobble is queued while Trakt is down")
+	assert.Equal(t, "stop", queued[0].Action)
+}
+
+func TestIntegrationWebhookSkimmedStopIsNotAFinish(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-dave", "", trakttest.User{Username: "dave"})
+	user := store.NewUser("dave", "access-dave", "refresh-dave", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+
+	// Jumping straight to the credits is a pause, not a watch.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "dave", 0)).Code)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.stop", "dave", 950)).Code)
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobbleStop))
+	require.Len(t, srv.Requests(trakttest.RouteScrobblePause), 1)
+	session, err := s.GetPlaybackSession(context.Background(), "player-1", "42")
+	require.NoError(t, err)
+	assert.Equal(t, store.SessionStopped, session.State)
+	assert.Equal(t, 95, session.Progress)
+
+	// Watching it through again is a finish.
+	// (Offsets differ from the first viewing so webhook dedupe lets them through.)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "dave", 10)).Code)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.pause", "dave", 500)).Code)
+	backdateSession(t, s, time.Second)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.resume", "dave", 500)).Code)
+	backdateSession(t, s, time.Second)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.stop", "dave", 960)).Code)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStop), 1)
+	session, err = s.GetPlaybackSession(context.Background(), "player-1", "42")
+	require.NoError(t, err)
+	assert.Equal(t, 1, session.Pauses)
+	assert.GreaterOrEqual(t, session.PausedTime, time.Second)
+	assert.GreaterOrEqual(t, session.WatchTime, time.Second)
+}
+
+func TestIntegrationWebhookHoldsStartUntilMinimumWatchTime(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-erin", "", trakttest.User{Username: "erin"})
+	user := store.NewUser("erin", "access-erin", "refresh-erin", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+	traktSrv.SetStartDelay(100 * time.Millisecond)
+
+	// Flipping past: played and paused before the delay, so nothing is sent.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "erin", 0)).Code)
+	assert.Equal(t, 1, traktSrv.PendingStarts())
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.pause", "erin", 10)).Code)
+	assert.Zero(t, traktSrv.PendingStarts())
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobbleStart))
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobblePause))
+
+	// Still playing once the delay passes, so the start goes out.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.resume", "erin", 10)).Code)
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobbleStart), "start is held")
+	require.Eventually(t, func() bool {
+		return len(srv.Requests(trakttest.RouteScrobbleStart)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, traktSrv.PendingStarts())
+
+	// Later pauses are sent as usual.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.pause", "erin", 300)).Code)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobblePause), 1)
+}
+
+func TestIntegrationConcurrentPlayersLatestWins(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-gina", "", trakttest.User{Username: "gina"})
+	user := store.NewUser("gina", "access-gina", "refresh-gina", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "gina", 100)).Code)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, onPlayer(movieWebhook("media.play", "gina", 200), "player-2")).Code)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStart), 2, "the newer player takes over")
+
+	// The first player no longer reaches Trakt until it starts again.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.pause", "gina", 150)).Code)
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobblePause))
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, onPlayer(movieWebhook("media.pause", "gina", 250), "player-2")).Code)
+	pauses := srv.Requests(trakttest.RouteScrobblePause)
+	require.Len(t, pauses, 1)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(pauses[0].Body, &body))
+	assert.EqualValues(t, 25, body["progress"])
+}
+
+func TestIntegrationConcurrentPlayersFirstWins(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-hal", "", trakttest.User{Username: "hal"})
+	user := store.NewUser("hal", "access-hal", "refresh-hal", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+	traktSrv.SetConflictPolicy(trakt.ConflictFirstWins)
+
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "hal", 100)).Code)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, onPlayer(movieWebhook("media.play", "hal", 200), "player-2")).Code)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStart), 1, "the second player waits")
+
+	// Once the first player stops, the second one scrobbles again.
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.stop", "hal", 300)).Code)
+	require.Equal(t, http.StatusOK, postWebhook(t, user.ID, onPlayer(movieWebhook("media.resume", "hal", 210), "player-2")).Code)
+	starts := srv.Requests(trakttest.RouteScrobbleStart)
+	require.Len(t, starts, 2)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(starts[1].Body, &body))
+	assert.EqualValues(t, 21, body["progress"])
+
+	_, err := trakt.ParseConflictPolicy("loudest-wins")
+	assert.Error(t, err)
+}
+
+func TestIntegrationWebhookRefreshesExpiringToken(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-old", "refresh-old", trakttest.User{Username: "bob"})
+	user := store.NewUser("bob", "access-old", "refresh-old", nil, time.Now().Add(time.Hour), time.Now(), s)
+
+	rr := postWebhook(t, user.ID, movieWebhook("media.play", "bob", 0))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	require.Len(t, srv.Requests(trakttest.RouteToken), 1)
+	refreshed := s.GetUser(user.ID)
+	require.NotNil(t, refreshed)
+	assert.NotEqual(t, "access-old", refreshed.AccessToken)
+	started := srv.Requests(trakttest.RouteScrobbleStart)
+	require.Len(t, started, 1)
+	assert.Equal(t, refreshed.AccessToken, started[0].Token(), "scrobble uses the refreshed token")
+}
+
+func TestIntegrationDrainSendsQueuedScrobbles(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-carol", "", trakttest.User{Username: "carol"})
+	user := store.NewUser("carol", "access-carol", "refresh-carol", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+	ctx := context.Background()
+
+	title, year := "Heat", 1995
+	for i, action := range []string{"start", "stop"} {
+		event := store.QueuedScrobbleEvent{
+			UserID:     user.ID,
+			Action:     action,
+			Progress:   95,
+			PlayerUUID: "player-1",
+			RatingKey:  fmt.Sprintf("rk-%d", i),
+			CreatedAt:  time.Now().Add(time.Duration(i-2) * time.Minute),
+		}
+		event.ScrobbleBody = common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Title: &title, Year: &year}}
+		require.NoError(t, s.EnqueueScrobble(ctx, event))
+	}
+
+	drainUserQueue(ctx, s, providers, user.ID)
+
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStart), 1)
+	stops := srv.Requests(trakttest.RouteScrobbleStop)
+	require.Len(t, stops, 1)
+	assert.Equal(t, "access-carol", stops[0].Token())
+	size, err := s.GetQueueSize(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, size)
+}
@@ -0,0 +1,140 @@
+// Package adminauth authenticates admin dashboard accounts and decides which
+// role an admin action requires.
+package adminauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Role grants access to admin actions. Roles are ordered: each one may do
+// everything the roles below it may.
+type Role int
+
+const (
+	// RoleNone is the zero value; it allows nothing.
+	RoleNone Role = iota
+	// RoleViewer can read the dashboard, stats and queue status.
+	RoleViewer
+	// RoleOperator can also change users and queues: edit, scrobble, drain,
+	// restore from trash, add family members.
+	RoleOperator
+	// RoleAdmin can also delete users, family groups, members and linked
+	// providers.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// MarshalText encodes the role by name.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Allows reports whether r may perform an action that requires required.
+func (r Role) Allows(required Role) bool {
+	return r != RoleNone && r >= required
+}
+
+// ParseRole accepts "viewer", "operator" or "admin".
+func ParseRole(s string) (Role, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for role, name := range roleNames {
+		if name == s {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown admin role %q (want viewer, operator or admin)", s)
+}
+
+// Account is one admin login.
+type Account struct {
+	Username string
+	Role     Role
+	// digest is the SHA-256 of the password.
+	digest [sha256.Size]byte
+}
+
+// Accounts authenticates admin logins. The zero value has no accounts.
+type Accounts struct {
+	byName map[string]Account
+}
+
+// ParseAccounts parses a comma-separated list of "username:role:password"
+// entries. A password of the form "sha256:<hex>" is taken as the digest of
+// the real password so it need not be stored in plain text. Passwords may
+// contain ':' but not ','.
+func ParseAccounts(s string) (*Accounts, error) {
+	a := &Accounts{byName: make(map[string]Account)}
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("admin account %q must be username:role:password", parts[0])
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		account := Account{Username: strings.ToLower(parts[0]), Role: role}
+		if hexDigest, ok := strings.CutPrefix(parts[2], "sha256:"); ok {
+			raw, err := hex.DecodeString(hexDigest)
+			if err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("admin account %q has an invalid sha256 password digest", account.Username)
+			}
+			copy(account.digest[:], raw)
+		} else {
+			account.digest = sha256.Sum256([]byte(parts[2]))
+		}
+		if _, dup := a.byName[account.Username]; dup {
+			return nil, fmt.Errorf("admin account %q is listed twice", account.Username)
+		}
+		a.byName[account.Username] = account
+	}
+	return a, nil
+}
+
+// Enabled reports whether any account is configured. Without accounts the
+// dashboard is open, as it was before authentication existed.
+func (a *Accounts) Enabled() bool {
+	return a != nil && len(a.byName) > 0
+}
+
+// Len returns the number of accounts.
+func (a *Accounts) Len() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.byName)
+}
+
+// Authenticate returns the account for username if password matches.
+func (a *Accounts) Authenticate(username, password string) (Account, bool) {
+	if a == nil {
+		return Account{}, false
+	}
+	account, ok := a.byName[strings.ToLower(strings.TrimSpace(username))]
+	digest := sha256.Sum256([]byte(password))
+	// Compare even for unknown users so timing does not reveal which exist
+	match := subtle.ConstantTimeCompare(digest[:], account.digest[:]) == 1
+	if !ok || !match {
+		return Account{}, false
+	}
+	return account, true
+}
@@ -0,0 +1,54 @@
+package adminauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccounts(t *testing.T) {
+	digest := sha256.Sum256([]byte("hunter2"))
+	accounts, err := ParseAccounts("alice:admin:pa:ss, bob:viewer:sha256:" + hex.EncodeToString(digest[:]) + ",")
+	require.NoError(t, err)
+	assert.True(t, accounts.Enabled())
+	assert.Equal(t, 2, accounts.Len())
+
+	alice, ok := accounts.Authenticate("Alice", "pa:ss")
+	require.True(t, ok, "usernames ignore case and passwords may contain ':'")
+	assert.Equal(t, RoleAdmin, alice.Role)
+	bob, ok := accounts.Authenticate("bob", "hunter2")
+	require.True(t, ok, "sha256: passwords are digests")
+	assert.Equal(t, RoleViewer, bob.Role)
+
+	_, ok = accounts.Authenticate("bob", "sha256:"+hex.EncodeToString(digest[:]))
+	assert.False(t, ok, "the digest itself is not the password")
+	_, ok = accounts.Authenticate("carol", "")
+	assert.False(t, ok)
+
+	for _, bad := range []string{"alice:admin", "alice:root:pw", "alice:admin:sha256:zz", "a:admin:x,A:viewer:y"} {
+		_, err := ParseAccounts(bad)
+		assert.Error(t, err, bad)
+	}
+
+	var none *Accounts
+	assert.False(t, none.Enabled())
+	_, ok = none.Authenticate("alice", "pa:ss")
+	assert.False(t, ok)
+}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleOperator))
+	assert.True(t, RoleOperator.Allows(RoleOperator))
+	assert.False(t, RoleOperator.Allows(RoleAdmin))
+	assert.True(t, RoleViewer.Allows(RoleViewer))
+	assert.False(t, RoleViewer.Allows(RoleOperator))
+	assert.False(t, RoleNone.Allows(RoleNone))
+
+	role, err := ParseRole(" Operator ")
+	require.NoError(t, err)
+	assert.Equal(t, RoleOperator, role)
+	assert.Equal(t, "operator", role.String())
+}
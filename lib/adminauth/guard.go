@@ -0,0 +1,250 @@
+package adminauth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Login attempt outcomes.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Guard defaults.
+const (
+	DefaultLockoutThreshold = 5
+	DefaultLockoutBase      = 30 * time.Second
+	DefaultLockoutMax       = time.Hour
+
+	// maxAttempts bounds the audit log kept in memory.
+	maxAttempts = 200
+	// maxSources bounds how many client IPs the guard remembers.
+	maxSources = 1000
+	// successGap is how long repeated successful requests from the same IP
+	// and account count as one login. Basic auth resends credentials with
+	// every request, so auditing each would drown the log.
+	successGap = 30 * time.Minute
+	// failureMemory is how long a client's failures count towards a lockout.
+	failureMemory = 24 * time.Hour
+)
+
+// Attempt is one audited login.
+type Attempt struct {
+	Time     time.Time `json:"time"`
+	IP       string    `json:"ip"`
+	Username string    `json:"username"`
+	Outcome  string    `json:"outcome"`
+}
+
+// Lockout describes a client IP that failed to log in repeatedly.
+type Lockout struct {
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	Blocked     int       `json:"blocked"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Guard audits admin logins and locks out client IPs that keep failing.
+// After Threshold consecutive failures an IP is locked for BaseLockout,
+// doubling with every further failure up to MaxLockout. A successful login
+// clears the IP's failures. The zero value uses the defaults.
+type Guard struct {
+	Threshold   int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+
+	mu        sync.Mutex
+	attempts  []Attempt // newest last
+	sources   map[string]*Lockout
+	successes map[string]time.Time // ip + "\x00" + username -> last audited
+}
+
+// NewGuard creates a guard with the default thresholds.
+func NewGuard() *Guard {
+	return &Guard{}
+}
+
+// LockedFor returns how long ip must still wait before trying again, or 0.
+// A request turned away counts as blocked, not as an attempt.
+func (g *Guard) LockedFor(ip string, now time.Time) time.Duration {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	src, ok := g.sources[ip]
+	if !ok || !now.Before(src.LockedUntil) {
+		return 0
+	}
+	src.Blocked++
+	return src.LockedUntil.Sub(now)
+}
+
+// Fail records a failed login and returns the lockout it triggered, if any.
+func (g *Guard) Fail(ip, username string, now time.Time) time.Duration {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.audit(Attempt{Time: now, IP: ip, Username: username, Outcome: OutcomeFailure})
+
+	if g.sources == nil {
+		g.sources = make(map[string]*Lockout)
+	}
+	src, ok := g.sources[ip]
+	if ok && now.Sub(src.LastFailure) > failureMemory {
+		delete(g.sources, ip)
+		ok = false
+	}
+	if !ok {
+		if len(g.sources) >= maxSources {
+			g.evictOldest(now)
+		}
+		src = &Lockout{IP: ip}
+		g.sources[ip] = src
+	}
+	src.Failures++
+	src.LastFailure = now
+
+	threshold := g.Threshold
+	if threshold <= 0 {
+		threshold = DefaultLockoutThreshold
+	}
+	if src.Failures < threshold {
+		return 0
+	}
+	lock := g.lockoutFor(src.Failures - threshold)
+	src.LockedUntil = now.Add(lock)
+	return lock
+}
+
+// lockoutFor doubles the base lockout for each failure past the threshold.
+func (g *Guard) lockoutFor(extra int) time.Duration {
+	base, max := g.BaseLockout, g.MaxLockout
+	if base <= 0 {
+		base = DefaultLockoutBase
+	}
+	if max <= 0 {
+		max = DefaultLockoutMax
+	}
+	lock := base
+	for i := 0; i < extra && lock < max; i++ {
+		lock *= 2
+	}
+	if lock > max {
+		lock = max
+	}
+	return lock
+}
+
+// Succeed records a successful login and clears ip's failures. It reports
+// whether the login was audited (the first from ip for username in a while).
+func (g *Guard) Succeed(ip, username string, now time.Time) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.sources, ip)
+	if g.successes == nil {
+		g.successes = make(map[string]time.Time)
+	}
+	key := ip + "\x00" + username
+	if last, ok := g.successes[key]; ok && now.Sub(last) < successGap {
+		g.successes[key] = now
+		return false
+	}
+	if len(g.successes) >= maxSources {
+		for k, last := range g.successes {
+			if now.Sub(last) >= successGap {
+				delete(g.successes, k)
+			}
+		}
+	}
+	g.successes[key] = now
+	g.audit(Attempt{Time: now, IP: ip, Username: username, Outcome: OutcomeSuccess})
+	return true
+}
+
+// audit appends a to the bounded log. Callers must hold mu.
+func (g *Guard) audit(a Attempt) {
+	g.attempts = append(g.attempts, a)
+	if len(g.attempts) > maxAttempts {
+		g.attempts = g.attempts[len(g.attempts)-maxAttempts:]
+	}
+}
+
+// evictOldest forgets the client whose last failure is oldest, preferring
+// ones that are not locked. Callers must hold mu.
+func (g *Guard) evictOldest(now time.Time) {
+	var oldest *Lockout
+	for _, src := range g.sources {
+		if now.Before(src.LockedUntil) {
+			continue
+		}
+		if oldest == nil || src.LastFailure.Before(oldest.LastFailure) {
+			oldest = src
+		}
+	}
+	if oldest != nil {
+		delete(g.sources, oldest.IP)
+	}
+}
+
+// Prune drops audited logins older than before and returns how many it
+// dropped.
+func (g *Guard) Prune(before time.Time) int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	i := sort.Search(len(g.attempts), func(i int) bool { return !g.attempts[i].Time.Before(before) })
+	g.attempts = append([]Attempt(nil), g.attempts[i:]...)
+	return i
+}
+
+// Attempts returns the audited logins, newest first.
+func (g *Guard) Attempts() []Attempt {
+	if g == nil {
+		return []Attempt{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]Attempt, 0, len(g.attempts))
+	for i := len(g.attempts) - 1; i >= 0; i-- {
+		out = append(out, g.attempts[i])
+	}
+	return out
+}
+
+// Lockouts returns the clients with recent failures, currently locked ones
+// first, then by failure count.
+func (g *Guard) Lockouts(now time.Time) []Lockout {
+	if g == nil {
+		return []Lockout{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]Lockout, 0, len(g.sources))
+	for _, src := range g.sources {
+		if now.Sub(src.LastFailure) <= failureMemory {
+			out = append(out, *src)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		li, lj := now.Before(out[i].LockedUntil), now.Before(out[j].LockedUntil)
+		if li != lj {
+			return li
+		}
+		if out[i].Failures != out[j].Failures {
+			return out[i].Failures > out[j].Failures
+		}
+		return out[i].IP < out[j].IP
+	})
+	return out
+}
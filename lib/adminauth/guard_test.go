@@ -0,0 +1,58 @@
+package adminauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardLocksOutWithExponentialBackoff(t *testing.T) {
+	g := &Guard{Threshold: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute}
+	now := time.Now()
+
+	assert.Zero(t, g.Fail("10.0.0.1", "alice", now))
+	assert.Zero(t, g.Fail("10.0.0.1", "alice", now))
+	assert.Equal(t, time.Minute, g.Fail("10.0.0.1", "bob", now), "failures count per IP, not per account")
+	assert.Equal(t, 30*time.Second, g.LockedFor("10.0.0.1", now.Add(30*time.Second)))
+	assert.Zero(t, g.LockedFor("10.0.0.2", now), "other clients are not affected")
+	assert.Zero(t, g.LockedFor("10.0.0.1", now.Add(time.Minute)))
+
+	assert.Equal(t, 2*time.Minute, g.Fail("10.0.0.1", "alice", now.Add(time.Minute)))
+	assert.Equal(t, 4*time.Minute, g.Fail("10.0.0.1", "alice", now.Add(3*time.Minute)))
+	assert.Equal(t, 5*time.Minute, g.Fail("10.0.0.1", "alice", now.Add(7*time.Minute)), "capped at MaxLockout")
+
+	lockouts := g.Lockouts(now.Add(8 * time.Minute))
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, 6, lockouts[0].Failures)
+	assert.Equal(t, 1, lockouts[0].Blocked)
+
+	assert.True(t, g.Succeed("10.0.0.1", "alice", now.Add(time.Hour)))
+	assert.Empty(t, g.Lockouts(now.Add(time.Hour)), "a successful login clears failures")
+	assert.Zero(t, g.Fail("10.0.0.1", "alice", now.Add(time.Hour)))
+}
+
+func TestGuardAuditsAttempts(t *testing.T) {
+	g := NewGuard()
+	now := time.Now()
+	g.Fail("10.0.0.1", "mallory", now)
+	assert.True(t, g.Succeed("10.0.0.2", "alice", now.Add(time.Second)))
+	assert.False(t, g.Succeed("10.0.0.2", "alice", now.Add(time.Minute)), "repeated requests of one login are audited once")
+	assert.True(t, g.Succeed("10.0.0.3", "alice", now.Add(time.Minute)))
+	assert.True(t, g.Succeed("10.0.0.2", "alice", now.Add(2*time.Hour)))
+
+	attempts := g.Attempts()
+	require.Len(t, attempts, 4)
+	assert.Equal(t, Attempt{Time: now, IP: "10.0.0.1", Username: "mallory", Outcome: OutcomeFailure}, attempts[3])
+	assert.Equal(t, "10.0.0.2", attempts[0].IP, "newest first")
+
+	assert.Equal(t, 3, g.Prune(now.Add(time.Hour)))
+	require.Len(t, g.Attempts(), 1)
+	assert.Zero(t, g.Prune(now.Add(time.Hour)))
+
+	var none *Guard
+	assert.Zero(t, none.LockedFor("10.0.0.1", now))
+	assert.Empty(t, none.Attempts())
+	assert.Zero(t, none.Prune(now))
+}
@@ -0,0 +1,536 @@
+package adminauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDC defaults.
+const (
+	DefaultOIDCScopes      = "openid profile email"
+	DefaultOIDCGroupsClaim = "groups"
+
+	// oidcLoginTTL is how long a user has to finish logging in at the provider.
+	oidcLoginTTL = 10 * time.Minute
+	// maxPendingLogins bounds the logins started but not finished.
+	maxPendingLogins = 1000
+	// oidcClockSkew tolerates clock drift when checking token expiry.
+	oidcClockSkew = time.Minute
+	// jwksMinRefresh stops unknown key IDs from making us refetch the key set
+	// on every login.
+	jwksMinRefresh = time.Minute
+)
+
+var (
+	// ErrOIDCState is returned when a callback does not match a login in progress.
+	ErrOIDCState = errors.New("adminauth: unknown or expired login")
+	// ErrOIDCToken is returned when the provider's ID token fails verification.
+	ErrOIDCToken = errors.New("adminauth: invalid id token")
+	// ErrOIDCNoRole is returned when none of the user's groups maps to a role.
+	ErrOIDCNoRole = errors.New("adminauth: no admin role for this user")
+)
+
+// OIDCConfig configures login through an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes requested at login; DefaultOIDCScopes when empty. Providers such
+	// as Authelia only return groups when the "groups" scope is requested.
+	Scopes []string
+	// GroupsClaim names the ID token claim listing the user's groups.
+	GroupsClaim string
+	// RoleGroups maps provider groups to roles. A user in several groups gets
+	// the highest role; a user in none is refused.
+	RoleGroups map[string]Role
+	HTTPClient *http.Client
+}
+
+// OIDCIdentity is a user the provider vouched for.
+type OIDCIdentity struct {
+	Subject  string
+	Username string
+	Groups   []string
+	Role     Role
+}
+
+// OIDCProvider logs admins in with the authorization code flow (with PKCE).
+// Discovery happens on first use and is retried until it succeeds, so plaxt
+// starts even while the provider is down.
+type OIDCProvider struct {
+	cfg OIDCConfig
+
+	mu       sync.Mutex
+	meta     *oidcMetadata
+	keys     map[string]crypto.PublicKey
+	keysAt   time.Time
+	pending  map[string]oidcLogin
+	nowFunc  func() time.Time
+	randRead func([]byte) (int, error)
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	redirect string
+	next     string
+	expires  time.Time
+}
+
+// ParseRoleGroups parses a comma-separated list of "group:role" entries.
+// Group names may contain ':'; the role is taken after the last one.
+func ParseRoleGroups(s string) (map[string]Role, error) {
+	out := make(map[string]Role)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("role group %q must be group:role", entry)
+		}
+		role, err := ParseRole(entry[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		out[strings.TrimSpace(entry[:i])] = role
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no role groups configured")
+	}
+	return out, nil
+}
+
+// NewOIDCProvider validates cfg. It does not contact the provider.
+func NewOIDCProvider(cfg OIDCConfig) (*OIDCProvider, error) {
+	cfg.Issuer = strings.TrimRight(strings.TrimSpace(cfg.Issuer), "/")
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, errors.New("oidc issuer and client id are required")
+	}
+	if len(cfg.RoleGroups) == 0 {
+		return nil, errors.New("oidc needs at least one group mapped to a role")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = strings.Fields(DefaultOIDCScopes)
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = DefaultOIDCGroupsClaim
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{
+		cfg:      cfg,
+		pending:  make(map[string]oidcLogin),
+		nowFunc:  time.Now,
+		randRead: rand.Read,
+	}, nil
+}
+
+// Begin starts a login and returns the provider URL to send the browser to,
+// plus the state the callback must echo. redirectURI is plaxt's callback and
+// next is where to go after logging in.
+func (p *OIDCProvider) Begin(ctx context.Context, redirectURI, next string) (authURL, state string, err error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err = p.random()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := p.random()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := p.random()
+	if err != nil {
+		return "", "", err
+	}
+	now := p.nowFunc()
+	p.mu.Lock()
+	for k, login := range p.pending {
+		if now.After(login.expires) {
+			delete(p.pending, k)
+		}
+	}
+	if len(p.pending) >= maxPendingLogins {
+		p.mu.Unlock()
+		return "", "", errors.New("too many logins in progress")
+	}
+	p.pending[state] = oidcLogin{nonce: nonce, verifier: verifier, redirect: redirectURI, next: next, expires: now.Add(oidcLoginTTL)}
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", redirectURI)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + v.Encode(), state, nil
+}
+
+// Finish completes the login for state: it redeems code, verifies the ID
+// token and maps the user's groups to a role. It returns the identity and
+// the next URL passed to Begin. Each state can be finished once.
+func (p *OIDCProvider) Finish(ctx context.Context, state, code string) (*OIDCIdentity, string, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || p.nowFunc().After(login.expires) {
+		return nil, "", ErrOIDCState
+	}
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, login.next, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", login.redirect)
+	form.Set("code_verifier", login.verifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, login.next, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &tokens); err != nil {
+		return nil, login.next, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, login.next, fmt.Errorf("%w: token response has no id_token", ErrOIDCToken)
+	}
+	claims, err := p.verifyIDToken(ctx, tokens.IDToken, login.nonce)
+	if err != nil {
+		return nil, login.next, err
+	}
+
+	identity := &OIDCIdentity{Subject: claimString(claims, "sub"), Groups: claimStrings(claims, p.cfg.GroupsClaim)}
+	for _, name := range []string{"preferred_username", "email", "sub"} {
+		if v := claimString(claims, name); v != "" {
+			identity.Username = strings.ToLower(v)
+			break
+		}
+	}
+	for _, group := range identity.Groups {
+		if role := p.cfg.RoleGroups[group]; role > identity.Role {
+			identity.Role = role
+		}
+	}
+	if identity.Role == RoleNone {
+		return identity, login.next, ErrOIDCNoRole
+	}
+	return identity, login.next, nil
+}
+
+func (p *OIDCProvider) random() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := p.randRead(raw); err != nil {
+		return "", fmt.Errorf("failed to generate oidc state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// metadata fetches the discovery document once it is first needed.
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	meta := p.meta
+	p.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	meta = &oidcMetadata{}
+	if err := p.doJSON(req, meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimRight(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery returned issuer %q, want %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is missing endpoints")
+	}
+	p.mu.Lock()
+	p.meta = meta
+	p.mu.Unlock()
+	return meta, nil
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, out any) error {
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// verifyIDToken checks the token's signature against the provider's keys and
+// its issuer, audience, expiry and nonce, returning its claims.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrOIDCToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrOIDCToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrOIDCToken)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWS(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims", ErrOIDCToken)
+	}
+	now := p.nowFunc()
+	if strings.TrimRight(claimString(claims, "iss"), "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrOIDCToken)
+	}
+	aud := claimStrings(claims, "aud")
+	if !slices.Contains(aud, p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: wrong audience", ErrOIDCToken)
+	}
+	if azp := claimString(claims, "azp"); len(aud) > 1 && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: wrong authorized party", ErrOIDCToken)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrOIDCToken)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: wrong nonce", ErrOIDCToken)
+	}
+	if claimString(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrOIDCToken)
+	}
+	return claims, nil
+}
+
+// key returns the provider key kid, refetching the key set when kid is
+// unknown so provider key rotation needs no restart.
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	stale := p.nowFunc().Sub(p.keysAt) >= jwksMinRefresh
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrOIDCToken, kid)
+	}
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("oidc key fetch failed: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	p.mu.Lock()
+	p.keys, p.keysAt = keys, p.nowFunc()
+	p.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrOIDCToken, kid)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("bad rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifyJWS checks sig over signed for the algorithms providers use for ID
+// tokens. "none" and HMAC algorithms are rejected.
+func verifyJWS(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrOIDCToken, alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var err error
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		case "PS":
+			err = rsa.VerifyPSS(pub, hash, digest, sig, nil)
+		default:
+			err = errors.New("key type mismatch")
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(sig) != 2*size {
+			err = errors.New("key type mismatch")
+		} else if !ecdsa.Verify(pub, digest, new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])) {
+			err = errors.New("bad signature")
+		}
+	default:
+		err = errors.New("unsupported key")
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCToken, err)
+	}
+	return nil
+}
+
+func decodeSegment(seg string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func claimString(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// claimStrings reads a claim that may be a single string or a list.
+func claimStrings(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package adminauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdP is a minimal OpenID provider that issues ID tokens with the
+// claims the test sets.
+type fakeIdP struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+	// nonce and verifier are captured from the authorization request.
+	nonce     string
+	challenge string
+	verifier  string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "plaxt" || pass != "s3cret" || r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		idp.verifier = r.FormValue("code_verifier")
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t, idp.claims)})
+	})
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *fakeIdP) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	body, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login runs Begin and Finish with the claims the provider should return.
+func (idp *fakeIdP) login(t *testing.T, p *OIDCProvider, claims func(nonce string) map[string]any) (*OIDCIdentity, string, error) {
+	authURL, state, err := p.Begin(context.Background(), "https://plaxt.example/admin/oidc/callback", "/admin/queue")
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	idp.nonce, idp.challenge = q.Get("nonce"), q.Get("code_challenge")
+	idp.claims = claims(idp.nonce)
+	return p.Finish(context.Background(), state, "good-code")
+}
+
+func TestOIDCLoginMapsGroupsToRoles(t *testing.T) {
+	idp := newFakeIdP(t)
+	roles, err := ParseRoleGroups("plaxt-viewers:viewer, plaxt-admins:admin")
+	require.NoError(t, err)
+	p, err := NewOIDCProvider(OIDCConfig{Issuer: idp.srv.URL + "/", ClientID: "plaxt", ClientSecret: "s3cret", RoleGroups: roles})
+	require.NoError(t, err)
+
+	claims := func(groups ...any) func(string) map[string]any {
+		return func(nonce string) map[string]any {
+			return map[string]any{
+				"iss":                idp.srv.URL,
+				"aud":                "plaxt",
+				"sub":                "u-123",
+				"exp":                time.Now().Add(time.Minute).Unix(),
+				"nonce":              nonce,
+				"preferred_username": "Alice",
+				"groups":             groups,
+			}
+		}
+	}
+
+	identity, next, err := idp.login(t, p, claims("family", "plaxt-admins", "plaxt-viewers"))
+	require.NoError(t, err)
+	assert.Equal(t, "/admin/queue", next)
+	assert.Equal(t, "alice", identity.Username)
+	assert.Equal(t, "u-123", identity.Subject)
+	assert.Equal(t, RoleAdmin, identity.Role, "the highest mapped group wins")
+	challenge := sha256.Sum256([]byte(idp.verifier))
+	assert.Equal(t, idp.challenge, base64.RawURLEncoding.EncodeToString(challenge[:]), "PKCE verifier matches the challenge")
+
+	identity, _, err = idp.login(t, p, claims("plaxt-viewers"))
+	require.NoError(t, err)
+	assert.Equal(t, RoleViewer, identity.Role)
+
+	_, _, err = idp.login(t, p, claims("family"))
+	assert.ErrorIs(t, err, ErrOIDCNoRole)
+}
+
+func TestOIDCRejectsBadTokens(t *testing.T) {
+	idp := newFakeIdP(t)
+	p, err := NewOIDCProvider(OIDCConfig{Issuer: idp.srv.URL, ClientID: "plaxt", ClientSecret: "s3cret", RoleGroups: map[string]Role{"ops": RoleOperator}})
+	require.NoError(t, err)
+
+	valid := func(nonce string) map[string]any {
+		return map[string]any{
+			"iss":    idp.srv.URL,
+			"aud":    []string{"plaxt"},
+			"sub":    "u-1",
+			"exp":    time.Now().Add(time.Minute).Unix(),
+			"nonce":  nonce,
+			"email":  "ops@example.com",
+			"groups": "ops",
+		}
+	}
+	identity, _, err := idp.login(t, p, valid)
+	require.NoError(t, err)
+	assert.Equal(t, "ops@example.com", identity.Username, "email is used without preferred_username")
+	assert.Equal(t, RoleOperator, identity.Role, "a single group string is accepted")
+
+	for name, tweak := range map[string]func(map[string]any){
+		"wrong issuer":   func(c map[string]any) { c["iss"] = "https://evil.example" },
+		"wrong audience": func(c map[string]any) { c["aud"] = "someone-else" },
+		"expired":        func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"wrong nonce":    func(c map[string]any) { c["nonce"] = "replayed" },
+	} {
+		_, _, err := idp.login(t, p, func(nonce string) map[string]any {
+			c := valid(nonce)
+			tweak(c)
+			return c
+		})
+		assert.ErrorIs(t, err, ErrOIDCToken, name)
+	}
+
+	_, state, err := p.Begin(context.Background(), "https://plaxt.example/cb", "/admin")
+	require.NoError(t, err)
+	idp.claims = map[string]any{}
+	_, _, err = p.Finish(context.Background(), state, "good-code")
+	assert.ErrorIs(t, err, ErrOIDCToken, "tokens without the required claims are rejected")
+	_, _, err = p.Finish(context.Background(), state, "good-code")
+	assert.ErrorIs(t, err, ErrOIDCState, "a state is single use")
+	_, _, err = p.Finish(context.Background(), "made-up", "good-code")
+	assert.ErrorIs(t, err, ErrOIDCState)
+}
+
+func TestParseRoleGroups(t *testing.T) {
+	roles, err := ParseRoleGroups("cn=admins,ou=groups:admin")
+	assert.Error(t, err, "commas separate entries")
+	assert.Nil(t, roles)
+
+	roles, err = ParseRoleGroups("/plaxt:admins:admin, ops:operator")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Role{"/plaxt:admins": RoleAdmin, "ops": RoleOperator}, roles)
+
+	_, err = ParseRoleGroups("ops:root")
+	assert.Error(t, err)
+	_, err = ParseRoleGroups("")
+	assert.Error(t, err)
+}
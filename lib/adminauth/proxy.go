@@ -0,0 +1,96 @@
+package adminauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DefaultProxyGroupsHeader is the header Authelia and similar proxies use to
+// pass the user's groups.
+const DefaultProxyGroupsHeader = "Remote-Groups"
+
+// ProxyAuth trusts the user an authenticating reverse proxy (Authelia,
+// oauth2-proxy, …) puts in a request header. Headers are only believed on
+// connections from Trusted addresses; anyone else could set them.
+type ProxyAuth struct {
+	UserHeader   string
+	GroupsHeader string
+	// RoleGroups maps the proxy's groups to roles. When empty every user
+	// the proxy lets through is an admin.
+	RoleGroups map[string]Role
+	Trusted    []netip.Prefix
+}
+
+// ParseTrustedProxies parses a comma-separated list of IPs and CIDRs.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			out = append(out, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no trusted proxies configured")
+	}
+	return out, nil
+}
+
+// TrustedPeer reports whether peer (an IP without port) is a trusted proxy.
+func (p *ProxyAuth) TrustedPeer(peer string) bool {
+	addr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.Trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identify returns the user the proxy authenticated and their role. ok is
+// false when the request did not come through a trusted proxy or carries no
+// user. A known user in none of RoleGroups gets RoleNone.
+func (p *ProxyAuth) Identify(h http.Header, peer string) (username string, role Role, ok bool) {
+	if p == nil || !p.TrustedPeer(peer) {
+		return "", RoleNone, false
+	}
+	username = strings.ToLower(strings.TrimSpace(h.Get(p.UserHeader)))
+	if username == "" {
+		return "", RoleNone, false
+	}
+	if len(p.RoleGroups) == 0 {
+		return username, RoleAdmin, true
+	}
+	header := p.GroupsHeader
+	if header == "" {
+		header = DefaultProxyGroupsHeader
+	}
+	for _, value := range h.Values(header) {
+		for _, group := range strings.Split(value, ",") {
+			if r := p.RoleGroups[strings.TrimSpace(group)]; r > role {
+				role = r
+			}
+		}
+	}
+	return username, role, true
+}
@@ -0,0 +1,55 @@
+package adminauth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyAuthTrustsOnlyConfiguredProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5, ::1")
+	require.NoError(t, err)
+	p := &ProxyAuth{UserHeader: "Remote-User", Trusted: trusted}
+
+	h := http.Header{}
+	h.Set("Remote-User", " Alice ")
+	user, role, ok := p.Identify(h, "10.1.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, RoleAdmin, role, "without role groups every proxied user is an admin")
+
+	_, _, ok = p.Identify(h, "192.168.1.6")
+	assert.False(t, ok, "headers from other clients are ignored")
+	_, _, ok = p.Identify(h, "::ffff:192.168.1.5")
+	assert.True(t, ok, "IPv4-mapped addresses match")
+	_, _, ok = p.Identify(http.Header{}, "::1")
+	assert.False(t, ok, "no user header means no proxy login")
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParseTrustedProxies("")
+	assert.Error(t, err)
+}
+
+func TestProxyAuthMapsGroups(t *testing.T) {
+	trusted, err := ParseTrustedProxies("127.0.0.1")
+	require.NoError(t, err)
+	p := &ProxyAuth{
+		UserHeader: "X-Forwarded-User",
+		RoleGroups: map[string]Role{"plaxt-ops": RoleOperator, "plaxt-view": RoleViewer},
+		Trusted:    trusted,
+	}
+	h := http.Header{}
+	h.Set("X-Forwarded-User", "bob")
+	h.Set("Remote-Groups", "dev, plaxt-view,plaxt-ops")
+	_, role, ok := p.Identify(h, "127.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, RoleOperator, role)
+
+	p.GroupsHeader = "X-Forwarded-Groups"
+	_, role, ok = p.Identify(h, "127.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, RoleNone, role, "users outside the required groups get no role")
+}
@@ -0,0 +1,74 @@
+package adminauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionKey signs short-lived browser session tokens, such as the one that
+// records an account passed its second factor. Tokens are bound to a subject
+// and expire; a new key (e.g. on restart) invalidates every token.
+type SessionKey []byte
+
+// NewSessionKey returns a random 256-bit key.
+func NewSessionKey() (SessionKey, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	return key, nil
+}
+
+// Issue returns a token for subject (usually a username) valid until expires.
+func (k SessionKey) Issue(subject string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(subject)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + k.mac(payload)
+}
+
+// Check reports whether token was issued for subject and has not expired.
+func (k SessionKey) Check(token, subject string, now time.Time) bool {
+	got, ok := k.Subject(token, now)
+	return ok && got == subject
+}
+
+// Subject returns the subject token was issued for, if it is valid and has
+// not expired.
+func (k SessionKey) Subject(token string, now time.Time) (string, bool) {
+	if len(k) == 0 {
+		return "", false
+	}
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(k.mac(payload))) {
+		return "", false
+	}
+	name, expiry, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return "", false
+	}
+	return string(raw), true
+}
+
+func (k SessionKey) mac(payload string) string {
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
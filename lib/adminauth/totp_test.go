@@ -0,0 +1,97 @@
+package adminauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "time %d", unix)
+	}
+	_, err := TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestVerifyTOTPToleratesSkewAndRejectsReplay(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := TOTPStep(now)
+	code, err := TOTPCode(rfcSecret, step)
+	require.NoError(t, err)
+
+	got, ok := VerifyTOTP(rfcSecret, code, now, 0)
+	require.True(t, ok)
+	assert.Equal(t, step, got)
+	_, ok = VerifyTOTP(rfcSecret, code, now, got)
+	assert.False(t, ok, "a used code cannot be replayed")
+
+	_, ok = VerifyTOTP(rfcSecret, code, now.Add(TOTPPeriod), 0)
+	assert.True(t, ok, "one step of clock drift is accepted")
+	_, ok = VerifyTOTP(rfcSecret, code, now.Add(3*TOTPPeriod), 0)
+	assert.False(t, ok)
+	_, ok = VerifyTOTP(rfcSecret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestNewTOTPSecretAndURL(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+	_, err = TOTPCode(secret, 1)
+	assert.NoError(t, err)
+
+	url := TOTPURL("plaxt", "alice", secret)
+	assert.True(t, strings.HasPrefix(url, "otpauth://totp/plaxt:alice?"), url)
+	assert.Contains(t, url, "secret="+secret)
+	assert.Contains(t, url, "issuer=plaxt")
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+	require.Len(t, hashes, RecoveryCodeCount)
+	for i, code := range codes {
+		assert.NotEqual(t, code, hashes[i], "codes are stored hashed")
+	}
+
+	rest, ok := UseRecoveryCode(hashes, strings.ToUpper(strings.ReplaceAll(codes[3], "-", "")))
+	require.True(t, ok, "dashes and case are ignored")
+	assert.Len(t, rest, RecoveryCodeCount-1)
+	assert.Len(t, hashes, RecoveryCodeCount, "the input is not modified")
+	_, ok = UseRecoveryCode(rest, codes[3])
+	assert.False(t, ok, "a used code is gone")
+	_, ok = UseRecoveryCode(rest, "bogus")
+	assert.False(t, ok)
+}
+
+func TestSessionKeyBindsUserAndExpiry(t *testing.T) {
+	key, err := NewSessionKey()
+	require.NoError(t, err)
+	now := time.Now()
+	token := key.Issue("alice", now.Add(time.Hour))
+
+	assert.True(t, key.Check(token, "alice", now))
+	assert.False(t, key.Check(token, "bob", now), "tokens are bound to the account")
+	assert.False(t, key.Check(token, "alice", now.Add(2*time.Hour)), "tokens expire")
+	assert.False(t, key.Check(token+"0", "alice", now))
+
+	other, err := NewSessionKey()
+	require.NoError(t, err)
+	assert.False(t, other.Check(token, "alice", now), "a new key invalidates old tokens")
+	assert.False(t, SessionKey(nil).Check(token, "alice", now))
+}
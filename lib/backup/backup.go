@@ -0,0 +1,292 @@
+// Package backup snapshots the disk keystore into tar.gz archives, keeps a
+// bounded number of them locally, optionally uploads them to an S3-compatible
+// bucket and restores an archive back into place.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultInterval is used when Config.Interval is zero.
+	DefaultInterval = 24 * time.Hour
+	// DefaultRetention is used when Config.Retention is zero.
+	DefaultRetention = 7
+
+	archivePrefix = "keystore-"
+	archiveSuffix = ".tar.gz"
+	timeLayout    = "20060102T150405Z"
+)
+
+// ErrNoTarget is returned when neither a directory nor an S3 target is configured.
+var ErrNoTarget = errors.New("backup: no backup target configured")
+
+// Config controls where and how often backups are taken.
+type Config struct {
+	SourceDir string        // directory to archive (the disk keystore)
+	Dir       string        // local directory for archives; optional when S3 is set
+	Interval  time.Duration // time between backups
+	Retention int           // number of local archives to keep
+	S3        *S3Target     // optional remote copy
+}
+
+// Runner takes backups on a schedule.
+type Runner struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewRunner validates cfg and applies defaults.
+func NewRunner(cfg Config) (*Runner, error) {
+	if cfg.Dir == "" && cfg.S3 == nil {
+		return nil, ErrNoTarget
+	}
+	if cfg.SourceDir == "" {
+		cfg.SourceDir = "keystore"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	return &Runner{cfg: cfg, now: time.Now}, nil
+}
+
+// Start takes a backup immediately and then every Interval until ctx is done.
+func (r *Runner) Start(ctx context.Context) {
+	slog.Info("keystore backups enabled", "dir", r.cfg.Dir, "s3", r.cfg.S3 != nil, "interval", r.cfg.Interval, "retention", r.cfg.Retention)
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if name, err := r.RunOnce(ctx); err != nil {
+			slog.Error("keystore backup failed", "error", err)
+		} else {
+			slog.Info("keystore backup written", "archive", name)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce writes a single archive and prunes old ones. It returns the archive
+// file name.
+func (r *Runner) RunOnce(ctx context.Context) (string, error) {
+	var buf bytes.Buffer
+	if err := Create(r.cfg.SourceDir, &buf); err != nil {
+		return "", err
+	}
+	name := archivePrefix + r.now().UTC().Format(timeLayout) + archiveSuffix
+
+	if r.cfg.Dir != "" {
+		if err := os.MkdirAll(r.cfg.Dir, 0700); err != nil {
+			return "", fmt.Errorf("backup: create dir: %w", err)
+		}
+		// Write to a temp file first so a crash never leaves a truncated archive
+		// that looks complete.
+		path := filepath.Join(r.cfg.Dir, name)
+		if err := os.WriteFile(path+".tmp", buf.Bytes(), 0600); err != nil {
+			return "", fmt.Errorf("backup: write archive: %w", err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return "", fmt.Errorf("backup: finalize archive: %w", err)
+		}
+		if err := Prune(r.cfg.Dir, r.cfg.Retention); err != nil {
+			slog.Warn("keystore backup pruning failed", "dir", r.cfg.Dir, "error", err)
+		}
+	}
+	if r.cfg.S3 != nil {
+		if err := r.cfg.S3.Upload(ctx, name, buf.Bytes()); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// Create writes a gzip-compressed tar of sourceDir to w. Paths in the archive
+// are relative to sourceDir.
+func Create(sourceDir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("backup: archive %s: %w", sourceDir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("backup: close tar: %w", err)
+	}
+	return gz.Close()
+}
+
+// Prune removes the oldest archives in dir so at most keep remain.
+func Prune(dir string, keep int) error {
+	archives, err := List(dir)
+	if err != nil {
+		return err
+	}
+	if len(archives) <= keep {
+		return nil
+	}
+	for _, name := range archives[:len(archives)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the archive names in dir, oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), archivePrefix) && strings.HasSuffix(e.Name(), archiveSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	// The timestamp layout sorts lexically in chronological order
+	sort.Strings(names)
+	return names, nil
+}
+
+// Restore extracts archivePath into destDir. An existing destDir is moved
+// aside to destDir.pre-restore-<timestamp> rather than deleted; the new path
+// is returned so operators can clean it up.
+func Restore(archivePath, destDir string) (string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("backup: open archive: %w", err)
+	}
+	defer f.Close()
+
+	// Extract next to the destination first so a corrupt archive leaves the
+	// current keystore untouched.
+	staging := destDir + ".restoring"
+	if err := os.RemoveAll(staging); err != nil {
+		return "", err
+	}
+	if err := extract(f, staging); err != nil {
+		os.RemoveAll(staging)
+		return "", err
+	}
+
+	var previous string
+	if _, err := os.Stat(destDir); err == nil {
+		previous = destDir + ".pre-restore-" + time.Now().UTC().Format(timeLayout)
+		if err := os.Rename(destDir, previous); err != nil {
+			os.RemoveAll(staging)
+			return "", fmt.Errorf("backup: move current keystore aside: %w", err)
+		}
+	}
+	if err := os.Rename(staging, destDir); err != nil {
+		return previous, fmt.Errorf("backup: activate restored keystore: %w", err)
+	}
+	return previous, nil
+}
+
+func extract(r io.Reader, destDir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("backup: read gzip: %w", err)
+	}
+	defer gz.Close()
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("backup: read tar: %w", err)
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(hdr.Name))
+		// Reject entries that would escape destDir (e.g. "../../etc/passwd")
+		if !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("backup: invalid path in archive: %q", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
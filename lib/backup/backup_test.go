@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestCreateAndRestoreRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "keystore")
+	writeFile(t, filepath.Join(source, "u1.username"), "alice")
+	writeFile(t, filepath.Join(source, "family_groups", "g1", "group.json"), `{"id":"g1"}`)
+
+	archive := filepath.Join(root, "backup.tar.gz")
+	var buf bytes.Buffer
+	require.NoError(t, Create(source, &buf))
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0600))
+
+	// Simulate data loss after the backup
+	writeFile(t, filepath.Join(source, "u1.username"), "corrupted")
+
+	previous, err := Restore(archive, source)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(previous, source+".pre-restore-"))
+
+	data, err := os.ReadFile(filepath.Join(source, "u1.username"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", string(data))
+	data, err = os.ReadFile(filepath.Join(source, "family_groups", "g1", "group.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"g1"}`, string(data))
+	data, err = os.ReadFile(filepath.Join(previous, "u1.username"))
+	require.NoError(t, err)
+	assert.Equal(t, "corrupted", string(data))
+}
+
+func TestRunOnceKeepsOnlyRetainedArchives(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "keystore")
+	writeFile(t, filepath.Join(source, "u1.username"), "alice")
+	dir := filepath.Join(root, "backups")
+
+	runner, err := NewRunner(Config{SourceDir: source, Dir: dir, Retention: 2})
+	require.NoError(t, err)
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		at := base.Add(time.Duration(i) * time.Hour)
+		runner.now = func() time.Time { return at }
+		_, err := runner.RunOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	names, err := List(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keystore-20261001T020000Z.tar.gz", "keystore-20261001T030000Z.tar.gz"}, names)
+}
+
+func TestNewRunnerRequiresTarget(t *testing.T) {
+	_, err := NewRunner(Config{SourceDir: "keystore"})
+	assert.ErrorIs(t, err, ErrNoTarget)
+}
+
+func TestS3UploadSignsRequest(t *testing.T) {
+	var gotPath, gotAuth, gotHash string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	target := &S3Target{Endpoint: srv.URL, Bucket: "backups", Region: "eu-west-1", AccessKey: "AKID", SecretKey: "secret", Prefix: "plaxt/"}
+	require.NoError(t, target.Upload(context.Background(), "keystore-x.tar.gz", []byte("data")))
+
+	assert.Equal(t, "/backups/plaxt/keystore-x.tar.gz", gotPath)
+	assert.Equal(t, "data", string(gotBody))
+	assert.Equal(t, sha256Hex([]byte("data")), gotHash)
+	assert.Contains(t, gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/")
+	assert.Contains(t, gotAuth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=")
+}
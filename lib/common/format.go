@@ -0,0 +1,80 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultDateTimeLayout is used when no locale is configured or the locale
+// is not known.
+const DefaultDateTimeLayout = "2006-01-02 15:04 MST"
+
+// dateTimeLayouts maps lowercase BCP 47 tags, or just their language, to
+// the order and separators that locale writes dates and times in.
+var dateTimeLayouts = map[string]string{
+	"en":    "2 Jan 2006 15:04 MST",
+	"en-us": "Jan 2, 2006 3:04 PM MST",
+	"en-ca": "2006-01-02 3:04 PM MST",
+	"de":    "02.01.2006 15:04 MST",
+	"fr":    "02/01/2006 15:04 MST",
+	"es":    "02/01/2006 15:04 MST",
+	"it":    "02/01/2006 15:04 MST",
+	"pt":    "02/01/2006 15:04 MST",
+	"nl":    "02-01-2006 15:04 MST",
+	"pl":    "02.01.2006 15:04 MST",
+	"ru":    "02.01.2006 15:04 MST",
+	"sv":    "2006-01-02 15:04 MST",
+	"da":    "02.01.2006 15:04 MST",
+	"nb":    "02.01.2006 15:04 MST",
+	"fi":    "2.1.2006 15:04 MST",
+	"ja":    "2006/01/02 15:04 MST",
+	"zh":    "2006/01/02 15:04 MST",
+	"ko":    "2006. 01. 02. 15:04 MST",
+}
+
+// DateFormatter renders timestamps shown in the web UI in a configured
+// locale and time zone. The zero value writes DefaultDateTimeLayout in UTC.
+type DateFormatter struct {
+	layout   string
+	location *time.Location
+}
+
+// NewDateFormatter returns a formatter for locale (e.g. "de-DE" or "en_US")
+// in loc. Unknown locales fall back to their language, then to
+// DefaultDateTimeLayout; a nil loc means UTC.
+func NewDateFormatter(locale string, loc *time.Location) DateFormatter {
+	return DateFormatter{layout: localeLayout(locale), location: loc}
+}
+
+// KnownLocale reports whether locale, or its language, has its own layout.
+func KnownLocale(locale string) bool {
+	return localeLayout(locale) != ""
+}
+
+func localeLayout(locale string) string {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if i := strings.IndexByte(tag, '.'); i >= 0 {
+		// POSIX locales carry a charset, as in en_US.UTF-8
+		tag = tag[:i]
+	}
+	if layout, ok := dateTimeLayouts[tag]; ok {
+		return layout
+	}
+	if i := strings.IndexByte(tag, '-'); i > 0 {
+		return dateTimeLayouts[tag[:i]]
+	}
+	return ""
+}
+
+// DateTime formats t with minutes precision and the zone abbreviation.
+func (f DateFormatter) DateTime(t time.Time) string {
+	loc := f.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout := f.layout
+	if layout == "" {
+		layout = DefaultDateTimeLayout
+	}
+	return t.In(loc).Format(layout)
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateFormatter(t *testing.T) {
+	at := time.Date(2024, 3, 7, 18, 5, 0, 0, time.UTC)
+	berlin := time.FixedZone("CET", 3600)
+
+	tests := []struct {
+		name   string
+		locale string
+		loc    *time.Location
+		want   string
+	}{
+		{name: "zero value", want: "2024-03-07 18:05 UTC"},
+		{name: "unknown locale", locale: "xx-YY", want: "2024-03-07 18:05 UTC"},
+		{name: "us english", locale: "en-US", want: "Mar 7, 2024 6:05 PM UTC"},
+		{name: "posix tag", locale: "en_US.UTF-8", want: "Mar 7, 2024 6:05 PM UTC"},
+		{name: "language fallback", locale: "en-AU", want: "7 Mar 2024 18:05 UTC"},
+		{name: "german in berlin", locale: "de-DE", loc: berlin, want: "07.03.2024 19:05 CET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f DateFormatter
+			if tt.locale != "" || tt.loc != nil {
+				f = NewDateFormatter(tt.locale, tt.loc)
+			}
+			if got := f.DateTime(at); got != tt.want {
+				t.Errorf("DateTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if KnownLocale("xx") || !KnownLocale("fr-CA") {
+		t.Error("KnownLocale should accept languages with a layout only")
+	}
+}
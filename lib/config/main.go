@@ -8,6 +8,10 @@ import (
 var TraktClientId = getConfig("TRAKT_ID")
 var TraktClientSecret = getConfig("TRAKT_SECRET")
 
+// Optional Simkl credentials; Simkl dual-scrobbling is disabled when unset.
+var SimklClientId = getConfig("SIMKL_CLIENT_ID")
+var SimklClientSecret = getConfig("SIMKL_CLIENT_SECRET")
+
 func getConfig(name string) string {
 	if os.Getenv(name) != "" {
 		return os.Getenv(name)
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// Privacy logging keeps what users watch out of logs on shared hosts. When it
+// applies, titles, show names and display names are replaced by a keyed hash
+// so repeated log lines about the same item still correlate. The key is random
+// per process, so hashes cannot be matched against a list of known titles.
+var (
+	privacyMu    sync.RWMutex
+	privacyAll   bool
+	privacyUsers = map[string]bool{}
+	privacyKey   = newPrivacyKey()
+)
+
+func newPrivacyKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("logging: failed to generate privacy key: " + err.Error())
+	}
+	return key
+}
+
+// SetPrivacy turns privacy logging on or off for every user.
+func SetPrivacy(on bool) {
+	privacyMu.Lock()
+	defer privacyMu.Unlock()
+	privacyAll = on
+}
+
+// SetUserPrivacy records a user's own privacy logging preference.
+func SetUserPrivacy(userID string, on bool) {
+	privacyMu.Lock()
+	defer privacyMu.Unlock()
+	if on {
+		privacyUsers[userID] = true
+	} else {
+		delete(privacyUsers, userID)
+	}
+}
+
+// Private reports whether log lines about userID must be redacted.
+func Private(userID string) bool {
+	privacyMu.RLock()
+	defer privacyMu.RUnlock()
+	return privacyAll || privacyUsers[userID]
+}
+
+// Redact returns s unchanged, or its hash when privacy logging applies to
+// userID. Empty strings stay empty.
+func Redact(userID, s string) string {
+	if s == "" || !Private(userID) {
+		return s
+	}
+	mac := hmac.New(sha256.New, privacyKey)
+	mac.Write([]byte(s))
+	return "redacted:" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	t.Cleanup(func() {
+		SetPrivacy(false)
+		SetUserPrivacy("private", false)
+	})
+
+	assert.Equal(t, "Alien", Redact("someone", "Alien"))
+
+	SetUserPrivacy("private", true)
+	redacted := Redact("private", "Alien")
+	assert.True(t, strings.HasPrefix(redacted, "redacted:"))
+	assert.NotContains(t, redacted, "Alien")
+	assert.Equal(t, redacted, Redact("private", "Alien"), "hashes must correlate")
+	assert.NotEqual(t, redacted, Redact("private", "Aliens"))
+	assert.Equal(t, "", Redact("private", ""))
+	assert.Equal(t, "Alien", Redact("someone", "Alien"))
+
+	SetPrivacy(true)
+	assert.Equal(t, redacted, Redact("someone", "Alien"))
+
+	SetPrivacy(false)
+	SetUserPrivacy("private", false)
+	assert.Equal(t, "Alien", Redact("private", "Alien"))
+}
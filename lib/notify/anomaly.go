@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Alert kinds raised by FailureMonitor.
+const (
+	AlertFailureSpike = "failure_spike"
+	AlertUserFailing  = "user_failing"
+)
+
+// Alert describes an anomaly in scrobble outcomes.
+type Alert struct {
+	Kind     string    `json:"kind"`
+	Message  string    `json:"message"`
+	UserID   string    `json:"user_id,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Failures int       `json:"failures"`
+	Total    int       `json:"total"`
+	Window   string    `json:"window,omitempty"`
+	FiredAt  time.Time `json:"fired_at"`
+}
+
+// FailureMonitorConfig tunes FailureMonitor. Zero values use the defaults.
+type FailureMonitorConfig struct {
+	// Window is the rolling window the failure rate is computed over.
+	Window time.Duration
+	// FailureRate is the failure share (0-1] that triggers a spike alert.
+	FailureRate float64
+	// MinEvents is the number of outcomes needed before the rate is trusted.
+	MinEvents int
+	// UserFailures is the number of consecutive failures for one user that
+	// triggers a per-user alert.
+	UserFailures int
+	// Cooldown suppresses repeats of the same alert.
+	Cooldown time.Duration
+	// WebhookURL, when set, receives every alert as a JSON POST.
+	WebhookURL string
+	// WebhookKeys sign alert posts so the receiver can verify them; see Sign.
+	WebhookKeys []SigningKey
+}
+
+const (
+	defaultAlertWindow       = 15 * time.Minute
+	defaultAlertFailureRate  = 0.5
+	defaultAlertMinEvents    = 10
+	defaultAlertUserFailures = 5
+	defaultAlertCooldown     = time.Hour
+)
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// FailureMonitor watches scrobble outcomes and raises an Alert when the
+// failure rate over a rolling window crosses a threshold, or when a single
+// user fails several times in a row. Alerts are logged and optionally posted
+// to a webhook; the same alert fires at most once per cooldown.
+type FailureMonitor struct {
+	cfg    FailureMonitorConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	outcomes  []outcome
+	streaks   map[string]int
+	lastFired map[string]time.Time
+
+	// send delivers alerts; replaced in tests.
+	send func(Alert)
+}
+
+// NewFailureMonitor creates a monitor, filling unset config fields with defaults.
+func NewFailureMonitor(cfg FailureMonitorConfig) *FailureMonitor {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultAlertWindow
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = defaultAlertFailureRate
+	}
+	if cfg.MinEvents <= 0 {
+		cfg.MinEvents = defaultAlertMinEvents
+	}
+	if cfg.UserFailures <= 0 {
+		cfg.UserFailures = defaultAlertUserFailures
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultAlertCooldown
+	}
+	m := &FailureMonitor{
+		cfg:       cfg,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		streaks:   make(map[string]int),
+		lastFired: make(map[string]time.Time),
+	}
+	m.send = m.deliver
+	return m
+}
+
+// Config returns the effective configuration.
+func (m *FailureMonitor) Config() FailureMonitorConfig {
+	return m.cfg
+}
+
+// Observe records one scrobble outcome. It is safe to call on a nil monitor.
+func (m *FailureMonitor) Observe(userID, username string, failed bool, at time.Time) {
+	if m == nil {
+		return
+	}
+	var alerts []Alert
+
+	m.mu.Lock()
+	m.outcomes = append(m.outcomes, outcome{at: at, failed: failed})
+	cutoff := at.Add(-m.cfg.Window)
+	drop := 0
+	for drop < len(m.outcomes) && m.outcomes[drop].at.Before(cutoff) {
+		drop++
+	}
+	m.outcomes = m.outcomes[drop:]
+
+	failures := 0
+	for _, o := range m.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	total := len(m.outcomes)
+	if failed && total >= m.cfg.MinEvents && float64(failures)/float64(total) >= m.cfg.FailureRate &&
+		m.shouldFire(AlertFailureSpike, at) {
+		alerts = append(alerts, Alert{
+			Kind:     AlertFailureSpike,
+			Message:  fmt.Sprintf("%d of the last %d scrobbles failed within %s", failures, total, m.cfg.Window),
+			Failures: failures,
+			Total:    total,
+			Window:   m.cfg.Window.String(),
+			FiredAt:  at,
+		})
+	}
+
+	if userID != "" {
+		if !failed {
+			delete(m.streaks, userID)
+		} else {
+			m.streaks[userID]++
+			streak := m.streaks[userID]
+			if streak >= m.cfg.UserFailures && m.shouldFire(AlertUserFailing+"/"+userID, at) {
+				alerts = append(alerts, Alert{
+					Kind:     AlertUserFailing,
+					Message:  fmt.Sprintf("last %d scrobbles for %s failed", streak, username),
+					UserID:   userID,
+					Username: username,
+					Failures: streak,
+					Total:    streak,
+					FiredAt:  at,
+				})
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		m.send(alert)
+	}
+}
+
+// shouldFire reports whether key is outside its cooldown and, if so, starts
+// a new one. Callers must hold mu.
+func (m *FailureMonitor) shouldFire(key string, at time.Time) bool {
+	if last, ok := m.lastFired[key]; ok && at.Sub(last) < m.cfg.Cooldown {
+		return false
+	}
+	m.lastFired[key] = at
+	return true
+}
+
+func (m *FailureMonitor) deliver(alert Alert) {
+	slog.Error("scrobble anomaly detected",
+		"alert", alert.Kind,
+		"message", alert.Message,
+		"user_id", alert.UserID,
+		"username", alert.Username,
+		"failures", alert.Failures,
+		"total", alert.Total,
+	)
+	if m.cfg.WebhookURL == "" {
+		return
+	}
+	go func() {
+		if err := m.post(context.Background(), alert); err != nil {
+			slog.Warn("alert webhook delivery failed", "alert", alert.Kind, "error", err)
+		}
+	}()
+}
+
+// post sends payload (an Alert or Heartbeat) to the alert webhook.
+func (m *FailureMonitor) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := Sign(req, body, m.cfg.WebhookKeys, time.Now()); err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMonitor(cfg FailureMonitorConfig) (*FailureMonitor, *[]Alert) {
+	m := NewFailureMonitor(cfg)
+	var fired []Alert
+	m.send = func(a Alert) { fired = append(fired, a) }
+	return m, &fired
+}
+
+func TestFailureMonitorFiresOnFailureSpike(t *testing.T) {
+	m, fired := newTestMonitor(FailureMonitorConfig{MinEvents: 4, FailureRate: 0.5, UserFailures: 100})
+	now := time.Now()
+
+	m.Observe("u1", "alice", false, now)
+	m.Observe("u2", "bob", false, now)
+	m.Observe("u1", "alice", true, now)
+	assert.Empty(t, *fired, "too few events to trust the rate")
+
+	m.Observe("u2", "bob", true, now)
+	require.Len(t, *fired, 1)
+	assert.Equal(t, AlertFailureSpike, (*fired)[0].Kind)
+	assert.Equal(t, 2, (*fired)[0].Failures)
+	assert.Equal(t, 4, (*fired)[0].Total)
+
+	m.Observe("u3", "carol", true, now.Add(time.Minute))
+	assert.Len(t, *fired, 1, "cooldown suppresses repeats")
+}
+
+func TestFailureMonitorForgetsOutcomesOutsideWindow(t *testing.T) {
+	m, fired := newTestMonitor(FailureMonitorConfig{Window: time.Minute, MinEvents: 3, UserFailures: 100})
+	now := time.Now()
+
+	m.Observe("u1", "alice", true, now.Add(-10*time.Minute))
+	m.Observe("u2", "bob", true, now.Add(-10*time.Minute))
+	m.Observe("u3", "carol", false, now)
+	m.Observe("u3", "carol", true, now)
+	assert.Empty(t, *fired)
+}
+
+func TestFailureMonitorFiresWhenUserKeepsFailing(t *testing.T) {
+	m, fired := newTestMonitor(FailureMonitorConfig{MinEvents: 1000, UserFailures: 3})
+	now := time.Now()
+
+	m.Observe("u1", "alice", true, now)
+	m.Observe("u1", "alice", true, now)
+	m.Observe("u1", "alice", false, now)
+	m.Observe("u1", "alice", true, now)
+	m.Observe("u1", "alice", true, now)
+	assert.Empty(t, *fired, "a success resets the streak")
+
+	m.Observe("u2", "bob", true, now)
+	m.Observe("u1", "alice", true, now)
+	require.Len(t, *fired, 1)
+	assert.Equal(t, AlertUserFailing, (*fired)[0].Kind)
+	assert.Equal(t, "u1", (*fired)[0].UserID)
+	assert.Equal(t, 3, (*fired)[0].Failures)
+}
+
+func TestFailureMonitorPostsAlertToWebhook(t *testing.T) {
+	received := make(chan Alert, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		received <- alert
+	}))
+	defer srv.Close()
+
+	m := NewFailureMonitor(FailureMonitorConfig{MinEvents: 1000, UserFailures: 1, WebhookURL: srv.URL})
+	m.Observe("u1", "alice", true, time.Now())
+
+	select {
+	case alert := <-received:
+		assert.Equal(t, AlertUserFailing, alert.Kind)
+		assert.Equal(t, "alice", alert.Username)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestFailureMonitorSignsWebhook(t *testing.T) {
+	received := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received <- Verify(r.Header, body, []byte("s3cret"), 0, time.Now())
+	}))
+	defer srv.Close()
+
+	keys, err := ParseSigningKeys("k1:s3cret")
+	require.NoError(t, err)
+	m := NewFailureMonitor(FailureMonitorConfig{MinEvents: 1000, UserFailures: 1, WebhookURL: srv.URL, WebhookKeys: keys})
+	m.Observe("u1", "alice", true, time.Now())
+
+	select {
+	case err := <-received:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestFailureMonitorNilIsNoop(t *testing.T) {
+	var m *FailureMonitor
+	assert.NotPanics(t, func() { m.Observe("u1", "alice", true, time.Now()) })
+}
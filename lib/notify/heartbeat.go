@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AlertHeartbeat is the kind of the periodic summary sent by SendHeartbeat.
+const AlertHeartbeat = "heartbeat"
+
+// Heartbeat summarises one period of activity. Receiving it regularly shows
+// plaxt is alive; a heartbeat without webhooks shows Plex stopped calling it.
+type Heartbeat struct {
+	Kind          string         `json:"kind"`
+	Message       string         `json:"message"`
+	Period        string         `json:"period"`
+	Webhooks      int            `json:"webhooks_received"`
+	Scrobbles     int            `json:"scrobbles"`
+	Failures      int            `json:"failures"`
+	Queued        int            `json:"queued"`
+	TokenWarnings []TokenWarning `json:"token_warnings"`
+	SentAt        time.Time      `json:"sent_at"`
+}
+
+// TokenWarning names a user whose Trakt token expires soon or has expired.
+// Tokens are refreshed when a webhook arrives, so these users have been
+// quiet for a while.
+type TokenWarning struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SendHeartbeat fills in Kind and Message, logs hb and posts it to the alert
+// webhook when one is configured. It is safe to call on a nil monitor.
+func (m *FailureMonitor) SendHeartbeat(ctx context.Context, hb Heartbeat) error {
+	if m == nil {
+		return nil
+	}
+	hb.Kind = AlertHeartbeat
+	if hb.TokenWarnings == nil {
+		hb.TokenWarnings = []TokenWarning{}
+	}
+	summary := fmt.Sprintf("%d webhooks received, %d scrobbles sent, %d failed, %d queued, %d tokens expiring",
+		hb.Webhooks, hb.Scrobbles, hb.Failures, hb.Queued, len(hb.TokenWarnings))
+	hb.Message = fmt.Sprintf("last %s: %s", hb.Period, summary)
+	level := slog.LevelInfo
+	if hb.Webhooks == 0 {
+		hb.Message = fmt.Sprintf("no webhooks received in the last %s; check the Plex webhook still points at plaxt (%s)", hb.Period, summary)
+		level = slog.LevelWarn
+	}
+	slog.Log(ctx, level, "heartbeat",
+		"message", hb.Message,
+		"webhooks", hb.Webhooks,
+		"scrobbles", hb.Scrobbles,
+		"failures", hb.Failures,
+		"queued", hb.Queued,
+		"token_warnings", len(hb.TokenWarnings),
+	)
+	if m.cfg.WebhookURL == "" {
+		return nil
+	}
+	if err := m.post(ctx, hb); err != nil {
+		return fmt.Errorf("heartbeat delivery failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendHeartbeatPostsSummary(t *testing.T) {
+	var got Heartbeat
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	m := NewFailureMonitor(FailureMonitorConfig{WebhookURL: srv.URL})
+	require.NoError(t, m.SendHeartbeat(context.Background(), Heartbeat{Period: "24h0m0s", Webhooks: 12, Scrobbles: 10, Failures: 1, SentAt: time.Now()}))
+	assert.Equal(t, AlertHeartbeat, got.Kind)
+	assert.Equal(t, 10, got.Scrobbles)
+	assert.Equal(t, "last 24h0m0s: 12 webhooks received, 10 scrobbles sent, 1 failed, 0 queued, 0 tokens expiring", got.Message)
+	assert.NotNil(t, got.TokenWarnings)
+
+	require.NoError(t, m.SendHeartbeat(context.Background(), Heartbeat{Period: "24h0m0s", TokenWarnings: []TokenWarning{{UserID: "u1", Username: "alice"}}}))
+	assert.Contains(t, got.Message, "no webhooks received in the last 24h0m0s")
+	assert.Equal(t, "alice", got.TokenWarnings[0].Username)
+}
+
+func TestSendHeartbeatReportsDeliveryFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	m := NewFailureMonitor(FailureMonitorConfig{WebhookURL: srv.URL})
+	assert.ErrorContains(t, m.SendHeartbeat(context.Background(), Heartbeat{Period: "1h0m0s"}), "HTTP 502")
+	assert.NoError(t, NewFailureMonitor(FailureMonitorConfig{}).SendHeartbeat(context.Background(), Heartbeat{}), "without a webhook it only logs")
+
+	var none *FailureMonitor
+	assert.NoError(t, none.SendHeartbeat(context.Background(), Heartbeat{}))
+}
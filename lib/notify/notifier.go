@@ -3,6 +3,8 @@ package notify
 import (
 	"context"
 	"log/slog"
+
+	"crovlune/plaxt/lib/logging"
 )
 
 // Notifier provides banner notification functionality for family group events.
@@ -22,7 +24,7 @@ func (n *Notifier) NotifyPermanentFailure(ctx context.Context, groupID, memberID
 		"group_id", groupID,
 		"member_id", memberID,
 		"member_username", memberUsername,
-		"media_title", mediaTitle,
+		"media_title", logging.Redact(memberID, mediaTitle),
 		"error", errorMsg,
 		"notification_type", "permanent_failure",
 	)
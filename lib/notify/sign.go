@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on signed outgoing webhooks.
+const (
+	HeaderTimestamp = "X-Plaxt-Timestamp"
+	HeaderNonce     = "X-Plaxt-Nonce"
+	HeaderSignature = "X-Plaxt-Signature"
+)
+
+// DefaultSignatureTolerance is how far a timestamp may drift before Verify
+// treats the request as a replay.
+const DefaultSignatureTolerance = 5 * time.Minute
+
+var (
+	// ErrSignatureMissing is returned when a request carries no signature headers.
+	ErrSignatureMissing = errors.New("notify: webhook signature missing")
+	// ErrSignatureExpired is returned when the timestamp is outside the tolerance.
+	ErrSignatureExpired = errors.New("notify: webhook signature expired")
+	// ErrSignatureMismatch is returned when no signature matches the key.
+	ErrSignatureMismatch = errors.New("notify: webhook signature mismatch")
+)
+
+// SigningKey is a secret shared with one webhook target. ID names the key in
+// the signature header so receivers can rotate keys without downtime.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseSigningKeys parses a comma-separated list of "[id:]secret" entries.
+// A bare secret is named by the first 8 hex digits of its SHA-256. Every key
+// signs each request; list the new key first while rotating, then drop the
+// old one once receivers have switched.
+func ParseSigningKeys(s string) ([]SigningKey, error) {
+	var keys []SigningKey
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			id, secret = "", entry
+		}
+		id, secret = strings.TrimSpace(id), strings.TrimSpace(secret)
+		if secret == "" {
+			return nil, fmt.Errorf("signing key %q has an empty secret", id)
+		}
+		if id == "" {
+			id = KeyFingerprint([]byte(secret))
+		}
+		if strings.ContainsAny(id, "=, ") {
+			return nil, fmt.Errorf("signing key id %q must not contain '=', ',' or spaces", id)
+		}
+		keys = append(keys, SigningKey{ID: id, Secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
+// KeyFingerprint names a secret by the first 8 hex digits of its SHA-256.
+func KeyFingerprint(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Sign adds timestamp, nonce and signature headers to req for body. The
+// signature header holds one "id=hex" pair per key, each the HMAC-SHA256 of
+// "<timestamp>.<nonce>.<body>". It does nothing without keys.
+func Sign(req *http.Request, body []byte, keys []SigningKey, now time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key.ID+"="+signature(key.Secret, timestamp, nonceHex, body))
+	}
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonceHex)
+	req.Header.Set(HeaderSignature, strings.Join(parts, ","))
+	return nil
+}
+
+// Verify checks the signature headers of a received webhook against secret.
+// Receivers should also remember nonces for the tolerance window to reject
+// replays within it.
+func Verify(header http.Header, body, secret []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, nonce, sigs := header.Get(HeaderTimestamp), header.Get(HeaderNonce), header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || sigs == "" {
+		return ErrSignatureMissing
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureMismatch
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultSignatureTolerance
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return ErrSignatureExpired
+	}
+	want := signature(secret, timestamp, nonce, body)
+	for _, part := range strings.Split(sigs, ",") {
+		_, got, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && hmac.Equal([]byte(got), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func signature(secret []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSigningKeys(t *testing.T) {
+	keys, err := ParseSigningKeys(" new:s3cret , legacy ,")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, SigningKey{ID: "new", Secret: []byte("s3cret")}, keys[0])
+	assert.Equal(t, KeyFingerprint([]byte("legacy")), keys[1].ID)
+	assert.Len(t, keys[1].ID, 8)
+
+	_, err = ParseSigningKeys("empty:")
+	assert.Error(t, err)
+	_, err = ParseSigningKeys("bad id:secret")
+	assert.Error(t, err)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"kind":"failure_spike"}`)
+	keys := []SigningKey{{ID: "new", Secret: []byte("new-secret")}, {ID: "old", Secret: []byte("old-secret")}}
+	now := time.Now()
+	req, _ := http.NewRequest(http.MethodPost, "http://example.test", nil)
+	require.NoError(t, Sign(req, body, keys, now))
+	assert.Regexp(t, `^new=[0-9a-f]{64},old=[0-9a-f]{64}$`, req.Header.Get(HeaderSignature))
+
+	assert.NoError(t, Verify(req.Header, body, []byte("new-secret"), 0, now))
+	assert.NoError(t, Verify(req.Header, body, []byte("old-secret"), 0, now), "receivers still on the old key verify during rotation")
+	assert.ErrorIs(t, Verify(req.Header, body, []byte("other"), 0, now), ErrSignatureMismatch)
+	assert.ErrorIs(t, Verify(req.Header, []byte(`{"kind":"forged"}`), []byte("new-secret"), 0, now), ErrSignatureMismatch)
+	assert.ErrorIs(t, Verify(req.Header, body, []byte("new-secret"), time.Minute, now.Add(2*time.Minute)), ErrSignatureExpired)
+	assert.ErrorIs(t, Verify(http.Header{}, body, []byte("new-secret"), 0, now), ErrSignatureMissing)
+
+	again, _ := http.NewRequest(http.MethodPost, "http://example.test", nil)
+	require.NoError(t, Sign(again, body, keys, now))
+	assert.NotEqual(t, req.Header.Get(HeaderNonce), again.Header.Get(HeaderNonce))
+
+	unsigned, _ := http.NewRequest(http.MethodPost, "http://example.test", nil)
+	require.NoError(t, Sign(unsigned, body, nil, now))
+	assert.Empty(t, unsigned.Header.Get(HeaderSignature))
+}
@@ -0,0 +1,153 @@
+// Package plex is a minimal plex.tv API client used to register plaxt's
+// webhook on a user's Plex account so they do not have to paste it by hand.
+package plex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	apiBaseURL  = "https://plex.tv"
+	webhookPath = "/api/v2/user/webhooks"
+	productName = "Plaxt"
+)
+
+var (
+	// ErrUnauthorized is returned when plex.tv rejects the X-Plex-Token.
+	ErrUnauthorized = errors.New("plex: token rejected")
+	// ErrPlexPassRequired is returned when the account may not manage
+	// webhooks, which Plex limits to Plex Pass subscribers.
+	ErrPlexPassRequired = errors.New("plex: webhooks require a Plex Pass subscription")
+)
+
+// Client talks to plex.tv on behalf of the owner of an X-Plex-Token.
+type Client struct {
+	// BaseURL overrides the plex.tv origin; tests point it at a fake server.
+	BaseURL string
+	// ClientID is sent as X-Plex-Client-Identifier.
+	ClientID   string
+	httpClient *http.Client
+}
+
+// New constructs a plex.tv client with a 10s timeout.
+func New(clientID string) *Client {
+	return &Client{
+		BaseURL:    apiBaseURL,
+		ClientID:   clientID,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Webhooks returns the webhook URLs registered on the token's account.
+func (c *Client) Webhooks(ctx context.Context, token string) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, nil, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var hooks []struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hooks); err != nil {
+		return nil, fmt.Errorf("plex webhooks decode error: %w", err)
+	}
+	urls := make([]string, 0, len(hooks))
+	for _, h := range hooks {
+		urls = append(urls, h.URL)
+	}
+	return urls, nil
+}
+
+// SetWebhooks replaces the account's webhook list with urls.
+func (c *Client) SetWebhooks(ctx context.Context, token string, urls []string) error {
+	form := url.Values{}
+	for _, u := range urls {
+		form.Add("urls[]", u)
+	}
+	resp, err := c.do(ctx, http.MethodPost, form, token)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// EnsureWebhook adds webhookURL to the account unless it is already
+// registered, keeping every other webhook in place. added reports whether
+// the list had to be changed.
+func (c *Client) EnsureWebhook(ctx context.Context, token, webhookURL string) (added bool, err error) {
+	urls, err := c.Webhooks(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range urls {
+		if u == webhookURL {
+			return false, nil
+		}
+	}
+	if err := c.SetWebhooks(ctx, token, append(urls, webhookURL)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// do executes a request against the webhooks endpoint and converts non-2xx
+// responses into errors.
+func (c *Client) do(ctx context.Context, method string, form url.Values, token string) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+webhookPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Token", token)
+	req.Header.Set("X-Plex-Client-Identifier", c.ClientID)
+	req.Header.Set("X-Plex-Product", productName)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("plex %s %s: %w", method, webhookPath, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		resp.Body.Close()
+		return nil, ErrUnauthorized
+	case resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, ErrPlexPassRequired
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := strings.TrimSpace(string(b))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, &HTTPError{Code: resp.StatusCode, Message: msg}
+	}
+	return resp, nil
+}
+
+// HTTPError is returned for other non-2xx plex.tv responses.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("plex http %d: %s", e.Code, e.Message)
+}
@@ -0,0 +1,77 @@
+package plex
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePlex serves the webhooks endpoint backed by an in-memory list.
+func fakePlex(t *testing.T, hooks *[]string, status int) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, webhookPath, r.URL.Path)
+		assert.Equal(t, "plex-token", r.Header.Get("X-Plex-Token"))
+		assert.Equal(t, "plaxt-test", r.Header.Get("X-Plex-Client-Identifier"))
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[`))
+			for i, h := range *hooks {
+				if i > 0 {
+					w.Write([]byte(`,`))
+				}
+				w.Write([]byte(`{"url":"` + h + `"}`))
+			}
+			w.Write([]byte(`]`))
+		case http.MethodPost:
+			require.NoError(t, r.ParseForm())
+			*hooks = r.PostForm["urls[]"]
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	c := New("plaxt-test")
+	c.BaseURL = srv.URL
+	return c
+}
+
+func TestEnsureWebhookAddsOnce(t *testing.T) {
+	hooks := []string{"https://other.example/hook"}
+	c := fakePlex(t, &hooks, 0)
+	ctx := context.Background()
+
+	added, err := c.EnsureWebhook(ctx, "plex-token", "https://plaxt.example/api?id=abc")
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, []string{"https://other.example/hook", "https://plaxt.example/api?id=abc"}, hooks,
+		"existing webhooks are kept")
+
+	added, err = c.EnsureWebhook(ctx, "plex-token", "https://plaxt.example/api?id=abc")
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Len(t, hooks, 2)
+}
+
+func TestEnsureWebhookErrors(t *testing.T) {
+	var hooks []string
+	ctx := context.Background()
+
+	_, err := fakePlex(t, &hooks, http.StatusUnauthorized).EnsureWebhook(ctx, "plex-token", "https://plaxt.example/api")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = fakePlex(t, &hooks, http.StatusForbidden).EnsureWebhook(ctx, "plex-token", "https://plaxt.example/api")
+	assert.ErrorIs(t, err, ErrPlexPassRequired)
+
+	_, err = fakePlex(t, &hooks, http.StatusServiceUnavailable).EnsureWebhook(ctx, "plex-token", "https://plaxt.example/api")
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+}
@@ -0,0 +1,168 @@
+// Package preferences defines the per-user preferences document stored as
+// store.UserPreferences: the fields a user can set, the JSON Schema describing
+// them, and validation of documents against it.
+//
+// New per-user toggles are added as a Field plus a member of Preferences.
+package preferences
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"crovlune/plaxt/plexhooks"
+)
+
+// Field types supported by the schema.
+const (
+	TypeBoolean     = "boolean"
+	TypeStringArray = "string[]"
+)
+
+// Field describes one preference.
+type Field struct {
+	Name        string
+	Type        string
+	Description string
+	Enum        []string // allowed values of a TypeStringArray item; nil allows any
+}
+
+// Fields lists every preference a user can set.
+var Fields = []Field{
+	{
+		Name:        "paused",
+		Type:        TypeBoolean,
+		Description: "Stop scrobbling for this user without removing them.",
+	},
+	{
+		Name:        "libraries",
+		Type:        TypeStringArray,
+		Description: "Only scrobble items from these Plex library sections, matched by title ignoring case. Empty scrobbles every library.",
+	},
+	{
+		Name:        "exclude_types",
+		Type:        TypeStringArray,
+		Description: "Media types that are never scrobbled.",
+		Enum:        []string{"movie", "episode"},
+	},
+	{
+		Name:        "privacy_logging",
+		Type:        TypeBoolean,
+		Description: "Replace this user's media titles and display names in logs with a hash.",
+	},
+}
+
+// Preferences is the decoded document. The zero value scrobbles everything.
+type Preferences struct {
+	Paused         bool     `json:"paused,omitempty"`
+	Libraries      []string `json:"libraries,omitempty"`
+	ExcludeTypes   []string `json:"exclude_types,omitempty"`
+	PrivacyLogging bool     `json:"privacy_logging,omitempty"`
+}
+
+// ValidationError reports the first field of a document that breaks the schema.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Schema returns the JSON Schema of the preferences document.
+func Schema() map[string]any {
+	properties := make(map[string]any, len(Fields))
+	for _, f := range Fields {
+		prop := map[string]any{"description": f.Description}
+		switch f.Type {
+		case TypeBoolean:
+			prop["type"] = "boolean"
+		case TypeStringArray:
+			items := map[string]any{"type": "string"}
+			if f.Enum != nil {
+				items["enum"] = f.Enum
+			}
+			prop["type"] = "array"
+			prop["items"] = items
+		}
+		properties[f.Name] = prop
+	}
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Plaxt user preferences",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+}
+
+// Parse validates data against the schema and decodes it.
+func Parse(data []byte) (Preferences, error) {
+	var prefs Preferences
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return prefs, &ValidationError{Message: "preferences must be a JSON object"}
+	}
+	for name, raw := range object {
+		i := slices.IndexFunc(Fields, func(f Field) bool { return f.Name == name })
+		if i < 0 {
+			return prefs, &ValidationError{Field: name, Message: "unknown preference"}
+		}
+		if err := Fields[i].check(raw); err != nil {
+			return prefs, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&prefs); err != nil {
+		return prefs, &ValidationError{Message: err.Error()}
+	}
+	return prefs, nil
+}
+
+func (f Field) check(raw json.RawMessage) error {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return &ValidationError{Field: f.Name, Message: "must not be null"}
+	}
+	switch f.Type {
+	case TypeBoolean:
+		var v bool
+		if json.Unmarshal(raw, &v) != nil {
+			return &ValidationError{Field: f.Name, Message: "must be a boolean"}
+		}
+	case TypeStringArray:
+		var v []string
+		if json.Unmarshal(raw, &v) != nil {
+			return &ValidationError{Field: f.Name, Message: "must be an array of strings"}
+		}
+		for _, item := range v {
+			if f.Enum != nil && !slices.Contains(f.Enum, item) {
+				return &ValidationError{Field: f.Name, Message: fmt.Sprintf("%q is not one of %s", item, strings.Join(f.Enum, ", "))}
+			}
+		}
+	}
+	return nil
+}
+
+// SkipReason reports why a webhook for meta should not be scrobbled under
+// these preferences: "paused", "library" or "type". It returns "" to scrobble.
+func (p Preferences) SkipReason(meta plexhooks.Metadata) string {
+	if p.Paused {
+		return "paused"
+	}
+	if len(p.Libraries) > 0 && !slices.ContainsFunc(p.Libraries, func(l string) bool {
+		return strings.EqualFold(strings.TrimSpace(l), meta.LibrarySectionTitle)
+	}) {
+		return "library"
+	}
+	if slices.Contains(p.ExcludeTypes, strings.ToLower(meta.Type)) {
+		return "type"
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package preferences
+
+import (
+	"testing"
+
+	"crovlune/plaxt/plexhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValidatesAgainstSchema(t *testing.T) {
+	prefs, err := Parse([]byte(`{"paused":false,"libraries":["Movies"],"exclude_types":["episode"],"privacy_logging":true}`))
+	require.NoError(t, err)
+	assert.Equal(t, Preferences{Libraries: []string{"Movies"}, ExcludeTypes: []string{"episode"}, PrivacyLogging: true}, prefs)
+
+	prefs, err = Parse([]byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, Preferences{}, prefs)
+
+	for doc, want := range map[string]string{
+		`[]`:                         "preferences must be a JSON object",
+		`null`:                       "preferences must be a JSON object",
+		`{"theme":"dark"}`:           "theme: unknown preference",
+		`{"paused":"yes"}`:           "paused: must be a boolean",
+		`{"paused":null}`:            "paused: must not be null",
+		`{"libraries":"Movies"}`:     "libraries: must be an array of strings",
+		`{"exclude_types":["clip"]}`: `exclude_types: "clip" is not one of movie, episode`,
+	} {
+		_, err := Parse([]byte(doc))
+		var verr *ValidationError
+		if assert.ErrorAs(t, err, &verr, doc) {
+			assert.Equal(t, want, verr.Error(), doc)
+		}
+	}
+}
+
+func TestSchemaDescribesEveryField(t *testing.T) {
+	schema := Schema()
+	assert.Equal(t, false, schema["additionalProperties"])
+	properties := schema["properties"].(map[string]any)
+	require.Len(t, properties, len(Fields))
+	excludeTypes := properties["exclude_types"].(map[string]any)
+	assert.Equal(t, "array", excludeTypes["type"])
+	assert.Equal(t, []string{"movie", "episode"}, excludeTypes["items"].(map[string]any)["enum"])
+	assert.Equal(t, "boolean", properties["paused"].(map[string]any)["type"])
+}
+
+func TestSkipReason(t *testing.T) {
+	movie := plexhooks.Metadata{Type: "movie", LibrarySectionTitle: "Movies"}
+	episode := plexhooks.Metadata{Type: "episode", LibrarySectionTitle: "Anime"}
+
+	assert.Empty(t, Preferences{}.SkipReason(movie))
+	assert.Equal(t, "paused", Preferences{Paused: true}.SkipReason(movie))
+	assert.Empty(t, Preferences{Libraries: []string{" movies "}}.SkipReason(movie))
+	assert.Equal(t, "library", Preferences{Libraries: []string{"Movies"}}.SkipReason(episode))
+	assert.Equal(t, "type", Preferences{ExcludeTypes: []string{"episode"}}.SkipReason(episode))
+	assert.Empty(t, Preferences{ExcludeTypes: []string{"episode"}}.SkipReason(movie))
+}
@@ -0,0 +1,119 @@
+// Package provider defines the contract implemented by scrobble targets so
+// webhook dispatch, the offline queue drain and the retry worker do not depend
+// on a specific tracker's API.
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+)
+
+// DefaultName identifies the primary provider. Queued events without an
+// explicit provider are routed here for backwards compatibility.
+const DefaultName = "trakt"
+
+var (
+	// ErrUnknownProvider is returned when no provider is registered under a name.
+	ErrUnknownProvider = errors.New("provider: unknown provider")
+	// ErrRefreshNotSupported is returned by providers whose tokens do not expire.
+	ErrRefreshNotSupported = errors.New("provider: token refresh not supported")
+)
+
+// Token is the result of an OAuth refresh.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+	IssuedAt     time.Time // zero when the provider does not report it
+}
+
+// ScrobbleProvider sends scrobbles to a single tracking service.
+type ScrobbleProvider interface {
+	// Name is the stable, lowercase identifier used to key stored tokens (e.g. "simkl").
+	Name() string
+	// Scrobble sends a start/pause/stop event for the given media. Transient
+	// failures (429/5xx, timeouts) must mention the status code so callers can
+	// decide whether to retry.
+	Scrobble(ctx context.Context, action string, item common.CacheItem, accessToken string) error
+	// HealthCheck reports whether the provider's API is reachable.
+	HealthCheck(ctx context.Context) error
+	// RefreshToken exchanges a refresh token for a new access token.
+	RefreshToken(ctx context.Context, refreshToken, redirectURI string) (Token, error)
+}
+
+// Registry holds the configured providers keyed by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]ScrobbleProvider
+}
+
+// NewRegistry returns a registry containing the given providers.
+func NewRegistry(providers ...ScrobbleProvider) *Registry {
+	r := &Registry{providers: make(map[string]ScrobbleProvider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds or replaces a provider.
+func (r *Registry) Register(p ScrobbleProvider) {
+	if p == nil {
+		return
+	}
+	r.mu.Lock()
+	r.providers[strings.ToLower(p.Name())] = p
+	r.mu.Unlock()
+}
+
+// Get returns the provider registered under name. An empty name resolves to
+// DefaultName.
+func (r *Registry) Get(name string) (ScrobbleProvider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultName
+	}
+	if r == nil {
+		return nil, ErrUnknownProvider
+	}
+	r.mu.RLock()
+	p, ok := r.providers[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// All returns every registered provider sorted by name.
+func (r *Registry) All() []ScrobbleProvider {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ScrobbleProvider, 0, len(r.providers))
+	for _, p := range r.providers {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+// Secondary returns every registered provider except DefaultName.
+func (r *Registry) Secondary() []ScrobbleProvider {
+	all := r.All()
+	out := all[:0]
+	for _, p := range all {
+		if p.Name() != DefaultName {
+			out = append(out, p)
+		}
+	}
+	return out
+}
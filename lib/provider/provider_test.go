@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"crovlune/plaxt/lib/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProvider struct{ name string }
+
+func (s stubProvider) Name() string { return s.name }
+
+func (s stubProvider) Scrobble(ctx context.Context, action string, item common.CacheItem, accessToken string) error {
+	return nil
+}
+
+func (s stubProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (s stubProvider) RefreshToken(ctx context.Context, refreshToken, redirectURI string) (Token, error) {
+	return Token{}, ErrRefreshNotSupported
+}
+
+func TestRegistryGetDefaultsToTrakt(t *testing.T) {
+	r := NewRegistry(stubProvider{name: "trakt"}, stubProvider{name: "simkl"})
+
+	p, err := r.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultName, p.Name())
+
+	p, err = r.Get(" SIMKL ")
+	require.NoError(t, err)
+	assert.Equal(t, "simkl", p.Name())
+
+	_, err = r.Get("letterboxd")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestRegistrySecondaryExcludesDefault(t *testing.T) {
+	r := NewRegistry(stubProvider{name: "trakt"}, stubProvider{name: "simkl"})
+
+	secondary := r.Secondary()
+	require.Len(t, secondary, 1)
+	assert.Equal(t, "simkl", secondary[0].Name())
+	assert.Len(t, r.All(), 2)
+
+	var nilRegistry *Registry
+	assert.Empty(t, nilRegistry.Secondary())
+}
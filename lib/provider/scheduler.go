@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority orders outbound scrobbles competing for provider capacity.
+type Priority int
+
+const (
+	// PriorityLive is a scrobble for something being watched right now.
+	PriorityLive Priority = iota
+	// PriorityBacklog is catch-up work from the offline queue or retry worker.
+	PriorityBacklog
+)
+
+// String returns "live" or "backlog".
+func (p Priority) String() string {
+	if p == PriorityBacklog {
+		return "backlog"
+	}
+	return "live"
+}
+
+// Scheduler defaults
+const (
+	DefaultSchedulerSlots      = 4
+	DefaultSchedulerLiveWeight = 4
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose scrobbles are scheduled at p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority stored in ctx, PriorityLive when none is.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// SchedulerStats is a point-in-time snapshot of a Scheduler.
+type SchedulerStats struct {
+	Slots          int    `json:"slots"`
+	LiveWeight     int    `json:"live_weight"`
+	InFlight       int    `json:"in_flight"`
+	LiveWaiting    int    `json:"live_waiting"`
+	BacklogWaiting int    `json:"backlog_waiting"`
+	LiveGranted    uint64 `json:"live_granted"`
+	BacklogGranted uint64 `json:"backlog_granted"`
+}
+
+// Scheduler bounds concurrent outbound scrobbles and hands free slots to live
+// requests before backlog ones, so a queue drain never delays what is being
+// watched. To keep the backlog moving under sustained live load, every
+// liveWeight consecutive live grants are followed by one backlog grant when
+// backlog work is waiting. A nil *Scheduler admits everything immediately.
+type Scheduler struct {
+	slots      int
+	liveWeight int
+
+	mu         sync.Mutex
+	inFlight   int
+	liveStreak int
+	waiting    [2][]chan struct{} // indexed by Priority
+	granted    [2]uint64
+}
+
+// NewScheduler returns a scheduler allowing slots concurrent scrobbles.
+// Non-positive arguments select the defaults.
+func NewScheduler(slots, liveWeight int) *Scheduler {
+	if slots <= 0 {
+		slots = DefaultSchedulerSlots
+	}
+	if liveWeight <= 0 {
+		liveWeight = DefaultSchedulerLiveWeight
+	}
+	return &Scheduler{slots: slots, liveWeight: liveWeight}
+}
+
+// Acquire blocks until a slot is free for priority p or ctx is done. The
+// returned release must be called once the request finishes.
+func (s *Scheduler) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	if p != PriorityBacklog {
+		p = PriorityLive
+	}
+
+	s.mu.Lock()
+	if s.inFlight < s.slots && len(s.waiting[PriorityLive]) == 0 && len(s.waiting[PriorityBacklog]) == 0 {
+		s.grantLocked(p)
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	ready := make(chan struct{})
+	s.waiting[p] = append(s.waiting[p], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, ch := range s.waiting[p] {
+			if ch == ready {
+				s.waiting[p] = append(s.waiting[p][:i], s.waiting[p][i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// Granted while giving up; hand the slot on.
+		s.inFlight--
+		s.dispatchLocked()
+		return nil, ctx.Err()
+	}
+}
+
+// Stats returns the scheduler's current state.
+func (s *Scheduler) Stats() SchedulerStats {
+	if s == nil {
+		return SchedulerStats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		Slots:          s.slots,
+		LiveWeight:     s.liveWeight,
+		InFlight:       s.inFlight,
+		LiveWaiting:    len(s.waiting[PriorityLive]),
+		BacklogWaiting: len(s.waiting[PriorityBacklog]),
+		LiveGranted:    s.granted[PriorityLive],
+		BacklogGranted: s.granted[PriorityBacklog],
+	}
+}
+
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.dispatchLocked()
+}
+
+// dispatchLocked hands free slots to waiters, live first.
+func (s *Scheduler) dispatchLocked() {
+	for s.inFlight < s.slots {
+		live, backlog := len(s.waiting[PriorityLive]) > 0, len(s.waiting[PriorityBacklog]) > 0
+		var p Priority
+		switch {
+		case live && backlog && s.liveStreak >= s.liveWeight:
+			p = PriorityBacklog
+		case live:
+			p = PriorityLive
+		case backlog:
+			p = PriorityBacklog
+		default:
+			return
+		}
+		ready := s.waiting[p][0]
+		s.waiting[p] = s.waiting[p][1:]
+		s.grantLocked(p)
+		close(ready)
+	}
+}
+
+func (s *Scheduler) grantLocked(p Priority) {
+	s.inFlight++
+	s.granted[p]++
+	if p == PriorityLive {
+		s.liveStreak++
+	} else {
+		s.liveStreak = 0
+	}
+}
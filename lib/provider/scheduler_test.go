@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerPrefersLiveWithoutStarvingBacklog(t *testing.T) {
+	s := NewScheduler(1, 2)
+	hold, err := s.Acquire(context.Background(), PriorityLive)
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	wait := func(name string, p Priority, live, backlog int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), p)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}()
+		require.Eventually(t, func() bool {
+			st := s.Stats()
+			return st.LiveWaiting == live && st.BacklogWaiting == backlog
+		}, time.Second, time.Millisecond)
+	}
+	wait("b1", PriorityBacklog, 0, 1)
+	wait("b2", PriorityBacklog, 0, 2)
+	wait("l1", PriorityLive, 1, 2)
+	wait("l2", PriorityLive, 2, 2)
+	wait("l3", PriorityLive, 3, 2)
+
+	hold()
+	wg.Wait()
+	// The held slot counts as the first live grant of the streak
+	assert.Equal(t, []string{"l1", "b1", "l2", "l3", "b2"}, order)
+
+	st := s.Stats()
+	assert.Zero(t, st.InFlight)
+	assert.EqualValues(t, 4, st.LiveGranted)
+	assert.EqualValues(t, 2, st.BacklogGranted)
+}
+
+func TestSchedulerAcquireHonoursContext(t *testing.T) {
+	s := NewScheduler(1, 0)
+	hold, err := s.Acquire(context.Background(), PriorityLive)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, PriorityBacklog)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, s.Stats().BacklogWaiting)
+
+	hold()
+	release, err := s.Acquire(context.Background(), PriorityBacklog)
+	require.NoError(t, err)
+	release()
+	assert.Zero(t, s.Stats().InFlight)
+}
+
+func TestSchedulerDefaults(t *testing.T) {
+	var nilScheduler *Scheduler
+	release, err := nilScheduler.Acquire(context.Background(), PriorityBacklog)
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, SchedulerStats{}, nilScheduler.Stats())
+
+	assert.Equal(t, PriorityLive, PriorityFromContext(context.Background()))
+	assert.Equal(t, "backlog", PriorityFromContext(WithPriority(context.Background(), PriorityBacklog)).String())
+	st := NewScheduler(0, 0).Stats()
+	assert.Equal(t, DefaultSchedulerSlots, st.Slots)
+	assert.Equal(t, DefaultSchedulerLiveWeight, st.LiveWeight)
+}
@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/trakt"
+	"crovlune/plaxt/lib/trakt/trakttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerRetriesAgainstMockTrakt(t *testing.T) {
+	ctx := context.Background()
+	srv := trakttest.NewServer(t)
+	srv.SetUser("member-token", "", trakttest.User{Username: "dad"})
+	s := store.NewMemoryStore()
+	tr := trakt.New("client-id", "client-secret", s)
+	tr.SetBaseURL(srv.URL)
+
+	now := time.Now().UTC()
+	group := &store.FamilyGroup{ID: "group-1", PlexUsername: "household", CreatedAt: now, UpdatedAt: now}
+	member := &store.GroupMember{
+		ID:                  "member-1",
+		TempLabel:           "Dad",
+		TraktUsername:       "dad",
+		AccessToken:         "member-token",
+		AuthorizationStatus: store.GroupMemberStatusAuthorized,
+		CreatedAt:           now,
+	}
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, group, []*store.GroupMember{member}))
+
+	title, year := "Heat", 1995
+	payload, err := json.Marshal(common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Title: &title, Year: &year}})
+	require.NoError(t, err)
+	require.NoError(t, s.EnqueueRetryItem(ctx, &store.RetryQueueItem{
+		ID:            "retry-1",
+		FamilyGroupID: group.ID,
+		GroupMemberID: member.ID,
+		Payload:       payload,
+		NextAttemptAt: now.Add(-time.Minute),
+	}))
+
+	notifier := &mockNotifier{}
+	worker := NewWorker(WorkerConfig{Repo: NewPostgresRepo(s), Provider: tr, Notifier: notifier, Store: s})
+
+	// Trakt is down for the first attempt, so the item is rescheduled.
+	srv.FailNext(trakttest.RouteScrobbleStop, http.StatusServiceUnavailable, 1)
+	worker.processBatch(ctx)
+	due, err := s.ListDueRetryItems(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].AttemptCount)
+	assert.Contains(t, due[0].LastError, "503")
+
+	// The next attempt goes through and clears the item.
+	worker.processItem(ctx, due[0])
+	due, err = s.ListDueRetryItems(ctx, time.Now().Add(24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	assert.Empty(t, notifier.calls)
+
+	stops := srv.Requests(trakttest.RouteScrobbleStop)
+	require.Len(t, stops, 2)
+	assert.Equal(t, "member-token", stops[1].Token())
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/provider"
 	"crovlune/plaxt/lib/store"
 )
 
@@ -28,14 +29,6 @@ const (
 	MaxBackoffDelay = 30 * time.Minute
 )
 
-// TraktScrobbler defines the interface for sending scrobbles to Trakt.
-// This allows the worker to call BroadcastScrobble without circular dependencies.
-type TraktScrobbler interface {
-	// ScrobbleFromQueue sends a queued scrobble to Trakt.
-	// Returns nil on success, error on failure (transient or permanent).
-	ScrobbleFromQueue(action string, item common.CacheItem, accessToken string) error
-}
-
 // Notifier defines the interface for sending notifications to group owners.
 type Notifier interface {
 	// NotifyPermanentFailure sends a banner notification for a permanently failed scrobble.
@@ -45,7 +38,7 @@ type Notifier interface {
 // Worker processes the retry queue with exponential backoff and permanent failure handling.
 type Worker struct {
 	repo         *PostgresRepo
-	trakt        TraktScrobbler
+	provider     provider.ScrobbleProvider
 	notifier     Notifier
 	pollInterval time.Duration
 	batchSize    int
@@ -55,7 +48,7 @@ type Worker struct {
 // WorkerConfig configures the queue worker.
 type WorkerConfig struct {
 	Repo         *PostgresRepo
-	Trakt        TraktScrobbler
+	Provider     provider.ScrobbleProvider // Target for retried scrobbles (Trakt)
 	Notifier     Notifier
 	Store        store.Store
 	PollInterval time.Duration
@@ -73,7 +66,7 @@ func NewWorker(cfg WorkerConfig) *Worker {
 
 	return &Worker{
 		repo:         cfg.Repo,
-		trakt:        cfg.Trakt,
+		provider:     cfg.Provider,
 		notifier:     cfg.Notifier,
 		pollInterval: cfg.PollInterval,
 		batchSize:    cfg.BatchSize,
@@ -167,8 +160,8 @@ func (w *Worker) processItem(ctx context.Context, item *store.RetryQueueItem) {
 	// Extract action from item (default to "stop" if not stored)
 	action := "stop" // TODO: Store action in RetryQueueItem if needed
 
-	// Attempt scrobble
-	err = w.trakt.ScrobbleFromQueue(action, cacheItem, member.AccessToken)
+	// Attempt scrobble; retries yield to live scrobbles
+	err = w.provider.Scrobble(provider.WithPriority(ctx, provider.PriorityBacklog), action, cacheItem, member.AccessToken)
 
 	if err == nil {
 		// Success - remove from queue
@@ -8,23 +8,32 @@ import (
 	"time"
 
 	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/provider"
 	"crovlune/plaxt/lib/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// mockTraktScrobbler implements TraktScrobbler for testing
+// mockTraktScrobbler implements provider.ScrobbleProvider for testing
 type mockTraktScrobbler struct {
 	scrobbleFn func(action string, item common.CacheItem, token string) error
 }
 
-func (m *mockTraktScrobbler) ScrobbleFromQueue(action string, item common.CacheItem, token string) error {
+func (m *mockTraktScrobbler) Name() string { return provider.DefaultName }
+
+func (m *mockTraktScrobbler) Scrobble(ctx context.Context, action string, item common.CacheItem, token string) error {
 	if m.scrobbleFn != nil {
 		return m.scrobbleFn(action, item, token)
 	}
 	return nil
 }
 
+func (m *mockTraktScrobbler) HealthCheck(ctx context.Context) error { return nil }
+
+func (m *mockTraktScrobbler) RefreshToken(ctx context.Context, refreshToken, redirectURI string) (provider.Token, error) {
+	return provider.Token{}, provider.ErrRefreshNotSupported
+}
+
 // mockNotifier implements Notifier for testing
 type mockNotifier struct {
 	notifyFn func(ctx context.Context, groupID, memberID, username, mediaTitle, errorMsg string) error
@@ -151,7 +160,7 @@ func TestWorker_processItem_Success(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:     repo,
-		Trakt:    mockTrakt,
+		Provider: mockTrakt,
 		Notifier: nil,
 		Store:    mockStore,
 	})
@@ -216,7 +225,7 @@ func TestWorker_processItem_TransientFailure(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:     repo,
-		Trakt:    mockTrakt,
+		Provider: mockTrakt,
 		Notifier: nil,
 		Store:    mockStore,
 	})
@@ -294,7 +303,7 @@ func TestWorker_processItem_PermanentFailure(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:     repo,
-		Trakt:    mockTrakt,
+		Provider: mockTrakt,
 		Notifier: mockNotifier,
 		Store:    mockStore,
 	})
@@ -351,7 +360,7 @@ func TestWorker_processItem_MemberNotFound(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:     repo,
-		Trakt:    nil, // Should not be called
+		Provider: nil, // Should not be called
 		Notifier: nil,
 		Store:    mockStore,
 	})
@@ -399,7 +408,7 @@ func TestWorker_processItem_InvalidPayload(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:     repo,
-		Trakt:    nil, // Should not be called
+		Provider: nil, // Should not be called
 		Notifier: nil,
 		Store:    mockStore,
 	})
@@ -465,7 +474,7 @@ func TestWorker_processBatch(t *testing.T) {
 		repo := NewPostgresRepo(mockStore)
 		worker := NewWorker(WorkerConfig{
 			Repo:     repo,
-			Trakt:    mockTrakt,
+			Provider: mockTrakt,
 			Notifier: nil,
 			Store:    mockStore,
 		})
@@ -489,7 +498,7 @@ func TestWorker_processBatch(t *testing.T) {
 		repo := NewPostgresRepo(mockStore)
 		worker := NewWorker(WorkerConfig{
 			Repo:     repo,
-			Trakt:    nil,
+			Provider: nil,
 			Notifier: nil,
 			Store:    mockStore,
 		})
@@ -508,7 +517,7 @@ func TestWorker_processBatch(t *testing.T) {
 		repo := NewPostgresRepo(mockStore)
 		worker := NewWorker(WorkerConfig{
 			Repo:     repo,
-			Trakt:    nil,
+			Provider: nil,
 			Notifier: nil,
 			Store:    mockStore,
 		})
@@ -530,7 +539,7 @@ func TestWorker_Start_ContextCancellation(t *testing.T) {
 	repo := NewPostgresRepo(mockStore)
 	worker := NewWorker(WorkerConfig{
 		Repo:         repo,
-		Trakt:        nil,
+		Provider:     nil,
 		Notifier:     nil,
 		Store:        mockStore,
 		PollInterval: 1 * time.Millisecond, // Very short for testing
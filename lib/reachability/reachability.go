@@ -0,0 +1,264 @@
+// Package reachability checks that the URL Plex posts webhooks to can be
+// reached, one step at a time, so a failure can be pinned on DNS, the
+// network, TLS or a reverse proxy rather than reported as "no scrobbles".
+package reachability
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Step names, in the order they run.
+const (
+	StepDNS     = "dns"
+	StepConnect = "connect"
+	StepTLS     = "tls"
+	StepHTTP    = "http"
+)
+
+// Step is the outcome of one stage of a check. Hint suggests a fix when the
+// step failed.
+type Step struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+}
+
+// Report collects the steps of a check. Steps stop at the first failure.
+type Report struct {
+	URL       string   `json:"url"`
+	Reachable bool     `json:"reachable"`
+	Steps     []Step   `json:"steps"`
+	Warnings  []string `json:"warnings"`
+}
+
+// Checker probes URLs. The zero value uses the system resolver and roots.
+type Checker struct {
+	// Timeout bounds each step; defaults to 5s.
+	Timeout time.Duration
+	// TLSConfig overrides the TLS settings; tests use it to trust a fake CA.
+	TLSConfig *tls.Config
+	// Resolver overrides the system resolver.
+	Resolver *net.Resolver
+}
+
+const defaultTimeout = 5 * time.Second
+
+// Check fetches target and expects a 200 response whose body is exactly
+// expect, which proves the request reached this plaxt rather than some other
+// server behind the same name.
+func (c *Checker) Check(ctx context.Context, target, expect string) Report {
+	report := Report{URL: target, Steps: []Step{}, Warnings: []string{}}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		report.Steps = append(report.Steps, Step{Name: StepDNS, Detail: "not an http(s) URL", Hint: "Open the wizard on the address Plex should use."})
+		return report
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	step, addrs := c.resolve(ctx, host)
+	report.Steps = append(report.Steps, step)
+	if !step.OK {
+		return report
+	}
+	if allPrivate(addrs) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s only resolves to private addresses; a Plex server outside this network cannot reach it.", host))
+	}
+
+	step, conn := c.connect(ctx, net.JoinHostPort(host, port))
+	report.Steps = append(report.Steps, step)
+	if !step.OK {
+		return report
+	}
+	conn.Close()
+
+	if u.Scheme == "https" {
+		step = c.handshake(ctx, net.JoinHostPort(host, port), host)
+		report.Steps = append(report.Steps, step)
+		if !step.OK {
+			return report
+		}
+	} else {
+		report.Warnings = append(report.Warnings, "The webhook URL uses plain http, so Plex sends play events unencrypted.")
+	}
+
+	step = c.fetch(ctx, target, expect)
+	report.Steps = append(report.Steps, step)
+	report.Reachable = step.OK
+	return report
+}
+
+func (c *Checker) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+func (c *Checker) tlsConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{}
+	if c.TLSConfig != nil {
+		cfg = c.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = serverName
+	}
+	return cfg
+}
+
+func (c *Checker) resolve(ctx context.Context, host string) (Step, []net.IP) {
+	start := time.Now()
+	step := Step{Name: StepDNS}
+	if ip := net.ParseIP(host); ip != nil {
+		step.OK, step.Detail = true, "address literal"
+		return step, []net.IP{ip}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	resolver := c.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	step.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		step.Detail = err.Error()
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			step.Hint = "The name does not exist in DNS. Check the spelling or add the record."
+		} else {
+			step.Hint = "DNS lookup failed. Check the resolver this server uses."
+		}
+		return step, nil
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	names := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+		names = append(names, a.IP.String())
+	}
+	step.OK, step.Detail = true, strings.Join(names, ", ")
+	return step, ips
+}
+
+func (c *Checker) connect(ctx context.Context, addr string) (Step, net.Conn) {
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: c.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	step := Step{Name: StepConnect, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		step.Detail = err.Error()
+		step.Hint = "Nothing accepted the connection. Check the port, firewall and port forwarding."
+		return step, nil
+	}
+	step.OK, step.Detail = true, addr
+	return step, conn
+}
+
+func (c *Checker) handshake(ctx context.Context, addr, host string) Step {
+	start := time.Now()
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: c.timeout()}, Config: c.tlsConfig(host)}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	step := Step{Name: StepTLS, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		step.Detail = err.Error()
+		step.Hint = tlsHint(err, host)
+		return step
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	step.OK = true
+	if len(state.PeerCertificates) > 0 {
+		step.Detail = "certificate valid until " + state.PeerCertificates[0].NotAfter.UTC().Format("2006-01-02")
+	}
+	return step
+}
+
+func tlsHint(err error, host string) string {
+	var unknownCA x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknownCA):
+		return "The certificate is not signed by a trusted authority. Plex rejects self-signed certificates; use one from a public CA such as Let's Encrypt."
+	case errors.As(err, &hostErr):
+		return fmt.Sprintf("The certificate does not cover %s. Issue one for this name or use the name it covers.", host)
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "The certificate has expired or is not valid yet. Renew it."
+	case strings.Contains(err.Error(), "first record does not look like a TLS handshake"):
+		return "The port speaks plain http. Use an http:// URL or enable TLS on the proxy."
+	}
+	return "The TLS handshake failed. Check the proxy's certificate setup."
+}
+
+func (c *Checker) fetch(ctx context.Context, target, expect string) Step {
+	start := time.Now()
+	client := &http.Client{
+		Timeout:   c.timeout(),
+		Transport: &http.Transport{TLSClientConfig: c.tlsConfig(""), Proxy: nil},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	step := Step{Name: StepHTTP}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		step.Detail = err.Error()
+		return step
+	}
+	resp, err := client.Do(req)
+	step.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		step.Detail = err.Error()
+		step.Hint = "The server accepted the connection but did not answer. Check the proxy's timeouts and upstream."
+		return step
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	step.Detail = resp.Status
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		step.Hint = fmt.Sprintf("The URL redirects to %s. Plex does not follow redirects for webhooks; use the final address.", resp.Header.Get("Location"))
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		step.Hint = "A reverse proxy answered but could not reach plaxt. Check the proxy's upstream address."
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		step.Hint = "Something in front of plaxt requires authentication. Exempt the /api path so Plex can post webhooks."
+	case resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != expect:
+		step.Hint = "A different server answered. Check that the proxy forwards this host and path to plaxt."
+	default:
+		step.OK = true
+	}
+	return step
+}
+
+// allPrivate reports whether every address is loopback, link-local or in a
+// private range.
+func allPrivate(ips []net.IP) bool {
+	if len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package reachability
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stepNames(r Report) []string {
+	names := make([]string, 0, len(r.Steps))
+	for _, s := range r.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestCheckReachable(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("nonce-1"))
+	}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	c := &Checker{TLSConfig: &tls.Config{RootCAs: roots}}
+
+	report := c.Check(context.Background(), srv.URL+"/reachability/nonce-1", "nonce-1")
+	assert.True(t, report.Reachable)
+	assert.Equal(t, []string{StepDNS, StepConnect, StepTLS, StepHTTP}, stepNames(report))
+	assert.NotEmpty(t, report.Warnings, "loopback only resolves privately")
+}
+
+func TestCheckUntrustedCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	report := (&Checker{}).Check(context.Background(), srv.URL, "nonce-1")
+	assert.False(t, report.Reachable)
+	require.Equal(t, []string{StepDNS, StepConnect, StepTLS}, stepNames(report))
+	assert.Contains(t, report.Steps[2].Hint, "self-signed")
+}
+
+func TestCheckProxyProblems(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		hint   string
+	}{
+		{"bad gateway", http.StatusBadGateway, "", "upstream"},
+		{"other server", http.StatusOK, "<html>welcome</html>", "different server"},
+		{"redirect", http.StatusMovedPermanently, "", "redirects"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.status == http.StatusMovedPermanently {
+					w.Header().Set("Location", "https://elsewhere.example/")
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			report := (&Checker{}).Check(context.Background(), srv.URL, "nonce-1")
+			assert.False(t, report.Reachable)
+			require.Equal(t, []string{StepDNS, StepConnect, StepHTTP}, stepNames(report))
+			assert.Contains(t, report.Steps[2].Hint, tc.hint)
+		})
+	}
+}
+
+func TestCheckConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	report := (&Checker{}).Check(context.Background(), url, "nonce-1")
+	assert.False(t, report.Reachable)
+	require.Equal(t, []string{StepDNS, StepConnect}, stepNames(report))
+	assert.NotEmpty(t, report.Steps[1].Hint)
+}
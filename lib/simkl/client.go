@@ -0,0 +1,184 @@
+// Package simkl is a minimal Simkl API client used as an optional secondary
+// scrobble target alongside Trakt.
+package simkl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/provider"
+)
+
+// ProviderName identifies Simkl in stored provider tokens.
+const ProviderName = "simkl"
+
+const (
+	apiBaseURL   = "https://api.simkl.com"
+	authorizeURL = "https://simkl.com/oauth/authorize"
+)
+
+// Client talks to the Simkl API. Simkl's scrobble endpoints mirror Trakt's,
+// so the same ScrobbleBody is sent unchanged.
+type Client struct {
+	ClientID     string
+	clientSecret string
+	userAgent    string
+	httpClient   *http.Client
+}
+
+// New constructs a Simkl client with a 10s timeout.
+func New(clientID, clientSecret string) *Client {
+	return &Client{
+		ClientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetUserAgent sets the User-Agent sent with every request.
+func (c *Client) SetUserAgent(ua string) {
+	c.userAgent = strings.TrimSpace(ua)
+}
+
+// Name implements provider.ScrobbleProvider.
+func (c *Client) Name() string {
+	return ProviderName
+}
+
+// AuthorizeURL returns the Simkl OAuth consent URL.
+func (c *Client) AuthorizeURL(redirectURI, state string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.ClientID)
+	params.Set("redirect_uri", redirectURI)
+	if state != "" {
+		params.Set("state", state)
+	}
+	return authorizeURL + "?" + params.Encode()
+}
+
+// TokenResult is the OAuth token response. Simkl access tokens do not expire
+// and no refresh token is issued.
+type TokenResult struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+}
+
+// ExchangeCode trades an authorization code for an access token.
+func (c *Client) ExchangeCode(ctx context.Context, code, redirectURI string) (TokenResult, error) {
+	var result TokenResult
+	payload, err := json.Marshal(map[string]string{
+		"code":          code,
+		"client_id":     c.ClientID,
+		"client_secret": c.clientSecret,
+		"redirect_uri":  redirectURI,
+		"grant_type":    "authorization_code",
+	})
+	if err != nil {
+		return result, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/oauth/token", payload, "")
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("simkl oauth decode error: %w", err)
+	}
+	if result.AccessToken == "" {
+		return result, errors.New("simkl oauth response missing access token")
+	}
+	return result, nil
+}
+
+// Scrobble implements provider.ScrobbleProvider.
+func (c *Client) Scrobble(ctx context.Context, action string, item common.CacheItem, accessToken string) error {
+	switch action {
+	case "start", "pause", "stop":
+	default:
+		return fmt.Errorf("invalid scrobble action %q", action)
+	}
+	payload, err := json.Marshal(item.Body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/scrobble/"+action, payload, accessToken)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// HealthCheck implements provider.ScrobbleProvider using an unauthenticated
+// ID lookup, which only needs the client ID.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/search/id?imdb=tt0111161", nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RefreshToken implements provider.ScrobbleProvider. Simkl tokens never
+// expire, so there is nothing to refresh.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken, redirectURI string) (provider.Token, error) {
+	return provider.Token{}, provider.ErrRefreshNotSupported
+}
+
+// do executes a request and converts non-2xx responses into errors.
+func (c *Client) do(ctx context.Context, method, path string, payload []byte, accessToken string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiBaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("simkl-api-key", c.ClientID)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("simkl %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := strings.TrimSpace(string(b))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, &HTTPError{Code: resp.StatusCode, Message: msg}
+	}
+	return resp, nil
+}
+
+// HTTPError is returned for non-2xx Simkl responses.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("simkl http %d: %s", e.Code, e.Message)
+}
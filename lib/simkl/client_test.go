@@ -0,0 +1,86 @@
+package simkl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/provider"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func newTestClient(rt roundTripFunc) *Client {
+	c := New("simkl-id", "simkl-secret")
+	c.httpClient = &http.Client{Transport: rt}
+	return c
+}
+
+func TestScrobbleSendsBodyAndHeaders(t *testing.T) {
+	c := newTestClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/scrobble/stop", req.URL.Path)
+		assert.Equal(t, "simkl-id", req.Header.Get("simkl-api-key"))
+		assert.Equal(t, "Bearer tok", req.Header.Get("Authorization"))
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		assert.EqualValues(t, 95, payload["progress"])
+		assert.NotNil(t, payload["movie"])
+		return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("{}")), Header: make(http.Header)}, nil
+	})
+
+	imdb := "tt0111161"
+	body := common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Ids: common.Ids{Imdb: &imdb}}}
+	require.NoError(t, c.Scrobble(context.Background(), "stop", common.CacheItem{Body: body}, "tok"))
+}
+
+func TestScrobbleReturnsHTTPError(t *testing.T) {
+	c := newTestClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Body: io.NopCloser(strings.NewReader("")), Header: make(http.Header)}, nil
+	})
+
+	err := c.Scrobble(context.Background(), "start", common.CacheItem{}, "tok")
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
+
+func TestExchangeCode(t *testing.T) {
+	c := newTestClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/oauth/token", req.URL.Path)
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		assert.Equal(t, "the-code", payload["code"])
+		assert.Equal(t, "simkl-secret", payload["client_secret"])
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"access_token":"abc","token_type":"bearer"}`)), Header: make(http.Header)}, nil
+	})
+
+	result, err := c.ExchangeCode(context.Background(), "the-code", "https://plaxt.example/simkl/callback")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", result.AccessToken)
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	c := New("simkl-id", "")
+	u := c.AuthorizeURL("https://plaxt.example/simkl/callback", "state-1")
+	assert.Contains(t, u, "client_id=simkl-id")
+	assert.Contains(t, u, "state=state-1")
+	assert.Contains(t, u, "response_type=code")
+}
+
+func TestRefreshTokenNotSupported(t *testing.T) {
+	c := New("simkl-id", "simkl-secret")
+	_, err := c.RefreshToken(context.Background(), "refresh", "")
+	assert.ErrorIs(t, err, provider.ErrRefreshNotSupported)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// ActivityRetention bounds how long hourly activity counters are kept.
+// It covers the longest window the admin dashboard reports (7 days).
+const ActivityRetention = 8 * 24 * time.Hour
+
+// activityLayout names hourly buckets; it sorts chronologically.
+const activityLayout = "2006010215"
+
+// ErrInvalidActivityKind is returned for counters the store does not track.
+var ErrInvalidActivityKind = errors.New("store: invalid activity kind")
+
+// ActivityKind names one of the hourly activity counters.
+type ActivityKind string
+
+const (
+	// ActivityScrobble counts scrobbles Trakt accepted.
+	ActivityScrobble ActivityKind = "scrobbles"
+	// ActivityFailure counts scrobbles Trakt rejected or that failed for good.
+	ActivityFailure ActivityKind = "failures"
+	// ActivityQueued counts scrobbles queued while Trakt was unavailable.
+	ActivityQueued ActivityKind = "queued"
+)
+
+// Valid reports whether the kind is one of the tracked counters.
+func (k ActivityKind) Valid() bool {
+	switch k {
+	case ActivityScrobble, ActivityFailure, ActivityQueued:
+		return true
+	}
+	return false
+}
+
+// ActivityCounts holds the counters of one bucket.
+type ActivityCounts struct {
+	Scrobbles int `json:"scrobbles"`
+	Failures  int `json:"failures"`
+	Queued    int `json:"queued"`
+}
+
+// Add increments the counter named by kind by n.
+func (c *ActivityCounts) Add(kind ActivityKind, n int) {
+	switch kind {
+	case ActivityScrobble:
+		c.Scrobbles += n
+	case ActivityFailure:
+		c.Failures += n
+	case ActivityQueued:
+		c.Queued += n
+	}
+}
+
+// Merge adds every counter of other to c.
+func (c *ActivityCounts) Merge(other ActivityCounts) {
+	c.Scrobbles += other.Scrobbles
+	c.Failures += other.Failures
+	c.Queued += other.Queued
+}
+
+// ActivityBucket is one hour of activity starting at Start (UTC).
+type ActivityBucket struct {
+	Start time.Time `json:"start"`
+	ActivityCounts
+}
+
+// ActivityBucketStart returns the start of the hourly bucket containing t.
+func ActivityBucketStart(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+func activityKey(t time.Time) string {
+	return ActivityBucketStart(t).Format(activityLayout)
+}
+
+func parseActivityKey(key string) (time.Time, bool) {
+	start, err := time.Parse(activityLayout, key)
+	return start, err == nil
+}
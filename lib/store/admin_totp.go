@@ -0,0 +1,54 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAdminTOTPNotFound is returned when an admin account has no TOTP enrollment.
+	ErrAdminTOTPNotFound = errors.New("store: admin totp enrollment not found")
+	// ErrInvalidAdminTOTP is returned when required fields are missing.
+	ErrInvalidAdminTOTP = errors.New("store: admin totp enrollment is invalid")
+)
+
+// AdminTOTP is the second factor of one admin account. An enrollment is
+// pending until Confirmed; only confirmed enrollments are enforced.
+type AdminTOTP struct {
+	Username string `json:"username"`
+	// Secret is the base32 TOTP key shared with the authenticator app.
+	Secret    string `json:"secret"`
+	Confirmed bool   `json:"confirmed"`
+	// RecoveryCodes holds the SHA-256 (hex) of each unused recovery code.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// LastStep is the last accepted time step, so a code cannot be replayed.
+	LastStep  int64     `json:"last_step,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate normalises the username, defaults the timestamps and ensures the
+// username and secret are set.
+func (t *AdminTOTP) Validate() error {
+	if t == nil {
+		return ErrInvalidAdminTOTP
+	}
+	t.Username = strings.ToLower(strings.TrimSpace(t.Username))
+	now := time.Now().UTC()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = now
+	}
+	t.CreatedAt, t.UpdatedAt = t.CreatedAt.UTC(), t.UpdatedAt.UTC()
+	if t.Username == "" || strings.TrimSpace(t.Secret) == "" {
+		return ErrInvalidAdminTOTP
+	}
+	return nil
+}
+
+func adminTOTPKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
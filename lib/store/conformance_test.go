@@ -0,0 +1,29 @@
+package store_test
+
+import (
+	"testing"
+
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/store/storetest"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
+
+func TestDiskStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		// The disk store writes to ./keystore
+		t.Chdir(t.TempDir())
+		return store.NewDiskStore()
+	})
+}
+
+func TestConsulStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		srv := storetest.NewFakeConsul(t)
+		return store.NewConsulStore(srv.URL, "", "plaxt/")
+	})
+}
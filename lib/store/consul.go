@@ -0,0 +1,198 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulKV is a KVBackend backed by the Consul KV HTTP API. Every key is
+// stored under prefix so plaxt can share a cluster with other services.
+type ConsulKV struct {
+	addr   string
+	token  string
+	prefix string
+	client *http.Client
+}
+
+// NewConsulKV creates a Consul KV backend. addr is the agent URL, e.g.
+// http://127.0.0.1:8500; token is an optional ACL token.
+func NewConsulKV(addr, token, prefix string) *ConsulKV {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &ConsulKV{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		prefix: prefix,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewConsulStore creates a KVStore backed by Consul.
+func NewConsulStore(addr, token, prefix string) *KVStore {
+	return NewKVStore(NewConsulKV(addr, token, prefix))
+}
+
+type consulPair struct {
+	Key         string
+	Value       []byte // base64 in the API, decoded by encoding/json
+	ModifyIndex uint64
+}
+
+func (c *ConsulKV) keyURL(key string, query url.Values) string {
+	segments := strings.Split(c.prefix+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	u := c.addr + "/v1/kv/" + strings.Join(segments, "/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func (c *ConsulKV) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %s %s: %w", method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func consulError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("consul: %s %s: status %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+func (c *ConsulKV) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.keyURL(key, nil), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, 0, ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, consulError(resp)
+	}
+	var pairs []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, 0, fmt.Errorf("consul: decode %s: %w", key, err)
+	}
+	if len(pairs) == 0 {
+		return nil, 0, ErrKeyNotFound
+	}
+	return pairs[0].Value, pairs[0].ModifyIndex, nil
+}
+
+func (c *ConsulKV) put(ctx context.Context, key string, value []byte, query url.Values) (bool, error) {
+	resp, err := c.do(ctx, http.MethodPut, c.keyURL(key, query), value)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, consulError(resp)
+	}
+	// Consul answers writes with a bare true/false
+	var ok bool
+	if err := json.NewDecoder(resp.Body).Decode(&ok); err != nil {
+		return false, fmt.Errorf("consul: decode put %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+func (c *ConsulKV) Put(ctx context.Context, key string, value []byte) error {
+	ok, err := c.put(ctx, key, value, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("consul: put %s was rejected", key)
+	}
+	return nil
+}
+
+func (c *ConsulKV) CompareAndSwap(ctx context.Context, key string, value []byte, index uint64) (bool, error) {
+	return c.put(ctx, key, value, url.Values{"cas": {strconv.FormatUint(index, 10)}})
+}
+
+func (c *ConsulKV) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.keyURL(key, nil), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return consulError(resp)
+	}
+	return nil
+}
+
+func (c *ConsulKV) List(ctx context.Context, prefix string) ([]KVPair, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.keyURL(prefix, url.Values{"recurse": {"true"}}), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, consulError(resp)
+	}
+	var pairs []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, fmt.Errorf("consul: decode %s: %w", prefix, err)
+	}
+	out := make([]KVPair, 0, len(pairs))
+	for _, p := range pairs {
+		out = append(out, KVPair{
+			Key:   strings.TrimPrefix(p.Key, c.prefix),
+			Value: p.Value,
+			Index: p.ModifyIndex,
+		})
+	}
+	return out, nil
+}
+
+// Ping checks that the agent has an elected leader.
+func (c *ConsulKV) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, c.addr+"/v1/status/leader", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return consulError(resp)
+	}
+	var leader string
+	if err := json.NewDecoder(resp.Body).Decode(&leader); err != nil {
+		return fmt.Errorf("consul: decode leader: %w", err)
+	}
+	if leader == "" {
+		return fmt.Errorf("consul: no cluster leader")
+	}
+	return nil
+}
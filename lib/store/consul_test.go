@@ -0,0 +1,106 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulKVCompareAndSwap(t *testing.T) {
+	srv := storetest.NewFakeConsul(t)
+	kv := store.NewConsulKV(srv.URL, "", "plaxt")
+	ctx := context.Background()
+
+	ok, err := kv.CompareAndSwap(ctx, "a/b", []byte("one"), 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = kv.CompareAndSwap(ctx, "a/b", []byte("two"), 0)
+	require.NoError(t, err)
+	assert.False(t, ok, "create-only swap must fail once the key exists")
+
+	value, index, err := kv.Get(ctx, "a/b")
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+	ok, err = kv.CompareAndSwap(ctx, "a/b", []byte("two"), index)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	pairs, err := kv.List(ctx, "a/")
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, "a/b", pairs[0].Key)
+	assert.Equal(t, "two", string(pairs[0].Value))
+
+	require.NoError(t, kv.Delete(ctx, "a/b"))
+	_, _, err = kv.Get(ctx, "a/b")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.NoError(t, kv.Ping(ctx))
+}
+
+func TestConsulStoreUsersAndQueue(t *testing.T) {
+	srv := storetest.NewFakeConsul(t)
+	s := store.NewConsulStore(srv.URL, "", "plaxt/")
+	ctx := context.Background()
+
+	s.WriteUser(store.User{ID: "u1", Username: "Alice", AccessToken: "a", RefreshToken: "r", Updated: time.Now()})
+	user := s.GetUserByName("alice")
+	require.NotNil(t, user)
+	assert.Equal(t, "u1", user.ID)
+	assert.Equal(t, int64(1), user.Version)
+
+	stale := *user
+	user.AccessToken = "a2"
+	require.NoError(t, s.CompareAndSwapUser(ctx, *user))
+	assert.ErrorIs(t, s.CompareAndSwapUser(ctx, stale), store.ErrUserVersionConflict)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"e1", "e2"} {
+		require.NoError(t, s.EnqueueScrobble(ctx, store.QueuedScrobbleEvent{
+			ID:         id,
+			UserID:     "u1",
+			Action:     "start",
+			Progress:   50,
+			PlayerUUID: "player",
+			RatingKey:  "42",
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	events, err := s.DequeueScrobbles(ctx, "u1", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "e1", events[0].ID)
+
+	require.NoError(t, s.DeleteQueuedScrobble(ctx, "e1"))
+	size, err := s.GetQueueSize(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, size)
+
+	assert.True(t, s.DeleteUser("u1", "alice"))
+	assert.Nil(t, s.GetUser("u1"))
+	assert.Nil(t, s.GetUserByName("alice"))
+}
+
+func TestConsulStoreFamilyGroupPlexUsernameIsUnique(t *testing.T) {
+	srv := storetest.NewFakeConsul(t)
+	s := store.NewConsulStore(srv.URL, "", "plaxt/")
+	ctx := context.Background()
+
+	group := &store.FamilyGroup{PlexUsername: "household"}
+	members := []*store.GroupMember{{TempLabel: "Dad"}, {TempLabel: "Kid"}}
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, group, members))
+	assert.ErrorIs(t, s.CreateFamilyGroup(ctx, &store.FamilyGroup{PlexUsername: "household"}), store.ErrDuplicateFamilyGroup)
+
+	listed, err := s.ListGroupMembers(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	require.NoError(t, s.DeleteFamilyGroup(ctx, group.ID))
+	_, err = s.GetFamilyGroupByPlex(ctx, "household")
+	assert.ErrorIs(t, err, store.ErrFamilyGroupNotFound)
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +25,11 @@ import (
 type DiskStore struct {
 	fallbackBuffers map[string]*InMemoryBuffer
 	bufferMu        sync.RWMutex
+	historyMu       sync.Mutex
+	userMu          sync.Mutex
+	statsMu         sync.Mutex
+	queueLogMu      sync.Mutex
+	queueLogAppends int
 }
 
 // NewDiskStore will instantiate the disk storage
@@ -37,13 +45,48 @@ func (s DiskStore) Ping(ctx context.Context) error {
 }
 
 // WriteUser will write a user object to disk
-func (s DiskStore) WriteUser(user User) {
+func (s *DiskStore) WriteUser(user User) {
+	s.userMu.Lock()
+	defer s.userMu.Unlock()
+	s.writeUserFields(user, s.readVersion(user.ID)+1)
+}
+
+// CompareAndSwapUser writes the user only if its version matches the stored one.
+func (s *DiskStore) CompareAndSwapUser(ctx context.Context, user User) error {
+	s.userMu.Lock()
+	defer s.userMu.Unlock()
+	if _, err := s.readField(user.ID, "username"); err != nil {
+		return ErrUserNotFound
+	}
+	current := s.readVersion(user.ID)
+	if current != user.Version {
+		return ErrUserVersionConflict
+	}
+	s.writeUserFields(user, current+1)
+	return nil
+}
+
+func (s *DiskStore) writeUserFields(user User, version int64) {
 	s.writeField(user.ID, "username", user.Username)
 	s.writeField(user.ID, "access", user.AccessToken)
 	s.writeField(user.ID, "refresh", user.RefreshToken)
 	s.writeField(user.ID, "updated", user.Updated.Format("01-02-2006"))
 	s.writeField(user.ID, "trakt_display_name", user.TraktDisplayName)
 	s.writeField(user.ID, "token_expiry", user.TokenExpiry.Format(time.RFC3339))
+	if !user.TokenIssuedAt.IsZero() {
+		s.writeField(user.ID, "token_issued_at", user.TokenIssuedAt.Format(time.RFC3339))
+	}
+	s.writeField(user.ID, "version", strconv.FormatInt(version, 10))
+}
+
+// readVersion returns the stored user version, treating legacy users as 0.
+func (s *DiskStore) readVersion(id string) int64 {
+	raw, err := s.readField(id, "version")
+	if err != nil {
+		return 0
+	}
+	version, _ := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	return version
 }
 
 // GetUser will load a user from disk
@@ -74,6 +117,10 @@ func (s DiskStore) GetUser(id string) *User {
 			tokenExpiry = parsedExpiry
 		}
 	}
+	var tokenIssuedAt time.Time
+	if issuedStr, err := s.readField(id, "token_issued_at"); err == nil && issuedStr != "" {
+		tokenIssuedAt, _ = time.Parse(time.RFC3339, issuedStr)
+	}
 
 	user := User{
 		ID:               id,
@@ -83,6 +130,8 @@ func (s DiskStore) GetUser(id string) *User {
 		TraktDisplayName: displayName,
 		Updated:          updated,
 		TokenExpiry:      tokenExpiry,
+		TokenIssuedAt:    tokenIssuedAt,
+		Version:          s.readVersion(id),
 	}
 
 	return &user
@@ -111,6 +160,8 @@ func (s DiskStore) DeleteUser(id, username string) bool {
 	s.eraseField(id, "refresh")
 	s.eraseField(id, "trakt_display_name")
 	s.eraseField(id, "token_expiry")
+	s.eraseField(id, "token_issued_at")
+	s.eraseField(id, "version")
 	return true
 }
 
@@ -571,6 +622,12 @@ func (s DiskStore) CreateFamilyGroup(ctx context.Context, group *FamilyGroup) er
 	return nil
 }
 
+// CreateFamilyGroupWithMembers creates a group and its members, removing any
+// partially written records if a step fails.
+func (s *DiskStore) CreateFamilyGroupWithMembers(ctx context.Context, group *FamilyGroup, members []*GroupMember) error {
+	return createFamilyGroupWithCleanup(ctx, s, group, members)
+}
+
 func (s DiskStore) GetFamilyGroup(ctx context.Context, groupID string) (*FamilyGroup, error) {
 	groupFile := filepath.Join(familyGroupBasePath, groupID, "group.json")
 	data, err := os.ReadFile(groupFile)
@@ -879,6 +936,830 @@ func (s DiskStore) DeleteNotification(ctx context.Context, notificationID string
 
 // ========== FALLBACK BUFFER HELPERS ==========
 
+// ========== PROVIDER TOKEN STORAGE ==========
+
+const providerTokenBasePath = "keystore/provider_tokens"
+
+func providerTokenFile(userID, provider string) string {
+	return filepath.Join(providerTokenBasePath, userID, strings.ToLower(provider)+".json")
+}
+
+func (s *DiskStore) SaveProviderToken(ctx context.Context, token *ProviderToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.touch()
+
+	tokenFile := providerTokenFile(token.UserID, token.Provider)
+	if err := os.MkdirAll(filepath.Dir(tokenFile), 0755); err != nil {
+		return fmt.Errorf("failed to create provider token directory: %w", err)
+	}
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal provider token: %w", err)
+	}
+	if err := os.WriteFile(tokenFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write provider token file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetProviderToken(ctx context.Context, userID, provider string) (*ProviderToken, error) {
+	data, err := os.ReadFile(providerTokenFile(userID, provider))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrProviderTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to read provider token file: %w", err)
+	}
+
+	var token ProviderToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal provider token: %w", err)
+	}
+	return &token, nil
+}
+
+func (s *DiskStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	if err := os.Remove(providerTokenFile(userID, provider)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete provider token file: %w", err)
+	}
+	return nil
+}
+
+// ========== WATCH HISTORY STORAGE ==========
+
+const watchHistoryBasePath = "keystore/watch_history"
+
+func watchHistoryFile(userID string) string {
+	return filepath.Join(watchHistoryBasePath, userID+".json")
+}
+
+func (s *DiskStore) RecordWatchedMovie(ctx context.Context, movie *WatchedMovie) error {
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	movies, err := s.readWatchHistory(movie.UserID)
+	if err != nil {
+		return err
+	}
+	movies = append(movies, *movie)
+	if len(movies) > MaxWatchedMoviesPerUser {
+		movies = movies[len(movies)-MaxWatchedMoviesPerUser:]
+	}
+
+	historyFile := watchHistoryFile(movie.UserID)
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0755); err != nil {
+		return fmt.Errorf("failed to create watch history directory: %w", err)
+	}
+	data, err := json.Marshal(movies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch history: %w", err)
+	}
+	if err := os.WriteFile(historyFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watch history file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) ListWatchedMovies(ctx context.Context, userID string) ([]WatchedMovie, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	return s.readWatchHistory(strings.TrimSpace(userID))
+}
+
+func (s *DiskStore) readWatchHistory(userID string) ([]WatchedMovie, error) {
+	data, err := os.ReadFile(watchHistoryFile(userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []WatchedMovie{}, nil
+		}
+		return nil, fmt.Errorf("failed to read watch history file: %w", err)
+	}
+	var movies []WatchedMovie
+	if err := json.Unmarshal(data, &movies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal watch history: %w", err)
+	}
+	return movies, nil
+}
+
+// ========== QUEUE EVENT LOG STORAGE ==========
+
+const (
+	queueLogFile = "keystore/queue_events.log"
+	// queueLogCompactEvery sets how many appends pass between rewrites that
+	// drop expired and excess events from the append-only file.
+	queueLogCompactEvery = 500
+)
+
+func (s *DiskStore) AppendQueueLogEvent(ctx context.Context, event QueueLogEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue log event: %w", err)
+	}
+
+	s.queueLogMu.Lock()
+	defer s.queueLogMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(queueLogFile), 0755); err != nil {
+		return fmt.Errorf("failed to create queue log directory: %w", err)
+	}
+	f, err := os.OpenFile(queueLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue log: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append queue log event: %w", err)
+	}
+
+	s.queueLogAppends++
+	if s.queueLogAppends%queueLogCompactEvery == 0 {
+		return s.compactQueueLog()
+	}
+	return nil
+}
+
+func (s *DiskStore) ListQueueLogEvents(ctx context.Context, query QueueLogQuery) ([]QueueLogEvent, error) {
+	s.queueLogMu.Lock()
+	defer s.queueLogMu.Unlock()
+
+	events, err := s.readQueueLog()
+	if err != nil {
+		return nil, err
+	}
+	return query.apply(trimQueueLog(events, time.Now())), nil
+}
+
+func (s *DiskStore) readQueueLog() ([]QueueLogEvent, error) {
+	data, err := os.ReadFile(queueLogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []QueueLogEvent{}, nil
+		}
+		return nil, fmt.Errorf("failed to read queue log: %w", err)
+	}
+	events := []QueueLogEvent{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var event QueueLogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			// a crash mid-append leaves a partial last line
+			slog.Warn("skipping corrupt queue log line", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// compactQueueLog rewrites the log without expired or excess events.
+// Callers must hold queueLogMu.
+func (s *DiskStore) compactQueueLog() error {
+	events, err := s.readQueueLog()
+	if err != nil {
+		return err
+	}
+	return writeQueueLog(trimQueueLog(events, time.Now()))
+}
+
+// writeQueueLog replaces the log with events, given newest first.
+// Callers must hold queueLogMu.
+func writeQueueLog(events []QueueLogEvent) error {
+	var buf strings.Builder
+	for i := len(events) - 1; i >= 0; i-- {
+		line, err := json.Marshal(events[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal queue log event: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	tmp := queueLogFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write queue log: %w", err)
+	}
+	if err := os.Rename(tmp, queueLogFile); err != nil {
+		return fmt.Errorf("failed to replace queue log: %w", err)
+	}
+	return nil
+}
+
+// ========== STATS STORAGE ==========
+
+const activityFile = "keystore/activity.json"
+
+func (s *DiskStore) IncrementActivity(ctx context.Context, kind ActivityKind, at time.Time) error {
+	if !kind.Valid() {
+		return ErrInvalidActivityKind
+	}
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	buckets, err := s.readActivity()
+	if err != nil {
+		return err
+	}
+	key := activityKey(at)
+	counts := buckets[key]
+	counts.Add(kind, 1)
+	buckets[key] = counts
+	cutoff := activityKey(time.Now().Add(-ActivityRetention))
+	for bucket := range buckets {
+		if bucket < cutoff {
+			delete(buckets, bucket)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(activityFile), 0755); err != nil {
+		return fmt.Errorf("failed to create stats directory: %w", err)
+	}
+	data, err := json.Marshal(buckets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %w", err)
+	}
+	if err := os.WriteFile(activityFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write activity: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) ListActivity(ctx context.Context, from, to time.Time) ([]ActivityBucket, error) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	buckets, err := s.readActivity()
+	if err != nil {
+		return nil, err
+	}
+	first, last := activityKey(from), activityKey(to)
+	out := []ActivityBucket{}
+	for key, counts := range buckets {
+		if key < first || key > last {
+			continue
+		}
+		if start, ok := parseActivityKey(key); ok {
+			out = append(out, ActivityBucket{Start: start, ActivityCounts: counts})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
+
+func (s *DiskStore) readActivity() (map[string]ActivityCounts, error) {
+	buckets := map[string]ActivityCounts{}
+	data, err := os.ReadFile(activityFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return buckets, nil
+		}
+		return nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal activity: %w", err)
+	}
+	return buckets, nil
+}
+
+// TotalQueueSize sums the queued events of every user.
+func (s *DiskStore) TotalQueueSize(ctx context.Context) (int, error) {
+	userIDs, err := s.ListUsersWithQueuedEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, userID := range userIDs {
+		size, err := s.GetQueueSize(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// ========== TRASH STORAGE ==========
+
+const trashBasePath = "keystore/trash"
+
+func trashFile(id string) string {
+	return filepath.Join(trashBasePath, id+".json")
+}
+
+func (s *DiskStore) PutTrashEntry(ctx context.Context, entry *TrashEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(trashBasePath, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+	if err := os.WriteFile(trashFile(entry.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write trash entry: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetTrashEntry(ctx context.Context, id string) (*TrashEntry, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, ErrTrashEntryNotFound
+	}
+	data, err := os.ReadFile(trashFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrTrashEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trash entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func (s *DiskStore) ListTrashEntries(ctx context.Context) ([]TrashEntry, error) {
+	files, err := os.ReadDir(trashBasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TrashEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+	entries := make([]TrashEntry, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		entry, err := s.GetTrashEntry(ctx, strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			slog.Warn("skipping unreadable trash entry", "file", file.Name(), "error", err)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].DeletedAt.After(entries[j].DeletedAt) })
+	return entries, nil
+}
+
+func (s *DiskStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil
+	}
+	if err := os.Remove(trashFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete trash entry: %w", err)
+	}
+	return nil
+}
+
+// ========== PLAYBACK SESSION STORAGE ==========
+
+const sessionBasePath = "keystore/sessions"
+
+func sessionFile(playerUUID, ratingKey string) string {
+	name := url.PathEscape(strings.TrimSpace(playerUUID)) + "_" + url.PathEscape(strings.TrimSpace(ratingKey))
+	return filepath.Join(sessionBasePath, name+".json")
+}
+
+func (s *DiskStore) PutPlaybackSession(ctx context.Context, session *PlaybackSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sessionBasePath, 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal playback session: %w", err)
+	}
+	if err := os.WriteFile(sessionFile(session.PlayerUUID, session.RatingKey), data, 0600); err != nil {
+		return fmt.Errorf("failed to write playback session: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*PlaybackSession, error) {
+	path := sessionFile(playerUUID, ratingKey)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to read playback session: %w", err)
+	}
+	var session PlaybackSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal playback session: %w", err)
+	}
+	if session.Expired(time.Now()) {
+		_ = os.Remove(path)
+		return nil, ErrPlaybackSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *DiskStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	if err := os.Remove(sessionFile(playerUUID, ratingKey)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete playback session: %w", err)
+	}
+	return nil
+}
+
+// ========== USER PREFERENCES STORAGE ==========
+
+const preferencesBasePath = "keystore/preferences"
+
+func preferencesFile(userID string) string {
+	return filepath.Join(preferencesBasePath, url.PathEscape(strings.TrimSpace(userID))+".json")
+}
+
+func (s *DiskStore) PutUserPreferences(ctx context.Context, prefs *UserPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(preferencesBasePath, 0755); err != nil {
+		return fmt.Errorf("failed to create preferences directory: %w", err)
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user preferences: %w", err)
+	}
+	if err := os.WriteFile(preferencesFile(prefs.UserID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write user preferences: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetUserPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	data, err := os.ReadFile(preferencesFile(userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUserPreferencesNotFound
+		}
+		return nil, fmt.Errorf("failed to read user preferences: %w", err)
+	}
+	var prefs UserPreferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+func (s *DiskStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	if err := os.Remove(preferencesFile(userID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete user preferences: %w", err)
+	}
+	return nil
+}
+
+// ========== WEBHOOK STATS STORAGE ==========
+
+const webhookStatsBasePath = "keystore/webhook_stats"
+
+// webhookStatsFile holds the daily counters of one subject, keyed by yyyymmdd.
+func webhookStatsFile(subject WebhookSubject, id string) string {
+	return filepath.Join(webhookStatsBasePath, string(subject), url.PathEscape(strings.TrimSpace(id))+".json")
+}
+
+func (s *DiskStore) IncrementWebhookStat(ctx context.Context, subject WebhookSubject, id string, outcome WebhookOutcome, at time.Time) error {
+	if err := validWebhookStat(subject, id, outcome); err != nil {
+		return err
+	}
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	path := webhookStatsFile(subject, id)
+	days, err := readWebhookStats(path)
+	if err != nil {
+		return err
+	}
+	key := webhookStatsKey(at)
+	counts := days[key]
+	counts.Add(outcome, 1)
+	days[key] = counts
+	cutoff := webhookStatsKey(time.Now().Add(-WebhookStatsRetention))
+	for day := range days {
+		if day < cutoff {
+			delete(days, day)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create webhook stats directory: %w", err)
+	}
+	data, err := json.Marshal(days)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook stats: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write webhook stats: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) ListWebhookStats(ctx context.Context, subject WebhookSubject, id string, from, to time.Time) ([]WebhookStatsDay, error) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	days, err := readWebhookStats(webhookStatsFile(subject, id))
+	if err != nil {
+		return nil, err
+	}
+	first, last := webhookStatsKey(from), webhookStatsKey(to)
+	out := []WebhookStatsDay{}
+	for key, counts := range days {
+		if key < first || key > last {
+			continue
+		}
+		if day, ok := parseWebhookStatsKey(key); ok {
+			out = append(out, WebhookStatsDay{Day: day, WebhookCounts: counts})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day.Before(out[j].Day) })
+	return out, nil
+}
+
+func readWebhookStats(path string) (map[string]WebhookCounts, error) {
+	days := map[string]WebhookCounts{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return days, nil
+		}
+		return nil, fmt.Errorf("failed to read webhook stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &days); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook stats: %w", err)
+	}
+	return days, nil
+}
+
+// ========== ADMIN TOTP STORAGE ==========
+
+const adminTOTPBasePath = "keystore/admin_totp"
+
+func adminTOTPFile(username string) string {
+	return filepath.Join(adminTOTPBasePath, url.PathEscape(adminTOTPKey(username))+".json")
+}
+
+func (s *DiskStore) PutAdminTOTP(ctx context.Context, t *AdminTOTP) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(adminTOTPBasePath, 0700); err != nil {
+		return fmt.Errorf("failed to create admin totp directory: %w", err)
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal admin totp: %w", err)
+	}
+	if err := os.WriteFile(adminTOTPFile(t.Username), data, 0600); err != nil {
+		return fmt.Errorf("failed to write admin totp: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetAdminTOTP(ctx context.Context, username string) (*AdminTOTP, error) {
+	data, err := os.ReadFile(adminTOTPFile(username))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrAdminTOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to read admin totp: %w", err)
+	}
+	var t AdminTOTP
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal admin totp: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *DiskStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	if err := os.Remove(adminTOTPFile(username)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete admin totp: %w", err)
+	}
+	return nil
+}
+
+// ========== PLEX WEBHOOK STORAGE ==========
+
+const plexWebhookBasePath = "keystore/plex_webhooks"
+
+func plexWebhookFile(id string) string {
+	return filepath.Join(plexWebhookBasePath, url.PathEscape(strings.TrimSpace(id))+".json")
+}
+
+func (s *DiskStore) PutPlexWebhook(ctx context.Context, w *PlexWebhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(plexWebhookBasePath, 0700); err != nil {
+		return fmt.Errorf("failed to create plex webhook directory: %w", err)
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plex webhook: %w", err)
+	}
+	if err := os.WriteFile(plexWebhookFile(w.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write plex webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) GetPlexWebhook(ctx context.Context, id string) (*PlexWebhook, error) {
+	data, err := os.ReadFile(plexWebhookFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrPlexWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to read plex webhook: %w", err)
+	}
+	var w PlexWebhook
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plex webhook: %w", err)
+	}
+	return &w, nil
+}
+
+func (s *DiskStore) ListPlexWebhooks(ctx context.Context) ([]PlexWebhook, error) {
+	files, err := os.ReadDir(plexWebhookBasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []PlexWebhook{}, nil
+		}
+		return nil, fmt.Errorf("failed to read plex webhook directory: %w", err)
+	}
+	hooks := make([]PlexWebhook, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(plexWebhookBasePath, file.Name()))
+		if err != nil {
+			slog.Warn("skipping unreadable plex webhook", "file", file.Name(), "error", err)
+			continue
+		}
+		var w PlexWebhook
+		if err := json.Unmarshal(data, &w); err != nil {
+			slog.Warn("skipping corrupt plex webhook", "file", file.Name(), "error", err)
+			continue
+		}
+		hooks = append(hooks, w)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks, nil
+}
+
+func (s *DiskStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	if err := os.Remove(plexWebhookFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete plex webhook: %w", err)
+	}
+	return nil
+}
+
+// ========== RETENTION STORAGE ==========
+
+func (s *DiskStore) PurgeBefore(ctx context.Context, category RetentionCategory, before time.Time) (int, error) {
+	switch category {
+	case RetentionHistory:
+		return s.purgeWatchHistory(before)
+	case RetentionQueueLog:
+		return s.purgeQueueLog(before)
+	case RetentionTelemetry:
+		return s.purgeTelemetry(before)
+	default:
+		return 0, ErrInvalidRetentionCategory
+	}
+}
+
+func (s *DiskStore) purgeWatchHistory(before time.Time) (int, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	entries, err := os.ReadDir(watchHistoryBasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to list watch history: %w", err)
+	}
+	purged := 0
+	for _, entry := range entries {
+		userID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		movies, err := s.readWatchHistory(userID)
+		if err != nil {
+			return purged, err
+		}
+		kept := slices.DeleteFunc(slices.Clone(movies), func(m WatchedMovie) bool { return m.WatchedAt.Before(before) })
+		if len(kept) == len(movies) {
+			continue
+		}
+		data, err := json.Marshal(kept)
+		if err != nil {
+			return purged, fmt.Errorf("failed to marshal watch history: %w", err)
+		}
+		if err := os.WriteFile(watchHistoryFile(userID), data, 0644); err != nil {
+			return purged, fmt.Errorf("failed to write watch history file: %w", err)
+		}
+		purged += len(movies) - len(kept)
+	}
+	return purged, nil
+}
+
+func (s *DiskStore) purgeQueueLog(before time.Time) (int, error) {
+	s.queueLogMu.Lock()
+	defer s.queueLogMu.Unlock()
+
+	events, err := s.readQueueLog()
+	if err != nil {
+		return 0, err
+	}
+	events = trimQueueLog(events, time.Now())
+	kept := slices.DeleteFunc(slices.Clone(events), func(e QueueLogEvent) bool { return e.Timestamp.Before(before) })
+	if len(kept) == len(events) {
+		return 0, nil
+	}
+	return len(events) - len(kept), writeQueueLog(kept)
+}
+
+func (s *DiskStore) purgeTelemetry(before time.Time) (int, error) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	purged := 0
+	buckets, err := s.readActivity()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := activityKey(before)
+	for bucket := range buckets {
+		if bucket < cutoff {
+			delete(buckets, bucket)
+			purged++
+		}
+	}
+	if purged > 0 {
+		data, err := json.Marshal(buckets)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal activity: %w", err)
+		}
+		if err := os.WriteFile(activityFile, data, 0644); err != nil {
+			return 0, fmt.Errorf("failed to write activity: %w", err)
+		}
+	}
+
+	cutoff = webhookStatsKey(before)
+	err = filepath.WalkDir(webhookStatsBasePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		days, err := readWebhookStats(path)
+		if err != nil {
+			return err
+		}
+		removed := 0
+		for day := range days {
+			if day < cutoff {
+				delete(days, day)
+				removed++
+			}
+		}
+		if removed == 0 {
+			return nil
+		}
+		data, err := json.Marshal(days)
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook stats: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write webhook stats: %w", err)
+		}
+		purged += removed
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return purged, err
+	}
+	return purged, nil
+}
+
 func (s *DiskStore) addToFallbackBuffer(userID string, event QueuedScrobbleEvent) {
 	s.bufferMu.Lock()
 	defer s.bufferMu.Unlock()
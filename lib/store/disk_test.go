@@ -60,6 +60,32 @@ func TestDiskGetUserLegacyWithoutDisplayName(t *testing.T) {
 	assert.Equal(t, "", user.TraktDisplayName)
 }
 
+func TestDiskCompareAndSwapUser(t *testing.T) {
+	_ = os.RemoveAll("keystore")
+	defer os.RemoveAll("keystore")
+
+	store := NewDiskStore()
+	ctx := context.Background()
+
+	store.WriteUser(User{ID: "u1", Username: "alice", AccessToken: "a1", RefreshToken: "r1"})
+	read := store.GetUser("u1")
+	assert.Equal(t, int64(1), read.Version)
+
+	// Another writer lands first; the stale copy must be rejected.
+	store.WriteUser(User{ID: "u1", Username: "alice", AccessToken: "a2", RefreshToken: "r2"})
+	stale := *read
+	stale.Username = "bob"
+	assert.ErrorIs(t, store.CompareAndSwapUser(ctx, stale), ErrUserVersionConflict)
+	assert.Equal(t, "alice", store.GetUser("u1").Username)
+
+	fresh := *store.GetUser("u1")
+	fresh.Username = "bob"
+	assert.NoError(t, store.CompareAndSwapUser(ctx, fresh))
+	assert.Equal(t, int64(3), store.GetUser("u1").Version)
+
+	assert.ErrorIs(t, store.CompareAndSwapUser(ctx, User{ID: "missing"}), ErrUserNotFound)
+}
+
 // ========== FAMILY GROUP TESTS ==========
 
 func TestDiskCreateFamilyGroup(t *testing.T) {
@@ -120,6 +146,34 @@ func TestDiskCreateFamilyGroupDuplicatePlexUsername(t *testing.T) {
 	assert.Contains(t, err.Error(), "already exists")
 }
 
+func TestDiskCreateFamilyGroupWithMembersRollsBack(t *testing.T) {
+	_ = os.RemoveAll("keystore")
+	defer os.RemoveAll("keystore")
+
+	store := NewDiskStore()
+	ctx := context.Background()
+
+	group := &FamilyGroup{ID: "group123", PlexUsername: "TV"}
+	members := []*GroupMember{
+		{ID: "member1", TempLabel: "Dad", TraktUsername: "same_user", AuthorizationStatus: "authorized"},
+		{ID: "member2", TempLabel: "Mom", TraktUsername: "same_user", AuthorizationStatus: "authorized"},
+	}
+
+	// The duplicate Trakt username fails the second insert
+	err := store.CreateFamilyGroupWithMembers(ctx, group, members)
+	assert.Error(t, err)
+
+	fg, err := store.GetFamilyGroup(ctx, "group123")
+	assert.NoError(t, err)
+	assert.Nil(t, fg)
+	byPlex, err := store.GetFamilyGroupByPlex(ctx, "tv")
+	assert.NoError(t, err)
+	assert.Nil(t, byPlex)
+	orphan, err := store.GetGroupMember(ctx, "member1")
+	assert.NoError(t, err)
+	assert.Nil(t, orphan)
+}
+
 func TestDiskListFamilyGroups(t *testing.T) {
 	_ = os.RemoveAll("keystore")
 	defer os.RemoveAll("keystore")
@@ -345,3 +399,57 @@ func TestDiskDeleteFamilyGroup(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, byPlex)
 }
+
+// ========== PROVIDER TOKEN TESTS ==========
+
+func TestDiskProviderTokenRoundTrip(t *testing.T) {
+	_ = os.RemoveAll("keystore")
+	defer os.RemoveAll("keystore")
+
+	store := NewDiskStore()
+	ctx := context.Background()
+
+	_, err := store.GetProviderToken(ctx, "user1", "simkl")
+	assert.ErrorIs(t, err, ErrProviderTokenNotFound)
+
+	token := &ProviderToken{UserID: "user1", Provider: "Simkl", AccessToken: "simkl-token"}
+	assert.NoError(t, store.SaveProviderToken(ctx, token))
+	assert.False(t, token.CreatedAt.IsZero())
+
+	loaded, err := store.GetProviderToken(ctx, "user1", "simkl")
+	assert.NoError(t, err)
+	assert.Equal(t, "simkl-token", loaded.AccessToken)
+	assert.Equal(t, "simkl", loaded.Provider)
+	assert.Nil(t, loaded.TokenExpiry)
+
+	assert.NoError(t, store.DeleteProviderToken(ctx, "user1", "simkl"))
+	assert.NoError(t, store.DeleteProviderToken(ctx, "user1", "simkl"))
+	_, err = store.GetProviderToken(ctx, "user1", "simkl")
+	assert.ErrorIs(t, err, ErrProviderTokenNotFound)
+
+	assert.ErrorIs(t, store.SaveProviderToken(ctx, &ProviderToken{UserID: "user1", Provider: "simkl"}), ErrInvalidProviderToken)
+}
+
+func TestDiskWatchHistoryRoundTrip(t *testing.T) {
+	_ = os.RemoveAll("keystore")
+	defer os.RemoveAll("keystore")
+
+	store := NewDiskStore()
+	ctx := context.Background()
+
+	movies, err := store.ListWatchedMovies(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Empty(t, movies)
+
+	assert.NoError(t, store.RecordWatchedMovie(ctx, &WatchedMovie{UserID: "user1", Title: "Heat", Year: 1995, Imdb: "tt0113277"}))
+	assert.NoError(t, store.RecordWatchedMovie(ctx, &WatchedMovie{UserID: "user1", Title: "Ronin", Year: 1998}))
+	assert.ErrorIs(t, store.RecordWatchedMovie(ctx, &WatchedMovie{UserID: "user1"}), ErrInvalidWatchedMovie)
+
+	movies, err = store.ListWatchedMovies(ctx, "user1")
+	assert.NoError(t, err)
+	if assert.Len(t, movies, 2) {
+		assert.Equal(t, "Heat", movies[0].Title)
+		assert.Equal(t, "Ronin", movies[1].Title)
+		assert.False(t, movies[0].WatchedAt.IsZero())
+	}
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+)
+
+// familyGroupWriter is the subset of Store used to create a family group one
+// record at a time.
+type familyGroupWriter interface {
+	CreateFamilyGroup(ctx context.Context, group *FamilyGroup) error
+	DeleteFamilyGroup(ctx context.Context, groupID string) error
+	AddGroupMember(ctx context.Context, member *GroupMember) error
+	RemoveGroupMember(ctx context.Context, groupID, memberID string) error
+}
+
+// createFamilyGroupWithCleanup creates the group and its members for backends
+// without multi-record transactions. If any member fails, everything written so
+// far is removed so the caller never sees a group with missing members.
+func createFamilyGroupWithCleanup(ctx context.Context, s familyGroupWriter, group *FamilyGroup, members []*GroupMember) error {
+	if group == nil {
+		return ErrInvalidFamilyGroup
+	}
+	if err := s.CreateFamilyGroup(ctx, group); err != nil {
+		return err
+	}
+
+	attempted := make([]string, 0, len(members))
+	for _, member := range members {
+		if member == nil {
+			rollbackFamilyGroup(ctx, s, group.ID, attempted)
+			return ErrInvalidGroupMember
+		}
+		member.FamilyGroupID = group.ID
+		// Track the member before writing it: a failed write may still have
+		// left a partial record behind.
+		attempted = append(attempted, member.ID)
+		if err := s.AddGroupMember(ctx, member); err != nil {
+			rollbackFamilyGroup(ctx, s, group.ID, attempted)
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackFamilyGroup is the compensating action for createFamilyGroupWithCleanup.
+// Cleanup runs even if the request context was cancelled; failures are logged
+// because the original error is what the caller needs to see.
+func rollbackFamilyGroup(ctx context.Context, s familyGroupWriter, groupID string, memberIDs []string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, memberID := range memberIDs {
+		if memberID == "" {
+			continue
+		}
+		if err := s.RemoveGroupMember(ctx, groupID, memberID); err != nil {
+			slog.Warn("family group rollback: failed to remove member", "group_id", groupID, "member_id", memberID, "error", err)
+		}
+	}
+	if err := s.DeleteFamilyGroup(ctx, groupID); err != nil {
+		slog.Warn("family group rollback: failed to delete group", "group_id", groupID, "error", err)
+	}
+}
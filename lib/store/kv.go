@@ -0,0 +1,1363 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+)
+
+// ErrKeyNotFound is returned by KVBackend.Get for missing keys.
+var ErrKeyNotFound = errors.New("store: key not found")
+
+// KVPair is a single entry returned by KVBackend.List.
+type KVPair struct {
+	Key   string // relative to the backend namespace
+	Value []byte
+	Index uint64 // modify index, usable with CompareAndSwap
+}
+
+// KVBackend is the minimal key/value contract KVStore needs. Keys are
+// slash-separated paths relative to the backend's namespace.
+type KVBackend interface {
+	// Get returns the value and its modify index.
+	Get(ctx context.Context, key string) ([]byte, uint64, error)
+	Put(ctx context.Context, key string, value []byte) error
+	// CompareAndSwap writes value only if the key's modify index still equals
+	// index; index 0 means the key must not exist yet.
+	CompareAndSwap(ctx context.Context, key string, value []byte, index uint64) (bool, error)
+	// Delete removes a key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns every key under prefix.
+	List(ctx context.Context, prefix string) ([]KVPair, error)
+	Ping(ctx context.Context) error
+}
+
+const (
+	kvUserPrefix          = "users/"
+	kvUsernamePrefix      = "usernames/"
+	kvScrobblePrefix      = "scrobbles/"
+	kvQueuePrefix         = "queue/"       // queue/{user}/{created_at_ns}-{event}
+	kvQueueIndexPrefix    = "queue_index/" // queue_index/{event} -> queue key
+	kvGroupPrefix         = "family_groups/"
+	kvGroupPlexPrefix     = "family_groups_by_plex/"
+	kvMemberPrefix        = "group_members/"
+	kvGroupMembersPrefix  = "family_group_members/" // family_group_members/{group}/{member}
+	kvRetryPrefix         = "retry_items/"
+	kvNotificationPrefix  = "notifications/"
+	kvProviderTokenPrefix = "provider_tokens/"
+	kvWatchHistoryPrefix  = "watch_history/" // watch_history/{user}/{watched_at_ns}-{n}
+	kvTrashPrefix         = "trash/"
+	kvSessionPrefix       = "playback_sessions/"
+	kvPreferencesPrefix   = "preferences/"
+	kvWebhookStatsPrefix  = "webhook_stats/"
+	kvAdminTOTPPrefix     = "admin_totp/"
+	kvPlexWebhookPrefix   = "plex_webhooks/"
+	kvActivityPrefix      = "activity/"  // activity/{yyyymmddhh} -> ActivityCounts
+	kvQueueLogPrefix      = "queue_log/" // queue_log/{timestamp_ns}-{n}
+
+	// kvCASAttempts bounds optimistic retry loops on contended keys.
+	kvCASAttempts = 5
+)
+
+// KVStore implements Store on top of a replicated key/value store such as
+// Consul KV, for small clusters that already run one and do not want to
+// operate Postgres.
+type KVStore struct {
+	kv KVBackend
+}
+
+// NewKVStore creates a store over the given backend.
+func NewKVStore(kv KVBackend) *KVStore {
+	return &KVStore{kv: kv}
+}
+
+type kvUser struct {
+	ID               string    `json:"id"`
+	Username         string    `json:"username"`
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TraktDisplayName string    `json:"trakt_display_name,omitempty"`
+	Updated          time.Time `json:"updated"`
+	TokenExpiry      time.Time `json:"token_expiry"`
+	TokenIssuedAt    time.Time `json:"token_issued_at,omitempty"`
+	Version          int64     `json:"version"`
+}
+
+func newKVUser(user User, version int64) kvUser {
+	return kvUser{
+		ID:               user.ID,
+		Username:         strings.ToLower(user.Username),
+		AccessToken:      user.AccessToken,
+		RefreshToken:     user.RefreshToken,
+		TraktDisplayName: user.TraktDisplayName,
+		Updated:          user.Updated,
+		TokenExpiry:      user.TokenExpiry,
+		TokenIssuedAt:    user.TokenIssuedAt,
+		Version:          version,
+	}
+}
+
+func (u kvUser) user(s *KVStore) User {
+	return User{
+		ID:               u.ID,
+		Username:         u.Username,
+		AccessToken:      u.AccessToken,
+		RefreshToken:     u.RefreshToken,
+		TraktDisplayName: u.TraktDisplayName,
+		Updated:          u.Updated,
+		TokenExpiry:      u.TokenExpiry,
+		TokenIssuedAt:    u.TokenIssuedAt,
+		Version:          u.Version,
+		store:            s,
+	}
+}
+
+// kvGroupMember keeps the member tokens, which GroupMember hides from JSON.
+type kvGroupMember struct {
+	GroupMember
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type kvScrobble struct {
+	Item      common.CacheItem `json:"item"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+func (s *KVStore) getJSON(ctx context.Context, key string, v any) (uint64, error) {
+	data, index, err := s.kv.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return 0, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return index, nil
+}
+
+func (s *KVStore) putJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return s.kv.Put(ctx, key, data)
+}
+
+func (s *KVStore) casJSON(ctx context.Context, key string, v any, index uint64) (bool, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return s.kv.CompareAndSwap(ctx, key, data, index)
+}
+
+// Ping checks that the backend is reachable.
+func (s *KVStore) Ping(ctx context.Context) error {
+	return s.kv.Ping(ctx)
+}
+
+// ========== USER METHODS ==========
+
+// WriteUser stores the user, bumping its version.
+func (s *KVStore) WriteUser(user User) {
+	ctx := context.Background()
+	key := kvUserPrefix + user.ID
+	for attempt := 0; ; attempt++ {
+		var current kvUser
+		index, err := s.getJSON(ctx, key, &current)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			panic(err)
+		}
+		ok, err := s.casJSON(ctx, key, newKVUser(user, current.Version+1), index)
+		if err != nil {
+			panic(err)
+		}
+		if ok {
+			break
+		}
+		if attempt >= kvCASAttempts {
+			panic(fmt.Errorf("write user %s: %w", user.ID, ErrUserVersionConflict))
+		}
+	}
+	// a username stays bound to the first id that claimed it
+	if s.GetUserByName(user.Username) == nil {
+		if err := s.kv.Put(ctx, kvUsernamePrefix+strings.ToLower(user.Username), []byte(user.ID)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// CompareAndSwapUser writes the user only if its version matches the stored one.
+func (s *KVStore) CompareAndSwapUser(ctx context.Context, user User) error {
+	key := kvUserPrefix + user.ID
+	var current kvUser
+	index, err := s.getJSON(ctx, key, &current)
+	if errors.Is(err, ErrKeyNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current.Version != user.Version {
+		return ErrUserVersionConflict
+	}
+	ok, err := s.casJSON(ctx, key, newKVUser(user, current.Version+1), index)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserVersionConflict
+	}
+	if s.GetUserByName(user.Username) == nil {
+		return s.kv.Put(ctx, kvUsernamePrefix+strings.ToLower(user.Username), []byte(user.ID))
+	}
+	return nil
+}
+
+func (s *KVStore) GetUser(id string) *User {
+	var stored kvUser
+	if _, err := s.getJSON(context.Background(), kvUserPrefix+id, &stored); err != nil {
+		if !errors.Is(err, ErrKeyNotFound) {
+			slog.Warn("failed to load user", "id", id, "error", err)
+		}
+		return nil
+	}
+	user := stored.user(s)
+	return &user
+}
+
+func (s *KVStore) GetUserByName(username string) *User {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		return nil
+	}
+	id, _, err := s.kv.Get(context.Background(), kvUsernamePrefix+username)
+	if err != nil {
+		return nil
+	}
+	user := s.GetUser(string(id))
+	// The mapping goes stale when a user is renamed or deleted
+	if user == nil || user.Username != username {
+		return nil
+	}
+	return user
+}
+
+func (s *KVStore) DeleteUser(id, username string) bool {
+	ctx := context.Background()
+	if err := s.kv.Delete(ctx, kvUserPrefix+id); err != nil {
+		slog.Error("failed to delete user", "id", id, "error", err)
+		return false
+	}
+	key := kvUsernamePrefix + strings.ToLower(username)
+	if owner, _, err := s.kv.Get(ctx, key); err == nil && string(owner) == id {
+		_ = s.kv.Delete(ctx, key)
+	}
+	return true
+}
+
+func (s *KVStore) ListUsers() []User {
+	pairs, err := s.kv.List(context.Background(), kvUserPrefix)
+	if err != nil {
+		panic(err)
+	}
+	users := make([]User, 0, len(pairs))
+	for _, pair := range pairs {
+		var stored kvUser
+		if err := json.Unmarshal(pair.Value, &stored); err != nil {
+			slog.Warn("skipping corrupt user", "key", pair.Key, "error", err)
+			continue
+		}
+		users = append(users, stored.user(s))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Updated.After(users[j].Updated)
+	})
+	return users
+}
+
+// ========== SCROBBLE CACHE ==========
+
+func (s *KVStore) GetScrobbleBody(playerUuid, ratingKey string) common.CacheItem {
+	item := common.CacheItem{Body: common.ScrobbleBody{Progress: 0}}
+	ctx := context.Background()
+	key := kvScrobblePrefix + playerUuid + "/" + ratingKey
+	var cached kvScrobble
+	if _, err := s.getJSON(ctx, key, &cached); err != nil {
+		return item
+	}
+	if time.Now().After(cached.ExpiresAt) {
+		_ = s.kv.Delete(ctx, key)
+		return item
+	}
+	return cached.Item
+}
+
+func (s *KVStore) WriteScrobbleBody(item common.CacheItem) {
+	key := kvScrobblePrefix + item.PlayerUuid + "/" + item.RatingKey
+	cached := kvScrobble{Item: item, ExpiresAt: time.Now().Add(scrobbleTimeout)}
+	if err := s.putJSON(context.Background(), key, cached); err != nil {
+		slog.Warn("failed to cache scrobble body", "player", item.PlayerUuid, "rating_key", item.RatingKey, "error", err)
+	}
+}
+
+// ========== QUEUE METHODS ==========
+
+// EnqueueScrobble adds a scrobble event, evicting the oldest once the
+// per-user limit is reached.
+func (s *KVStore) EnqueueScrobble(ctx context.Context, event QueuedScrobbleEvent) error {
+	if event.ID == "" {
+		id, err := generateEventID()
+		if err != nil {
+			return fmt.Errorf("failed to generate event ID: %w", err)
+		}
+		event.ID = id
+	}
+	if err := validateEvent(event); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	data, err := serializeEvent(event)
+	if err != nil {
+		return fmt.Errorf("failed to serialize event: %w", err)
+	}
+
+	queueSize, err := s.GetQueueSize(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if queueSize >= maxQueuePerUser {
+		if oldest, err := s.DequeueScrobbles(ctx, event.UserID, 1); err == nil && len(oldest) > 0 {
+			if err := s.DeleteQueuedScrobble(ctx, oldest[0].ID); err == nil {
+				slog.Warn("queue event dropped due to size limit",
+					"operation", "queue_event_dropped",
+					"user_id", event.UserID,
+					"queue_size", maxQueuePerUser,
+				)
+			}
+		}
+	}
+
+	key := fmt.Sprintf("%s%s/%020d-%s", kvQueuePrefix, event.UserID, event.CreatedAt.UnixNano(), event.ID)
+	if err := s.kv.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	if err := s.kv.Put(ctx, kvQueueIndexPrefix+event.ID, []byte(key)); err != nil {
+		return fmt.Errorf("failed to index event: %w", err)
+	}
+
+	slog.Info("queue event enqueued",
+		"operation", "queue_enqueue",
+		"user_id", event.UserID,
+		"event_id", event.ID,
+		"queue_size", queueSize+1,
+	)
+	return nil
+}
+
+func (s *KVStore) listQueue(ctx context.Context, userID string) ([]KVPair, error) {
+	pairs, err := s.kv.List(ctx, kvQueuePrefix+userID+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue: %w", err)
+	}
+	// Keys embed a zero-padded timestamp, so lexical order is FIFO order
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+// DequeueScrobbles retrieves the oldest events for a user without removing them.
+func (s *KVStore) DequeueScrobbles(ctx context.Context, userID string, limit int) ([]QueuedScrobbleEvent, error) {
+	pairs, err := s.listQueue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events := []QueuedScrobbleEvent{}
+	for _, pair := range pairs {
+		if len(events) >= limit {
+			break
+		}
+		event, err := deserializeEvent(pair.Value)
+		if err != nil {
+			slog.Warn("failed to deserialize queue event", "user_id", userID, "key", pair.Key, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// DeleteQueuedScrobble removes an event; missing events are ignored.
+func (s *KVStore) DeleteQueuedScrobble(ctx context.Context, eventID string) error {
+	key, _, err := s.kv.Get(ctx, kvQueueIndexPrefix+eventID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find event: %w", err)
+	}
+	if err := s.kv.Delete(ctx, string(key)); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return s.kv.Delete(ctx, kvQueueIndexPrefix+eventID)
+}
+
+// UpdateQueuedScrobbleRetry updates retry count for an event.
+func (s *KVStore) UpdateQueuedScrobbleRetry(ctx context.Context, eventID string, retryCount int) error {
+	key, _, err := s.kv.Get(ctx, kvQueueIndexPrefix+eventID)
+	if err != nil {
+		return fmt.Errorf("event not found: %s", eventID)
+	}
+	data, _, err := s.kv.Get(ctx, string(key))
+	if err != nil {
+		return fmt.Errorf("event not found: %s", eventID)
+	}
+	event, err := deserializeEvent(data)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize event: %w", err)
+	}
+	event.RetryCount = retryCount
+	event.LastAttempt = time.Now()
+	if data, err = serializeEvent(event); err != nil {
+		return fmt.Errorf("failed to serialize event: %w", err)
+	}
+	return s.kv.Put(ctx, string(key), data)
+}
+
+func (s *KVStore) GetQueueSize(ctx context.Context, userID string) (int, error) {
+	pairs, err := s.listQueue(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(pairs), nil
+}
+
+func (s *KVStore) GetQueueStatus(ctx context.Context, userID string) (common.QueueStatus, error) {
+	status := common.QueueStatus{
+		UserID: userID,
+		Mode:   "live",
+	}
+	events, err := s.DequeueScrobbles(ctx, userID, maxQueuePerUser)
+	if err != nil {
+		return status, err
+	}
+	status.QueueSize = len(events)
+	if len(events) > 0 {
+		status.OldestEventAge = time.Since(events[0].CreatedAt)
+	}
+	return status, nil
+}
+
+func (s *KVStore) ListUsersWithQueuedEvents(ctx context.Context) ([]string, error) {
+	pairs, err := s.kv.List(ctx, kvQueuePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+	seen := map[string]struct{}{}
+	userIDs := []string{}
+	for _, pair := range pairs {
+		userID, _, ok := strings.Cut(strings.TrimPrefix(pair.Key, kvQueuePrefix), "/")
+		if !ok || userID == "" {
+			continue
+		}
+		if _, dup := seen[userID]; !dup {
+			seen[userID] = struct{}{}
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+func (s *KVStore) PurgeQueueForUser(ctx context.Context, userID string) (int, error) {
+	events, err := s.DequeueScrobbles(ctx, userID, maxQueuePerUser*2)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if err := s.DeleteQueuedScrobble(ctx, event.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// ========== FAMILY GROUP METHODS ==========
+
+func (s *KVStore) CreateFamilyGroup(ctx context.Context, group *FamilyGroup) error {
+	if group == nil {
+		return ErrInvalidFamilyGroup
+	}
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if group.ID == "" {
+		group.ID = uuid()
+	}
+	now := time.Now().UTC()
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = now
+	}
+	group.UpdatedAt = now
+
+	// Claiming the Plex mapping first makes the username unique cluster-wide
+	claimed, err := s.kv.CompareAndSwap(ctx, kvGroupPlexPrefix+group.PlexUsername, []byte(group.ID), 0)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrDuplicateFamilyGroup
+	}
+	if err := s.putJSON(ctx, kvGroupPrefix+group.ID, group); err != nil {
+		_ = s.kv.Delete(ctx, kvGroupPlexPrefix+group.PlexUsername)
+		return err
+	}
+	return nil
+}
+
+// CreateFamilyGroupWithMembers creates a group and its members, removing any
+// partially written records if a step fails.
+func (s *KVStore) CreateFamilyGroupWithMembers(ctx context.Context, group *FamilyGroup, members []*GroupMember) error {
+	return createFamilyGroupWithCleanup(ctx, s, group, members)
+}
+
+func (s *KVStore) GetFamilyGroup(ctx context.Context, groupID string) (*FamilyGroup, error) {
+	var group FamilyGroup
+	if _, err := s.getJSON(ctx, kvGroupPrefix+strings.TrimSpace(groupID), &group); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrFamilyGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (s *KVStore) GetFamilyGroupByPlex(ctx context.Context, plexUsername string) (*FamilyGroup, error) {
+	plexUsername = strings.ToLower(strings.TrimSpace(plexUsername))
+	if plexUsername == "" {
+		return nil, ErrFamilyGroupNotFound
+	}
+	groupID, _, err := s.kv.Get(ctx, kvGroupPlexPrefix+plexUsername)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrFamilyGroupNotFound
+		}
+		return nil, err
+	}
+	return s.GetFamilyGroup(ctx, string(groupID))
+}
+
+func (s *KVStore) ListFamilyGroups(ctx context.Context) ([]*FamilyGroup, error) {
+	pairs, err := s.kv.List(ctx, kvGroupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*FamilyGroup, 0, len(pairs))
+	for _, pair := range pairs {
+		var group FamilyGroup
+		if err := json.Unmarshal(pair.Value, &group); err != nil {
+			slog.Warn("skipping corrupt family group", "key", pair.Key, "error", err)
+			continue
+		}
+		groups = append(groups, &group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].CreatedAt.Before(groups[j].CreatedAt) })
+	return groups, nil
+}
+
+// DeleteFamilyGroup removes the group with its members and retry items.
+func (s *KVStore) DeleteFamilyGroup(ctx context.Context, groupID string) error {
+	group, err := s.GetFamilyGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	members, err := s.ListGroupMembers(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := s.RemoveGroupMember(ctx, group.ID, member.ID); err != nil && !errors.Is(err, ErrGroupMemberNotFound) {
+			return err
+		}
+	}
+	items, err := s.kv.List(ctx, kvRetryPrefix)
+	if err != nil {
+		return err
+	}
+	for _, pair := range items {
+		var item RetryQueueItem
+		if json.Unmarshal(pair.Value, &item) == nil && item.FamilyGroupID == group.ID {
+			_ = s.kv.Delete(ctx, pair.Key)
+		}
+	}
+	if err := s.kv.Delete(ctx, kvGroupPrefix+group.ID); err != nil {
+		return err
+	}
+	return s.kv.Delete(ctx, kvGroupPlexPrefix+group.PlexUsername)
+}
+
+func (s *KVStore) AddGroupMember(ctx context.Context, member *GroupMember) error {
+	if member == nil {
+		return ErrInvalidGroupMember
+	}
+	if err := prepareGroupMember(member); err != nil {
+		return err
+	}
+	if _, err := s.GetFamilyGroup(ctx, member.FamilyGroupID); err != nil {
+		return err
+	}
+	if member.TraktUsername != "" {
+		if _, err := s.GetGroupMemberByTrakt(ctx, member.FamilyGroupID, member.TraktUsername); err == nil {
+			return ErrDuplicateGroupMember
+		}
+	}
+	if member.CreatedAt.IsZero() {
+		member.CreatedAt = time.Now().UTC()
+	}
+	if err := s.putMember(ctx, member); err != nil {
+		return err
+	}
+	return s.kv.Put(ctx, kvGroupMembersPrefix+member.FamilyGroupID+"/"+member.ID, []byte(member.ID))
+}
+
+func (s *KVStore) putMember(ctx context.Context, member *GroupMember) error {
+	return s.putJSON(ctx, kvMemberPrefix+member.ID, kvGroupMember{
+		GroupMember:  *member,
+		AccessToken:  member.AccessToken,
+		RefreshToken: member.RefreshToken,
+	})
+}
+
+func (s *KVStore) GetGroupMember(ctx context.Context, memberID string) (*GroupMember, error) {
+	var stored kvGroupMember
+	if _, err := s.getJSON(ctx, kvMemberPrefix+strings.TrimSpace(memberID), &stored); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrGroupMemberNotFound
+		}
+		return nil, err
+	}
+	member := stored.GroupMember
+	member.AccessToken = stored.AccessToken
+	member.RefreshToken = stored.RefreshToken
+	return &member, nil
+}
+
+func (s *KVStore) UpdateGroupMember(ctx context.Context, member *GroupMember) error {
+	if member == nil {
+		return ErrInvalidGroupMember
+	}
+	if member.TraktUsername != "" {
+		member.TraktUsername = strings.ToLower(member.TraktUsername)
+	}
+	if err := member.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.GetGroupMember(ctx, member.ID); err != nil {
+		return err
+	}
+	return s.putMember(ctx, member)
+}
+
+func (s *KVStore) RemoveGroupMember(ctx context.Context, groupID, memberID string) error {
+	membership := kvGroupMembersPrefix + groupID + "/" + memberID
+	if _, _, err := s.kv.Get(ctx, membership); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			// Clean up a member record written without its membership key
+			_ = s.kv.Delete(ctx, kvMemberPrefix+memberID)
+			return ErrGroupMemberNotFound
+		}
+		return err
+	}
+	if err := s.kv.Delete(ctx, membership); err != nil {
+		return err
+	}
+	return s.kv.Delete(ctx, kvMemberPrefix+memberID)
+}
+
+func (s *KVStore) ListGroupMembers(ctx context.Context, groupID string) ([]*GroupMember, error) {
+	pairs, err := s.kv.List(ctx, kvGroupMembersPrefix+groupID+"/")
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*GroupMember, 0, len(pairs))
+	for _, pair := range pairs {
+		member, err := s.GetGroupMember(ctx, string(pair.Value))
+		if err != nil {
+			slog.Warn("skipping missing group member", "group_id", groupID, "member_id", string(pair.Value), "error", err)
+			continue
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].CreatedAt.Before(members[j].CreatedAt) })
+	return members, nil
+}
+
+func (s *KVStore) GetGroupMemberByTrakt(ctx context.Context, groupID, traktUsername string) (*GroupMember, error) {
+	traktUsername = strings.ToLower(strings.TrimSpace(traktUsername))
+	members, err := s.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.TraktUsername == traktUsername {
+			return member, nil
+		}
+	}
+	return nil, ErrGroupMemberNotFound
+}
+
+// ========== RETRY QUEUE METHODS ==========
+
+func (s *KVStore) EnqueueRetryItem(ctx context.Context, item *RetryQueueItem) error {
+	if item == nil {
+		return ErrInvalidRetryItem
+	}
+	if item.ID == "" {
+		item.ID = uuid()
+	}
+	if item.Status == "" {
+		item.Status = RetryQueueStatusQueued
+	}
+	if err := item.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.GetGroupMember(ctx, item.GroupMemberID); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	return s.putJSON(ctx, kvRetryPrefix+item.ID, item)
+}
+
+// ListDueRetryItems returns due items and marks them retrying. Each item is
+// claimed with a compare-and-swap so concurrent workers never share one.
+func (s *KVStore) ListDueRetryItems(ctx context.Context, now time.Time, limit int) ([]*RetryQueueItem, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	pairs, err := s.kv.List(ctx, kvRetryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		item  RetryQueueItem
+		key   string
+		index uint64
+	}
+	var due []candidate
+	for _, pair := range pairs {
+		var item RetryQueueItem
+		if err := json.Unmarshal(pair.Value, &item); err != nil {
+			continue
+		}
+		if (item.Status == RetryQueueStatusQueued || item.Status == RetryQueueStatusRetrying) && !item.NextAttemptAt.After(now) {
+			due = append(due, candidate{item: item, key: pair.Key, index: pair.Index})
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].item.NextAttemptAt.Before(due[j].item.NextAttemptAt) })
+
+	items := []*RetryQueueItem{}
+	for _, c := range due {
+		if len(items) >= limit {
+			break
+		}
+		item := c.item
+		item.Status = RetryQueueStatusRetrying
+		item.UpdatedAt = time.Now().UTC()
+		ok, err := s.casJSON(ctx, c.key, item, c.index)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, &item)
+		}
+	}
+	return items, nil
+}
+
+func (s *KVStore) MarkRetrySuccess(ctx context.Context, id string) error {
+	key := kvRetryPrefix + strings.TrimSpace(id)
+	if _, _, err := s.kv.Get(ctx, key); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return ErrRetryItemNotFound
+		}
+		return err
+	}
+	return s.kv.Delete(ctx, key)
+}
+
+func (s *KVStore) MarkRetryFailure(ctx context.Context, id string, attempt int, nextAttempt time.Time, lastErr string, permanent bool) error {
+	key := kvRetryPrefix + strings.TrimSpace(id)
+	var item RetryQueueItem
+	if _, err := s.getJSON(ctx, key, &item); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return ErrRetryItemNotFound
+		}
+		return err
+	}
+	item.Status = RetryQueueStatusQueued
+	if permanent {
+		item.Status = RetryQueueStatusPermanentFailure
+		attempt = MaxRetryAttempts
+	}
+	item.AttemptCount = attempt
+	item.NextAttemptAt = nextAttempt
+	item.LastError = strings.TrimSpace(lastErr)
+	item.UpdatedAt = time.Now().UTC()
+	return s.putJSON(ctx, key, item)
+}
+
+// ========== NOTIFICATION METHODS ==========
+
+func (s *KVStore) CreateNotification(ctx context.Context, notification *Notification) error {
+	if err := notification.Validate(); err != nil {
+		return err
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	if err := s.putJSON(ctx, kvNotificationPrefix+notification.ID, notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+func (s *KVStore) GetNotifications(ctx context.Context, familyGroupID string, includeDismissed bool) ([]*Notification, error) {
+	pairs, err := s.kv.List(ctx, kvNotificationPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	var notifications []*Notification
+	for _, pair := range pairs {
+		var n Notification
+		if err := json.Unmarshal(pair.Value, &n); err != nil {
+			continue
+		}
+		if n.FamilyGroupID != familyGroupID || (n.Dismissed && !includeDismissed) {
+			continue
+		}
+		notifications = append(notifications, &n)
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].CreatedAt.After(notifications[j].CreatedAt) })
+	return notifications, nil
+}
+
+func (s *KVStore) DismissNotification(ctx context.Context, notificationID string) error {
+	key := kvNotificationPrefix + notificationID
+	var n Notification
+	if _, err := s.getJSON(ctx, key, &n); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return ErrNotificationNotFound
+		}
+		return fmt.Errorf("failed to dismiss notification: %w", err)
+	}
+	n.Dismissed = true
+	return s.putJSON(ctx, key, n)
+}
+
+func (s *KVStore) DeleteNotification(ctx context.Context, notificationID string) error {
+	key := kvNotificationPrefix + notificationID
+	if _, _, err := s.kv.Get(ctx, key); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return ErrNotificationNotFound
+		}
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	return s.kv.Delete(ctx, key)
+}
+
+// ========== PROVIDER TOKEN METHODS ==========
+
+func kvProviderTokenKey(userID, provider string) string {
+	return kvProviderTokenPrefix + strings.TrimSpace(userID) + "/" + strings.ToLower(strings.TrimSpace(provider))
+}
+
+func (s *KVStore) SaveProviderToken(ctx context.Context, token *ProviderToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.touch()
+	return s.putJSON(ctx, kvProviderTokenKey(token.UserID, token.Provider), token)
+}
+
+func (s *KVStore) GetProviderToken(ctx context.Context, userID, provider string) (*ProviderToken, error) {
+	var token ProviderToken
+	if _, err := s.getJSON(ctx, kvProviderTokenKey(userID, provider), &token); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrProviderTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *KVStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	return s.kv.Delete(ctx, kvProviderTokenKey(userID, provider))
+}
+
+// ========== QUEUE EVENT LOG METHODS ==========
+
+// AppendQueueLogEvent stores one key per event, then drops keys past
+// QueueLogRetention or beyond MaxQueueLogEvents.
+func (s *KVStore) AppendQueueLogEvent(ctx context.Context, event QueueLogEvent) error {
+	id, err := generateEventID()
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s%020d-%s", kvQueueLogPrefix, event.Timestamp.UnixNano(), id)
+	if err := s.putJSON(ctx, key, event); err != nil {
+		return fmt.Errorf("failed to append queue log event: %w", err)
+	}
+
+	pairs, err := s.kv.List(ctx, kvQueueLogPrefix)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key > pairs[j].Key })
+	cutoff := fmt.Sprintf("%s%020d", kvQueueLogPrefix, time.Now().Add(-QueueLogRetention).UnixNano())
+	for i, pair := range pairs {
+		if i >= MaxQueueLogEvents || pair.Key < cutoff {
+			_ = s.kv.Delete(ctx, pair.Key)
+		}
+	}
+	return nil
+}
+
+func (s *KVStore) ListQueueLogEvents(ctx context.Context, query QueueLogQuery) ([]QueueLogEvent, error) {
+	pairs, err := s.kv.List(ctx, kvQueueLogPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue log: %w", err)
+	}
+	events := make([]QueueLogEvent, 0, len(pairs))
+	for _, pair := range pairs {
+		var event QueueLogEvent
+		if err := json.Unmarshal(pair.Value, &event); err != nil {
+			slog.Warn("skipping corrupt queue log entry", "key", pair.Key, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return query.apply(trimQueueLog(events, time.Now())), nil
+}
+
+// ========== WATCH HISTORY METHODS ==========
+
+// RecordWatchedMovie stores one key per entry so a long history never hits
+// the backend's value size limit.
+func (s *KVStore) RecordWatchedMovie(ctx context.Context, movie *WatchedMovie) error {
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+	prefix := kvWatchHistoryPrefix + movie.UserID + "/"
+	id, err := generateEventID()
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s%020d-%s", prefix, movie.WatchedAt.UnixNano(), id)
+	if err := s.putJSON(ctx, key, movie); err != nil {
+		return fmt.Errorf("failed to record watched movie: %w", err)
+	}
+
+	pairs, err := s.kv.List(ctx, prefix)
+	if err != nil || len(pairs) <= MaxWatchedMoviesPerUser {
+		return nil
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	for _, pair := range pairs[:len(pairs)-MaxWatchedMoviesPerUser] {
+		_ = s.kv.Delete(ctx, pair.Key)
+	}
+	return nil
+}
+
+func (s *KVStore) ListWatchedMovies(ctx context.Context, userID string) ([]WatchedMovie, error) {
+	pairs, err := s.kv.List(ctx, kvWatchHistoryPrefix+strings.TrimSpace(userID)+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch history: %w", err)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	movies := make([]WatchedMovie, 0, len(pairs))
+	for _, pair := range pairs {
+		var movie WatchedMovie
+		if err := json.Unmarshal(pair.Value, &movie); err != nil {
+			slog.Warn("skipping corrupt watch history entry", "user_id", userID, "error", err)
+			continue
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+// ========== STATS METHODS ==========
+
+// IncrementActivity bumps the hourly bucket with a CAS loop. Creating a new
+// bucket also drops buckets older than ActivityRetention.
+func (s *KVStore) IncrementActivity(ctx context.Context, kind ActivityKind, at time.Time) error {
+	if !kind.Valid() {
+		return ErrInvalidActivityKind
+	}
+	key := kvActivityPrefix + activityKey(at)
+	for attempt := 0; ; attempt++ {
+		var counts ActivityCounts
+		index, err := s.getJSON(ctx, key, &counts)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("failed to read activity: %w", err)
+		}
+		counts.Add(kind, 1)
+		ok, err := s.casJSON(ctx, key, counts, index)
+		if err != nil {
+			return fmt.Errorf("failed to increment activity: %w", err)
+		}
+		if ok {
+			if index == 0 {
+				s.pruneActivity(ctx)
+			}
+			return nil
+		}
+		if attempt >= kvCASAttempts {
+			return fmt.Errorf("failed to increment activity: %s contended", key)
+		}
+	}
+}
+
+func (s *KVStore) pruneActivity(ctx context.Context) {
+	pairs, err := s.kv.List(ctx, kvActivityPrefix)
+	if err != nil {
+		return
+	}
+	cutoff := kvActivityPrefix + activityKey(time.Now().Add(-ActivityRetention))
+	for _, pair := range pairs {
+		if pair.Key < cutoff {
+			_ = s.kv.Delete(ctx, pair.Key)
+		}
+	}
+}
+
+func (s *KVStore) ListActivity(ctx context.Context, from, to time.Time) ([]ActivityBucket, error) {
+	pairs, err := s.kv.List(ctx, kvActivityPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	first, last := activityKey(from), activityKey(to)
+	out := []ActivityBucket{}
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, kvActivityPrefix)
+		if key < first || key > last {
+			continue
+		}
+		start, ok := parseActivityKey(key)
+		if !ok {
+			continue
+		}
+		bucket := ActivityBucket{Start: start}
+		if err := json.Unmarshal(pair.Value, &bucket.ActivityCounts); err != nil {
+			slog.Warn("skipping corrupt activity bucket", "key", pair.Key, "error", err)
+			continue
+		}
+		out = append(out, bucket)
+	}
+	return out, nil
+}
+
+// TotalQueueSize counts queued events across all users in one listing.
+func (s *KVStore) TotalQueueSize(ctx context.Context) (int, error) {
+	pairs, err := s.kv.List(ctx, kvQueuePrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list queues: %w", err)
+	}
+	return len(pairs), nil
+}
+
+// ========== TRASH METHODS ==========
+
+func (s *KVStore) PutTrashEntry(ctx context.Context, entry *TrashEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	return s.putJSON(ctx, kvTrashPrefix+entry.ID, entry)
+}
+
+func (s *KVStore) GetTrashEntry(ctx context.Context, id string) (*TrashEntry, error) {
+	var entry TrashEntry
+	if _, err := s.getJSON(ctx, kvTrashPrefix+strings.TrimSpace(id), &entry); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrTrashEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *KVStore) ListTrashEntries(ctx context.Context) ([]TrashEntry, error) {
+	pairs, err := s.kv.List(ctx, kvTrashPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash entries: %w", err)
+	}
+	entries := make([]TrashEntry, 0, len(pairs))
+	for _, pair := range pairs {
+		var entry TrashEntry
+		if err := json.Unmarshal(pair.Value, &entry); err != nil {
+			slog.Warn("skipping corrupt trash entry", "key", pair.Key, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].DeletedAt.After(entries[j].DeletedAt) })
+	return entries, nil
+}
+
+func (s *KVStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	return s.kv.Delete(ctx, kvTrashPrefix+strings.TrimSpace(id))
+}
+
+// ========== PLAYBACK SESSION METHODS ==========
+
+func (s *KVStore) PutPlaybackSession(ctx context.Context, session *PlaybackSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	return s.putJSON(ctx, kvSessionPrefix+session.PlayerUUID+"/"+session.RatingKey, session)
+}
+
+func (s *KVStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*PlaybackSession, error) {
+	key := kvSessionPrefix + strings.TrimSpace(playerUUID) + "/" + strings.TrimSpace(ratingKey)
+	var session PlaybackSession
+	if _, err := s.getJSON(ctx, key, &session); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, err
+	}
+	if session.Expired(time.Now()) {
+		_ = s.kv.Delete(ctx, key)
+		return nil, ErrPlaybackSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *KVStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	return s.kv.Delete(ctx, kvSessionPrefix+strings.TrimSpace(playerUUID)+"/"+strings.TrimSpace(ratingKey))
+}
+
+// ========== USER PREFERENCES METHODS ==========
+
+func (s *KVStore) PutUserPreferences(ctx context.Context, prefs *UserPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	return s.putJSON(ctx, kvPreferencesPrefix+prefs.UserID, prefs)
+}
+
+func (s *KVStore) GetUserPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	var prefs UserPreferences
+	if _, err := s.getJSON(ctx, kvPreferencesPrefix+strings.TrimSpace(userID), &prefs); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrUserPreferencesNotFound
+		}
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (s *KVStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	return s.kv.Delete(ctx, kvPreferencesPrefix+strings.TrimSpace(userID))
+}
+
+// ========== WEBHOOK STATS METHODS ==========
+
+// webhookStatsPrefix is webhook_stats/{subject}/{id}/; its keys end in the
+// day (yyyymmdd).
+func webhookStatsPrefix(subject WebhookSubject, id string) string {
+	return kvWebhookStatsPrefix + string(subject) + "/" + strings.TrimSpace(id) + "/"
+}
+
+// IncrementWebhookStat bumps the daily bucket with a CAS loop. Creating a new
+// bucket also drops the subject's days older than WebhookStatsRetention.
+func (s *KVStore) IncrementWebhookStat(ctx context.Context, subject WebhookSubject, id string, outcome WebhookOutcome, at time.Time) error {
+	if err := validWebhookStat(subject, id, outcome); err != nil {
+		return err
+	}
+	prefix := webhookStatsPrefix(subject, id)
+	key := prefix + webhookStatsKey(at)
+	for attempt := 0; ; attempt++ {
+		var counts WebhookCounts
+		index, err := s.getJSON(ctx, key, &counts)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("failed to read webhook stats: %w", err)
+		}
+		counts.Add(outcome, 1)
+		ok, err := s.casJSON(ctx, key, counts, index)
+		if err != nil {
+			return fmt.Errorf("failed to increment webhook stats: %w", err)
+		}
+		if ok {
+			if index == 0 {
+				s.pruneWebhookStats(ctx, prefix)
+			}
+			return nil
+		}
+		if attempt >= kvCASAttempts {
+			return fmt.Errorf("failed to increment webhook stats: %s contended", key)
+		}
+	}
+}
+
+func (s *KVStore) pruneWebhookStats(ctx context.Context, prefix string) {
+	pairs, err := s.kv.List(ctx, prefix)
+	if err != nil {
+		return
+	}
+	cutoff := prefix + webhookStatsKey(time.Now().Add(-WebhookStatsRetention))
+	for _, pair := range pairs {
+		if pair.Key < cutoff {
+			_ = s.kv.Delete(ctx, pair.Key)
+		}
+	}
+}
+
+func (s *KVStore) ListWebhookStats(ctx context.Context, subject WebhookSubject, id string, from, to time.Time) ([]WebhookStatsDay, error) {
+	prefix := webhookStatsPrefix(subject, id)
+	pairs, err := s.kv.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook stats: %w", err)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	first, last := webhookStatsKey(from), webhookStatsKey(to)
+	out := []WebhookStatsDay{}
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, prefix)
+		if key < first || key > last {
+			continue
+		}
+		day, ok := parseWebhookStatsKey(key)
+		if !ok {
+			continue
+		}
+		stats := WebhookStatsDay{Day: day}
+		if err := json.Unmarshal(pair.Value, &stats.WebhookCounts); err != nil {
+			slog.Warn("skipping corrupt webhook stats", "key", pair.Key, "error", err)
+			continue
+		}
+		out = append(out, stats)
+	}
+	return out, nil
+}
+
+// ========== ADMIN TOTP METHODS ==========
+
+func (s *KVStore) PutAdminTOTP(ctx context.Context, t *AdminTOTP) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	return s.putJSON(ctx, kvAdminTOTPPrefix+t.Username, t)
+}
+
+func (s *KVStore) GetAdminTOTP(ctx context.Context, username string) (*AdminTOTP, error) {
+	var t AdminTOTP
+	if _, err := s.getJSON(ctx, kvAdminTOTPPrefix+adminTOTPKey(username), &t); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrAdminTOTPNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *KVStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	return s.kv.Delete(ctx, kvAdminTOTPPrefix+adminTOTPKey(username))
+}
+
+// ========== RETENTION METHODS ==========
+
+// PurgeBefore compares keys against the cutoff; every category's keys end in
+// a sortable timestamp.
+func (s *KVStore) PurgeBefore(ctx context.Context, category RetentionCategory, before time.Time) (int, error) {
+	var prefix, cutoff string
+	switch category {
+	case RetentionHistory:
+		prefix, cutoff = kvWatchHistoryPrefix, fmt.Sprintf("%020d", before.UnixNano())
+	case RetentionQueueLog:
+		prefix, cutoff = kvQueueLogPrefix, fmt.Sprintf("%020d", before.UnixNano())
+	case RetentionTelemetry:
+		activity, err := s.purgeKeysBefore(ctx, kvActivityPrefix, activityKey(before))
+		if err != nil {
+			return activity, err
+		}
+		stats, err := s.purgeKeysBefore(ctx, kvWebhookStatsPrefix, webhookStatsKey(before))
+		return activity + stats, err
+	default:
+		return 0, ErrInvalidRetentionCategory
+	}
+	return s.purgeKeysBefore(ctx, prefix, cutoff)
+}
+
+// purgeKeysBefore deletes keys under prefix whose last path segment sorts
+// before cutoff.
+func (s *KVStore) purgeKeysBefore(ctx context.Context, prefix, cutoff string) (int, error) {
+	pairs, err := s.kv.List(ctx, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", strings.TrimSuffix(prefix, "/"), err)
+	}
+	purged := 0
+	for _, pair := range pairs {
+		if pair.Key[strings.LastIndex(pair.Key, "/")+1:] >= cutoff {
+			continue
+		}
+		if err := s.kv.Delete(ctx, pair.Key); err != nil {
+			return purged, fmt.Errorf("failed to delete %s: %w", pair.Key, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ========== PLEX WEBHOOK METHODS ==========
+
+func (s *KVStore) PutPlexWebhook(ctx context.Context, w *PlexWebhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	return s.putJSON(ctx, kvPlexWebhookPrefix+w.ID, w)
+}
+
+func (s *KVStore) GetPlexWebhook(ctx context.Context, id string) (*PlexWebhook, error) {
+	var w PlexWebhook
+	if _, err := s.getJSON(ctx, kvPlexWebhookPrefix+strings.TrimSpace(id), &w); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrPlexWebhookNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *KVStore) ListPlexWebhooks(ctx context.Context) ([]PlexWebhook, error) {
+	pairs, err := s.kv.List(ctx, kvPlexWebhookPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plex webhooks: %w", err)
+	}
+	hooks := make([]PlexWebhook, 0, len(pairs))
+	for _, pair := range pairs {
+		var w PlexWebhook
+		if err := json.Unmarshal(pair.Value, &w); err != nil {
+			slog.Warn("skipping corrupt plex webhook", "key", pair.Key, "error", err)
+			continue
+		}
+		hooks = append(hooks, w)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks, nil
+}
+
+func (s *KVStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	return s.kv.Delete(ctx, kvPlexWebhookPrefix+strings.TrimSpace(id))
+}
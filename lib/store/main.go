@@ -25,12 +25,19 @@ var (
 	ErrInvalidNotification = errors.New("store: invalid notification")
 	// ErrNotificationNotFound is returned when a notification lookup fails.
 	ErrNotificationNotFound = errors.New("store: notification not found")
+	// ErrUserNotFound is returned when a conditional user write targets a missing user.
+	ErrUserNotFound = errors.New("store: user not found")
+	// ErrUserVersionConflict is returned when a user changed since it was read.
+	ErrUserVersionConflict = errors.New("store: user was modified concurrently")
 )
 
 // Store is the interface for All the store types
 type Store interface {
 	// ========== EXISTING METHODS ==========
 	WriteUser(user User)
+	// CompareAndSwapUser writes user only if the stored version still equals
+	// user.Version, returning ErrUserVersionConflict otherwise.
+	CompareAndSwapUser(ctx context.Context, user User) error
 	GetUser(id string) *User
 	GetUserByName(username string) *User
 	DeleteUser(id, username string) bool
@@ -122,9 +129,20 @@ type Store interface {
 	//   - error: storage failure
 	PurgeQueueForUser(ctx context.Context, userID string) (int, error)
 
+	// ========== QUEUE EVENT LOG METHODS ==========
+
+	// AppendQueueLogEvent persists a queue monitoring event. Events older than
+	// QueueLogRetention or beyond MaxQueueLogEvents are dropped.
+	AppendQueueLogEvent(ctx context.Context, event QueueLogEvent) error
+	// ListQueueLogEvents returns persisted events matching query, newest first.
+	ListQueueLogEvents(ctx context.Context, query QueueLogQuery) ([]QueueLogEvent, error)
+
 	// ========== FAMILY GROUP METHODS ==========
 
 	CreateFamilyGroup(ctx context.Context, group *FamilyGroup) error
+	// CreateFamilyGroupWithMembers creates a group together with its initial
+	// members. Either all records are stored or none are.
+	CreateFamilyGroupWithMembers(ctx context.Context, group *FamilyGroup, members []*GroupMember) error
 	GetFamilyGroup(ctx context.Context, groupID string) (*FamilyGroup, error)
 	GetFamilyGroupByPlex(ctx context.Context, plexUsername string) (*FamilyGroup, error)
 	ListFamilyGroups(ctx context.Context) ([]*FamilyGroup, error)
@@ -148,6 +166,100 @@ type Store interface {
 	GetNotifications(ctx context.Context, familyGroupID string, includeDismissed bool) ([]*Notification, error)
 	DismissNotification(ctx context.Context, notificationID string) error
 	DeleteNotification(ctx context.Context, notificationID string) error
+
+	// ========== PROVIDER TOKEN METHODS ==========
+
+	// SaveProviderToken creates or replaces the token for (UserID, Provider).
+	SaveProviderToken(ctx context.Context, token *ProviderToken) error
+	// GetProviderToken returns ErrProviderTokenNotFound when the provider is not linked.
+	GetProviderToken(ctx context.Context, userID, provider string) (*ProviderToken, error)
+	// DeleteProviderToken unlinks a provider; deleting a missing token is not an error.
+	DeleteProviderToken(ctx context.Context, userID, provider string) error
+
+	// ========== WATCH HISTORY METHODS ==========
+
+	// RecordWatchedMovie appends a completed movie to the user's local history,
+	// keeping at most MaxWatchedMoviesPerUser entries.
+	RecordWatchedMovie(ctx context.Context, movie *WatchedMovie) error
+	// ListWatchedMovies returns the user's history ordered oldest first.
+	ListWatchedMovies(ctx context.Context, userID string) ([]WatchedMovie, error)
+
+	// ========== STATS METHODS ==========
+
+	// IncrementActivity adds one to the kind counter of the hourly bucket
+	// containing at. Buckets older than ActivityRetention are dropped.
+	IncrementActivity(ctx context.Context, kind ActivityKind, at time.Time) error
+	// ListActivity returns the non-empty hourly buckets from from's hour up to
+	// to, oldest first.
+	ListActivity(ctx context.Context, from, to time.Time) ([]ActivityBucket, error)
+	// TotalQueueSize returns the number of queued events across all users.
+	TotalQueueSize(ctx context.Context) (int, error)
+
+	// ========== TRASH METHODS ==========
+
+	// PutTrashEntry stores (or replaces) the snapshot of a deleted record.
+	PutTrashEntry(ctx context.Context, entry *TrashEntry) error
+	// GetTrashEntry returns ErrTrashEntryNotFound when no snapshot exists.
+	GetTrashEntry(ctx context.Context, id string) (*TrashEntry, error)
+	// ListTrashEntries returns all snapshots, most recently deleted first.
+	ListTrashEntries(ctx context.Context) ([]TrashEntry, error)
+	// DeleteTrashEntry removes a snapshot; deleting a missing entry is not an error.
+	DeleteTrashEntry(ctx context.Context, id string) error
+
+	// ========== PLAYBACK SESSION METHODS ==========
+
+	// PutPlaybackSession creates or replaces the session for (PlayerUUID, RatingKey).
+	PutPlaybackSession(ctx context.Context, session *PlaybackSession) error
+	// GetPlaybackSession returns ErrPlaybackSessionNotFound when no session
+	// exists or it has expired.
+	GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*PlaybackSession, error)
+	// DeletePlaybackSession removes a session; deleting a missing one is not an error.
+	DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error
+
+	// ========== USER PREFERENCES METHODS ==========
+
+	// PutUserPreferences creates or replaces the user's preferences document.
+	PutUserPreferences(ctx context.Context, prefs *UserPreferences) error
+	// GetUserPreferences returns ErrUserPreferencesNotFound when none were saved.
+	GetUserPreferences(ctx context.Context, userID string) (*UserPreferences, error)
+	// DeleteUserPreferences removes the document; deleting a missing one is not an error.
+	DeleteUserPreferences(ctx context.Context, userID string) error
+
+	// ========== WEBHOOK STATS METHODS ==========
+
+	// IncrementWebhookStat adds one to the outcome counter of the daily bucket
+	// of (subject, id) containing at. Days older than WebhookStatsRetention
+	// are dropped.
+	IncrementWebhookStat(ctx context.Context, subject WebhookSubject, id string, outcome WebhookOutcome, at time.Time) error
+	// ListWebhookStats returns the non-empty days of (subject, id) from from's
+	// day up to to, oldest first.
+	ListWebhookStats(ctx context.Context, subject WebhookSubject, id string, from, to time.Time) ([]WebhookStatsDay, error)
+
+	// ========== ADMIN TOTP METHODS ==========
+
+	// PutAdminTOTP creates or replaces the enrollment of t.Username.
+	PutAdminTOTP(ctx context.Context, t *AdminTOTP) error
+	// GetAdminTOTP returns ErrAdminTOTPNotFound when the account has not enrolled.
+	GetAdminTOTP(ctx context.Context, username string) (*AdminTOTP, error)
+	// DeleteAdminTOTP removes the enrollment; deleting a missing one is not an error.
+	DeleteAdminTOTP(ctx context.Context, username string) error
+
+	// ========== RETENTION METHODS ==========
+
+	// PurgeBefore deletes the category's records older than before and
+	// returns how many it removed. Counters count one per bucket.
+	PurgeBefore(ctx context.Context, category RetentionCategory, before time.Time) (int, error)
+
+	// ========== PLEX WEBHOOK METHODS ==========
+
+	// PutPlexWebhook creates or replaces the registration for w.ID.
+	PutPlexWebhook(ctx context.Context, w *PlexWebhook) error
+	// GetPlexWebhook returns ErrPlexWebhookNotFound when no token is saved for id.
+	GetPlexWebhook(ctx context.Context, id string) (*PlexWebhook, error)
+	// ListPlexWebhooks returns every registration, oldest first.
+	ListPlexWebhooks(ctx context.Context) ([]PlexWebhook, error)
+	// DeletePlexWebhook removes the registration; deleting a missing one is not an error.
+	DeletePlexWebhook(ctx context.Context, id string) error
 }
 
 // Utils
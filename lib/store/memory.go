@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// memoryKV is a process-local KVBackend. Nothing survives a restart.
+type memoryKV struct {
+	mu    sync.Mutex
+	data  map[string]KVPair
+	index uint64
+}
+
+// NewMemoryStore returns a Store that keeps everything in memory, for tests,
+// CI and demo deployments.
+func NewMemoryStore() *KVStore {
+	return NewKVStore(&memoryKV{data: map[string]KVPair{}})
+}
+
+func (m *memoryKV) Get(_ context.Context, key string) ([]byte, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pair, ok := m.data[key]
+	if !ok {
+		return nil, 0, ErrKeyNotFound
+	}
+	return append([]byte(nil), pair.Value...), pair.Index, nil
+}
+
+func (m *memoryKV) Put(_ context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value)
+	return nil
+}
+
+func (m *memoryKV) CompareAndSwap(_ context.Context, key string, value []byte, index uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data[key].Index != index {
+		return false, nil
+	}
+	m.set(key, value)
+	return true, nil
+}
+
+func (m *memoryKV) set(key string, value []byte) {
+	m.index++
+	m.data[key] = KVPair{Key: key, Value: append([]byte(nil), value...), Index: m.index}
+}
+
+func (m *memoryKV) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *memoryKV) List(_ context.Context, prefix string) ([]KVPair, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pairs []KVPair
+	for key, pair := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			pair.Value = append([]byte(nil), pair.Value...)
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
+}
+
+func (m *memoryKV) Ping(context.Context) error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreRetryItemLifecycle(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	group := &FamilyGroup{PlexUsername: "household"}
+	member := &GroupMember{TempLabel: "Dad"}
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, group, []*GroupMember{member}))
+
+	now := time.Now()
+	item := &RetryQueueItem{
+		FamilyGroupID: group.ID,
+		GroupMemberID: member.ID,
+		Payload:       json.RawMessage(`{"foo":"bar"}`),
+		NextAttemptAt: now.Add(-time.Minute),
+	}
+	require.NoError(t, s.EnqueueRetryItem(ctx, item))
+
+	due, err := s.ListDueRetryItems(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, RetryQueueStatusRetrying, due[0].Status)
+
+	require.NoError(t, s.MarkRetryFailure(ctx, item.ID, 1, now.Add(time.Hour), "timeout", false))
+	due, err = s.ListDueRetryItems(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due, "item rescheduled into the future must not be due")
+
+	require.NoError(t, s.MarkRetrySuccess(ctx, item.ID))
+	assert.ErrorIs(t, s.MarkRetrySuccess(ctx, item.ID), ErrRetryItemNotFound)
+}
+
+func TestMemoryStoreKeepsMemberTokens(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	group := &FamilyGroup{PlexUsername: "household"}
+	require.NoError(t, s.CreateFamilyGroup(ctx, group))
+	member := &GroupMember{FamilyGroupID: group.ID, TempLabel: "Dad", AccessToken: "access", RefreshToken: "refresh"}
+	require.NoError(t, s.AddGroupMember(ctx, member))
+
+	got, err := s.GetGroupMember(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "access", got.AccessToken)
+	assert.Equal(t, "refresh", got.RefreshToken)
+}
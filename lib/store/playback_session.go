@@ -0,0 +1,135 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// PlaybackSessionTTL is how long a session survives without a new event.
+const PlaybackSessionTTL = 24 * time.Hour
+
+// SessionSkimRatio is the share of the covered progress a session must have
+// actually played before a stop counts as finished. A session that jumps from
+// 0% to 95% with five minutes of playback was skimmed, not watched.
+const SessionSkimRatio = 0.25
+
+// Playback session states.
+const (
+	SessionPlaying = "playing"
+	SessionPaused  = "paused"
+	SessionStopped = "stopped"
+)
+
+var (
+	// ErrPlaybackSessionNotFound is returned when no live session exists.
+	ErrPlaybackSessionNotFound = errors.New("store: playback session not found")
+	// ErrInvalidPlaybackSession is returned when required fields are missing.
+	ErrInvalidPlaybackSession = errors.New("store: playback session is invalid")
+)
+
+// PlaybackSession follows one viewing of an item on a Plex player, keyed by
+// Player.UUID and ratingKey. Watch and pause time are measured by wall clock
+// between webhook events, so seeking does not count as watching.
+type PlaybackSession struct {
+	PlayerUUID    string        `json:"player_uuid"`
+	RatingKey     string        `json:"rating_key"`
+	UserID        string        `json:"user_id"`
+	State         string        `json:"state"`
+	StartedAt     time.Time     `json:"started_at"`
+	UpdatedAt     time.Time     `json:"updated_at"` // time of the last event
+	WatchTime     time.Duration `json:"watch_time"`
+	PausedTime    time.Duration `json:"paused_time"`
+	Pauses        int           `json:"pauses"`
+	StartProgress int           `json:"start_progress"`
+	Progress      int           `json:"progress"`
+	// Partial is set when the first event seen was not a play, e.g. after a
+	// restart mid-viewing. Partial sessions never count as skimmed.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// NewPlaybackSession starts a session with its first event. An empty state
+// (e.g. media.scrobble) is taken as playing.
+func NewPlaybackSession(playerUUID, ratingKey, userID, state string, at time.Time, progress int) *PlaybackSession {
+	s := &PlaybackSession{
+		PlayerUUID: playerUUID,
+		RatingKey:  ratingKey,
+		UserID:     userID,
+		Partial:    state != SessionPlaying,
+	}
+	if state == "" {
+		state = SessionPlaying
+	}
+	s.reset(state, at, progress)
+	return s
+}
+
+func (s *PlaybackSession) reset(state string, at time.Time, progress int) {
+	at = at.UTC()
+	s.State = state
+	s.StartedAt = at
+	s.UpdatedAt = at
+	s.WatchTime = 0
+	s.PausedTime = 0
+	s.Pauses = 0
+	s.StartProgress = progress
+	s.Progress = progress
+}
+
+// Advance credits the time since the previous event to the state the session
+// was in, then moves to state. An empty state keeps the current one. Playing
+// again after a stop begins a fresh session.
+func (s *PlaybackSession) Advance(state string, at time.Time, progress int) {
+	if s.State == SessionStopped && state == SessionPlaying {
+		s.Partial = false
+		s.reset(state, at, progress)
+		return
+	}
+	at = at.UTC()
+	if elapsed := at.Sub(s.UpdatedAt); elapsed > 0 {
+		switch s.State {
+		case SessionPlaying:
+			s.WatchTime += elapsed
+		case SessionPaused:
+			s.PausedTime += elapsed
+		}
+	}
+	if state == SessionPaused && s.State != SessionPaused {
+		s.Pauses++
+	}
+	if state != "" {
+		s.State = state
+	}
+	if at.After(s.UpdatedAt) {
+		s.UpdatedAt = at
+	}
+	s.Progress = progress
+}
+
+// Skimmed reports whether the session covered far more of an item of the
+// given length than it actually played.
+func (s PlaybackSession) Skimmed(duration time.Duration) bool {
+	if s.Partial || duration <= 0 || s.Progress <= s.StartProgress {
+		return false
+	}
+	covered := duration * time.Duration(s.Progress-s.StartProgress) / 100
+	return float64(s.WatchTime) < float64(covered)*SessionSkimRatio
+}
+
+// Expired reports whether the session has been idle past PlaybackSessionTTL.
+func (s PlaybackSession) Expired(now time.Time) bool {
+	return !now.Before(s.UpdatedAt.Add(PlaybackSessionTTL))
+}
+
+// Validate trims the key fields and ensures the session can be persisted.
+func (s *PlaybackSession) Validate() error {
+	if s == nil {
+		return ErrInvalidPlaybackSession
+	}
+	s.PlayerUUID = strings.TrimSpace(s.PlayerUUID)
+	s.RatingKey = strings.TrimSpace(s.RatingKey)
+	if s.PlayerUUID == "" || s.RatingKey == "" || s.UpdatedAt.IsZero() {
+		return ErrInvalidPlaybackSession
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaybackSessionAccumulatesWatchAndPauseTime(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	s := NewPlaybackSession("player-1", "42", "user-1", SessionPlaying, start, 0)
+
+	s.Advance(SessionPaused, start.Add(10*time.Minute), 10)
+	s.Advance(SessionPaused, start.Add(12*time.Minute), 10) // repeated pause is one gap
+	s.Advance(SessionPlaying, start.Add(15*time.Minute), 10)
+	s.Advance("", start.Add(40*time.Minute), 30) // media.scrobble keeps the state
+	s.Advance(SessionStopped, start.Add(50*time.Minute), 40)
+
+	assert.Equal(t, SessionStopped, s.State)
+	assert.Equal(t, 45*time.Minute, s.WatchTime)
+	assert.Equal(t, 5*time.Minute, s.PausedTime)
+	assert.Equal(t, 1, s.Pauses)
+	assert.Equal(t, 40, s.Progress)
+	assert.False(t, s.Skimmed(2*time.Hour))
+
+	// Out-of-order events never subtract time.
+	s.Advance(SessionStopped, start.Add(30*time.Minute), 40)
+	assert.Equal(t, 45*time.Minute, s.WatchTime)
+	assert.Equal(t, start.Add(50*time.Minute), s.UpdatedAt)
+
+	// Playing again after a stop is a new viewing.
+	s.Advance(SessionPlaying, start.Add(2*time.Hour), 0)
+	assert.Equal(t, start.Add(2*time.Hour), s.StartedAt)
+	assert.Zero(t, s.WatchTime)
+	assert.Zero(t, s.Pauses)
+}
+
+func TestPlaybackSessionSkimmed(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+
+	sample := NewPlaybackSession("player-1", "42", "user-1", SessionPlaying, start, 0)
+	sample.Advance(SessionStopped, start.Add(5*time.Minute), 95)
+	assert.True(t, sample.Skimmed(2*time.Hour), "five minutes is not watching 95%")
+	assert.False(t, sample.Skimmed(0), "unknown duration is never skimmed")
+
+	resumed := NewPlaybackSession("player-1", "42", "user-1", SessionPlaying, start, 80)
+	resumed.Advance(SessionStopped, start.Add(18*time.Minute), 95)
+	assert.False(t, resumed.Skimmed(2*time.Hour), "resuming near the end is a real finish")
+
+	partial := NewPlaybackSession("player-1", "42", "user-1", SessionPaused, start, 0)
+	partial.Advance(SessionPlaying, start.Add(time.Minute), 0)
+	partial.Advance(SessionStopped, start.Add(2*time.Minute), 95)
+	assert.True(t, partial.Partial)
+	assert.False(t, partial.Skimmed(2*time.Hour), "sessions picked up mid-viewing are trusted")
+
+	assert.False(t, sample.Expired(start.Add(time.Hour)))
+	assert.True(t, sample.Expired(start.Add(5*time.Minute+PlaybackSessionTTL)))
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPlexWebhookNotFound is returned when no Plex token is saved for an ID.
+	ErrPlexWebhookNotFound = errors.New("store: plex webhook registration not found")
+	// ErrInvalidPlexWebhook is returned when required fields are missing.
+	ErrInvalidPlexWebhook = errors.New("store: plex webhook registration is invalid")
+)
+
+// PlexWebhook is the Plex account token plaxt uses to keep its webhook
+// registered on that account. ID is the user or family group WebhookURL
+// points at.
+type PlexWebhook struct {
+	ID         string `json:"id"`
+	Token      string `json:"token"`
+	WebhookURL string `json:"webhook_url"`
+	// VerifiedAt is when the webhook was last seen on (or re-added to) the
+	// account; LastError holds the last failed check, cleared on success.
+	VerifiedAt time.Time `json:"verified_at,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate trims the fields, defaults the timestamps and ensures the ID,
+// token and URL are set.
+func (w *PlexWebhook) Validate() error {
+	if w == nil {
+		return ErrInvalidPlexWebhook
+	}
+	w.ID = strings.TrimSpace(w.ID)
+	w.Token = strings.TrimSpace(w.Token)
+	w.WebhookURL = strings.TrimSpace(w.WebhookURL)
+	now := time.Now().UTC()
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = now
+	}
+	if w.UpdatedAt.IsZero() {
+		w.UpdatedAt = now
+	}
+	w.CreatedAt, w.UpdatedAt = w.CreatedAt.UTC(), w.UpdatedAt.UTC()
+	if w.ID == "" || w.Token == "" || w.WebhookURL == "" {
+		return ErrInvalidPlexWebhook
+	}
+	return nil
+}
@@ -50,6 +50,14 @@ func NewPostgresqlClient(connStr string) *sql.DB {
 	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_expiry timestamp with time zone`); err != nil {
 		panic(err)
 	}
+	// Add token_issued_at column (migration)
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_issued_at timestamp with time zone`); err != nil {
+		panic(err)
+	}
+	// Add version column used for optimistic concurrency (migration)
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0`); err != nil {
+		panic(err)
+	}
 
 	// Create queued_scrobbles table (migration)
 	if _, err := db.Exec(`
@@ -70,6 +78,10 @@ func NewPostgresqlClient(connStr string) *sql.DB {
 		panic(err)
 	}
 
+	if _, err := db.Exec(`ALTER TABLE queued_scrobbles ADD COLUMN IF NOT EXISTS provider VARCHAR(32) NOT NULL DEFAULT ''`); err != nil {
+		panic(err)
+	}
+
 	// Create indexes
 	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_queued_scrobbles_user_time ON queued_scrobbles(user_id, created_at)`); err != nil {
 		panic(err)
@@ -138,6 +150,136 @@ func NewPostgresqlClient(connStr string) *sql.DB {
 		panic(err)
 	}
 
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS provider_tokens (
+			user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider VARCHAR(50) NOT NULL,
+			access_token TEXT NOT NULL,
+			refresh_token TEXT,
+			token_expiry TIMESTAMP WITH TIME ZONE,
+			account_name VARCHAR(255),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, provider)
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS watch_history (
+			id BIGSERIAL PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			title TEXT NOT NULL,
+			year INTEGER,
+			imdb VARCHAR(32),
+			tmdb INTEGER,
+			watched_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_watch_history_user_time ON watch_history(user_id, watched_at)`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS trash (
+			id VARCHAR(255) PRIMARY KEY,
+			kind VARCHAR(32) NOT NULL,
+			label VARCHAR(255) NOT NULL DEFAULT '',
+			deleted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			payload JSONB NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_preferences (
+			user_id VARCHAR(255) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			data JSONB NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS playback_sessions (
+			player_uuid VARCHAR(255) NOT NULL,
+			rating_key VARCHAR(255) NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			payload JSONB NOT NULL,
+			PRIMARY KEY (player_uuid, rating_key)
+		)
+	`); err != nil {
+		panic(err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_playback_sessions_updated ON playback_sessions(updated_at)`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS admin_totp (
+			username VARCHAR(255) PRIMARY KEY,
+			payload JSONB NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS plex_webhooks (
+			id VARCHAR(255) PRIMARY KEY,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_stats (
+			subject VARCHAR(32) NOT NULL,
+			subject_id VARCHAR(255) NOT NULL,
+			day TIMESTAMP WITH TIME ZONE NOT NULL,
+			received INTEGER NOT NULL DEFAULT 0,
+			processed INTEGER NOT NULL DEFAULT 0,
+			skipped INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (subject, subject_id, day)
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS activity_stats (
+			bucket TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+			scrobbles INTEGER NOT NULL DEFAULT 0,
+			failures INTEGER NOT NULL DEFAULT 0,
+			queued INTEGER NOT NULL DEFAULT 0
+		)
+	`); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS queue_event_log (
+			id BIGSERIAL PRIMARY KEY,
+			ts TIMESTAMP WITH TIME ZONE NOT NULL,
+			payload JSONB NOT NULL
+		)
+	`); err != nil {
+		panic(err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_queue_event_log_ts ON queue_event_log(ts)`); err != nil {
+		panic(err)
+	}
+
 	// Create indexes for family account tables
 	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_family_groups_plex_username ON family_groups(plex_username)`); err != nil {
 		panic(err)
@@ -179,10 +321,10 @@ func (s PostgresqlStore) WriteUser(user User) {
 	_, err := s.db.Exec(
 		`
 			INSERT INTO users
-				(id, username, access, refresh, trakt_display_name, updated, token_expiry)
-				VALUES($1, $2, $3, $4, $5, $6, $7)
+				(id, username, access, refresh, trakt_display_name, updated, token_expiry, token_issued_at)
+				VALUES($1, $2, $3, $4, $5, $6, $7, $8)
 			ON CONFLICT(id)
-			DO UPDATE set username=EXCLUDED.username, access=EXCLUDED.access, refresh=EXCLUDED.refresh, trakt_display_name=EXCLUDED.trakt_display_name, updated=EXCLUDED.updated, token_expiry=EXCLUDED.token_expiry
+			DO UPDATE set username=EXCLUDED.username, access=EXCLUDED.access, refresh=EXCLUDED.refresh, trakt_display_name=EXCLUDED.trakt_display_name, updated=EXCLUDED.updated, token_expiry=EXCLUDED.token_expiry, token_issued_at=EXCLUDED.token_issued_at, version=users.version+1
 		`,
 		user.ID,
 		user.Username,
@@ -191,12 +333,21 @@ func (s PostgresqlStore) WriteUser(user User) {
 		user.TraktDisplayName,
 		user.Updated,
 		user.TokenExpiry,
+		nullableIssuedAt(user.TokenIssuedAt),
 	)
 	if err != nil {
 		panic(err)
 	}
 }
 
+// nullableIssuedAt stores an unknown issue time as NULL.
+func nullableIssuedAt(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t, Valid: true}
+}
+
 // GetUser will load a user from postgres
 func (s PostgresqlStore) GetUser(id string) *User {
 	var username string
@@ -205,9 +356,11 @@ func (s PostgresqlStore) GetUser(id string) *User {
 	var updated time.Time
 	var displayName sql.NullString
 	var tokenExpiry sql.NullTime
+	var tokenIssuedAt sql.NullTime
+	var version int64
 
 	err := s.db.QueryRow(
-		"SELECT username, access, refresh, trakt_display_name, updated, token_expiry FROM users WHERE id=$1",
+		"SELECT username, access, refresh, trakt_display_name, updated, token_expiry, token_issued_at, version FROM users WHERE id=$1",
 		id,
 	).Scan(
 		&username,
@@ -216,6 +369,8 @@ func (s PostgresqlStore) GetUser(id string) *User {
 		&displayName,
 		&updated,
 		&tokenExpiry,
+		&tokenIssuedAt,
+		&version,
 	)
 	if err == sql.ErrNoRows {
 		return nil
@@ -238,12 +393,51 @@ func (s PostgresqlStore) GetUser(id string) *User {
 		TraktDisplayName: displayName.String,
 		Updated:          updated,
 		TokenExpiry:      expiry,
+		TokenIssuedAt:    tokenIssuedAt.Time,
+		Version:          version,
 		store:            s,
 	}
 
 	return &user
 }
 
+// CompareAndSwapUser updates the user only if its version matches the stored row.
+func (s *PostgresqlStore) CompareAndSwapUser(ctx context.Context, user User) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET username=$2, access=$3, refresh=$4, trakt_display_name=$5, updated=$6, token_expiry=$7, token_issued_at=$8, version=version+1
+		WHERE id=$1 AND version=$9
+	`,
+		user.ID,
+		user.Username,
+		user.AccessToken,
+		user.RefreshToken,
+		user.TraktDisplayName,
+		user.Updated,
+		user.TokenExpiry,
+		nullableIssuedAt(user.TokenIssuedAt),
+		user.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("compare and swap user: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("compare and swap user: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)`, user.ID).Scan(&exists); err != nil {
+		return fmt.Errorf("compare and swap user: %w", err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return ErrUserVersionConflict
+}
+
 // GetUserByName will load a user from postgres
 func (s PostgresqlStore) GetUserByName(username string) *User {
 	username = strings.ToLower(strings.TrimSpace(username))
@@ -268,7 +462,7 @@ func (s PostgresqlStore) DeleteUser(id, username string) bool {
 }
 
 func (s PostgresqlStore) ListUsers() []User {
-	rows, err := s.db.Query(`SELECT id, username, access, refresh, trakt_display_name, updated, token_expiry FROM users ORDER BY updated DESC`)
+	rows, err := s.db.Query(`SELECT id, username, access, refresh, trakt_display_name, updated, token_expiry, token_issued_at, version FROM users ORDER BY updated DESC`)
 	if err != nil {
 		panic(err)
 	}
@@ -284,8 +478,10 @@ func (s PostgresqlStore) ListUsers() []User {
 			display     sql.NullString
 			updated     time.Time
 			tokenExpiry sql.NullTime
+			issuedAt    sql.NullTime
+			version     int64
 		)
-		if err := rows.Scan(&id, &username, &access, &refresh, &display, &updated, &tokenExpiry); err != nil {
+		if err := rows.Scan(&id, &username, &access, &refresh, &display, &updated, &tokenExpiry, &issuedAt, &version); err != nil {
 			panic(err)
 		}
 
@@ -303,6 +499,8 @@ func (s PostgresqlStore) ListUsers() []User {
 			TraktDisplayName: display.String,
 			Updated:          updated,
 			TokenExpiry:      expiry,
+			TokenIssuedAt:    issuedAt.Time,
+			Version:          version,
 			store:            s,
 		}
 		users = append(users, user)
@@ -383,9 +581,9 @@ func (s *PostgresqlStore) EnqueueScrobble(ctx context.Context, event QueuedScrob
 	// Insert event (ON CONFLICT DO NOTHING for deduplication)
 	_, err = s.db.ExecContext(ctx, `
 		INSERT INTO queued_scrobbles
-			(id, user_id, scrobble_body, action, progress, created_at, retry_count, last_attempt, player_uuid, rating_key)
+			(id, user_id, scrobble_body, action, progress, created_at, retry_count, last_attempt, player_uuid, rating_key, provider)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		ON CONFLICT (player_uuid, rating_key) DO NOTHING
 	`,
 		event.ID,
@@ -398,6 +596,7 @@ func (s *PostgresqlStore) EnqueueScrobble(ctx context.Context, event QueuedScrob
 		sql.NullTime{Time: event.LastAttempt, Valid: !event.LastAttempt.IsZero()},
 		event.PlayerUUID,
 		event.RatingKey,
+		event.Provider,
 	)
 	if err != nil {
 		slog.Error("queue write failed, using fallback buffer",
@@ -425,7 +624,7 @@ func (s *PostgresqlStore) EnqueueScrobble(ctx context.Context, event QueuedScrob
 // DequeueScrobbles retrieves oldest N events from PostgreSQL.
 func (s *PostgresqlStore) DequeueScrobbles(ctx context.Context, userID string, limit int) ([]QueuedScrobbleEvent, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, user_id, scrobble_body, action, progress, created_at, retry_count, last_attempt, player_uuid, rating_key
+		SELECT id, user_id, scrobble_body, action, progress, created_at, retry_count, last_attempt, player_uuid, rating_key, provider
 		FROM queued_scrobbles
 		WHERE user_id = $1
 		ORDER BY created_at ASC
@@ -453,6 +652,7 @@ func (s *PostgresqlStore) DequeueScrobbles(ctx context.Context, userID string, l
 			&lastAttempt,
 			&event.PlayerUUID,
 			&event.RatingKey,
+			&event.Provider,
 		)
 		if err != nil {
 			slog.Warn("failed to scan queued event",
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IncrementActivity upserts the hourly bucket. When the upsert creates a new
+// bucket, buckets older than ActivityRetention are deleted.
+func (s *PostgresqlStore) IncrementActivity(ctx context.Context, kind ActivityKind, at time.Time) error {
+	if !kind.Valid() {
+		return ErrInvalidActivityKind
+	}
+	// kind is validated above and doubles as the column name
+	var created bool
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO activity_stats (bucket, %[1]s)
+		VALUES ($1, 1)
+		ON CONFLICT (bucket) DO UPDATE SET %[1]s = activity_stats.%[1]s + 1
+		RETURNING (xmax = 0)
+	`, kind), ActivityBucketStart(at)).Scan(&created); err != nil {
+		return fmt.Errorf("failed to increment activity: %w", err)
+	}
+	if created {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM activity_stats WHERE bucket < $1`,
+			ActivityBucketStart(time.Now().Add(-ActivityRetention))); err != nil {
+			return fmt.Errorf("failed to prune activity: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) ListActivity(ctx context.Context, from, to time.Time) ([]ActivityBucket, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT bucket, scrobbles, failures, queued
+		FROM activity_stats
+		WHERE bucket >= $1 AND bucket <= $2
+		ORDER BY bucket ASC
+	`, ActivityBucketStart(from), to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	out := []ActivityBucket{}
+	for rows.Next() {
+		var bucket ActivityBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Scrobbles, &bucket.Failures, &bucket.Queued); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		bucket.Start = bucket.Start.UTC()
+		out = append(out, bucket)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresqlStore) TotalQueueSize(ctx context.Context) (int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM queued_scrobbles`).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to get total queue size: %w", err)
+	}
+	return total, nil
+}
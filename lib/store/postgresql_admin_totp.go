@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+func (s *PostgresqlStore) PutAdminTOTP(ctx context.Context, t *AdminTOTP) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal admin totp: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO admin_totp (username, payload, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			updated_at = EXCLUDED.updated_at
+	`, t.Username, payload, t.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to store admin totp: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetAdminTOTP(ctx context.Context, username string) (*AdminTOTP, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT payload FROM admin_totp WHERE username = $1
+	`, adminTOTPKey(username)).Scan(&payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAdminTOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to get admin totp: %w", err)
+	}
+	var t AdminTOTP
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal admin totp: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *PostgresqlStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM admin_totp WHERE username = $1`, adminTOTPKey(username)); err != nil {
+		return fmt.Errorf("failed to delete admin totp: %w", err)
+	}
+	return nil
+}
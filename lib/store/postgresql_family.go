@@ -14,6 +14,12 @@ type rowScanner interface {
 	Scan(dest ...any) error
 }
 
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx so inserts can run
+// standalone or as part of a transaction.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 func scanFamilyGroupRow(rs rowScanner) (*FamilyGroup, error) {
 	var fg FamilyGroup
 	if err := rs.Scan(&fg.ID, &fg.PlexUsername, &fg.CreatedAt, &fg.UpdatedAt); err != nil {
@@ -78,8 +84,50 @@ func (s PostgresqlStore) CreateFamilyGroup(ctx context.Context, group *FamilyGro
 	if group.ID == "" {
 		group.ID = uuid()
 	}
+	return insertFamilyGroup(ctx, s.db, group)
+}
 
-	err := s.db.QueryRowContext(ctx, `
+// CreateFamilyGroupWithMembers inserts the group and all members in a single
+// transaction so a failure never leaves a group without its members.
+func (s *PostgresqlStore) CreateFamilyGroupWithMembers(ctx context.Context, group *FamilyGroup, members []*GroupMember) error {
+	if group == nil {
+		return ErrInvalidFamilyGroup
+	}
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if group.ID == "" {
+		group.ID = uuid()
+	}
+	for _, member := range members {
+		if member == nil {
+			return ErrInvalidGroupMember
+		}
+		member.FamilyGroupID = group.ID
+		if err := prepareGroupMember(member); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := insertFamilyGroup(ctx, tx, group); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, member := range members {
+		if err := insertGroupMember(ctx, tx, member); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func insertFamilyGroup(ctx context.Context, q rowQuerier, group *FamilyGroup) error {
+	err := q.QueryRowContext(ctx, `
 		INSERT INTO family_groups (id, plex_username)
 		VALUES ($1, $2)
 		RETURNING created_at, updated_at
@@ -186,6 +234,14 @@ func (s PostgresqlStore) AddGroupMember(ctx context.Context, member *GroupMember
 	if member == nil {
 		return ErrInvalidGroupMember
 	}
+	if err := prepareGroupMember(member); err != nil {
+		return err
+	}
+	return insertGroupMember(ctx, s.db, member)
+}
+
+// prepareGroupMember fills defaults and validates a member before insert.
+func prepareGroupMember(member *GroupMember) error {
 	if member.AuthorizationStatus == "" {
 		member.AuthorizationStatus = GroupMemberStatusPending
 	}
@@ -195,11 +251,11 @@ func (s PostgresqlStore) AddGroupMember(ctx context.Context, member *GroupMember
 	if member.TraktUsername != "" {
 		member.TraktUsername = strings.ToLower(member.TraktUsername)
 	}
-	if err := member.Validate(); err != nil {
-		return err
-	}
+	return member.Validate()
+}
 
-	err := s.db.QueryRowContext(ctx, `
+func insertGroupMember(ctx context.Context, q rowQuerier, member *GroupMember) error {
+	err := q.QueryRowContext(ctx, `
 		INSERT INTO group_members (
 			id, family_group_id, temp_label, trakt_username,
 			access_token, refresh_token, token_expiry, authorization_status
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func (s *PostgresqlStore) PutPlexWebhook(ctx context.Context, w *PlexWebhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plex webhook: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO plex_webhooks (id, payload, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			updated_at = EXCLUDED.updated_at
+	`, w.ID, payload, w.CreatedAt, w.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to store plex webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetPlexWebhook(ctx context.Context, id string) (*PlexWebhook, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT payload FROM plex_webhooks WHERE id = $1
+	`, strings.TrimSpace(id)).Scan(&payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPlexWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get plex webhook: %w", err)
+	}
+	var w PlexWebhook
+	if err := json.Unmarshal(payload, &w); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plex webhook: %w", err)
+	}
+	return &w, nil
+}
+
+func (s *PostgresqlStore) ListPlexWebhooks(ctx context.Context) ([]PlexWebhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM plex_webhooks ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plex webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []PlexWebhook{}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to scan plex webhook: %w", err)
+		}
+		var w PlexWebhook
+		if err := json.Unmarshal(payload, &w); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal plex webhook: %w", err)
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *PostgresqlStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM plex_webhooks WHERE id = $1`, strings.TrimSpace(id)); err != nil {
+		return fmt.Errorf("failed to delete plex webhook: %w", err)
+	}
+	return nil
+}
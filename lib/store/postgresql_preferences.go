@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *PostgresqlStore) PutUserPreferences(ctx context.Context, prefs *UserPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_preferences (user_id, data, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			updated_at = EXCLUDED.updated_at
+	`, prefs.UserID, []byte(prefs.Data), prefs.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to store user preferences: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetUserPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	var (
+		prefs UserPreferences
+		data  []byte
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, data, updated_at FROM user_preferences WHERE user_id = $1
+	`, strings.TrimSpace(userID)).Scan(&prefs.UserID, &data, &prefs.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserPreferencesNotFound
+		}
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	prefs.Data = data
+	return &prefs, nil
+}
+
+func (s *PostgresqlStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = $1`, strings.TrimSpace(userID)); err != nil {
+		return fmt.Errorf("failed to delete user preferences: %w", err)
+	}
+	return nil
+}
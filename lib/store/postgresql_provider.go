@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *PostgresqlStore) SaveProviderToken(ctx context.Context, token *ProviderToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.touch()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO provider_tokens
+			(user_id, provider, access_token, refresh_token, token_expiry, account_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, provider)
+		DO UPDATE SET access_token=EXCLUDED.access_token, refresh_token=EXCLUDED.refresh_token,
+			token_expiry=EXCLUDED.token_expiry, account_name=EXCLUDED.account_name, updated_at=EXCLUDED.updated_at
+	`,
+		token.UserID,
+		token.Provider,
+		token.AccessToken,
+		nullableString(token.RefreshToken),
+		nullableTime(token.TokenExpiry),
+		nullableString(token.AccountName),
+		token.CreatedAt,
+		token.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save provider token: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetProviderToken(ctx context.Context, userID, provider string) (*ProviderToken, error) {
+	var (
+		token   ProviderToken
+		refresh sql.NullString
+		expiry  sql.NullTime
+		account sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, provider, access_token, refresh_token, token_expiry, account_name, created_at, updated_at
+		FROM provider_tokens
+		WHERE user_id = $1 AND provider = $2
+	`, strings.TrimSpace(userID), strings.ToLower(strings.TrimSpace(provider))).Scan(
+		&token.UserID,
+		&token.Provider,
+		&token.AccessToken,
+		&refresh,
+		&expiry,
+		&account,
+		&token.CreatedAt,
+		&token.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrProviderTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider token: %w", err)
+	}
+	token.RefreshToken = refresh.String
+	token.AccountName = account.String
+	if expiry.Valid {
+		t := expiry.Time
+		token.TokenExpiry = &t
+	}
+	return &token, nil
+}
+
+func (s *PostgresqlStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM provider_tokens WHERE user_id = $1 AND provider = $2`,
+		strings.TrimSpace(userID), strings.ToLower(strings.TrimSpace(provider)))
+	if err != nil {
+		return fmt.Errorf("failed to delete provider token: %w", err)
+	}
+	return nil
+}
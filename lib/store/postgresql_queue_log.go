@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AppendQueueLogEvent inserts the event and drops rows past
+// QueueLogRetention or beyond MaxQueueLogEvents.
+func (s *PostgresqlStore) AppendQueueLogEvent(ctx context.Context, event QueueLogEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue log event: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO queue_event_log (ts, payload) VALUES ($1, $2)`, event.Timestamp, payload); err != nil {
+		return fmt.Errorf("failed to append queue log event: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM queue_event_log
+		WHERE ts < $1 OR id <= (
+			SELECT id FROM queue_event_log ORDER BY id DESC OFFSET $2 LIMIT 1
+		)
+	`, time.Now().Add(-QueueLogRetention), MaxQueueLogEvents); err != nil {
+		return fmt.Errorf("failed to prune queue log: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) ListQueueLogEvents(ctx context.Context, query QueueLogQuery) ([]QueueLogEvent, error) {
+	since := time.Now().Add(-QueueLogRetention)
+	if query.Since.After(since) {
+		since = query.Since
+	}
+	var until sql.NullTime
+	if !query.Until.IsZero() {
+		until = sql.NullTime{Time: query.Until, Valid: true}
+	}
+	var limit sql.NullInt64
+	if query.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(query.Limit), Valid: true}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT payload FROM queue_event_log
+		WHERE ts >= $1 AND ($2::timestamptz IS NULL OR ts <= $2)
+		ORDER BY ts DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, since, until, limit, max(query.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue log: %w", err)
+	}
+	defer rows.Close()
+
+	events := []QueueLogEvent{}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to scan queue log event: %w", err)
+		}
+		var event QueueLogEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			slog.Warn("skipping corrupt queue log entry", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *PostgresqlStore) PurgeBefore(ctx context.Context, category RetentionCategory, before time.Time) (int, error) {
+	var queries []string
+	var args []any
+	switch category {
+	case RetentionHistory:
+		queries = []string{`DELETE FROM watch_history WHERE watched_at < $1`}
+		args = []any{before}
+	case RetentionQueueLog:
+		queries = []string{`DELETE FROM queue_event_log WHERE ts < $1`}
+		args = []any{before}
+	case RetentionTelemetry:
+		queries = []string{`DELETE FROM activity_stats WHERE bucket < $1`, `DELETE FROM webhook_stats WHERE day < $1`}
+		args = []any{ActivityBucketStart(before), WebhookDayStart(before)}
+	default:
+		return 0, ErrInvalidRetentionCategory
+	}
+	purged := 0
+	for i, query := range queries {
+		res, err := s.db.ExecContext(ctx, query, args[i])
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", category, err)
+		}
+		n, _ := res.RowsAffected()
+		purged += int(n)
+	}
+	return purged, nil
+}
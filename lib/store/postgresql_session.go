@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (s *PostgresqlStore) PutPlaybackSession(ctx context.Context, session *PlaybackSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal playback session: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO playback_sessions (player_uuid, rating_key, updated_at, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (player_uuid, rating_key) DO UPDATE SET
+			updated_at = EXCLUDED.updated_at,
+			payload = EXCLUDED.payload
+	`, session.PlayerUUID, session.RatingKey, session.UpdatedAt, payload); err != nil {
+		return fmt.Errorf("failed to store playback session: %w", err)
+	}
+	// Idle sessions are pruned on write; there is no TTL in PostgreSQL.
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM playback_sessions WHERE updated_at < $1`, time.Now().Add(-PlaybackSessionTTL)); err != nil {
+		return fmt.Errorf("failed to prune playback sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*PlaybackSession, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT payload FROM playback_sessions WHERE player_uuid = $1 AND rating_key = $2
+	`, strings.TrimSpace(playerUUID), strings.TrimSpace(ratingKey)).Scan(&payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get playback session: %w", err)
+	}
+	var session PlaybackSession
+	if err := json.Unmarshal(payload, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal playback session: %w", err)
+	}
+	if session.Expired(time.Now()) {
+		return nil, ErrPlaybackSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *PostgresqlStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM playback_sessions WHERE player_uuid = $1 AND rating_key = $2
+	`, strings.TrimSpace(playerUUID), strings.TrimSpace(ratingKey)); err != nil {
+		return fmt.Errorf("failed to delete playback session: %w", err)
+	}
+	return nil
+}
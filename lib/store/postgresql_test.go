@@ -20,12 +20,13 @@ func TestPostgresqlLoadingUser(t *testing.T) {
 	defer db.Close()
 
 	tokenExpiry := time.Date(2019, 05, 25, 0, 0, 0, 0, time.UTC)
+	tokenIssuedAt := time.Date(2019, 02, 24, 23, 59, 0, 0, time.UTC)
 	mock.ExpectQuery(
-		"SELECT username, access, refresh, trakt_display_name, updated, token_expiry FROM users WHERE id=.*",
+		"SELECT username, access, refresh, trakt_display_name, updated, token_expiry, token_issued_at, version FROM users WHERE id=.*",
 	).WithArgs(
 		"id123",
 	).WillReturnRows(
-		sqlmock.NewRows([]string{"username", "access", "refresh", "trakt_display_name", "updated", "token_expiry"}).
+		sqlmock.NewRows([]string{"username", "access", "refresh", "trakt_display_name", "updated", "token_expiry", "token_issued_at", "version"}).
 			AddRow(
 				"halkeye",
 				"access123",
@@ -33,6 +34,8 @@ func TestPostgresqlLoadingUser(t *testing.T) {
 				"Halkeye",
 				time.Date(2019, 02, 25, 0, 0, 0, 0, time.UTC),
 				tokenExpiry,
+				tokenIssuedAt,
+				int64(3),
 			),
 	)
 
@@ -46,6 +49,8 @@ func TestPostgresqlLoadingUser(t *testing.T) {
 		TraktDisplayName: "Halkeye",
 		Updated:          time.Date(2019, 02, 25, 0, 0, 0, 0, time.UTC),
 		TokenExpiry:      tokenExpiry,
+		TokenIssuedAt:    tokenIssuedAt,
+		Version:          3,
 	})
 	actual, _ := json.Marshal(store.GetUser("id123"))
 
@@ -60,9 +65,10 @@ func TestPostgresqlSavingUser(t *testing.T) {
 	defer db.Close()
 
 	tokenExpiry := time.Date(2019, 05, 25, 0, 0, 0, 0, time.UTC)
+	tokenIssuedAt := time.Date(2019, 02, 24, 23, 59, 0, 0, time.UTC)
 	mock.ExpectExec("INSERT INTO ").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT").WithArgs("id123").WillReturnRows(
-		sqlmock.NewRows([]string{"username", "access", "refresh", "trakt_display_name", "updated", "token_expiry"}).
+		sqlmock.NewRows([]string{"username", "access", "refresh", "trakt_display_name", "updated", "token_expiry", "token_issued_at", "version"}).
 			AddRow(
 				"halkeye",
 				"access123",
@@ -70,6 +76,8 @@ func TestPostgresqlSavingUser(t *testing.T) {
 				"Halkeye",
 				time.Date(2019, 02, 25, 0, 0, 0, 0, time.UTC),
 				tokenExpiry,
+				tokenIssuedAt,
+				int64(3),
 			),
 	)
 
@@ -82,6 +90,8 @@ func TestPostgresqlSavingUser(t *testing.T) {
 		TraktDisplayName: "Halkeye",
 		Updated:          time.Date(2019, 02, 25, 0, 0, 0, 0, time.UTC),
 		TokenExpiry:      tokenExpiry,
+		TokenIssuedAt:    tokenIssuedAt,
+		Version:          3,
 		store:            store,
 	}
 
@@ -102,11 +112,11 @@ func TestPostgresqlListUsers(t *testing.T) {
 
 	tokenExpiry1 := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
 	tokenExpiry2 := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
-	rows := sqlmock.NewRows([]string{"id", "username", "access", "refresh", "trakt_display_name", "updated", "token_expiry"}).
-		AddRow("newest", "Alice", "access-new", "refresh-new", "Alice Smith", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), tokenExpiry1).
-		AddRow("older", "Bob", "access-old", "refresh-old", nil, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), tokenExpiry2)
+	rows := sqlmock.NewRows([]string{"id", "username", "access", "refresh", "trakt_display_name", "updated", "token_expiry", "token_issued_at", "version"}).
+		AddRow("newest", "Alice", "access-new", "refresh-new", "Alice Smith", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), tokenExpiry1, nil, int64(1)).
+		AddRow("older", "Bob", "access-old", "refresh-old", nil, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), tokenExpiry2, nil, int64(1))
 
-	mock.ExpectQuery("SELECT id, username, access, refresh, trakt_display_name, updated, token_expiry FROM users ORDER BY updated DESC").
+	mock.ExpectQuery("SELECT id, username, access, refresh, trakt_display_name, updated, token_expiry, token_issued_at, version FROM users ORDER BY updated DESC").
 		WillReturnRows(rows)
 
 	store := NewPostgresqlStore(db)
@@ -306,3 +316,73 @@ func TestPostgresqlStoreMarkRetrySuccessAndFailure(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	assert.ErrorIs(t, store.MarkRetryFailure(context.Background(), "retry-missing", MaxRetryAttempts, next, "fail", true), ErrRetryItemNotFound)
 }
+
+func TestPostgresqlStoreActivityPrunesOnNewBucket(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO activity_stats \(bucket, failures\)`).
+		WithArgs(ActivityBucketStart(now)).
+		WillReturnRows(sqlmock.NewRows([]string{"created"}).AddRow(true))
+	mock.ExpectExec(`DELETE FROM activity_stats WHERE bucket < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(`INSERT INTO activity_stats \(bucket, scrobbles\)`).
+		WithArgs(ActivityBucketStart(now)).
+		WillReturnRows(sqlmock.NewRows([]string{"created"}).AddRow(false))
+	mock.ExpectQuery(`SELECT bucket, scrobbles, failures, queued\s+FROM activity_stats`).
+		WithArgs(ActivityBucketStart(now.Add(-24*time.Hour)), now).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "scrobbles", "failures", "queued"}).
+			AddRow(ActivityBucketStart(now), 1, 1, 0))
+
+	store := NewPostgresqlStore(db)
+	assert.NoError(t, store.IncrementActivity(context.Background(), ActivityFailure, now))
+	assert.NoError(t, store.IncrementActivity(context.Background(), ActivityScrobble, now))
+	assert.ErrorIs(t, store.IncrementActivity(context.Background(), ActivityKind("count; DROP TABLE users"), now), ErrInvalidActivityKind)
+	buckets, err := store.ListActivity(context.Background(), now.Add(-24*time.Hour), now)
+	assert.NoError(t, err)
+	if assert.Len(t, buckets, 1) {
+		assert.Equal(t, ActivityCounts{Scrobbles: 1, Failures: 1}, buckets[0].ActivityCounts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresqlStoreQueueLogPagesWithinRetention(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(`INSERT INTO queue_event_log \(ts, payload\)`).
+		WithArgs(now, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM queue_event_log\s+WHERE ts < \$1`).
+		WithArgs(sqlmock.AnyArg(), MaxQueueLogEvents).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT payload FROM queue_event_log\s+WHERE ts >= \$1`).
+		WithArgs(now.Add(-time.Hour), sqlmock.AnyArg(), int64(10), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).
+			AddRow([]byte(`{"timestamp":"2025-01-01T00:00:00Z","operation":"queue_enqueue","user_id":"u1"}`)).
+			AddRow([]byte(`not json`)))
+
+	store := NewPostgresqlStore(db)
+	assert.NoError(t, store.AppendQueueLogEvent(context.Background(), QueueLogEvent{Timestamp: now, Operation: "queue_enqueue"}))
+	events, err := store.ListQueueLogEvents(context.Background(), QueueLogQuery{Since: now.Add(-time.Hour), Offset: 20, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1, "corrupt rows are skipped") {
+		assert.Equal(t, "u1", events[0].UserID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
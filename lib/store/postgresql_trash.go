@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *PostgresqlStore) PutTrashEntry(ctx context.Context, entry *TrashEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO trash (id, kind, label, deleted_at, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			label = EXCLUDED.label,
+			deleted_at = EXCLUDED.deleted_at,
+			payload = EXCLUDED.payload
+	`, entry.ID, entry.Kind, entry.Label, entry.DeletedAt, []byte(entry.Payload)); err != nil {
+		return fmt.Errorf("failed to store trash entry: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) GetTrashEntry(ctx context.Context, id string) (*TrashEntry, error) {
+	var (
+		entry   TrashEntry
+		payload []byte
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, kind, label, deleted_at, payload FROM trash WHERE id = $1
+	`, strings.TrimSpace(id)).Scan(&entry.ID, &entry.Kind, &entry.Label, &entry.DeletedAt, &payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTrashEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get trash entry: %w", err)
+	}
+	entry.Payload = payload
+	return &entry, nil
+}
+
+func (s *PostgresqlStore) ListTrashEntries(ctx context.Context) ([]TrashEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, kind, label, deleted_at, payload FROM trash ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []TrashEntry{}
+	for rows.Next() {
+		var (
+			entry   TrashEntry
+			payload []byte
+		)
+		if err := rows.Scan(&entry.ID, &entry.Kind, &entry.Label, &entry.DeletedAt, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan trash entry: %w", err)
+		}
+		entry.Payload = payload
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *PostgresqlStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM trash WHERE id = $1`, strings.TrimSpace(id)); err != nil {
+		return fmt.Errorf("failed to delete trash entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *PostgresqlStore) RecordWatchedMovie(ctx context.Context, movie *WatchedMovie) error {
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+
+	var year, tmdb sql.NullInt64
+	if movie.Year > 0 {
+		year = sql.NullInt64{Int64: int64(movie.Year), Valid: true}
+	}
+	if movie.Tmdb > 0 {
+		tmdb = sql.NullInt64{Int64: int64(movie.Tmdb), Valid: true}
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO watch_history (user_id, title, year, imdb, tmdb, watched_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, movie.UserID, movie.Title, year, nullableString(movie.Imdb), tmdb, movie.WatchedAt); err != nil {
+		return fmt.Errorf("failed to record watched movie: %w", err)
+	}
+
+	// Trim the oldest rows beyond the per-user cap
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM watch_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM watch_history WHERE user_id = $1 ORDER BY watched_at DESC, id DESC LIMIT $2
+		)
+	`, movie.UserID, MaxWatchedMoviesPerUser); err != nil {
+		return fmt.Errorf("failed to trim watch history: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) ListWatchedMovies(ctx context.Context, userID string) ([]WatchedMovie, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, title, year, imdb, tmdb, watched_at
+		FROM watch_history
+		WHERE user_id = $1
+		ORDER BY watched_at ASC, id ASC
+	`, strings.TrimSpace(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch history: %w", err)
+	}
+	defer rows.Close()
+
+	movies := []WatchedMovie{}
+	for rows.Next() {
+		var (
+			movie      WatchedMovie
+			year, tmdb sql.NullInt64
+			imdb       sql.NullString
+		)
+		if err := rows.Scan(&movie.UserID, &movie.Title, &year, &imdb, &tmdb, &movie.WatchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch history: %w", err)
+		}
+		movie.Year = int(year.Int64)
+		movie.Tmdb = int(tmdb.Int64)
+		movie.Imdb = imdb.String
+		movies = append(movies, movie)
+	}
+	return movies, rows.Err()
+}
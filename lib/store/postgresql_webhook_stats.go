@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IncrementWebhookStat upserts the daily bucket. When the upsert creates a new
+// bucket, the subject's days older than WebhookStatsRetention are deleted.
+func (s *PostgresqlStore) IncrementWebhookStat(ctx context.Context, subject WebhookSubject, id string, outcome WebhookOutcome, at time.Time) error {
+	if err := validWebhookStat(subject, id, outcome); err != nil {
+		return err
+	}
+	id = strings.TrimSpace(id)
+	// outcome is validated above and doubles as the column name
+	var created bool
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO webhook_stats (subject, subject_id, day, %[1]s)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (subject, subject_id, day) DO UPDATE SET %[1]s = webhook_stats.%[1]s + 1
+		RETURNING (xmax = 0)
+	`, outcome), string(subject), id, WebhookDayStart(at)).Scan(&created); err != nil {
+		return fmt.Errorf("failed to increment webhook stats: %w", err)
+	}
+	if created {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_stats WHERE subject = $1 AND subject_id = $2 AND day < $3`,
+			string(subject), id, WebhookDayStart(time.Now().Add(-WebhookStatsRetention))); err != nil {
+			return fmt.Errorf("failed to prune webhook stats: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresqlStore) ListWebhookStats(ctx context.Context, subject WebhookSubject, id string, from, to time.Time) ([]WebhookStatsDay, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT day, received, processed, skipped, failed
+		FROM webhook_stats
+		WHERE subject = $1 AND subject_id = $2 AND day >= $3 AND day <= $4
+		ORDER BY day ASC
+	`, string(subject), strings.TrimSpace(id), WebhookDayStart(from), to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook stats: %w", err)
+	}
+	defer rows.Close()
+
+	out := []WebhookStatsDay{}
+	for rows.Next() {
+		var day WebhookStatsDay
+		if err := rows.Scan(&day.Day, &day.Received, &day.Processed, &day.Skipped, &day.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook stats: %w", err)
+		}
+		day.Day = day.Day.UTC()
+		out = append(out, day)
+	}
+	return out, rows.Err()
+}
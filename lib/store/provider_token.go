@@ -0,0 +1,58 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrProviderTokenNotFound is returned when a user has not linked the provider.
+	ErrProviderTokenNotFound = errors.New("store: provider token not found")
+	// ErrInvalidProviderToken is returned when required fields are missing.
+	ErrInvalidProviderToken = errors.New("store: provider token is invalid")
+)
+
+// ProviderToken holds OAuth credentials for an additional scrobble target
+// (e.g. Simkl) linked to a Plaxt user. Trakt credentials stay on User.
+type ProviderToken struct {
+	UserID       string     `json:"user_id"`
+	Provider     string     `json:"provider"`
+	AccessToken  string     `json:"access_token"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	TokenExpiry  *time.Time `json:"token_expiry,omitempty"` // nil when the provider issues non-expiring tokens
+	AccountName  string     `json:"account_name,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Normalize trims identifiers and lowercases the provider name.
+func (t *ProviderToken) Normalize() {
+	if t == nil {
+		return
+	}
+	t.UserID = strings.TrimSpace(t.UserID)
+	t.Provider = strings.ToLower(strings.TrimSpace(t.Provider))
+	t.AccountName = strings.TrimSpace(t.AccountName)
+}
+
+// Validate ensures the token can be persisted.
+func (t *ProviderToken) Validate() error {
+	if t == nil {
+		return ErrInvalidProviderToken
+	}
+	t.Normalize()
+	if t.UserID == "" || t.Provider == "" || strings.TrimSpace(t.AccessToken) == "" {
+		return ErrInvalidProviderToken
+	}
+	return nil
+}
+
+// touch sets CreatedAt on first save and refreshes UpdatedAt.
+func (t *ProviderToken) touch() {
+	now := time.Now().UTC()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+}
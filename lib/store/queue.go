@@ -23,6 +23,9 @@ type QueuedScrobbleEvent struct {
 	Action       string              `json:"action"`        // "start" | "pause" | "stop"
 	Progress     int                 `json:"progress"`      // Playback progress percentage (0-100)
 
+	// Target provider name; empty means the primary provider (Trakt)
+	Provider string `json:"provider,omitempty"`
+
 	// Metadata
 	CreatedAt   time.Time `json:"created_at"`   // Original webhook receipt time
 	RetryCount  int       `json:"retry_count"`  // Number of send attempts (0-5)
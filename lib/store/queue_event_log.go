@@ -2,10 +2,71 @@ package store
 
 import (
 	"container/ring"
+	"context"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Bounds for the persisted queue event log. Older or excess events are
+// dropped by the store on append.
+const (
+	QueueLogRetention = 7 * 24 * time.Hour
+	MaxQueueLogEvents = 10000
+)
+
+// QueueLogQuery selects queue events, newest first.
+type QueueLogQuery struct {
+	Since  time.Time // inclusive; zero means unbounded
+	Until  time.Time // inclusive; zero means unbounded
+	Offset int
+	Limit  int // zero or negative means no limit
+}
+
+func (q QueueLogQuery) matches(event QueueLogEvent) bool {
+	if !q.Since.IsZero() && event.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && event.Timestamp.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// apply filters and pages events that are already sorted newest first.
+func (q QueueLogQuery) apply(events []QueueLogEvent) []QueueLogEvent {
+	matched := make([]QueueLogEvent, 0, len(events))
+	for _, event := range events {
+		if q.matches(event) {
+			matched = append(matched, event)
+		}
+	}
+	if q.Offset >= len(matched) {
+		return []QueueLogEvent{}
+	}
+	matched = matched[max(q.Offset, 0):]
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+// trimQueueLog sorts events newest first and drops those past
+// QueueLogRetention or beyond MaxQueueLogEvents.
+func trimQueueLog(events []QueueLogEvent, now time.Time) []QueueLogEvent {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.After(events[j].Timestamp) })
+	cutoff := now.Add(-QueueLogRetention)
+	kept := events[:0]
+	for _, event := range events {
+		if event.Timestamp.Before(cutoff) || len(kept) >= MaxQueueLogEvents {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	return kept
+}
+
 // QueueLogEvent represents a single queue operation for monitoring/debugging.
 type QueueLogEvent struct {
 	Timestamp  time.Time `json:"timestamp"`
@@ -20,10 +81,13 @@ type QueueLogEvent struct {
 }
 
 // QueueEventLog is a thread-safe circular buffer for storing recent queue events.
+// When persistence is enabled every event is also written to the store, so
+// history survives restarts and can be paged beyond the ring's capacity.
 type QueueEventLog struct {
 	events   *ring.Ring
 	capacity int
 	mu       sync.RWMutex
+	store    Store
 }
 
 // NewQueueEventLog creates a new queue event log with the specified capacity.
@@ -38,10 +102,35 @@ func NewQueueEventLog(capacity int) *QueueEventLog {
 // Oldest events are automatically evicted when capacity is reached.
 func (l *QueueEventLog) Append(event QueueLogEvent) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	l.events.Value = event
 	l.events = l.events.Next()
+	persist := l.store
+	l.mu.Unlock()
+
+	if persist != nil {
+		if err := persist.AppendQueueLogEvent(context.Background(), event); err != nil {
+			slog.Warn("queue event log persist failed", "operation", event.Operation, "error", err)
+		}
+	}
+}
+
+// Persist writes every subsequent event to s as well as the in-memory ring.
+func (l *QueueEventLog) Persist(s Store) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store = s
+}
+
+// Query returns events matching q, newest first. It reads the store when
+// persistence is enabled and the in-memory ring otherwise.
+func (l *QueueEventLog) Query(ctx context.Context, q QueueLogQuery) ([]QueueLogEvent, error) {
+	l.mu.RLock()
+	persist := l.store
+	l.mu.RUnlock()
+	if persist != nil {
+		return persist.ListQueueLogEvents(ctx, q)
+	}
+	return q.apply(l.GetRecent(l.capacity)), nil
 }
 
 // GetRecent returns up to N most recent events in reverse chronological order.
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +22,7 @@ const (
 	accessTokenTimeout = 75 * 24 * time.Hour
 	scrobbleFormat     = "goplaxt:scrobble:%s:%s"
 	scrobbleTimeout    = 3 * time.Hour
+	sessionFormat      = "goplaxt:session:%s:%s"
 )
 
 // RedisStore is a storage engine that writes to redis
@@ -79,6 +81,48 @@ func (s RedisStore) WriteUser(user User) {
 	ctx := context.Background()
 	currentUser := s.GetUserByName(user.Username)
 	pipe := s.client.Pipeline()
+	s.queueUserWrite(ctx, pipe, user, currentUser)
+	pipe.HIncrBy(ctx, userPrefix+user.ID, "version", 1)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CompareAndSwapUser writes the user only if its version matches the stored
+// one. The user hash is WATCHed so a concurrent writer aborts the transaction.
+func (s *RedisStore) CompareAndSwapUser(ctx context.Context, user User) error {
+	key := userPrefix + user.ID
+	currentUser := s.GetUserByName(user.Username)
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrUserNotFound
+		}
+		version, err := tx.HGet(ctx, key, "version").Int64()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if version != user.Version {
+			return ErrUserVersionConflict
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.queueUserWrite(ctx, pipe, user, currentUser)
+			pipe.HSet(ctx, key, "version", version+1)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return ErrUserVersionConflict
+	}
+	return err
+}
+
+func (s *RedisStore) queueUserWrite(ctx context.Context, pipe redis.Pipeliner, user User, currentUser *User) {
 	key := userPrefix + user.ID
 	pipe.HSet(ctx, key, "username", user.Username)
 	pipe.HSet(ctx, key, "access", user.AccessToken)
@@ -86,6 +130,9 @@ func (s RedisStore) WriteUser(user User) {
 	pipe.HSet(ctx, key, "updated", user.Updated.Format("01-02-2006"))
 	pipe.HSet(ctx, key, "trakt_display_name", user.TraktDisplayName)
 	pipe.HSet(ctx, key, "token_expiry", user.TokenExpiry.Format(time.RFC3339))
+	if !user.TokenIssuedAt.IsZero() {
+		pipe.HSet(ctx, key, "token_issued_at", user.TokenIssuedAt.Format(time.RFC3339))
+	}
 	pipe.Expire(ctx, key, accessTokenTimeout)
 	// a username should always be occupied by the first id binded to it unless it's expired
 	if currentUser == nil {
@@ -94,10 +141,6 @@ func (s RedisStore) WriteUser(user User) {
 		// extend the TTL on refresh
 		pipe.Expire(ctx, userMapPrefix+user.Username, accessTokenTimeout)
 	}
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		panic(err)
-	}
 }
 
 // GetUser will load a user from redis
@@ -119,6 +162,11 @@ func (s RedisStore) GetUser(id string) *User {
 			tokenExpiry = parsedExpiry
 		}
 	}
+	var tokenIssuedAt time.Time
+	if issuedStr := data["token_issued_at"]; issuedStr != "" {
+		tokenIssuedAt, _ = time.Parse(time.RFC3339, issuedStr)
+	}
+	version, _ := strconv.ParseInt(data["version"], 10, 64)
 
 	user := User{
 		ID:               id,
@@ -128,6 +176,8 @@ func (s RedisStore) GetUser(id string) *User {
 		TraktDisplayName: data["trakt_display_name"],
 		Updated:          updated,
 		TokenExpiry:      tokenExpiry,
+		TokenIssuedAt:    tokenIssuedAt,
+		Version:          version,
 		store:            s,
 	}
 
@@ -571,6 +621,12 @@ func (s RedisStore) CreateFamilyGroup(ctx context.Context, group *FamilyGroup) e
 	return nil
 }
 
+// CreateFamilyGroupWithMembers creates a group and its members, removing any
+// partially written records if a step fails.
+func (s *RedisStore) CreateFamilyGroupWithMembers(ctx context.Context, group *FamilyGroup, members []*GroupMember) error {
+	return createFamilyGroupWithCleanup(ctx, s, group, members)
+}
+
 func (s RedisStore) GetFamilyGroup(ctx context.Context, groupID string) (*FamilyGroup, error) {
 	groupKey := familyGroupPrefix + groupID
 	data, err := s.client.Get(ctx, groupKey).Result()
@@ -832,6 +888,53 @@ func (s RedisStore) DeleteNotification(ctx context.Context, notificationID strin
 	return ErrNotSupported
 }
 
+// ========== PROVIDER TOKEN METHODS ==========
+
+const providerTokenPrefix = "goplaxt:provider_token:"
+
+func providerTokenKey(userID, provider string) string {
+	return providerTokenPrefix + userID + ":" + strings.ToLower(provider)
+}
+
+func (s *RedisStore) SaveProviderToken(ctx context.Context, token *ProviderToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.touch()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal provider token: %w", err)
+	}
+	if err := s.client.Set(ctx, providerTokenKey(token.UserID, token.Provider), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save provider token: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetProviderToken(ctx context.Context, userID, provider string) (*ProviderToken, error) {
+	data, err := s.client.Get(ctx, providerTokenKey(userID, provider)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrProviderTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get provider token: %w", err)
+	}
+
+	var token ProviderToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal provider token: %w", err)
+	}
+	return &token, nil
+}
+
+func (s *RedisStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	if err := s.client.Del(ctx, providerTokenKey(userID, provider)).Err(); err != nil {
+		return fmt.Errorf("failed to delete provider token: %w", err)
+	}
+	return nil
+}
+
 // ========== FALLBACK BUFFER HELPERS ==========
 
 func (s *RedisStore) addToFallbackBuffer(userID string, event QueuedScrobbleEvent) {
@@ -883,3 +986,577 @@ func (s *RedisStore) flushFallbackBuffer(ctx context.Context, userID string) {
 		"event_count", len(events),
 	)
 }
+
+// ========== WATCH HISTORY METHODS ==========
+
+const watchHistoryPrefix = "goplaxt:watch_history:"
+
+func (s *RedisStore) RecordWatchedMovie(ctx context.Context, movie *WatchedMovie) error {
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(movie)
+	if err != nil {
+		return fmt.Errorf("failed to marshal watched movie: %w", err)
+	}
+
+	key := watchHistoryPrefix + movie.UserID
+	pipe := s.client.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -MaxWatchedMoviesPerUser, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record watched movie: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) ListWatchedMovies(ctx context.Context, userID string) ([]WatchedMovie, error) {
+	entries, err := s.client.LRange(ctx, watchHistoryPrefix+strings.TrimSpace(userID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch history: %w", err)
+	}
+	movies := make([]WatchedMovie, 0, len(entries))
+	for _, entry := range entries {
+		var movie WatchedMovie
+		if err := json.Unmarshal([]byte(entry), &movie); err != nil {
+			slog.Warn("skipping corrupt watch history entry", "user_id", userID, "error", err)
+			continue
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+// ========== QUEUE EVENT LOG METHODS ==========
+
+// queueLogKey is a sorted set scored by event time in milliseconds, which
+// lets range reads double as time filters. Members carry a random prefix so
+// identical events stay distinct.
+const queueLogKey = "goplaxt:queue_log"
+
+func (s *RedisStore) AppendQueueLogEvent(ctx context.Context, event QueueLogEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue log event: %w", err)
+	}
+	id, err := generateEventID()
+	if err != nil {
+		return err
+	}
+	cutoff := strconv.FormatInt(time.Now().Add(-QueueLogRetention).UnixMilli(), 10)
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, queueLogKey, redis.Z{
+		Score:  float64(event.Timestamp.UnixMilli()),
+		Member: id + ":" + string(data),
+	})
+	pipe.ZRemRangeByScore(ctx, queueLogKey, "-inf", "("+cutoff)
+	pipe.ZRemRangeByRank(ctx, queueLogKey, 0, -int64(MaxQueueLogEvents)-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append queue log event: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) ListQueueLogEvents(ctx context.Context, query QueueLogQuery) ([]QueueLogEvent, error) {
+	since := time.Now().Add(-QueueLogRetention)
+	if query.Since.After(since) {
+		since = query.Since
+	}
+	rng := &redis.ZRangeBy{Min: strconv.FormatInt(since.UnixMilli(), 10), Max: "+inf"}
+	if !query.Until.IsZero() {
+		rng.Max = strconv.FormatInt(query.Until.UnixMilli(), 10)
+	}
+	if query.Limit > 0 {
+		rng.Offset, rng.Count = int64(max(query.Offset, 0)), int64(query.Limit)
+	}
+
+	members, err := s.client.ZRevRangeByScore(ctx, queueLogKey, rng).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read queue log: %w", err)
+	}
+	events := make([]QueueLogEvent, 0, len(members))
+	for _, member := range members {
+		_, raw, _ := strings.Cut(member, ":")
+		var event QueueLogEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			slog.Warn("skipping corrupt queue log entry", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	if query.Limit > 0 {
+		// Redis already applied the page
+		return events, nil
+	}
+	return QueueLogQuery{Offset: query.Offset}.apply(events), nil
+}
+
+// ========== STATS METHODS ==========
+
+const activityPrefix = "goplaxt:activity:"
+
+func (s *RedisStore) IncrementActivity(ctx context.Context, kind ActivityKind, at time.Time) error {
+	if !kind.Valid() {
+		return ErrInvalidActivityKind
+	}
+	key := activityPrefix + activityKey(at)
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, string(kind), 1)
+	pipe.Expire(ctx, key, ActivityRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to increment activity: %w", err)
+	}
+	return nil
+}
+
+// ListActivity reads one hash per hour in the range; buckets older than
+// ActivityRetention have expired and are not requested.
+func (s *RedisStore) ListActivity(ctx context.Context, from, to time.Time) ([]ActivityBucket, error) {
+	if oldest := time.Now().Add(-ActivityRetention); from.Before(oldest) {
+		from = oldest
+	}
+	pipe := s.client.Pipeline()
+	starts := []time.Time{}
+	cmds := []*redis.MapStringStringCmd{}
+	for start := ActivityBucketStart(from); !start.After(to); start = start.Add(time.Hour) {
+		starts = append(starts, start)
+		cmds = append(cmds, pipe.HGetAll(ctx, activityPrefix+activityKey(start)))
+	}
+	out := []ActivityBucket{}
+	if len(cmds) == 0 {
+		return out, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+	for i, cmd := range cmds {
+		fields, err := cmd.Result()
+		if err != nil || len(fields) == 0 {
+			continue
+		}
+		bucket := ActivityBucket{Start: starts[i]}
+		for field, value := range fields {
+			if n, err := strconv.Atoi(value); err == nil {
+				bucket.Add(ActivityKind(field), n)
+			}
+		}
+		out = append(out, bucket)
+	}
+	return out, nil
+}
+
+// TotalQueueSize sums the queued events of every user.
+func (s *RedisStore) TotalQueueSize(ctx context.Context) (int, error) {
+	userIDs, err := s.ListUsersWithQueuedEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, userID := range userIDs {
+		size, err := s.GetQueueSize(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// ========== TRASH METHODS ==========
+
+const trashKey = "goplaxt:trash"
+
+func (s *RedisStore) PutTrashEntry(ctx context.Context, entry *TrashEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+	if err := s.client.HSet(ctx, trashKey, entry.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to store trash entry: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetTrashEntry(ctx context.Context, id string) (*TrashEntry, error) {
+	data, err := s.client.HGet(ctx, trashKey, strings.TrimSpace(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrTrashEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get trash entry: %w", err)
+	}
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trash entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func (s *RedisStore) ListTrashEntries(ctx context.Context) ([]TrashEntry, error) {
+	raw, err := s.client.HGetAll(ctx, trashKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash entries: %w", err)
+	}
+	entries := make([]TrashEntry, 0, len(raw))
+	for id, data := range raw {
+		var entry TrashEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			slog.Warn("skipping corrupt trash entry", "id", id, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].DeletedAt.After(entries[j].DeletedAt) })
+	return entries, nil
+}
+
+func (s *RedisStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	if err := s.client.HDel(ctx, trashKey, strings.TrimSpace(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete trash entry: %w", err)
+	}
+	return nil
+}
+
+// ========== PLAYBACK SESSION METHODS ==========
+
+func (s *RedisStore) PutPlaybackSession(ctx context.Context, session *PlaybackSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal playback session: %w", err)
+	}
+	key := fmt.Sprintf(sessionFormat, session.PlayerUUID, session.RatingKey)
+	// Expire relative to the last event, not the write
+	ttl := time.Until(session.UpdatedAt.Add(PlaybackSessionTTL))
+	if ttl <= 0 {
+		if err := s.client.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("failed to store playback session: %w", err)
+		}
+		return nil
+	}
+	if err := s.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store playback session: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*PlaybackSession, error) {
+	key := fmt.Sprintf(sessionFormat, strings.TrimSpace(playerUUID), strings.TrimSpace(ratingKey))
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get playback session: %w", err)
+	}
+	var session PlaybackSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal playback session: %w", err)
+	}
+	return &session, nil
+}
+
+func (s *RedisStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	key := fmt.Sprintf(sessionFormat, strings.TrimSpace(playerUUID), strings.TrimSpace(ratingKey))
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete playback session: %w", err)
+	}
+	return nil
+}
+
+// ========== USER PREFERENCES METHODS ==========
+
+const preferencesKey = "goplaxt:preferences"
+
+func (s *RedisStore) PutUserPreferences(ctx context.Context, prefs *UserPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user preferences: %w", err)
+	}
+	if err := s.client.HSet(ctx, preferencesKey, prefs.UserID, data).Err(); err != nil {
+		return fmt.Errorf("failed to store user preferences: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetUserPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	data, err := s.client.HGet(ctx, preferencesKey, strings.TrimSpace(userID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrUserPreferencesNotFound
+		}
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	var prefs UserPreferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+func (s *RedisStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	if err := s.client.HDel(ctx, preferencesKey, strings.TrimSpace(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete user preferences: %w", err)
+	}
+	return nil
+}
+
+// ========== WEBHOOK STATS METHODS ==========
+
+// webhookStatsFormat is goplaxt:webhook_stats:{subject}:{id}:{yyyymmdd}.
+const webhookStatsFormat = "goplaxt:webhook_stats:%s:%s:%s"
+
+func (s *RedisStore) IncrementWebhookStat(ctx context.Context, subject WebhookSubject, id string, outcome WebhookOutcome, at time.Time) error {
+	if err := validWebhookStat(subject, id, outcome); err != nil {
+		return err
+	}
+	key := fmt.Sprintf(webhookStatsFormat, subject, strings.TrimSpace(id), webhookStatsKey(at))
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, string(outcome), 1)
+	pipe.Expire(ctx, key, WebhookStatsRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to increment webhook stats: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookStats reads one hash per day in the range; days older than
+// WebhookStatsRetention have expired and are not requested.
+func (s *RedisStore) ListWebhookStats(ctx context.Context, subject WebhookSubject, id string, from, to time.Time) ([]WebhookStatsDay, error) {
+	if oldest := time.Now().Add(-WebhookStatsRetention); from.Before(oldest) {
+		from = oldest
+	}
+	id = strings.TrimSpace(id)
+	pipe := s.client.Pipeline()
+	days := []time.Time{}
+	cmds := []*redis.MapStringStringCmd{}
+	for day := WebhookDayStart(from); !day.After(to); day = day.Add(24 * time.Hour) {
+		days = append(days, day)
+		cmds = append(cmds, pipe.HGetAll(ctx, fmt.Sprintf(webhookStatsFormat, subject, id, webhookStatsKey(day))))
+	}
+	out := []WebhookStatsDay{}
+	if len(cmds) == 0 {
+		return out, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read webhook stats: %w", err)
+	}
+	for i, cmd := range cmds {
+		fields, err := cmd.Result()
+		if err != nil || len(fields) == 0 {
+			continue
+		}
+		stats := WebhookStatsDay{Day: days[i]}
+		for field, value := range fields {
+			if n, err := strconv.Atoi(value); err == nil {
+				stats.Add(WebhookOutcome(field), n)
+			}
+		}
+		out = append(out, stats)
+	}
+	return out, nil
+}
+
+// ========== ADMIN TOTP METHODS ==========
+
+const adminTOTPHashKey = "goplaxt:admin_totp"
+
+func (s *RedisStore) PutAdminTOTP(ctx context.Context, t *AdminTOTP) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal admin totp: %w", err)
+	}
+	if err := s.client.HSet(ctx, adminTOTPHashKey, t.Username, data).Err(); err != nil {
+		return fmt.Errorf("failed to store admin totp: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetAdminTOTP(ctx context.Context, username string) (*AdminTOTP, error) {
+	data, err := s.client.HGet(ctx, adminTOTPHashKey, adminTOTPKey(username)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrAdminTOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to get admin totp: %w", err)
+	}
+	var t AdminTOTP
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal admin totp: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *RedisStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	if err := s.client.HDel(ctx, adminTOTPHashKey, adminTOTPKey(username)).Err(); err != nil {
+		return fmt.Errorf("failed to delete admin totp: %w", err)
+	}
+	return nil
+}
+
+// ========== RETENTION METHODS ==========
+
+// PurgeBefore trims the queue log stream by entry ID and deletes counter
+// hashes by the time at the end of their key. Watch history lists are in
+// append order, so each is trimmed from the head up to its first entry
+// watched at or after before.
+func (s *RedisStore) PurgeBefore(ctx context.Context, category RetentionCategory, before time.Time) (int, error) {
+	switch category {
+	case RetentionHistory:
+		return s.purgeWatchHistory(ctx, before)
+	case RetentionQueueLog:
+		n, err := s.client.ZRemRangeByScore(ctx, queueLogKey, "-inf", "("+strconv.FormatInt(before.UnixMilli(), 10)).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to trim queue log: %w", err)
+		}
+		return int(n), nil
+	case RetentionTelemetry:
+		activity, err := s.purgeKeysBefore(ctx, activityPrefix+"*", activityKey(before))
+		if err != nil {
+			return activity, err
+		}
+		stats, err := s.purgeKeysBefore(ctx, "goplaxt:webhook_stats:*", webhookStatsKey(before))
+		return activity + stats, err
+	default:
+		return 0, ErrInvalidRetentionCategory
+	}
+}
+
+func (s *RedisStore) purgeWatchHistory(ctx context.Context, before time.Time) (int, error) {
+	keys, err := s.scanKeys(ctx, watchHistoryPrefix+"*")
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, key := range keys {
+		entries, err := s.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return purged, fmt.Errorf("failed to read watch history: %w", err)
+		}
+		old := 0
+		for _, entry := range entries {
+			var movie WatchedMovie
+			if json.Unmarshal([]byte(entry), &movie) == nil && !movie.WatchedAt.Before(before) {
+				break
+			}
+			old++
+		}
+		if old == 0 {
+			continue
+		}
+		if err := s.client.LTrim(ctx, key, int64(old), -1).Err(); err != nil {
+			return purged, fmt.Errorf("failed to trim watch history: %w", err)
+		}
+		purged += old
+	}
+	return purged, nil
+}
+
+// purgeKeysBefore deletes keys matching pattern whose last ':' segment sorts
+// before cutoff.
+func (s *RedisStore) purgeKeysBefore(ctx context.Context, pattern, cutoff string) (int, error) {
+	keys, err := s.scanKeys(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+	stale := []string{}
+	for _, key := range keys {
+		if key[strings.LastIndex(key, ":")+1:] < cutoff {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	n, err := s.client.Del(ctx, stale...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired keys: %w", err)
+	}
+	return int(n), nil
+}
+
+func (s *RedisStore) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var cursor uint64
+	var keys []string
+	for {
+		var err error
+		var scanKeys []string
+		scanKeys, cursor, err = s.client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redis keys: %w", err)
+		}
+		keys = append(keys, scanKeys...)
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// ========== PLEX WEBHOOK METHODS ==========
+
+const plexWebhookHashKey = "goplaxt:plex_webhooks"
+
+func (s *RedisStore) PutPlexWebhook(ctx context.Context, w *PlexWebhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plex webhook: %w", err)
+	}
+	if err := s.client.HSet(ctx, plexWebhookHashKey, w.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to store plex webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetPlexWebhook(ctx context.Context, id string) (*PlexWebhook, error) {
+	data, err := s.client.HGet(ctx, plexWebhookHashKey, strings.TrimSpace(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrPlexWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get plex webhook: %w", err)
+	}
+	var w PlexWebhook
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plex webhook: %w", err)
+	}
+	return &w, nil
+}
+
+func (s *RedisStore) ListPlexWebhooks(ctx context.Context) ([]PlexWebhook, error) {
+	raw, err := s.client.HGetAll(ctx, plexWebhookHashKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plex webhooks: %w", err)
+	}
+	hooks := make([]PlexWebhook, 0, len(raw))
+	for id, data := range raw {
+		var w PlexWebhook
+		if err := json.Unmarshal([]byte(data), &w); err != nil {
+			slog.Warn("skipping corrupt plex webhook", "id", id, "error", err)
+			continue
+		}
+		hooks = append(hooks, w)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks, nil
+}
+
+func (s *RedisStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	if err := s.client.HDel(ctx, plexWebhookHashKey, strings.TrimSpace(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete plex webhook: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package store_test
+
+import (
+	"testing"
+
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/store/storetest"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		srv, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(srv.Close)
+		return store.NewRedisStore(store.NewRedisClient(srv.Addr(), ""))
+	})
+}
@@ -64,6 +64,8 @@ func TestSavingUser(t *testing.T) {
 	}
 
 	originalUser.save()
+	// The store bumps the version on every write
+	originalUser.Version = 1
 
 	assert.Equal(t, s.HGet("goplaxt:user:id123", "username"), "halkeye")
 	assert.Equal(t, s.HGet("goplaxt:user:id123", "access"), "access123")
@@ -0,0 +1,34 @@
+package store
+
+import "errors"
+
+// ErrInvalidRetentionCategory is returned for categories PurgeBefore does
+// not know.
+var ErrInvalidRetentionCategory = errors.New("store: invalid retention category")
+
+// RetentionCategory names a kind of time-stamped record the store can purge.
+// A purge only ever shortens how long records are kept; the bounds each
+// category already enforces on write (MaxWatchedMoviesPerUser,
+// QueueLogRetention, ActivityRetention, WebhookStatsRetention) still apply.
+type RetentionCategory string
+
+const (
+	// RetentionHistory is the local watch history (WatchedMovie).
+	RetentionHistory RetentionCategory = "history"
+	// RetentionQueueLog is the queue event log (QueueLogEvent).
+	RetentionQueueLog RetentionCategory = "queue_log"
+	// RetentionTelemetry is the hourly activity and daily webhook counters.
+	RetentionTelemetry RetentionCategory = "telemetry"
+)
+
+// RetentionCategories lists every category PurgeBefore accepts.
+var RetentionCategories = []RetentionCategory{RetentionHistory, RetentionQueueLog, RetentionTelemetry}
+
+// Valid reports whether the category is one the store can purge.
+func (c RetentionCategory) Valid() bool {
+	switch c {
+	case RetentionHistory, RetentionQueueLog, RetentionTelemetry:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package storetest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeConsulPair struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+// fakeConsul implements the subset of the Consul KV API used by store.ConsulKV.
+type fakeConsul struct {
+	mu    sync.Mutex
+	data  map[string]fakeConsulPair
+	index uint64
+}
+
+// NewFakeConsul starts an in-process server speaking the Consul KV HTTP API
+// and stops it when the test ends.
+func NewFakeConsul(t *testing.T) *httptest.Server {
+	fc := &fakeConsul{data: map[string]fakeConsulPair{}}
+	srv := httptest.NewServer(fc)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/v1/status/leader" {
+		_ = json.NewEncoder(w).Encode("127.0.0.1:8300")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	switch r.Method {
+	case http.MethodGet:
+		var out []fakeConsulPair
+		if r.URL.Query().Get("recurse") != "" {
+			for k, p := range f.data {
+				if strings.HasPrefix(k, key) {
+					out = append(out, p)
+				}
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+		} else if p, ok := f.data[key]; ok {
+			out = append(out, p)
+		}
+		if len(out) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if cas := r.URL.Query().Get("cas"); cas != "" {
+			want, _ := strconv.ParseUint(cas, 10, 64)
+			if f.data[key].ModifyIndex != want {
+				_ = json.NewEncoder(w).Encode(false)
+				return
+			}
+		}
+		f.index++
+		f.data[key] = fakeConsulPair{Key: key, Value: body, ModifyIndex: f.index}
+		_ = json.NewEncoder(w).Encode(true)
+	case http.MethodDelete:
+		delete(f.data, key)
+		_ = json.NewEncoder(w).Encode(true)
+	}
+}
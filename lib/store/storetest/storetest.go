@@ -0,0 +1,614 @@
+// Package storetest holds behavioural tests that every store.Store backend
+// must pass, so backends cannot silently diverge from one another.
+//
+// A backend's test file calls Run with a factory returning a fresh, empty
+// store for each subtest:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store { return store.NewMemoryStore() })
+//	}
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"crovlune/plaxt/lib/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// QueueLimit is the per-user queue capacity documented on store.Store.
+const QueueLimit = 1000
+
+// Factory returns an empty store. It is called once per subtest.
+type Factory func(t *testing.T) store.Store
+
+// Run executes the full suite against the backend built by newStore.
+// Operations a backend reports as store.ErrNotSupported are skipped.
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"UserCRUD", testUserCRUD},
+		{"UserCompareAndSwap", testUserCompareAndSwap},
+		{"QueueFIFO", testQueueFIFO},
+		{"QueueEviction", testQueueEviction},
+		{"FamilyGroupLifecycle", testFamilyGroupLifecycle},
+		{"FamilyGroupAtomicity", testFamilyGroupAtomicity},
+		{"RetryTransitions", testRetryTransitions},
+		{"NotificationFlow", testNotificationFlow},
+		{"Activity", testActivity},
+		{"QueueLog", testQueueLog},
+		{"PlaybackSession", testPlaybackSession},
+		{"UserPreferences", testUserPreferences},
+		{"WebhookStats", testWebhookStats},
+		{"AdminTOTP", testAdminTOTP},
+		{"Retention", testRetention},
+		{"PlexWebhook", testPlexWebhook},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func skipIfNotSupported(t *testing.T, err error) {
+	t.Helper()
+	if errors.Is(err, store.ErrNotSupported) {
+		t.Skip("not supported by this backend")
+	}
+}
+
+// assertGroupMissing accepts both conventions backends use for a missing
+// group: a nil result or ErrFamilyGroupNotFound.
+func assertGroupMissing(t *testing.T, group *store.FamilyGroup, err error) {
+	t.Helper()
+	if err != nil {
+		assert.ErrorIs(t, err, store.ErrFamilyGroupNotFound)
+		return
+	}
+	assert.Nil(t, group)
+}
+
+func testUserCRUD(t *testing.T, s store.Store) {
+	// Midnight, because some backends persist only the date
+	updated := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s.WriteUser(store.User{
+		ID:               "user-1",
+		Username:         "alice",
+		AccessToken:      "access",
+		RefreshToken:     "refresh",
+		TraktDisplayName: "Alice",
+		Updated:          updated,
+		TokenExpiry:      updated.Add(90 * 24 * time.Hour),
+	})
+	s.WriteUser(store.User{ID: "user-2", Username: "bob", AccessToken: "a", RefreshToken: "r", Updated: updated.AddDate(0, 0, -1)})
+
+	user := s.GetUser("user-1")
+	require.NotNil(t, user)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "access", user.AccessToken)
+	assert.Equal(t, "refresh", user.RefreshToken)
+	assert.Equal(t, "Alice", user.TraktDisplayName)
+	assert.True(t, user.Updated.Equal(updated), "updated: got %v", user.Updated)
+
+	byName := s.GetUserByName("alice")
+	require.NotNil(t, byName)
+	assert.Equal(t, "user-1", byName.ID)
+	assert.Nil(t, s.GetUserByName("nobody"))
+
+	users := s.ListUsers()
+	require.Len(t, users, 2)
+	assert.Equal(t, "user-1", users[0].ID, "users are listed most recently updated first")
+
+	assert.True(t, s.DeleteUser("user-1", "alice"))
+	assert.Nil(t, s.GetUser("user-1"))
+	assert.Len(t, s.ListUsers(), 1)
+}
+
+func testUserCompareAndSwap(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	s.WriteUser(store.User{ID: "user-1", Username: "alice", AccessToken: "a", RefreshToken: "r", Updated: time.Now()})
+
+	user := s.GetUser("user-1")
+	require.NotNil(t, user)
+	stale := *user
+
+	user.AccessToken = "a2"
+	require.NoError(t, s.CompareAndSwapUser(ctx, *user))
+	assert.ErrorIs(t, s.CompareAndSwapUser(ctx, stale), store.ErrUserVersionConflict)
+
+	current := s.GetUser("user-1")
+	require.NotNil(t, current)
+	assert.Equal(t, "a2", current.AccessToken)
+	assert.Greater(t, current.Version, stale.Version)
+
+	assert.ErrorIs(t, s.CompareAndSwapUser(ctx, store.User{ID: "missing", Username: "ghost"}), store.ErrUserNotFound)
+}
+
+func queuedEvent(userID string, n int, createdAt time.Time) store.QueuedScrobbleEvent {
+	return store.QueuedScrobbleEvent{
+		ID:         fmt.Sprintf("%s-event-%04d", userID, n),
+		UserID:     userID,
+		Action:     "stop",
+		Progress:   95,
+		PlayerUUID: "player-1",
+		RatingKey:  fmt.Sprintf("%d", n),
+		CreatedAt:  createdAt,
+	}
+}
+
+func testQueueFIFO(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.EnqueueScrobble(ctx, queuedEvent("user-1", i, base.Add(time.Duration(i)*time.Second))))
+	}
+	require.NoError(t, s.EnqueueScrobble(ctx, queuedEvent("user-2", 0, base)))
+
+	events, err := s.DequeueScrobbles(ctx, "user-1", 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "user-1-event-0000", events[0].ID)
+	assert.Equal(t, "user-1-event-0001", events[1].ID)
+
+	size, err := s.GetQueueSize(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, size, "dequeue must not remove events")
+
+	require.NoError(t, s.DeleteQueuedScrobble(ctx, events[0].ID))
+	require.NoError(t, s.UpdateQueuedScrobbleRetry(ctx, events[1].ID, 2))
+	events, err = s.DequeueScrobbles(ctx, "user-1", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "user-1-event-0001", events[0].ID)
+	assert.Equal(t, 2, events[0].RetryCount)
+
+	users, err := s.ListUsersWithQueuedEvents(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user-1", "user-2"}, users)
+	total, err := s.TotalQueueSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	purged, err := s.PurgeQueueForUser(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	size, err = s.GetQueueSize(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Zero(t, size)
+}
+
+func testQueueEviction(t *testing.T, s store.Store) {
+	if testing.Short() {
+		t.Skip("fills a queue to capacity")
+	}
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= QueueLimit; i++ {
+		require.NoError(t, s.EnqueueScrobble(ctx, queuedEvent("user-1", i, base.Add(time.Duration(i)*time.Second))))
+	}
+
+	size, err := s.GetQueueSize(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, QueueLimit, size)
+
+	events, err := s.DequeueScrobbles(ctx, "user-1", 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "user-1-event-0001", events[0].ID, "the oldest event is evicted first")
+}
+
+func newGroup(id, plexUsername string) *store.FamilyGroup {
+	now := time.Now().UTC()
+	return &store.FamilyGroup{ID: id, PlexUsername: plexUsername, CreatedAt: now, UpdatedAt: now}
+}
+
+func newMember(id, label, traktUsername string) *store.GroupMember {
+	member := &store.GroupMember{
+		ID:                  id,
+		TempLabel:           label,
+		AuthorizationStatus: store.GroupMemberStatusPending,
+		CreatedAt:           time.Now().UTC(),
+	}
+	if traktUsername != "" {
+		member.TraktUsername = traktUsername
+		member.AuthorizationStatus = store.GroupMemberStatusAuthorized
+	}
+	return member
+}
+
+func testFamilyGroupLifecycle(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	group := newGroup("group-1", "household")
+	members := []*store.GroupMember{newMember("member-1", "Dad", "dad"), newMember("member-2", "Kid", "")}
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, group, members))
+
+	got, err := s.GetFamilyGroupByPlex(ctx, "household")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "group-1", got.ID)
+
+	assert.Error(t, s.CreateFamilyGroup(ctx, newGroup("group-2", "household")), "plex usernames are unique")
+
+	listed, err := s.ListGroupMembers(ctx, "group-1")
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	dad, err := s.GetGroupMemberByTrakt(ctx, "group-1", "dad")
+	require.NoError(t, err)
+	require.NotNil(t, dad)
+	assert.Equal(t, "member-1", dad.ID)
+
+	require.NoError(t, s.RemoveGroupMember(ctx, "group-1", "member-2"))
+	listed, err = s.ListGroupMembers(ctx, "group-1")
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	require.NoError(t, s.DeleteFamilyGroup(ctx, "group-1"))
+	got, err = s.GetFamilyGroup(ctx, "group-1")
+	assertGroupMissing(t, got, err)
+}
+
+func testFamilyGroupAtomicity(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	// The second member reuses the first one's Trakt username, so every
+	// backend rejects it after the group and first member were written.
+	group := newGroup("group-1", "household")
+	members := []*store.GroupMember{newMember("member-1", "Dad", "dad"), newMember("member-2", "Also Dad", "dad")}
+	require.Error(t, s.CreateFamilyGroupWithMembers(ctx, group, members))
+
+	got, err := s.GetFamilyGroupByPlex(ctx, "household")
+	assertGroupMissing(t, got, err)
+	got, err = s.GetFamilyGroup(ctx, "group-1")
+	assertGroupMissing(t, got, err)
+	groups, err := s.ListFamilyGroups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	// The plex username is free again after the rollback
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, newGroup("group-2", "household"), []*store.GroupMember{newMember("member-3", "Dad", "dad")}))
+}
+
+func testRetryTransitions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	require.NoError(t, s.CreateFamilyGroupWithMembers(ctx, newGroup("group-1", "household"), []*store.GroupMember{newMember("member-1", "Dad", "dad")}))
+
+	now := time.Now().UTC()
+	item := &store.RetryQueueItem{
+		ID:            "retry-1",
+		FamilyGroupID: "group-1",
+		GroupMemberID: "member-1",
+		Payload:       json.RawMessage(`{"action":"stop"}`),
+		NextAttemptAt: now.Add(-time.Minute),
+		Status:        store.RetryQueueStatusQueued,
+	}
+	err := s.EnqueueRetryItem(ctx, item)
+	skipIfNotSupported(t, err)
+	require.NoError(t, err)
+
+	due, err := s.ListDueRetryItems(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, store.RetryQueueStatusRetrying, due[0].Status)
+
+	require.NoError(t, s.MarkRetryFailure(ctx, "retry-1", 1, now.Add(time.Hour), "timeout", false))
+	due, err = s.ListDueRetryItems(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due, "rescheduled items are not due yet")
+	due, err = s.ListDueRetryItems(ctx, now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].AttemptCount)
+	assert.Equal(t, "timeout", due[0].LastError)
+
+	require.NoError(t, s.MarkRetryFailure(ctx, "retry-1", 2, now, "unauthorized", true))
+	due, err = s.ListDueRetryItems(ctx, now.Add(24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due, "permanent failures are never retried")
+
+	require.NoError(t, s.MarkRetrySuccess(ctx, "retry-1"))
+	assert.ErrorIs(t, s.MarkRetrySuccess(ctx, "retry-1"), store.ErrRetryItemNotFound)
+}
+
+func testNotificationFlow(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	notification := &store.Notification{
+		ID:            "notification-1",
+		FamilyGroupID: "group-1",
+		Type:          store.NotificationTypeMemberAdded,
+		Message:       "Dad joined the family group",
+	}
+	err := s.CreateNotification(ctx, notification)
+	skipIfNotSupported(t, err)
+	require.NoError(t, err)
+
+	active, err := s.GetNotifications(ctx, "group-1", false)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "Dad joined the family group", active[0].Message)
+
+	other, err := s.GetNotifications(ctx, "group-2", true)
+	require.NoError(t, err)
+	assert.Empty(t, other)
+
+	require.NoError(t, s.DismissNotification(ctx, "notification-1"))
+	active, err = s.GetNotifications(ctx, "group-1", false)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	all, err := s.GetNotifications(ctx, "group-1", true)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.True(t, all[0].Dismissed)
+
+	require.NoError(t, s.DeleteNotification(ctx, "notification-1"))
+	assert.ErrorIs(t, s.DeleteNotification(ctx, "notification-1"), store.ErrNotificationNotFound)
+}
+
+func testActivity(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now))
+	require.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now))
+	require.NoError(t, s.IncrementActivity(ctx, store.ActivityFailure, now))
+	require.NoError(t, s.IncrementActivity(ctx, store.ActivityQueued, now.Add(-3*time.Hour)))
+	require.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-3*24*time.Hour)))
+	assert.ErrorIs(t, s.IncrementActivity(ctx, store.ActivityKind("bogus"), now), store.ErrInvalidActivityKind)
+
+	buckets, err := s.ListActivity(ctx, now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, store.ActivityBucketStart(now.Add(-3*time.Hour)), buckets[0].Start.UTC())
+	assert.Equal(t, store.ActivityCounts{Queued: 1}, buckets[0].ActivityCounts)
+	assert.Equal(t, store.ActivityBucketStart(now), buckets[1].Start.UTC())
+	assert.Equal(t, store.ActivityCounts{Scrobbles: 2, Failures: 1}, buckets[1].ActivityCounts)
+
+	buckets, err = s.ListActivity(ctx, now.Add(-7*24*time.Hour), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, buckets, 2, "buckets after to are excluded")
+	assert.Equal(t, 1, buckets[0].Scrobbles)
+}
+
+func testQueueLog(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.AppendQueueLogEvent(ctx, store.QueueLogEvent{
+			Timestamp: now.Add(time.Duration(i-5) * time.Minute),
+			Operation: "queue_enqueue",
+			UserID:    "user-1",
+			QueueSize: i + 1,
+		}))
+	}
+	require.NoError(t, s.AppendQueueLogEvent(ctx, store.QueueLogEvent{
+		Timestamp: now.Add(-store.QueueLogRetention - time.Hour),
+		Operation: "queue_enqueue",
+		UserID:    "user-1",
+	}))
+
+	events, err := s.ListQueueLogEvents(ctx, store.QueueLogQuery{})
+	require.NoError(t, err)
+	require.Len(t, events, 5, "events past retention are dropped")
+	assert.Equal(t, 5, events[0].QueueSize, "newest first")
+	assert.Equal(t, 1, events[4].QueueSize)
+
+	events, err = s.ListQueueLogEvents(ctx, store.QueueLogQuery{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, []int{4, 3}, []int{events[0].QueueSize, events[1].QueueSize})
+
+	events, err = s.ListQueueLogEvents(ctx, store.QueueLogQuery{
+		Since: now.Add(-4 * time.Minute),
+		Until: now.Add(-2 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, 4, events[0].QueueSize)
+	assert.Equal(t, 2, events[2].QueueSize)
+}
+
+func testPlaybackSession(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	_, err := s.GetPlaybackSession(ctx, "player-1", "42")
+	skipIfNotSupported(t, err)
+	assert.ErrorIs(t, err, store.ErrPlaybackSessionNotFound)
+	assert.ErrorIs(t, s.PutPlaybackSession(ctx, &store.PlaybackSession{RatingKey: "42"}), store.ErrInvalidPlaybackSession)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	session := store.NewPlaybackSession("player-1", "42", "user-1", store.SessionPlaying, start, 10)
+	session.Advance(store.SessionPaused, start.Add(20*time.Minute), 30)
+	require.NoError(t, s.PutPlaybackSession(ctx, session))
+	require.NoError(t, s.PutPlaybackSession(ctx, store.NewPlaybackSession("player-1", "43", "user-1", store.SessionPlaying, start, 0)))
+
+	got, err := s.GetPlaybackSession(ctx, "player-1", "42")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.UserID)
+	assert.Equal(t, store.SessionPaused, got.State)
+	assert.Equal(t, 20*time.Minute, got.WatchTime)
+	assert.Equal(t, 1, got.Pauses)
+	assert.Equal(t, 30, got.Progress)
+	assert.True(t, start.Equal(got.StartedAt))
+
+	stale := store.NewPlaybackSession("player-2", "42", "user-1", store.SessionPlaying, time.Now().Add(-store.PlaybackSessionTTL-time.Hour), 0)
+	require.NoError(t, s.PutPlaybackSession(ctx, stale))
+	_, err = s.GetPlaybackSession(ctx, "player-2", "42")
+	assert.ErrorIs(t, err, store.ErrPlaybackSessionNotFound, "idle sessions expire")
+
+	require.NoError(t, s.DeletePlaybackSession(ctx, "player-1", "42"))
+	require.NoError(t, s.DeletePlaybackSession(ctx, "player-1", "42"))
+	_, err = s.GetPlaybackSession(ctx, "player-1", "42")
+	assert.ErrorIs(t, err, store.ErrPlaybackSessionNotFound)
+	_, err = s.GetPlaybackSession(ctx, "player-1", "43")
+	assert.NoError(t, err, "sessions are keyed by rating key")
+}
+
+func testUserPreferences(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := store.NewUser("prefs-user", "access", "refresh", nil, time.Now().Add(time.Hour), time.Now(), s)
+	_, err := s.GetUserPreferences(ctx, user.ID)
+	skipIfNotSupported(t, err)
+	assert.ErrorIs(t, err, store.ErrUserPreferencesNotFound)
+	assert.ErrorIs(t, s.PutUserPreferences(ctx, &store.UserPreferences{UserID: user.ID, Data: json.RawMessage(`[1]`)}), store.ErrInvalidUserPreferences)
+
+	require.NoError(t, s.PutUserPreferences(ctx, &store.UserPreferences{UserID: user.ID, Data: json.RawMessage(`{"paused":true}`)}))
+	require.NoError(t, s.PutUserPreferences(ctx, &store.UserPreferences{UserID: user.ID, Data: json.RawMessage(`{"paused": false, "libraries": ["Movies"]}`)}))
+	got, err := s.GetUserPreferences(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.UserID)
+	assert.JSONEq(t, `{"paused":false,"libraries":["Movies"]}`, string(got.Data), "put replaces the document")
+	assert.False(t, got.UpdatedAt.IsZero())
+
+	require.NoError(t, s.DeleteUserPreferences(ctx, user.ID))
+	require.NoError(t, s.DeleteUserPreferences(ctx, user.ID))
+	_, err = s.GetUserPreferences(ctx, user.ID)
+	assert.ErrorIs(t, err, store.ErrUserPreferencesNotFound)
+}
+
+func testWebhookStats(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+	user, group := store.WebhookSubjectUser, store.WebhookSubjectFamilyGroup
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookReceived, now))
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookReceived, now))
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookProcessed, now))
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookSkipped, now))
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookFailed, now.Add(-2*24*time.Hour)))
+	require.NoError(t, s.IncrementWebhookStat(ctx, user, "user-2", store.WebhookReceived, now))
+	require.NoError(t, s.IncrementWebhookStat(ctx, group, "user-1", store.WebhookReceived, now))
+	assert.ErrorIs(t, s.IncrementWebhookStat(ctx, user, "user-1", store.WebhookOutcome("bogus"), now), store.ErrInvalidWebhookOutcome)
+	assert.ErrorIs(t, s.IncrementWebhookStat(ctx, user, " ", store.WebhookReceived, now), store.ErrInvalidWebhookSubject)
+
+	days, err := s.ListWebhookStats(ctx, user, "user-1", now.Add(-7*24*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, days, 2, "other users and groups are counted separately")
+	assert.Equal(t, store.WebhookDayStart(now.Add(-2*24*time.Hour)), days[0].Day.UTC())
+	assert.Equal(t, store.WebhookCounts{Failed: 1}, days[0].WebhookCounts)
+	assert.Equal(t, store.WebhookDayStart(now), days[1].Day.UTC())
+	assert.Equal(t, store.WebhookCounts{Received: 2, Processed: 1, Skipped: 1}, days[1].WebhookCounts)
+
+	days, err = s.ListWebhookStats(ctx, user, "user-1", now.Add(-7*24*time.Hour), now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, days, 1, "days after to are excluded")
+
+	days, err = s.ListWebhookStats(ctx, group, "missing", now.Add(-7*24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Empty(t, days)
+}
+
+func testAdminTOTP(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	_, err := s.GetAdminTOTP(ctx, "root")
+	skipIfNotSupported(t, err)
+	assert.ErrorIs(t, err, store.ErrAdminTOTPNotFound)
+	assert.ErrorIs(t, s.PutAdminTOTP(ctx, &store.AdminTOTP{Username: "root"}), store.ErrInvalidAdminTOTP)
+
+	require.NoError(t, s.PutAdminTOTP(ctx, &store.AdminTOTP{Username: "Root", Secret: "JBSWY3DPEHPK3PXP"}))
+	require.NoError(t, s.PutAdminTOTP(ctx, &store.AdminTOTP{
+		Username:      "root",
+		Secret:        "JBSWY3DPEHPK3PXP",
+		Confirmed:     true,
+		RecoveryCodes: []string{"a", "b"},
+		LastStep:      42,
+	}))
+	got, err := s.GetAdminTOTP(ctx, " ROOT ")
+	require.NoError(t, err)
+	assert.Equal(t, "root", got.Username, "usernames are case-insensitive")
+	assert.True(t, got.Confirmed, "put replaces the enrollment")
+	assert.Equal(t, []string{"a", "b"}, got.RecoveryCodes)
+	assert.Equal(t, int64(42), got.LastStep)
+
+	require.NoError(t, s.DeleteAdminTOTP(ctx, "root"))
+	require.NoError(t, s.DeleteAdminTOTP(ctx, "root"))
+	_, err = s.GetAdminTOTP(ctx, "root")
+	assert.ErrorIs(t, err, store.ErrAdminTOTPNotFound)
+}
+
+func testRetention(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+	old, recent := now.Add(-4*24*time.Hour), now.Add(-time.Hour)
+	cutoff := now.Add(-2 * 24 * time.Hour)
+
+	for _, at := range []time.Time{old, recent} {
+		require.NoError(t, s.RecordWatchedMovie(ctx, &store.WatchedMovie{UserID: "user-1", Title: "Alien", WatchedAt: at}))
+		require.NoError(t, s.AppendQueueLogEvent(ctx, store.QueueLogEvent{Timestamp: at, Operation: "queue_enqueue", UserID: "user-1"}))
+		require.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, at))
+		require.NoError(t, s.IncrementWebhookStat(ctx, store.WebhookSubjectUser, "user-1", store.WebhookReceived, at))
+	}
+
+	n, err := s.PurgeBefore(ctx, store.RetentionHistory, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	movies, err := s.ListWatchedMovies(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+	assert.WithinDuration(t, recent, movies[0].WatchedAt, time.Second)
+
+	n, err = s.PurgeBefore(ctx, store.RetentionQueueLog, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	events, err := s.ListQueueLogEvents(ctx, store.QueueLogQuery{})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	n, err = s.PurgeBefore(ctx, store.RetentionTelemetry, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "one activity bucket and one webhook day")
+	buckets, err := s.ListActivity(ctx, now.Add(-7*24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Len(t, buckets, 1)
+	days, err := s.ListWebhookStats(ctx, store.WebhookSubjectUser, "user-1", now.Add(-7*24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Len(t, days, 1)
+
+	n, err = s.PurgeBefore(ctx, store.RetentionHistory, cutoff)
+	require.NoError(t, err)
+	assert.Zero(t, n, "purging again removes nothing")
+
+	_, err = s.PurgeBefore(ctx, store.RetentionCategory("bogus"), cutoff)
+	assert.ErrorIs(t, err, store.ErrInvalidRetentionCategory)
+}
+
+func testPlexWebhook(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	_, err := s.GetPlexWebhook(ctx, "user-1")
+	skipIfNotSupported(t, err)
+	assert.ErrorIs(t, err, store.ErrPlexWebhookNotFound)
+	assert.ErrorIs(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{ID: "user-1"}), store.ErrInvalidPlexWebhook)
+
+	first := time.Now().Add(-time.Hour)
+	require.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{
+		ID: "user-1", Token: "tok-1", WebhookURL: "https://plaxt.example/api?id=user-1", CreatedAt: first,
+	}))
+	require.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{
+		ID: "group-1", Token: "tok-2", WebhookURL: "https://plaxt.example/api?id=group-1",
+	}))
+	require.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{
+		ID: "user-1", Token: "tok-3", WebhookURL: "https://plaxt.example/api?id=user-1",
+		CreatedAt: first, LastError: "unauthorized",
+	}))
+
+	got, err := s.GetPlexWebhook(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tok-3", got.Token, "put replaces the registration")
+	assert.Equal(t, "unauthorized", got.LastError)
+
+	list, err := s.ListPlexWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "user-1", list[0].ID, "oldest first")
+	assert.Equal(t, "group-1", list[1].ID)
+
+	require.NoError(t, s.DeletePlexWebhook(ctx, "user-1"))
+	require.NoError(t, s.DeletePlexWebhook(ctx, "user-1"))
+	_, err = s.GetPlexWebhook(ctx, "user-1")
+	assert.ErrorIs(t, err, store.ErrPlexWebhookNotFound)
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// TrashRetention is how long a deleted user or family group can be restored.
+const TrashRetention = 30 * 24 * time.Hour
+
+// Trash entry kinds.
+const (
+	TrashKindUser        = "user"
+	TrashKindFamilyGroup = "family_group"
+)
+
+var (
+	// ErrTrashEntryNotFound is returned when a trash lookup fails.
+	ErrTrashEntryNotFound = errors.New("store: trash entry not found")
+	// ErrInvalidTrashEntry is returned when required fields are missing.
+	ErrInvalidTrashEntry = errors.New("store: trash entry is invalid")
+)
+
+// TrashEntry is a snapshot of a deleted user or family group. The live
+// records are removed on delete; Payload holds everything needed to recreate
+// them until DeletedAt+TrashRetention.
+type TrashEntry struct {
+	ID        string          `json:"id"` // ID of the deleted user or group
+	Kind      string          `json:"kind"`
+	Label     string          `json:"label"` // username or Plex username, for display
+	DeletedAt time.Time       `json:"deleted_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Normalize trims identifiers and defaults DeletedAt to now.
+func (e *TrashEntry) Normalize() {
+	if e == nil {
+		return
+	}
+	e.ID = strings.TrimSpace(e.ID)
+	e.Kind = strings.TrimSpace(e.Kind)
+	e.Label = strings.TrimSpace(e.Label)
+	if e.DeletedAt.IsZero() {
+		e.DeletedAt = time.Now()
+	}
+	e.DeletedAt = e.DeletedAt.UTC()
+}
+
+// Validate ensures the entry can be persisted.
+func (e *TrashEntry) Validate() error {
+	if e == nil {
+		return ErrInvalidTrashEntry
+	}
+	e.Normalize()
+	if e.ID == "" || len(e.Payload) == 0 {
+		return ErrInvalidTrashEntry
+	}
+	if e.Kind != TrashKindUser && e.Kind != TrashKindFamilyGroup {
+		return ErrInvalidTrashEntry
+	}
+	return nil
+}
+
+// ExpiresAt is when the entry stops being restorable.
+func (e TrashEntry) ExpiresAt() time.Time {
+	return e.DeletedAt.Add(TrashRetention)
+}
+
+// Expired reports whether the restore window has passed.
+func (e TrashEntry) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt())
+}
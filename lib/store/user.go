@@ -22,7 +22,11 @@ type User struct {
 	TraktDisplayName string
 	Updated          time.Time
 	TokenExpiry      time.Time // When the access token expires
-	store            store
+	TokenIssuedAt    time.Time // When Trakt issued the access token; zero if unknown
+	// Version is bumped by the store on every write. CompareAndSwapUser only
+	// succeeds while it still matches the stored value.
+	Version int64
+	store   store
 }
 
 // uuid returns a random UUIDv4 string.
@@ -39,8 +43,9 @@ func uuid() string {
 
 // NewUser creates and persists a new user object with the given tokens.
 // If displayName is provided, it is normalized and truncated to the allowed length.
-// tokenExpiry is the time when the access token expires.
-func NewUser(username, accessToken, refreshToken string, displayName *string, tokenExpiry time.Time, store store) User {
+// tokenExpiry is the time when the access token expires and tokenIssuedAt when
+// Trakt created it; a zero tokenIssuedAt means now.
+func NewUser(username, accessToken, refreshToken string, displayName *string, tokenExpiry, tokenIssuedAt time.Time, store store) User {
 	id := uuid()
 	var normalizedName string
 	if displayName != nil {
@@ -54,20 +59,28 @@ func NewUser(username, accessToken, refreshToken string, displayName *string, to
 		TraktDisplayName: normalizedName,
 		Updated:          time.Now(),
 		TokenExpiry:      tokenExpiry,
+		TokenIssuedAt:    tokenIssuedAt,
 		store:            store,
 	}
+	if user.TokenIssuedAt.IsZero() {
+		user.TokenIssuedAt = user.Updated
+	}
 	user.save()
 	return user
 }
 
 // UpdateUser updates the tokens of an existing user. If displayName is provided,
 // it replaces the stored Trakt display name (after normalization/truncation).
-// tokenExpiry is the time when the access token expires.
-func (user *User) UpdateUser(accessToken, refreshToken string, displayName *string, tokenExpiry time.Time) {
+// tokenExpiry and tokenIssuedAt behave as in NewUser.
+func (user *User) UpdateUser(accessToken, refreshToken string, displayName *string, tokenExpiry, tokenIssuedAt time.Time) {
 	user.AccessToken = accessToken
 	user.RefreshToken = refreshToken
 	user.Updated = time.Now()
 	user.TokenExpiry = tokenExpiry
+	user.TokenIssuedAt = tokenIssuedAt
+	if user.TokenIssuedAt.IsZero() {
+		user.TokenIssuedAt = user.Updated
+	}
 	if displayName != nil {
 		normalizedName, _ := common.NormalizeDisplayName(*displayName)
 		user.TraktDisplayName = normalizedName
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUserPreferencesNotFound is returned when a user has never saved preferences.
+	ErrUserPreferencesNotFound = errors.New("store: user preferences not found")
+	// ErrInvalidUserPreferences is returned when required fields are missing.
+	ErrInvalidUserPreferences = errors.New("store: user preferences are invalid")
+)
+
+// UserPreferences is a user's preferences document. The store keeps Data as
+// an opaque JSON object; its schema lives in lib/preferences.
+type UserPreferences struct {
+	UserID    string          `json:"user_id"`
+	Data      json.RawMessage `json:"data"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Validate trims the user ID, defaults UpdatedAt to now and ensures Data is
+// a JSON object.
+func (p *UserPreferences) Validate() error {
+	if p == nil {
+		return ErrInvalidUserPreferences
+	}
+	p.UserID = strings.TrimSpace(p.UserID)
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now()
+	}
+	p.UpdatedAt = p.UpdatedAt.UTC()
+	var object map[string]json.RawMessage
+	if p.UserID == "" || json.Unmarshal(p.Data, &object) != nil || object == nil {
+		return ErrInvalidUserPreferences
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ func TestNewUserAppliesDisplayNameLimit(t *testing.T) {
 	display := strings.Repeat("x", 60)
 	capture := &captureStore{}
 	expiry := time.Now().Add(90 * 24 * time.Hour)
-	user := NewUser("alice", "atk", "rtk", &display, expiry, capture)
+	user := NewUser("alice", "atk", "rtk", &display, expiry, time.Time{}, capture)
 
 	assert.Equal(t, "alice", user.Username)
 	assert.Len(t, user.TraktDisplayName, 50)
@@ -32,17 +32,17 @@ func TestUpdateUserRespectsOptionalDisplayName(t *testing.T) {
 	initialName := "Alice"
 	capture := &captureStore{}
 	expiry := time.Now().Add(90 * 24 * time.Hour)
-	user := NewUser("alice", "atk", "rtk", &initialName, expiry, capture)
+	user := NewUser("alice", "atk", "rtk", &initialName, expiry, time.Time{}, capture)
 
 	// Nil display name keeps existing value.
 	newExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user.UpdateUser("atk2", "rtk2", nil, newExpiry)
+	user.UpdateUser("atk2", "rtk2", nil, newExpiry, time.Time{})
 	assert.Equal(t, "Alice", user.TraktDisplayName)
 
 	// Providing a shorter name replaces it.
 	newName := "Bob"
 	newExpiry2 := time.Now().Add(90 * 24 * time.Hour)
-	user.UpdateUser("atk3", "rtk3", &newName, newExpiry2)
+	user.UpdateUser("atk3", "rtk3", &newName, newExpiry2, time.Time{})
 	assert.Equal(t, "Bob", user.TraktDisplayName)
 	assert.Equal(t, capture.lastUser.TraktDisplayName, "Bob")
 }
@@ -50,7 +50,7 @@ func TestUpdateUserRespectsOptionalDisplayName(t *testing.T) {
 func TestUpdateDisplayNameTruncatesAndPreservesTimestamps(t *testing.T) {
 	capture := &captureStore{}
 	expiry := time.Now().Add(90 * 24 * time.Hour)
-	user := NewUser("alice", "atk", "rtk", nil, expiry, capture)
+	user := NewUser("alice", "atk", "rtk", nil, expiry, time.Time{}, capture)
 	initialUpdated := user.Updated
 	tooLong := strings.Repeat("Z", common.MaxTraktDisplayNameLength+5)
 
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxWatchedMoviesPerUser caps the local watch history kept per user.
+const MaxWatchedMoviesPerUser = 10000
+
+// ErrInvalidWatchedMovie is returned when required fields are missing.
+var ErrInvalidWatchedMovie = errors.New("store: watched movie is invalid")
+
+// WatchedMovie is a completed movie scrobble kept locally so it can be
+// exported to services without a write API (e.g. a Letterboxd diary import).
+type WatchedMovie struct {
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	Year      int       `json:"year,omitempty"`
+	Imdb      string    `json:"imdb,omitempty"`
+	Tmdb      int       `json:"tmdb,omitempty"`
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+// Normalize trims identifiers and defaults WatchedAt to now.
+func (m *WatchedMovie) Normalize() {
+	if m == nil {
+		return
+	}
+	m.UserID = strings.TrimSpace(m.UserID)
+	m.Title = strings.TrimSpace(m.Title)
+	m.Imdb = strings.TrimSpace(m.Imdb)
+	if m.WatchedAt.IsZero() {
+		m.WatchedAt = time.Now()
+	}
+	m.WatchedAt = m.WatchedAt.UTC()
+}
+
+// Validate ensures the entry can be persisted.
+func (m *WatchedMovie) Validate() error {
+	if m == nil {
+		return ErrInvalidWatchedMovie
+	}
+	m.Normalize()
+	if m.UserID == "" || m.Title == "" {
+		return ErrInvalidWatchedMovie
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// WebhookStatsRetention bounds how long daily webhook counters are kept.
+const WebhookStatsRetention = 31 * 24 * time.Hour
+
+// webhookStatsLayout names daily buckets; it sorts chronologically.
+const webhookStatsLayout = "20060102"
+
+var (
+	// ErrInvalidWebhookOutcome is returned for outcomes the store does not track.
+	ErrInvalidWebhookOutcome = errors.New("store: invalid webhook outcome")
+	// ErrInvalidWebhookSubject is returned when a counter names no user or group.
+	ErrInvalidWebhookSubject = errors.New("store: invalid webhook stats subject")
+)
+
+// WebhookSubject is the kind of record webhook counters are kept for.
+type WebhookSubject string
+
+const (
+	// WebhookSubjectUser keys counters by plaxt user ID.
+	WebhookSubjectUser WebhookSubject = "user"
+	// WebhookSubjectFamilyGroup keys counters by family group ID.
+	WebhookSubjectFamilyGroup WebhookSubject = "family_group"
+)
+
+// Valid reports whether the subject is one counters are kept for.
+func (s WebhookSubject) Valid() bool {
+	return s == WebhookSubjectUser || s == WebhookSubjectFamilyGroup
+}
+
+// WebhookOutcome names one of the daily webhook counters.
+type WebhookOutcome string
+
+const (
+	// WebhookReceived counts webhooks that reached plaxt for the subject.
+	WebhookReceived WebhookOutcome = "received"
+	// WebhookProcessed counts webhooks handed to the scrobbler.
+	WebhookProcessed WebhookOutcome = "processed"
+	// WebhookSkipped counts webhooks ignored on purpose (extras, preferences,
+	// events from other accounts).
+	WebhookSkipped WebhookOutcome = "skipped"
+	// WebhookFailed counts webhooks that could not be handled.
+	WebhookFailed WebhookOutcome = "failed"
+)
+
+// Valid reports whether the outcome is one of the tracked counters.
+func (o WebhookOutcome) Valid() bool {
+	switch o {
+	case WebhookReceived, WebhookProcessed, WebhookSkipped, WebhookFailed:
+		return true
+	}
+	return false
+}
+
+// WebhookCounts holds the counters of one day.
+type WebhookCounts struct {
+	Received  int `json:"received"`
+	Processed int `json:"processed"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// Add increments the counter named by outcome by n.
+func (c *WebhookCounts) Add(outcome WebhookOutcome, n int) {
+	switch outcome {
+	case WebhookReceived:
+		c.Received += n
+	case WebhookProcessed:
+		c.Processed += n
+	case WebhookSkipped:
+		c.Skipped += n
+	case WebhookFailed:
+		c.Failed += n
+	}
+}
+
+// Merge adds every counter of other to c.
+func (c *WebhookCounts) Merge(other WebhookCounts) {
+	c.Received += other.Received
+	c.Processed += other.Processed
+	c.Skipped += other.Skipped
+	c.Failed += other.Failed
+}
+
+// WebhookStatsDay is one UTC day of webhook counters.
+type WebhookStatsDay struct {
+	Day time.Time `json:"day"`
+	WebhookCounts
+}
+
+// WebhookDayStart returns the start of the UTC day containing t.
+func WebhookDayStart(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func webhookStatsKey(t time.Time) string {
+	return WebhookDayStart(t).Format(webhookStatsLayout)
+}
+
+func parseWebhookStatsKey(key string) (time.Time, bool) {
+	day, err := time.Parse(webhookStatsLayout, key)
+	return day, err == nil
+}
+
+// validWebhookStat checks the arguments shared by every IncrementWebhookStat.
+func validWebhookStat(subject WebhookSubject, id string, outcome WebhookOutcome) error {
+	if !subject.Valid() || strings.TrimSpace(id) == "" {
+		return ErrInvalidWebhookSubject
+	}
+	if !outcome.Valid() {
+		return ErrInvalidWebhookOutcome
+	}
+	return nil
+}
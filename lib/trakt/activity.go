@@ -0,0 +1,20 @@
+package trakt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"crovlune/plaxt/lib/store"
+)
+
+// RecordActivity adds one event to the hourly activity counters shown on the
+// admin dashboard. Failures are logged only and never block scrobbling.
+func RecordActivity(ctx context.Context, s store.Store, kind store.ActivityKind, at time.Time) {
+	if s == nil {
+		return
+	}
+	if err := s.IncrementActivity(ctx, kind, at); err != nil {
+		slog.Warn("activity stats update failed", "kind", kind, "error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"crovlune/plaxt/lib/store"
+)
+
+// ConflictPolicy decides which player scrobbles when one user plays on two
+// players at once.
+type ConflictPolicy string
+
+const (
+	// ConflictLatestWins hands Trakt to the player that started most recently.
+	ConflictLatestWins ConflictPolicy = "latest-wins"
+	// ConflictFirstWins keeps Trakt on the player that started first until it
+	// stops or goes idle.
+	ConflictFirstWins ConflictPolicy = "first-wins"
+)
+
+// How long a session keeps its claim on the user without new events.
+const (
+	conflictPlayingIdle = 4 * time.Hour
+	conflictPausedIdle  = 15 * time.Minute
+)
+
+// ParseConflictPolicy accepts "latest-wins" or "first-wins".
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case ConflictLatestWins, ConflictFirstWins:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q (want latest-wins or first-wins)", s)
+}
+
+// SetConflictPolicy sets how concurrent players of one user are resolved.
+// The default is ConflictLatestWins.
+func (t *Trakt) SetConflictPolicy(p ConflictPolicy) {
+	t.conflictMu.Lock()
+	defer t.conflictMu.Unlock()
+	t.conflictPolicy = p
+}
+
+// activePlayback is the session currently allowed to scrobble for a user.
+type activePlayback struct {
+	key        string // player:ratingKey
+	playerUUID string
+	ratingKey  string
+}
+
+// claimPlayback reports whether session may scrobble action for user, given
+// any other session of the user that is still active. The winning session
+// becomes the user's active one; a stop releases it.
+func (t *Trakt) claimPlayback(ctx context.Context, key, action string, session *store.PlaybackSession, user store.User) bool {
+	if session == nil || user.ID == "" {
+		return true
+	}
+	t.conflictMu.Lock()
+	defer t.conflictMu.Unlock()
+	if t.activePlayback == nil {
+		t.activePlayback = make(map[string]activePlayback)
+	}
+
+	owner, ok := t.activePlayback[user.ID]
+	if ok && owner.key != key && t.sessionActive(ctx, owner) {
+		if t.conflictPolicy == ConflictFirstWins || action != actionStart {
+			slog.Info("scrobble suppressed: another player is active", "username", user.Username, "plaxt_id", user.ID, "action", action, "player", session.PlayerUUID, "active_player", owner.playerUUID, "policy", t.policy())
+			return false
+		}
+		slog.Info("scrobble conflict: latest player takes over", "username", user.Username, "plaxt_id", user.ID, "player", session.PlayerUUID, "previous_player", owner.playerUUID)
+	}
+
+	if session.State == store.SessionStopped {
+		delete(t.activePlayback, user.ID)
+	} else {
+		t.activePlayback[user.ID] = activePlayback{key: key, playerUUID: session.PlayerUUID, ratingKey: session.RatingKey}
+	}
+	return true
+}
+
+// ownsPlayback reports whether key may still scrobble for userID.
+func (t *Trakt) ownsPlayback(userID, key string) bool {
+	t.conflictMu.Lock()
+	defer t.conflictMu.Unlock()
+	owner, ok := t.activePlayback[userID]
+	return !ok || owner.key == key
+}
+
+// sessionActive reports whether owner's stored session still holds the user.
+func (t *Trakt) sessionActive(ctx context.Context, owner activePlayback) bool {
+	session, err := t.storage.GetPlaybackSession(ctx, owner.playerUUID, owner.ratingKey)
+	if err != nil {
+		return false
+	}
+	idle := time.Since(session.UpdatedAt)
+	switch session.State {
+	case store.SessionPlaying:
+		return idle < conflictPlayingIdle
+	case store.SessionPaused:
+		return idle < conflictPausedIdle
+	}
+	return false
+}
+
+func (t *Trakt) policy() ConflictPolicy {
+	if t.conflictPolicy == "" {
+		return ConflictLatestWins
+	}
+	return t.conflictPolicy
+}
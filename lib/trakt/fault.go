@@ -0,0 +1,125 @@
+package trakt
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FaultKind is a failure the transport can simulate instead of calling Trakt.
+type FaultKind string
+
+const (
+	FaultUnavailable FaultKind = "503"
+	FaultRateLimited FaultKind = "429"
+	FaultTimeout     FaultKind = "timeout"
+)
+
+// FaultInjectedHeader marks responses produced by fault injection.
+const FaultInjectedHeader = "X-Plaxt-Fault-Injected"
+
+// AllFaultKinds lists every supported fault, the default when none are given.
+var AllFaultKinds = []FaultKind{FaultUnavailable, FaultRateLimited, FaultTimeout}
+
+// faultConfig is swapped atomically so SetFaultInjection is safe at runtime.
+type faultConfig struct {
+	percent float64
+	kinds   []FaultKind
+}
+
+// faultTimeoutError looks like a client timeout to callers, so queueing and
+// retry logic treats it exactly like a real one.
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "trakt fault injection: simulated timeout" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+// ParseFaultInjection parses a percentage such as "10" or "12.5%".
+func ParseFaultInjection(v string) (float64, error) {
+	v = strings.TrimSuffix(strings.TrimSpace(v), "%")
+	percent, err := strconv.ParseFloat(v, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid fault injection percentage %q", v)
+	}
+	return percent, nil
+}
+
+// ParseFaultKinds parses a comma-separated list such as "503,timeout".
+// An empty string selects AllFaultKinds.
+func ParseFaultKinds(v string) ([]FaultKind, error) {
+	var kinds []FaultKind
+	for _, part := range strings.Split(v, ",") {
+		switch kind := FaultKind(strings.ToLower(strings.TrimSpace(part))); kind {
+		case "":
+			continue
+		case FaultUnavailable, FaultRateLimited, FaultTimeout:
+			kinds = append(kinds, kind)
+		default:
+			return nil, fmt.Errorf("unknown fault kind %q", part)
+		}
+	}
+	if len(kinds) == 0 {
+		return AllFaultKinds, nil
+	}
+	return kinds, nil
+}
+
+// SetFaultInjection makes the given percentage of outbound Trakt API calls
+// fail with one of kinds (all kinds when empty) without reaching Trakt. OAuth
+// calls are never faulted so onboarding keeps working. Zero disables it.
+// Meant for exercising queueing, retries and drains in staging.
+func (t *Trakt) SetFaultInjection(percent float64, kinds []FaultKind) {
+	if t.transport == nil {
+		return
+	}
+	if percent <= 0 {
+		t.transport.faults.Store(nil)
+		return
+	}
+	if len(kinds) == 0 {
+		kinds = AllFaultKinds
+	}
+	t.transport.faults.Store(&faultConfig{percent: min(percent, 100), kinds: kinds})
+}
+
+// pickFault returns the fault to simulate for req, or "" to send it to Trakt.
+func (t *transport) pickFault(req *http.Request) FaultKind {
+	cfg := t.faults.Load()
+	if cfg == nil || strings.HasPrefix(req.URL.Path, "/oauth/") || t.roll()*100 >= cfg.percent {
+		return ""
+	}
+	t.stats.faults.Add(1)
+	kind := cfg.kinds[rand.IntN(len(cfg.kinds))]
+	slog.Debug("trakt fault injected", "method", req.Method, "path", req.URL.Path, "fault", kind)
+	return kind
+}
+
+// faultResponse builds the simulated failure for kind.
+func faultResponse(req *http.Request, kind FaultKind) (*http.Response, error) {
+	if kind == FaultTimeout {
+		return nil, faultTimeoutError{}
+	}
+	status := http.StatusServiceUnavailable
+	header := make(http.Header)
+	header.Set(FaultInjectedHeader, string(kind))
+	header.Set("Content-Type", "application/json")
+	if kind == FaultRateLimited {
+		status = http.StatusTooManyRequests
+		header.Set("Retry-After", "1")
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"error":"fault injected"}`)),
+		Request:    req,
+	}, nil
+}
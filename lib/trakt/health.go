@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"crovlune/plaxt/lib/provider"
 )
 
 // Health check intervals
@@ -24,23 +26,24 @@ type HealthCheckState struct {
 	mu                  sync.RWMutex
 }
 
-// HealthChecker manages adaptive health checks for Trakt API availability.
+// HealthChecker manages adaptive health checks for a scrobble provider's API
+// availability (Trakt in practice).
 type HealthChecker struct {
 	state     HealthCheckState
-	stateChan chan string // Emits "live" or "queue" on state changes
-	trakt     *Trakt      // For authenticated health checks
+	stateChan chan string               // Emits "live" or "queue" on state changes
+	target    provider.ScrobbleProvider // Provider being checked
 	mu        sync.RWMutex
 }
 
-// NewHealthChecker creates a new health checker.
-func NewHealthChecker(trakt *Trakt) *HealthChecker {
+// NewHealthChecker creates a new health checker for the given provider.
+func NewHealthChecker(target provider.ScrobbleProvider) *HealthChecker {
 	return &HealthChecker{
 		state: HealthCheckState{
 			Mode:          "live",
 			CheckInterval: ShortHealthCheckInterval,
 		},
 		stateChan: make(chan string, 10), // Buffered to prevent blocking
-		trakt:     trakt,
+		target:    target,
 	}
 }
 
@@ -75,35 +78,33 @@ func (h *HealthChecker) runHealthCheckLoop(ctx context.Context) {
 	}
 }
 
-// CheckHealth performs a health check against Trakt API.
-// Returns true if Trakt is available, false otherwise.
+// CheckHealth performs a health check against the provider API.
+// Returns true if the provider is available, false otherwise.
 func (h *HealthChecker) CheckHealth() bool {
 	h.mu.RLock()
-	trakt := h.trakt
+	target := h.target
 	h.mu.RUnlock()
 
-	if trakt == nil {
-		slog.Warn("health check skipped: no Trakt client available")
+	if target == nil {
+		slog.Warn("health check skipped: no provider available")
 		return false
 	}
 
-	// Use GET /users/settings as health check endpoint (requires auth)
-	// This endpoint is lightweight and confirms API availability
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Make a simple request to check API availability
-	// We'll implement this method on Trakt client later
-	err := trakt.HealthCheck(ctx)
+	err := target.HealthCheck(ctx)
 	if err != nil {
-		slog.Warn("trakt health check failed",
+		slog.Warn("provider health check failed",
+			"provider", target.Name(),
 			"error", err,
 			"operation", "health_check_failure",
 		)
 		return false
 	}
 
-	slog.Info("trakt health check succeeded",
+	slog.Info("provider health check succeeded",
+		"provider", target.Name(),
 		"operation", "health_check_success",
 	)
 	return true
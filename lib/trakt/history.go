@@ -0,0 +1,268 @@
+package trakt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+)
+
+// History push defaults. Trakt limits authenticated POSTs to roughly one per
+// second, so batches are spaced out accordingly.
+const (
+	DefaultHistoryBatchSize = 100
+	DefaultHistoryInterval  = time.Second
+)
+
+// HistoryItem is a single watched item to import into a user's Trakt history.
+// Movies are identified by ids or title+year; episodes by show ids (or
+// title) plus season/episode, or by episode-level ids alone.
+type HistoryItem struct {
+	Type      string    `json:"type"` // "movie" | "episode"
+	Imdb      string    `json:"imdb,omitempty"`
+	Tmdb      int       `json:"tmdb,omitempty"`
+	Tvdb      int       `json:"tvdb,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Year      int       `json:"year,omitempty"`
+	Season    *int      `json:"season,omitempty"`
+	Episode   *int      `json:"episode,omitempty"`
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+// HistoryReport summarises the outcome of a history push.
+type HistoryReport struct {
+	Total         int      `json:"total"`
+	Batches       int      `json:"batches"`
+	FailedBatches int      `json:"failed_batches"`
+	AddedMovies   int      `json:"added_movies"`
+	AddedEpisodes int      `json:"added_episodes"`
+	NotFound      int      `json:"not_found"`
+	Invalid       int      `json:"invalid"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+type historyMovie struct {
+	Title     *string    `json:"title,omitempty"`
+	Year      *int       `json:"year,omitempty"`
+	Ids       common.Ids `json:"ids"`
+	WatchedAt string     `json:"watched_at"`
+}
+
+type historyEpisode struct {
+	Number    int         `json:"number,omitempty"`
+	Ids       *common.Ids `json:"ids,omitempty"`
+	WatchedAt string      `json:"watched_at"`
+}
+
+type historySeason struct {
+	Number   int              `json:"number"`
+	Episodes []historyEpisode `json:"episodes"`
+}
+
+type historyShow struct {
+	Title   *string         `json:"title,omitempty"`
+	Year    *int            `json:"year,omitempty"`
+	Ids     common.Ids      `json:"ids"`
+	Seasons []historySeason `json:"seasons"`
+}
+
+type historyRequest struct {
+	Movies   []historyMovie   `json:"movies,omitempty"`
+	Shows    []historyShow    `json:"shows,omitempty"`
+	Episodes []historyEpisode `json:"episodes,omitempty"`
+}
+
+type historyResponse struct {
+	Added struct {
+		Movies   int `json:"movies"`
+		Episodes int `json:"episodes"`
+	} `json:"added"`
+	NotFound struct {
+		Movies   []json.RawMessage `json:"movies"`
+		Shows    []json.RawMessage `json:"shows"`
+		Seasons  []json.RawMessage `json:"seasons"`
+		Episodes []json.RawMessage `json:"episodes"`
+	} `json:"not_found"`
+}
+
+func (h HistoryItem) ids() (common.Ids, bool) {
+	ids := common.Ids{}
+	found := false
+	if imdb := strings.TrimSpace(h.Imdb); imdb != "" {
+		ids.Imdb = &imdb
+		found = true
+	}
+	if h.Tmdb > 0 {
+		tmdb := h.Tmdb
+		ids.Tmdb = &tmdb
+		found = true
+	}
+	if h.Tvdb > 0 {
+		tvdb := h.Tvdb
+		ids.Tvdb = &tvdb
+		found = true
+	}
+	return ids, found
+}
+
+// Validate reports whether the item carries enough information to be matched.
+func (h HistoryItem) Validate() error {
+	_, hasIds := h.ids()
+	title := strings.TrimSpace(h.Title)
+	switch strings.ToLower(strings.TrimSpace(h.Type)) {
+	case "movie":
+		if !hasIds && (title == "" || h.Year == 0) {
+			return fmt.Errorf("movie requires imdb, tmdb, tvdb or title and year")
+		}
+	case "episode":
+		if h.Season != nil || h.Episode != nil {
+			if h.Season == nil || h.Episode == nil {
+				return fmt.Errorf("episode requires both season and episode numbers")
+			}
+			if !hasIds && title == "" {
+				return fmt.Errorf("episode requires show ids or title")
+			}
+		} else if !hasIds {
+			return fmt.Errorf("episode requires episode ids or show and season/episode numbers")
+		}
+	default:
+		return fmt.Errorf("type must be movie or episode")
+	}
+	return nil
+}
+
+// add appends the item to the sync payload. Items must already be validated.
+func (r *historyRequest) add(h HistoryItem) {
+	watchedAt := h.WatchedAt
+	if watchedAt.IsZero() {
+		watchedAt = time.Now()
+	}
+	watched := watchedAt.UTC().Format(time.RFC3339)
+	ids, hasIds := h.ids()
+
+	var title *string
+	var year *int
+	if !hasIds {
+		t := strings.TrimSpace(h.Title)
+		title = &t
+		if h.Year > 0 {
+			y := h.Year
+			year = &y
+		}
+	}
+
+	if strings.EqualFold(strings.TrimSpace(h.Type), "movie") {
+		r.Movies = append(r.Movies, historyMovie{Title: title, Year: year, Ids: ids, WatchedAt: watched})
+		return
+	}
+	if h.Season != nil && h.Episode != nil {
+		r.Shows = append(r.Shows, historyShow{
+			Title: title,
+			Year:  year,
+			Ids:   ids,
+			Seasons: []historySeason{{
+				Number:   *h.Season,
+				Episodes: []historyEpisode{{Number: *h.Episode, WatchedAt: watched}},
+			}},
+		})
+		return
+	}
+	r.Episodes = append(r.Episodes, historyEpisode{Ids: &ids, WatchedAt: watched})
+}
+
+// PushHistory adds watched items to the user's Trakt history via /sync/history.
+// Items are sent in batches of batchSize, waiting interval between batches to
+// respect Trakt rate limits. Invalid items are skipped and counted; a failed
+// batch does not stop the remaining batches unless ctx is cancelled.
+func (t *Trakt) PushHistory(ctx context.Context, accessToken string, items []HistoryItem, batchSize int, interval time.Duration) HistoryReport {
+	if batchSize <= 0 {
+		batchSize = DefaultHistoryBatchSize
+	}
+	report := HistoryReport{Total: len(items)}
+
+	valid := make([]HistoryItem, 0, len(items))
+	for i, item := range items {
+		if err := item.Validate(); err != nil {
+			report.Invalid++
+			report.Errors = append(report.Errors, fmt.Sprintf("item %d: %v", i+1, err))
+			continue
+		}
+		valid = append(valid, item)
+	}
+
+	for start := 0; start < len(valid); start += batchSize {
+		if start > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				report.Errors = append(report.Errors, ctx.Err().Error())
+				return report
+			case <-time.After(interval):
+			}
+		}
+		end := start + batchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+
+		var payload historyRequest
+		for _, item := range valid[start:end] {
+			payload.add(item)
+		}
+
+		report.Batches++
+		resp, err := t.syncHistory(ctx, accessToken, payload)
+		if err != nil {
+			report.FailedBatches++
+			report.Errors = append(report.Errors, fmt.Sprintf("batch %d: %v", report.Batches, err))
+			slog.Warn("trakt history batch failed", "batch", report.Batches, "items", end-start, "error", err)
+			if ctx.Err() != nil {
+				return report
+			}
+			continue
+		}
+		report.AddedMovies += resp.Added.Movies
+		report.AddedEpisodes += resp.Added.Episodes
+		report.NotFound += len(resp.NotFound.Movies) + len(resp.NotFound.Shows) + len(resp.NotFound.Seasons) + len(resp.NotFound.Episodes)
+	}
+	return report
+}
+
+func (t *Trakt) syncHistory(ctx context.Context, accessToken string, payload historyRequest) (historyResponse, error) {
+	var result historyResponse
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.apiURL("/sync/history"), bytes.NewBuffer(body))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(resp.Body)
+		msg := strings.TrimSpace(string(b))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return result, NewHttpError(resp.StatusCode, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("decode sync/history response: %w", err)
+	}
+	return result, nil
+}
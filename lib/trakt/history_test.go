@@ -0,0 +1,74 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestPushHistoryBatchesAndReports(t *testing.T) {
+	var batches []historyRequest
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/sync/history", req.URL.Path)
+		var payload historyRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		batches = append(batches, payload)
+		body := fmt.Sprintf(`{"added":{"movies":%d,"episodes":%d},"not_found":{"movies":[],"shows":[],"episodes":[]}}`,
+			len(payload.Movies), len(payload.Shows)+len(payload.Episodes))
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	watched := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	items := []HistoryItem{
+		{Type: "movie", Imdb: "tt0111161", WatchedAt: watched},
+		{Type: "movie", Title: "Heat", Year: 1995, WatchedAt: watched},
+		{Type: "episode", Tvdb: 81189, Season: intPtr(5), Episode: intPtr(14), WatchedAt: watched},
+		{Type: "episode", Title: "Missing numbers", Season: intPtr(1)},
+	}
+
+	report := tr.PushHistory(context.Background(), "token", items, 2, 0)
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.Invalid)
+	assert.Equal(t, 2, report.Batches)
+	assert.Equal(t, 0, report.FailedBatches)
+	assert.Equal(t, 2, report.AddedMovies)
+	assert.Equal(t, 1, report.AddedEpisodes)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "2024-03-01T20:00:00Z", batches[0].Movies[0].WatchedAt)
+	require.Len(t, batches[1].Shows, 1)
+	assert.Equal(t, 5, batches[1].Shows[0].Seasons[0].Number)
+}
+
+func TestPushHistoryCountsFailedBatches(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Status:     "401 Unauthorized",
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	report := tr.PushHistory(context.Background(), "token", []HistoryItem{{Type: "movie", Imdb: "tt1"}}, 0, 0)
+
+	assert.Equal(t, 1, report.Batches)
+	assert.Equal(t, 1, report.FailedBatches)
+	assert.NotEmpty(t, report.Errors)
+}
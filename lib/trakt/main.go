@@ -18,6 +18,8 @@ import (
 	"time"
 
 	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/logging"
+	"crovlune/plaxt/lib/provider"
 	"crovlune/plaxt/lib/store"
 	"crovlune/plaxt/plexhooks"
 )
@@ -34,14 +36,17 @@ const (
 	actionStop  = "stop"
 )
 
-// New constructs a Trakt client with sane defaults (10s timeout) and a
-// concurrency lock to prevent duplicate scrobble processing.
+// New constructs a Trakt client with sane defaults (10s timeout, instrumented
+// transport) and a concurrency lock to prevent duplicate scrobble processing.
 func New(clientId, clientSecret string, storage store.Store) *Trakt {
+	tr := newTransport(http.DefaultTransport, clientId)
 	return &Trakt{
 		ClientId:     clientId,
 		clientSecret: clientSecret,
 		storage:      storage,
-		httpClient:   &http.Client{Timeout: time.Second * 10},
+		httpClient:   &http.Client{Timeout: time.Second * 10, Transport: tr},
+		transport:    tr,
+		baseURL:      DefaultBaseURL,
 		ml:           common.NewMultipleLock(),
 	}
 }
@@ -60,18 +65,12 @@ func (t *Trakt) FetchDisplayName(ctx context.Context, accessToken string) (strin
 		return "", false, errors.New("missing access token for display name lookup")
 	}
 
-	req, err := http.NewRequest(http.MethodGet, "https://api.trakt.tv/users/settings", nil)
-	if err != nil {
-		return "", false, err
-	}
-	req = req.WithContext(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.apiURL("/users/settings"), nil)
 	if err != nil {
 		return "", false, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-	req.Header.Set("trakt-api-version", "2")
-	req.Header.Set("trakt-api-key", t.ClientId)
 
 resp, err := t.httpClient.Do(req)
 	if err != nil {
@@ -105,8 +104,9 @@ resp, err := t.httpClient.Do(req)
 	return normalized, truncated, nil
 }
 
-// AuthRequest authorize the connection with Trakt
-func (t *Trakt) AuthRequest(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
+// AuthRequest authorize the connection with Trakt. The request is aborted
+// when ctx is cancelled. Exactly one of the return values is non-nil.
+func (t *Trakt) AuthRequest(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*TokenResponse, *TokenError) {
 	values := map[string]string{
 		"code":          code,
 		"refresh_token": refreshToken,
@@ -118,67 +118,61 @@ func (t *Trakt) AuthRequest(redirectURI, username, code, refreshToken, grantType
 	jsonValue, err := json.Marshal(values)
 	if err != nil {
 		slog.Error("trakt oauth marshal error", "error", err)
-		return map[string]interface{}{"error": "marshal_error", "error_description": err.Error()}, false
+		return nil, &TokenError{Code: "marshal_error", Description: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.apiURL("/oauth/token"), bytes.NewBuffer(jsonValue))
+	if err != nil {
+		slog.Error("trakt oauth build request error", "error", err)
+		return nil, &TokenError{Code: "http_error", Description: err.Error()}
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := t.httpClient.Post("https://api.trakt.tv/oauth/token", "application/json", bytes.NewBuffer(jsonValue))
+	resp, err := t.httpClient.Do(req)
 	if err != nil {
 		slog.Error("trakt oauth request error", "error", err)
-		return map[string]interface{}{"error": "http_error", "error_description": err.Error()}, false
+		return nil, &TokenError{Code: "http_error", Description: err.Error()}
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
-
 	if resp.StatusCode != http.StatusOK {
-		// Read error response body for detailed error information
+		tokenErr := &TokenError{
+			HTTPStatus:     resp.StatusCode,
+			HTTPStatusText: resp.Status,
+			Code:           "Unknown error",
+		}
+		// Trakt typically returns {"error": "invalid_grant", "error_description": "..."}
 		bodyBytes, readErr := io.ReadAll(resp.Body)
-		errorDetail := "Unknown error"
-		errorDescription := ""
-
 		if readErr == nil && len(bodyBytes) > 0 {
-			var errorResponse map[string]interface{}
+			var errorResponse TokenError
 			if jsonErr := json.Unmarshal(bodyBytes, &errorResponse); jsonErr == nil {
-				// Trakt typically returns {"error": "invalid_grant", "error_description": "..."}
-				if errMsg, ok := errorResponse["error"].(string); ok {
-					errorDetail = errMsg
-				}
-				if errDesc, ok := errorResponse["error_description"].(string); ok {
-					errorDescription = errDesc
+				if errorResponse.Code != "" {
+					tokenErr.Code = errorResponse.Code
 				}
+				tokenErr.Description = errorResponse.Description
 			} else {
 				// If JSON parsing fails, use raw body as error detail
-				errorDetail = string(bodyBytes)
+				tokenErr.Code = string(bodyBytes)
 			}
 		}
 
-		slog.Error("trakt oauth error", "http_status", resp.StatusCode, "http_status_text", resp.Status, "error", errorDetail, "error_description", errorDescription)
-
-		// Include error details in result for caller to use
-		result = map[string]interface{}{
-			"http_status":       resp.StatusCode,
-			"http_status_text":  resp.Status,
-			"error":             errorDetail,
-			"error_description": errorDescription,
-		}
-		return result, false
+		slog.Error("trakt oauth error", "http_status", resp.StatusCode, "http_status_text", resp.Status, "error", tokenErr.Code, "error_description", tokenErr.Description)
+		return nil, tokenErr
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var token TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
 		slog.Error("trakt oauth decode error", "error", err)
-		return map[string]interface{}{"error": "decode_error", "error_description": err.Error()}, false
-	}
-
-	// Log the expires_in value if present for debugging
-	if expiresIn, ok := result["expires_in"].(float64); ok {
-		slog.Debug("trakt oauth token received", "expires_in_seconds", expiresIn)
+		return nil, &TokenError{Code: "decode_error", Description: err.Error()}
 	}
 
-	return result, true
+	slog.Debug("trakt oauth token received", "expires_in_seconds", token.ExpiresIn)
+	return &token, nil
 }
 
-// Handle determine if an item is a show or a movie
-func (t *Trakt) Handle(hook *plexhooks.Webhook, user store.User) {
+// Handle determine if an item is a show or a movie. Outbound Trakt calls are
+// bound to ctx so a cancelled webhook request aborts them.
+func (t *Trakt) Handle(ctx context.Context, hook *plexhooks.Webhook, user store.User) {
 	if hook == nil {
 		slog.Error("webhook missing payload")
 		return
@@ -197,7 +191,13 @@ func (t *Trakt) Handle(hook *plexhooks.Webhook, user store.User) {
 	if event == "" {
 		slog.Info("webhook ignored: no action", "event", hook.Event)
 		return
-	} else if cache.ServerUuid == hook.Server.UUID {
+	}
+	session := t.trackSession(ctx, hook, user, progress)
+	if event == actionStop && session != nil && session.Skimmed(time.Duration(hook.Metadata.Duration)*time.Millisecond) {
+		slog.Info("webhook finish ignored: playback skimmed", "username", user.Username, "plaxt_id", user.ID, "progress", progress, "watch_time", session.WatchTime.Round(time.Second).String())
+		event = actionPause
+	}
+	if cache.ServerUuid == hook.Server.UUID {
 		itemChanged = false
 		if cache.LastAction == actionStop || (cache.LastAction == event && progress == cache.Body.Progress) {
 			slog.Info("webhook duplicate event ignored", "username", user.Username, "plaxt_id", user.ID, "event", hook.Event)
@@ -237,9 +237,15 @@ func (t *Trakt) Handle(hook *plexhooks.Webhook, user store.User) {
 	if strings.ToLower(hook.Metadata.Type) == "episode" && hook.Metadata.GrandparentTitle != "" {
 		mediaHint = fmt.Sprintf("%s - S%02dE%02d %s", hook.Metadata.GrandparentTitle, hook.Metadata.ParentIndex, hook.Metadata.Index, hook.Metadata.Title)
 	}
+	if !t.claimPlayback(ctx, lockKey, event, session, user) {
+		return
+	}
+	if t.holdStart(ctx, lockKey, event, session, cache, user) {
+		return
+	}
 	finished := event == actionStop && progress >= ProgressThreshold
-		slog.Info("webhook handle", "username", user.Username, "plaxt_id", user.ID, "action", event, "media", mediaHint, "progress", progress, "finished", finished)
-	t.scrobbleRequest(event, cache, user)
+		slog.Info("webhook handle", "username", user.Username, "plaxt_id", user.ID, "action", event, "media", logging.Redact(user.ID, mediaHint), "progress", progress, "finished", finished)
+	t.scrobbleRequest(ctx, event, cache, user)
 }
 
 func (t *Trakt) handleShow(hook *plexhooks.Webhook) *common.ScrobbleBody {
@@ -378,13 +384,11 @@ func (t *Trakt) findMovie(hook *plexhooks.Webhook) *common.ScrobbleBody {
 	}
 }
 
-func (t *Trakt) makeRequest(url string) ([]map[string]interface{}, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (t *Trakt) makeRequest(ctx context.Context, url string) ([]map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil { return nil, err }
 
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("trakt-api-version", "2")
-	req.Header.Add("trakt-api-key", t.ClientId)
 
 	resp, err := t.httpClient.Do(req)
 	if err != nil { return nil, err }
@@ -402,11 +406,11 @@ func (t *Trakt) makeRequest(url string) ([]map[string]interface{}, error) {
 	return results, nil
 }
 
-func (t *Trakt) scrobbleRequest(action string, item common.CacheItem, user store.User) {
-	URL := fmt.Sprintf("https://api.trakt.tv/scrobble/%s", action)
+func (t *Trakt) scrobbleRequest(ctx context.Context, action string, item common.CacheItem, user store.User) {
+	URL := t.apiURL("/scrobble/" + action)
 
 	body, _ := json.Marshal(item.Body)
-	req, err := http.NewRequest("POST", URL, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", URL, bytes.NewBuffer(body))
 	if err != nil {
 		slog.Error("scrobble build request error", "username", user.Username, "plaxt_id", user.ID, "action", action, "error", err)
 		return
@@ -414,12 +418,16 @@ func (t *Trakt) scrobbleRequest(action string, item common.CacheItem, user store
 
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", user.AccessToken))
-	req.Header.Add("trakt-api-version", "2")
-	req.Header.Add("trakt-api-key", t.ClientId)
 
-	resp, err := t.httpClient.Do(req)
+	resp, err := t.sendScrobble(req)
 	if err != nil {
-		slog.Error("scrobble http error", "username", user.Username, "plaxt_id", user.ID, "action", action, "error", err)
+		if ctx.Err() != nil {
+			// Caller went away (shutdown or request timeout); the event is
+			// queued so it is not lost with the aborted request.
+			slog.Warn("scrobble aborted", "username", user.Username, "plaxt_id", user.ID, "action", action, "error", ctx.Err())
+		} else {
+			slog.Error("scrobble http error", "username", user.Username, "plaxt_id", user.ID, "action", action, "error", err)
+		}
 		// Network error - queue the event
 		t.enqueueScrobbleEvent(user, item, action)
 		return
@@ -463,9 +471,16 @@ func (t *Trakt) scrobbleRequest(action string, item common.CacheItem, user store
 			}
 		}
 		finished := action == actionStop && item.Body.Progress >= ProgressThreshold
-		slog.Info("scrobble success", "username", user.Username, "plaxt_id", user.ID, "action", action, "media", media, "progress", item.Body.Progress, "finished", finished, "trigger", item.Trigger)
+		slog.Info("scrobble success", "username", user.Username, "plaxt_id", user.ID, "action", action, "media", logging.Redact(user.ID, media), "progress", item.Body.Progress, "finished", finished, "trigger", item.Trigger)
+		RecordActivity(ctx, t.storage, store.ActivityScrobble, time.Now())
+		t.monitor.Observe(user.ID, user.Username, false, time.Now())
+		if finished {
+			RecordWatched(ctx, t.storage, user.ID, action, item.Body, time.Now())
+		}
 	} else {
 		slog.Error("scrobble failure", "username", user.Username, "plaxt_id", user.ID, "action", action, "status", resp.StatusCode, "trigger", item.Trigger)
+		RecordActivity(ctx, t.storage, store.ActivityFailure, time.Now())
+		t.monitor.Observe(user.ID, user.Username, true, time.Now())
 	}
 }
 
@@ -491,6 +506,8 @@ func (t *Trakt) enqueueScrobbleEvent(user store.User, item common.CacheItem, act
 		return
 	}
 
+	RecordActivity(ctx, t.storage, store.ActivityQueued, time.Now())
+
 	// Log the enqueue event for monitoring
 	if t.queueEventLog != nil {
 		queueSize, _ := t.storage.GetQueueSize(ctx, user.ID)
@@ -513,6 +530,36 @@ func (t *Trakt) enqueueScrobbleEvent(user store.User, item common.CacheItem, act
 	)
 }
 
+// trackSession advances the playback session for hook's player and item and
+// persists it. Storage errors are logged and yield nil, so session tracking
+// never blocks a scrobble.
+func (t *Trakt) trackSession(ctx context.Context, hook *plexhooks.Webhook, user store.User, progress int) *store.PlaybackSession {
+	var state string
+	switch hook.Event {
+	case "media.play", "media.resume", "playback.started":
+		state = store.SessionPlaying
+	case "media.pause":
+		state = store.SessionPaused
+	case "media.stop":
+		state = store.SessionStopped
+	}
+	now := time.Now()
+	session, err := t.storage.GetPlaybackSession(ctx, hook.Player.UUID, hook.Metadata.RatingKey)
+	switch {
+	case errors.Is(err, store.ErrPlaybackSessionNotFound):
+		session = store.NewPlaybackSession(hook.Player.UUID, hook.Metadata.RatingKey, user.ID, state, now, progress)
+	case err != nil:
+		slog.Warn("playback session lookup failed", "player", hook.Player.UUID, "rating_key", hook.Metadata.RatingKey, "error", err)
+		return nil
+	default:
+		session.Advance(state, now, progress)
+	}
+	if err := t.storage.PutPlaybackSession(ctx, session); err != nil {
+		slog.Warn("playback session save failed", "player", hook.Player.UUID, "rating_key", hook.Metadata.RatingKey, "error", err)
+	}
+	return session
+}
+
 func (t *Trakt) getAction(hook *plexhooks.Webhook) (action string, item common.CacheItem, progress int) {
 	item = t.storage.GetScrobbleBody(hook.Player.UUID, hook.Metadata.RatingKey)
 	if hook.Metadata.Duration > 0 {
@@ -555,13 +602,11 @@ func NewHttpError(code int, message string) HttpError {
 func (t *Trakt) HealthCheck(ctx context.Context) error {
 	// Use GET /users/settings as health check endpoint
 	// This is a lightweight endpoint that confirms API availability
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.trakt.tv/", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.apiURL("/"), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
 
-	req.Header.Set("trakt-api-version", "2")
-	req.Header.Set("trakt-api-key", t.ClientId)
 
 	resp, err := t.httpClient.Do(req)
 	if err != nil {
@@ -577,23 +622,48 @@ func (t *Trakt) HealthCheck(ctx context.Context) error {
 	return fmt.Errorf("trakt API returned status %d", resp.StatusCode)
 }
 
-// ScrobbleFromQueue sends a queued scrobble event to Trakt.
-// Returns nil on success, error otherwise.
-func (t *Trakt) ScrobbleFromQueue(action string, item common.CacheItem, accessToken string) error {
-	URL := fmt.Sprintf("https://api.trakt.tv/scrobble/%s", action)
+// Name implements provider.ScrobbleProvider.
+func (t *Trakt) Name() string {
+	return provider.DefaultName
+}
+
+// RefreshToken implements provider.ScrobbleProvider on top of AuthRequest.
+func (t *Trakt) RefreshToken(ctx context.Context, refreshToken, redirectURI string) (provider.Token, error) {
+	result, tokenErr := t.AuthRequest(ctx, redirectURI, "", "", refreshToken, "refresh_token")
+	if tokenErr != nil {
+		desc := tokenErr.Description
+		if desc == "" {
+			desc = tokenErr.Code
+		}
+		return provider.Token{}, fmt.Errorf("trakt token refresh failed: %s", desc)
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		return provider.Token{}, errors.New("trakt token refresh response missing tokens")
+	}
+	now := time.Now()
+	return provider.Token{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		IssuedAt:     result.IssuedAt(now),
+		ExpiresAt:    result.ExpiresAt(now),
+	}, nil
+}
+
+// Scrobble implements provider.ScrobbleProvider. It is used by the queue
+// drain and retry worker and updates the scrobble cache on success.
+func (t *Trakt) Scrobble(ctx context.Context, action string, item common.CacheItem, accessToken string) error {
+	URL := t.apiURL("/scrobble/" + action)
 
 	body, _ := json.Marshal(item.Body)
-	req, err := http.NewRequest("POST", URL, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", URL, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to build scrobble request: %w", err)
 	}
 
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-	req.Header.Add("trakt-api-version", "2")
-	req.Header.Add("trakt-api-key", t.ClientId)
 
-	resp, err := t.httpClient.Do(req)
+	resp, err := t.sendScrobble(req)
 	if err != nil {
 		return fmt.Errorf("scrobble http error: %w", err)
 	}
@@ -619,6 +689,50 @@ func (t *Trakt) ScrobbleFromQueue(action string, item common.CacheItem, accessTo
 	return fmt.Errorf("scrobble failed with status %d", resp.StatusCode)
 }
 
+// SubmitScrobble sends a single scrobble built outside the webhook flow (e.g.
+// a manual admin submission) and returns the body echoed back by Trakt.
+// Non-2xx responses are returned as HttpError carrying the Trakt status code.
+func (t *Trakt) SubmitScrobble(ctx context.Context, action string, body common.ScrobbleBody, accessToken string) (common.ScrobbleBody, error) {
+	switch action {
+	case actionStart, actionPause, actionStop:
+	default:
+		return common.ScrobbleBody{}, fmt.Errorf("invalid scrobble action %q", action)
+	}
+	URL := t.apiURL("/scrobble/" + action)
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return common.ScrobbleBody{}, fmt.Errorf("failed to encode scrobble body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return common.ScrobbleBody{}, fmt.Errorf("failed to build scrobble request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return common.ScrobbleBody{}, fmt.Errorf("scrobble http error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(resp.Body)
+		msg := strings.TrimSpace(string(b))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return common.ScrobbleBody{}, NewHttpError(resp.StatusCode, msg)
+	}
+
+	var result common.ScrobbleBody
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return common.ScrobbleBody{}, fmt.Errorf("scrobble decode error: %w", err)
+	}
+	return result, nil
+}
+
 // ParseWebhookForScrobble extracts scrobble action and body from a Plex webhook.
 // Returns (scrobbleBody, action, shouldScrobble) where shouldScrobble indicates
 // if the webhook is eligible for scrobbling.
@@ -717,7 +831,7 @@ func (t *Trakt) BroadcastScrobble(
 			defer wg.Done()
 
 			// Build scrobble request
-			URL := fmt.Sprintf("https://api.trakt.tv/scrobble/%s", action)
+			URL := t.apiURL("/scrobble/" + action)
 			bodyJSON, _ := json.Marshal(body)
 
 			req, err := http.NewRequestWithContext(ctx, "POST", URL, bytes.NewBuffer(bodyJSON))
@@ -728,11 +842,9 @@ func (t *Trakt) BroadcastScrobble(
 
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", m.AccessToken))
-			req.Header.Set("trakt-api-version", "2")
-			req.Header.Set("trakt-api-key", t.ClientId)
 
 			// Execute HTTP request
-			resp, err := t.httpClient.Do(req)
+			resp, err := t.sendScrobble(req)
 			if err != nil {
 				// Network error - should be queued
 				resultChan <- result{member: m, err: fmt.Errorf("http error: %w", err), status: 0}
@@ -740,7 +852,7 @@ func (t *Trakt) BroadcastScrobble(
 				slog.Error("broadcast scrobble failure",
 					"timestamp", time.Now().Format(time.RFC3339),
 					"member_username", m.TraktUsername,
-					"media_title", mediaTitle,
+					"media_title", logging.Redact(m.ID, mediaTitle),
 					"error", err.Error(),
 					"event_id", eventID,
 					"action", action,
@@ -760,7 +872,7 @@ func (t *Trakt) BroadcastScrobble(
 				slog.Warn("broadcast scrobble transient failure",
 					"timestamp", time.Now().Format(time.RFC3339),
 					"member_username", m.TraktUsername,
-					"media_title", mediaTitle,
+					"media_title", logging.Redact(m.ID, mediaTitle),
 					"error", errMsg,
 					"event_id", eventID,
 					"action", action,
@@ -772,11 +884,13 @@ func (t *Trakt) BroadcastScrobble(
 			// Success
 			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
 				resultChan <- result{member: m, err: nil, status: resp.StatusCode}
+				RecordActivity(ctx, t.storage, store.ActivityScrobble, time.Now())
+				t.monitor.Observe(m.ID, m.TraktUsername, false, time.Now())
 				// Log success per FR-008b
 				slog.Info("broadcast scrobble success",
 					"timestamp", time.Now().Format(time.RFC3339),
 					"member_username", m.TraktUsername,
-					"media_title", mediaTitle,
+					"media_title", logging.Redact(m.ID, mediaTitle),
 					"event_id", eventID,
 					"action", action,
 					"progress", body.Progress,
@@ -789,10 +903,12 @@ func (t *Trakt) BroadcastScrobble(
 			errMsg := fmt.Sprintf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
 			resultChan <- result{member: m, err: errors.New(errMsg), status: resp.StatusCode}
 			// Log per FR-008b
+			RecordActivity(ctx, t.storage, store.ActivityFailure, time.Now())
+			t.monitor.Observe(m.ID, m.TraktUsername, true, time.Now())
 			slog.Error("broadcast scrobble permanent failure",
 				"timestamp", time.Now().Format(time.RFC3339),
 				"member_username", m.TraktUsername,
-				"media_title", mediaTitle,
+				"media_title", logging.Redact(m.ID, mediaTitle),
 				"error", errMsg,
 				"event_id", eventID,
 				"action", action,
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"crovlune/plaxt/lib/common"
 	"crovlune/plaxt/lib/store"
@@ -22,7 +23,8 @@ func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
 
 func newTestTrakt(rt roundTripFunc) *Trakt {
 	tr := New("client-id", "client-secret", nil)
-	tr.httpClient = &http.Client{Transport: rt}
+	tr.transport = newTransport(rt, tr.ClientId)
+	tr.httpClient = &http.Client{Transport: tr.transport}
 	return tr
 }
 
@@ -258,6 +260,87 @@ func TestBroadcastScrobbleContextCancellation(t *testing.T) {
 	}
 }
 
+func TestAuthRequestContextCancellation(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	tr := newTestTrakt(handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, tokenErr := tr.AuthRequest(ctx, "https://plaxt.example/authorize", "user", "code", "", "authorization_code")
+	assert.Nil(t, result)
+	require.NotNil(t, tokenErr)
+	assert.Equal(t, "http_error", tokenErr.Code)
+	assert.Contains(t, tokenErr.Description, "context canceled")
+}
+
+func TestAuthRequestDecodesTokenResponse(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"access_token":"a","refresh_token":"r","token_type":"bearer","expires_in":7776000,"scope":"public","created_at":1760000000}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	result, tokenErr := newTestTrakt(handler).AuthRequest(context.Background(), "https://plaxt.example/authorize", "user", "code", "", "authorization_code")
+	require.Nil(t, tokenErr)
+	assert.Equal(t, "a", result.AccessToken)
+	assert.Equal(t, "r", result.RefreshToken)
+	assert.Equal(t, int64(7776000), result.ExpiresIn)
+	assert.Equal(t, int64(1760000000), result.CreatedAt)
+}
+
+func TestTokenResponseExpiryUsesTraktClock(t *testing.T) {
+	localNow := time.Unix(1760000000, 0).Add(10 * time.Minute) // host clock runs ahead
+	token := &TokenResponse{ExpiresIn: 7776000, CreatedAt: 1760000000}
+	assert.Equal(t, time.Unix(1760000000, 0), token.IssuedAt(localNow))
+	assert.Equal(t, time.Unix(1760000000+7776000, 0), token.ExpiresAt(localNow))
+
+	legacy := &TokenResponse{ExpiresIn: 60}
+	assert.Equal(t, localNow, legacy.IssuedAt(localNow))
+	assert.Equal(t, localNow.Add(time.Minute), legacy.ExpiresAt(localNow))
+	assert.True(t, (&TokenResponse{}).ExpiresAt(localNow).IsZero())
+}
+
+func TestAuthRequestDecodesTokenError(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Status:     "400 Bad Request",
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error":"invalid_grant","error_description":"code expired"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	result, tokenErr := newTestTrakt(handler).AuthRequest(context.Background(), "https://plaxt.example/authorize", "user", "code", "", "authorization_code")
+	assert.Nil(t, result)
+	require.NotNil(t, tokenErr)
+	assert.Equal(t, http.StatusBadRequest, tokenErr.HTTPStatus)
+	assert.Equal(t, "invalid_grant", tokenErr.Code)
+	assert.Equal(t, "HTTP 400 - invalid_grant (code expired)", tokenErr.Error())
+}
+
+func TestScrobbleContextCancellation(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	tr := newTestTrakt(handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := tr.Scrobble(ctx, "start", common.CacheItem{}, "token")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestBroadcastScrobbleEmptyMembers(t *testing.T) {
 	// Empty member list
 	tr := newTestTrakt(nil)
@@ -357,3 +440,50 @@ func TestBroadcastScrobblePermanentVsTransientErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestSubmitScrobbleReturnsHttpErrorOnFailure(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/scrobble/stop", req.URL.Path)
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	_, err := tr.SubmitScrobble(context.Background(), "stop", common.ScrobbleBody{Progress: 100}, "token")
+	var httpErr HttpError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestSubmitScrobbleRejectsUnknownAction(t *testing.T) {
+	tr := newTestTrakt(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("no request expected")
+		return nil, nil
+	}))
+	_, err := tr.SubmitScrobble(context.Background(), "rewind", common.ScrobbleBody{}, "token")
+	require.Error(t, err)
+}
+
+func TestWatchedMovieFromScrobble(t *testing.T) {
+	title, year, imdb := "Heat", 1995, "tt0113277"
+	body := common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Title: &title, Year: &year, Ids: common.Ids{Imdb: &imdb}}}
+	watchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	movie, ok := WatchedMovieFromScrobble("u1", "stop", body, watchedAt)
+	require.True(t, ok)
+	assert.Equal(t, "Heat", movie.Title)
+	assert.Equal(t, 1995, movie.Year)
+	assert.Equal(t, "tt0113277", movie.Imdb)
+	assert.Equal(t, watchedAt, movie.WatchedAt)
+
+	_, ok = WatchedMovieFromScrobble("u1", "pause", body, watchedAt)
+	assert.False(t, ok)
+	body.Progress = 50
+	_, ok = WatchedMovieFromScrobble("u1", "stop", body, watchedAt)
+	assert.False(t, ok)
+}
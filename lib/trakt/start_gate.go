@@ -0,0 +1,104 @@
+package trakt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/store"
+)
+
+// pendingStart is a start scrobble waiting for its session to reach the
+// minimum watch time.
+type pendingStart struct {
+	ctx       context.Context
+	startedAt time.Time
+	item      common.CacheItem
+	user      store.User
+	timer     *time.Timer
+}
+
+// SetStartDelay holds back start scrobbles until a playback session has
+// played for d, so flipping through episodes does not spam Trakt's "now
+// watching". Pauses before the start went out are dropped; finishes are
+// never held. Zero disables the gate.
+func (t *Trakt) SetStartDelay(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	t.startDelay = d
+}
+
+// holdStart cancels any start waiting on key, then defers a start or drops a
+// pause while session has played for less than the start delay. It reports
+// whether action was held. The caller holds t.ml for key.
+func (t *Trakt) holdStart(ctx context.Context, key, action string, session *store.PlaybackSession, item common.CacheItem, user store.User) bool {
+	t.cancelPendingStart(key)
+	if t.startDelay <= 0 || session == nil || action == actionStop {
+		return false
+	}
+	remaining := t.startDelay - session.WatchTime
+	if remaining <= 0 {
+		return false
+	}
+	if action == actionPause {
+		slog.Info("scrobble pause dropped: start not sent", "username", user.Username, "plaxt_id", user.ID, "watch_time", session.WatchTime.Round(time.Second).String())
+		return true
+	}
+
+	p := &pendingStart{
+		ctx:       context.WithoutCancel(ctx),
+		startedAt: session.StartedAt,
+		item:      item,
+		user:      user,
+	}
+	t.pendingMu.Lock()
+	if t.pendingStarts == nil {
+		t.pendingStarts = make(map[string]*pendingStart)
+	}
+	t.pendingStarts[key] = p
+	p.timer = time.AfterFunc(remaining, func() { t.firePendingStart(key, p) })
+	t.pendingMu.Unlock()
+	slog.Info("scrobble start held", "username", user.Username, "plaxt_id", user.ID, "delay", remaining.Round(time.Second).String())
+	return true
+}
+
+func (t *Trakt) cancelPendingStart(key string) {
+	t.pendingMu.Lock()
+	defer t.pendingMu.Unlock()
+	if p, ok := t.pendingStarts[key]; ok {
+		p.timer.Stop()
+		delete(t.pendingStarts, key)
+	}
+}
+
+// firePendingStart sends a held start if no later event superseded it, the
+// same session is still playing and no other player took over the user.
+func (t *Trakt) firePendingStart(key string, p *pendingStart) {
+	t.ml.Lock(key)
+	defer t.ml.Unlock(key)
+
+	t.pendingMu.Lock()
+	current := t.pendingStarts[key] == p
+	if current {
+		delete(t.pendingStarts, key)
+	}
+	t.pendingMu.Unlock()
+	if !current {
+		return
+	}
+
+	session, err := t.storage.GetPlaybackSession(p.ctx, p.item.PlayerUuid, p.item.RatingKey)
+	if err != nil || session.State != store.SessionPlaying || !session.StartedAt.Equal(p.startedAt) || !t.ownsPlayback(p.user.ID, key) {
+		return
+	}
+	t.scrobbleRequest(p.ctx, actionStart, p.item, p.user)
+}
+
+// PendingStarts returns how many start scrobbles are waiting on the gate.
+func (t *Trakt) PendingStarts() int {
+	t.pendingMu.Lock()
+	defer t.pendingMu.Unlock()
+	return len(t.pendingStarts)
+}
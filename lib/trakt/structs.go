@@ -3,8 +3,13 @@ package trakt
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/notify"
+	"crovlune/plaxt/lib/provider"
 	"crovlune/plaxt/lib/store"
 )
 
@@ -15,8 +20,20 @@ type Trakt struct {
 	clientSecret  string
 	storage       store.Store
 	httpClient    *http.Client
+	transport     *transport
 	ml            common.MultipleLock
 	queueEventLog *store.QueueEventLog
+	monitor       *notify.FailureMonitor
+	scheduler     *provider.Scheduler
+	baseURL       string
+
+	startDelay    time.Duration
+	pendingMu     sync.Mutex
+	pendingStarts map[string]*pendingStart
+
+	conflictMu     sync.Mutex
+	conflictPolicy ConflictPolicy
+	activePlayback map[string]activePlayback // by user ID
 }
 
 // HttpError implements the error interface for HTTP errors returned by handlers.
@@ -25,6 +42,56 @@ type HttpError struct {
 	Message string
 }
 
+// TokenResponse is a successful response from Trakt's /oauth/token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	ExpiresIn    int64  `json:"expires_in"` // seconds
+	CreatedAt    int64  `json:"created_at"` // unix seconds, set by Trakt
+}
+
+// IssuedAt returns when Trakt created the token. Using Trakt's clock keeps the
+// expiry accurate on hosts with clock skew; fallback is used for responses
+// without created_at.
+func (t *TokenResponse) IssuedAt(fallback time.Time) time.Time {
+	if t.CreatedAt > 0 {
+		return time.Unix(t.CreatedAt, 0)
+	}
+	return fallback
+}
+
+// ExpiresAt returns IssuedAt plus expires_in, or the zero time when the
+// response has no expires_in.
+func (t *TokenResponse) ExpiresAt(fallback time.Time) time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return t.IssuedAt(fallback).Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// TokenError describes a failed token exchange. HTTPStatus is 0 when the
+// request never got a response.
+type TokenError struct {
+	HTTPStatus     int    `json:"-"`
+	HTTPStatusText string `json:"-"`
+	Code           string `json:"error"`             // e.g. invalid_grant, http_error
+	Description    string `json:"error_description"` // human-readable detail, may be empty
+}
+
+// Error implements the error interface
+func (e *TokenError) Error() string {
+	msg := e.Code
+	if e.HTTPStatus != 0 {
+		msg = fmt.Sprintf("HTTP %d - %s", e.HTTPStatus, e.Code)
+	}
+	if e.Description != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Description)
+	}
+	return msg
+}
+
 // BroadcastError represents a failed scrobble attempt for a specific group member.
 // Used by BroadcastScrobble to return actionable error information including
 // member details for retry queue enrollment.
@@ -58,3 +125,47 @@ func (b BroadcastError) IsRetryable() bool {
 func (t *Trakt) SetQueueEventLog(log *store.QueueEventLog) {
 	t.queueEventLog = log
 }
+
+// DefaultBaseURL is the Trakt API root used unless SetBaseURL overrides it.
+const DefaultBaseURL = "https://api.trakt.tv"
+
+// SetBaseURL points the client at another API root, such as a mock server in
+// tests. An empty string restores DefaultBaseURL.
+func (t *Trakt) SetBaseURL(base string) {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	t.baseURL = base
+}
+
+// apiURL joins path (starting with "/") onto the configured API root.
+func (t *Trakt) apiURL(path string) string {
+	if t.baseURL == "" {
+		return DefaultBaseURL + path
+	}
+	return t.baseURL + path
+}
+
+// SetFailureMonitor sets the monitor that is told about every scrobble outcome.
+func (t *Trakt) SetFailureMonitor(m *notify.FailureMonitor) {
+	t.monitor = m
+}
+
+// SetScheduler sets the scheduler that orders scrobbles by priority when
+// they compete for Trakt capacity. Requests are live unless their context was
+// marked with provider.WithPriority. nil sends everything immediately.
+func (t *Trakt) SetScheduler(s *provider.Scheduler) {
+	t.scheduler = s
+}
+
+// sendScrobble performs req once the scheduler grants it a slot.
+func (t *Trakt) sendScrobble(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	release, err := t.scheduler.Acquire(ctx, provider.PriorityFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return t.httpClient.Do(req)
+}
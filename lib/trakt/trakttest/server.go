@@ -0,0 +1,282 @@
+// Package trakttest provides an in-process fake of the Trakt API for
+// integration tests. Point a client at it with trakt.Trakt.SetBaseURL:
+//
+//	srv := trakttest.NewServer(t)
+//	tr := trakt.New("client-id", "client-secret", storage)
+//	tr.SetBaseURL(srv.URL)
+//
+// Every endpoint succeeds by default. Tests script failures per route with
+// Enqueue (e.g. a 503 outage followed by recovery) and inspect what the
+// client sent with Requests.
+package trakttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Routes served by Server, in "METHOD /path" form.
+const (
+	RouteToken         = "POST /oauth/token"
+	RouteUserSettings  = "GET /users/settings"
+	RouteScrobbleStart = "POST /scrobble/start"
+	RouteScrobblePause = "POST /scrobble/pause"
+	RouteScrobbleStop  = "POST /scrobble/stop"
+	RouteSyncHistory   = "POST /sync/history"
+	RouteRoot          = "GET /"
+)
+
+// TokenLifetime is the expires_in reported for issued tokens (90 days, like Trakt).
+const TokenLifetime = 90 * 24 * time.Hour
+
+// Response is a scripted reply. Body is JSON-encoded unless it is a string.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   any
+}
+
+// Request is a call received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Route returns the request's route key, e.g. "POST /scrobble/start".
+func (r Request) Route() string {
+	return r.Method + " " + r.Path
+}
+
+// Token returns the bearer token the request was sent with.
+func (r Request) Token() string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// User is the account returned by /users/settings for an access token.
+type User struct {
+	Username string
+	Name     string
+}
+
+// Server is an httptest-backed fake of the Trakt API.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	scripts  map[string][]Response
+	requests []Request
+	users    map[string]User // access token -> user
+	refresh  map[string]User // refresh tokens that may be exchanged
+	issued   int
+}
+
+// NewServer starts a server that is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		scripts: make(map[string][]Response),
+		users:   make(map[string]User),
+		refresh: make(map[string]User),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Enqueue scripts the next replies for route, used in order before the
+// default behaviour resumes.
+func (s *Server) Enqueue(route string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[route] = append(s.scripts[route], responses...)
+}
+
+// FailNext makes the next n calls to route return status.
+func (s *Server) FailNext(route string, status, n int) {
+	responses := make([]Response, n)
+	for i := range responses {
+		responses[i] = Response{Status: status, Body: map[string]string{"error": http.StatusText(status)}}
+	}
+	s.Enqueue(route, responses...)
+}
+
+// SetUser registers the account returned for accessToken. refreshToken, when
+// not empty, becomes valid for the refresh_token grant.
+func (s *Server) SetUser(accessToken, refreshToken string, user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[accessToken] = user
+	if refreshToken != "" {
+		s.refresh[refreshToken] = user
+	}
+}
+
+// Requests returns the calls received for route, or every call when route
+// is empty, oldest first.
+func (s *Server) Requests(route string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Request{}
+	for _, req := range s.requests {
+		if route == "" || req.Route() == route {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var scripted *Response
+	if queue := s.scripts[req.Route()]; len(queue) > 0 {
+		scripted = &queue[0]
+		s.scripts[req.Route()] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if scripted != nil {
+		writeResponse(w, *scripted)
+		return
+	}
+	writeResponse(w, s.handle(req))
+}
+
+func (s *Server) handle(req Request) Response {
+	if req.Header.Get("trakt-api-key") == "" && req.Route() != RouteToken {
+		return errorResponse(http.StatusForbidden, "invalid_api_key")
+	}
+	switch req.Route() {
+	case RouteRoot:
+		return Response{Status: http.StatusOK, Body: map[string]string{}}
+	case RouteToken:
+		return s.token(req)
+	case RouteUserSettings:
+		user, ok := s.user(req)
+		if !ok {
+			return errorResponse(http.StatusUnauthorized, "invalid_token")
+		}
+		return Response{Status: http.StatusOK, Body: map[string]any{
+			"user": map[string]any{"username": user.Username, "name": user.Name},
+		}}
+	case RouteScrobbleStart, RouteScrobblePause, RouteScrobbleStop:
+		if _, ok := s.user(req); !ok {
+			return errorResponse(http.StatusUnauthorized, "invalid_token")
+		}
+		var echo map[string]any
+		if err := json.Unmarshal(req.Body, &echo); err != nil {
+			return errorResponse(http.StatusBadRequest, "invalid_json")
+		}
+		echo["id"] = len(s.Requests(""))
+		echo["action"] = strings.TrimPrefix(req.Path, "/scrobble/")
+		return Response{Status: http.StatusCreated, Body: echo}
+	case RouteSyncHistory:
+		if _, ok := s.user(req); !ok {
+			return errorResponse(http.StatusUnauthorized, "invalid_token")
+		}
+		var payload struct {
+			Movies   []json.RawMessage `json:"movies"`
+			Episodes []json.RawMessage `json:"episodes"`
+		}
+		if err := json.Unmarshal(req.Body, &payload); err != nil {
+			return errorResponse(http.StatusBadRequest, "invalid_json")
+		}
+		return Response{Status: http.StatusCreated, Body: map[string]any{
+			"added":     map[string]int{"movies": len(payload.Movies), "episodes": len(payload.Episodes)},
+			"not_found": map[string][]any{"movies": {}, "shows": {}, "seasons": {}, "episodes": {}},
+		}}
+	}
+	return errorResponse(http.StatusNotFound, "not_found")
+}
+
+// token implements the authorization_code and refresh_token grants. Issued
+// tokens are valid for the other endpoints; for code grants the code doubles
+// as the account's username so tests can tell accounts apart.
+func (s *Server) token(req Request) Response {
+	var in struct {
+		Code         string `json:"code"`
+		RefreshToken string `json:"refresh_token"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		GrantType    string `json:"grant_type"`
+	}
+	if err := json.Unmarshal(req.Body, &in); err != nil {
+		return errorResponse(http.StatusBadRequest, "invalid_request")
+	}
+	if in.ClientID == "" || in.ClientSecret == "" {
+		return errorResponse(http.StatusUnauthorized, "invalid_client")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var user User
+	switch previous, ok := s.refresh[in.RefreshToken]; {
+	case in.GrantType == "authorization_code" && in.Code != "":
+		user = User{Username: in.Code, Name: in.Code}
+	case in.GrantType == "refresh_token" && ok:
+		delete(s.refresh, in.RefreshToken)
+		user = previous
+	default:
+		return Response{Status: http.StatusBadRequest, Body: map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "The provided authorization grant is invalid, expired or revoked.",
+		}}
+	}
+
+	s.issued++
+	access := fmt.Sprintf("access-%d", s.issued)
+	refresh := fmt.Sprintf("refresh-%d", s.issued)
+	s.users[access] = user
+	s.refresh[refresh] = user
+	return Response{Status: http.StatusOK, Body: map[string]any{
+		"access_token":  access,
+		"refresh_token": refresh,
+		"token_type":    "bearer",
+		"scope":         "public",
+		"expires_in":    int64(TokenLifetime.Seconds()),
+		"created_at":    time.Now().Unix(),
+	}}
+}
+
+func (s *Server) user(req Request) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[req.Token()]
+	return user, ok
+}
+
+func errorResponse(status int, code string) Response {
+	return Response{Status: status, Body: map[string]string{"error": code}}
+}
+
+func writeResponse(w http.ResponseWriter, resp Response) {
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	if text, ok := resp.Body.(string); ok {
+		w.WriteHeader(resp.Status)
+		_, _ = io.WriteString(w, text)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Status)
+	if resp.Body != nil {
+		_ = json.NewEncoder(w).Encode(resp.Body)
+	}
+}
@@ -0,0 +1,87 @@
+package trakt_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/lib/trakt"
+	"crovlune/plaxt/lib/trakt/trakttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockClient(t *testing.T) (*trakt.Trakt, *trakttest.Server) {
+	srv := trakttest.NewServer(t)
+	tr := trakt.New("client-id", "client-secret", store.NewMemoryStore())
+	tr.SetBaseURL(srv.URL)
+	return tr, srv
+}
+
+func TestMockServerAuthorizeAndRefresh(t *testing.T) {
+	tr, srv := newMockClient(t)
+	ctx := context.Background()
+
+	token, tokenErr := tr.AuthRequest(ctx, "http://plaxt.test/authorize", "alice", "alice", "", "authorization_code")
+	require.Nil(t, tokenErr)
+	assert.NotEmpty(t, token.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(trakttest.TokenLifetime), token.ExpiresAt(time.Now()), time.Minute)
+
+	name, _, err := tr.FetchDisplayName(ctx, token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", name)
+
+	refreshed, tokenErr := tr.AuthRequest(ctx, "http://plaxt.test/authorize", "alice", "", token.RefreshToken, "refresh_token")
+	require.Nil(t, tokenErr)
+	assert.NotEqual(t, token.AccessToken, refreshed.AccessToken)
+
+	_, tokenErr = tr.AuthRequest(ctx, "http://plaxt.test/authorize", "alice", "", token.RefreshToken, "refresh_token")
+	require.NotNil(t, tokenErr, "refresh tokens are single use")
+	assert.Equal(t, "invalid_grant", tokenErr.Code)
+
+	sent := srv.Requests(trakttest.RouteToken)
+	require.Len(t, sent, 3)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(sent[0].Body, &body))
+	assert.Equal(t, "client-id", body["client_id"])
+	assert.Equal(t, "http://plaxt.test/authorize", body["redirect_uri"])
+}
+
+func TestMockServerScriptedOutage(t *testing.T) {
+	tr, srv := newMockClient(t)
+	srv.SetUser("token-1", "", trakttest.User{Username: "bob"})
+	srv.FailNext(trakttest.RouteScrobbleStop, http.StatusServiceUnavailable, 1)
+
+	title, year := "Heat", 1995
+	item := common.CacheItem{Body: common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Title: &title, Year: &year}}}
+
+	err := tr.Scrobble(context.Background(), "stop", item, "token-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+
+	require.NoError(t, tr.Scrobble(context.Background(), "stop", item, "token-1"), "outage is over")
+	err = tr.Scrobble(context.Background(), "stop", item, "unknown-token")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	sent := srv.Requests(trakttest.RouteScrobbleStop)
+	require.Len(t, sent, 3)
+	assert.Equal(t, "token-1", sent[1].Token())
+	assert.Equal(t, "2", sent[1].Header.Get("trakt-api-version"))
+}
+
+func TestMockServerHistoryPush(t *testing.T) {
+	tr, srv := newMockClient(t)
+	srv.SetUser("token-1", "", trakttest.User{Username: "carol"})
+
+	report := tr.PushHistory(context.Background(), "token-1", []trakt.HistoryItem{
+		{Type: "movie", Title: "Heat", Year: 1995, Tmdb: 949, WatchedAt: time.Now()},
+	}, 10, 0)
+	assert.Equal(t, 1, report.AddedMovies)
+	assert.Zero(t, report.FailedBatches)
+	assert.Len(t, srv.Requests(trakttest.RouteSyncHistory), 1)
+}
@@ -0,0 +1,283 @@
+package trakt
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CorrelationIDHeader is forwarded on every outbound Trakt request when the
+// request context carries a correlation ID.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// Transport defaults
+const (
+	userAgentProduct            = "plaxt"
+	DefaultSlowRequestThreshold = 2 * time.Second
+	getRetryBaseDelay           = 250 * time.Millisecond
+)
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the given correlation ID so the
+// transport can attach it to outbound requests.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID stored in ctx, if any.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// TransportMetrics is a point-in-time snapshot of outbound Trakt HTTP activity.
+type TransportMetrics struct {
+	Requests      uint64         `json:"requests"`
+	Errors        uint64         `json:"errors"`
+	Retries       uint64         `json:"retries"`
+	SlowRequests  uint64         `json:"slow_requests"`
+	Faults        uint64         `json:"faults_injected,omitempty"`
+	AvgLatencyMs  int64          `json:"avg_latency_ms"`
+	StatusCounts  map[int]uint64 `json:"status_counts"`
+	LastRequestAt *time.Time     `json:"last_request_at,omitempty"`
+}
+
+// transportStats accumulates counters shared by all requests of a client.
+type transportStats struct {
+	requests     atomic.Uint64
+	errors       atomic.Uint64
+	retries      atomic.Uint64
+	slow         atomic.Uint64
+	faults       atomic.Uint64
+	latencyTotal atomic.Int64
+	lastRequest  atomic.Int64
+
+	mu       sync.Mutex
+	byStatus map[int]uint64
+}
+
+func (s *transportStats) record(status int, d time.Duration, err error) {
+	s.requests.Add(1)
+	s.latencyTotal.Add(int64(d))
+	s.lastRequest.Store(time.Now().UnixNano())
+	if err != nil {
+		s.errors.Add(1)
+		return
+	}
+	s.mu.Lock()
+	if s.byStatus == nil {
+		s.byStatus = make(map[int]uint64)
+	}
+	s.byStatus[status]++
+	s.mu.Unlock()
+}
+
+func (s *transportStats) snapshot() TransportMetrics {
+	m := TransportMetrics{
+		Requests:     s.requests.Load(),
+		Errors:       s.errors.Load(),
+		Retries:      s.retries.Load(),
+		SlowRequests: s.slow.Load(),
+		Faults:       s.faults.Load(),
+		StatusCounts: make(map[int]uint64),
+	}
+	if m.Requests > 0 {
+		m.AvgLatencyMs = time.Duration(s.latencyTotal.Load() / int64(m.Requests)).Milliseconds()
+	}
+	if last := s.lastRequest.Load(); last > 0 {
+		t := time.Unix(0, last)
+		m.LastRequestAt = &t
+	}
+	s.mu.Lock()
+	for code, n := range s.byStatus {
+		m.StatusCounts[code] = n
+	}
+	s.mu.Unlock()
+	return m
+}
+
+// transport wraps the underlying RoundTripper for every Trakt call. It adds
+// the Trakt API headers, forwards the correlation ID, records metrics, logs
+// slow calls and optionally retries idempotent GETs on transient failures.
+// In staging it can also inject simulated failures (see SetFaultInjection).
+type transport struct {
+	base     http.RoundTripper
+	clientId string
+	stats    *transportStats
+
+	slowThreshold atomic.Int64 // time.Duration
+	getRetries    atomic.Int32
+	userAgent     atomic.Value // string
+	faults        atomic.Pointer[faultConfig]
+
+	// roll returns a number in [0, 1) deciding whether a fault is injected.
+	roll func() float64
+}
+
+func newTransport(base http.RoundTripper, clientId string) *transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base:     base,
+		clientId: clientId,
+		stats:    &transportStats{},
+		roll:     rand.Float64,
+	}
+	t.slowThreshold.Store(int64(DefaultSlowRequestThreshold))
+	t.userAgent.Store(UserAgent("", ""))
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not mutate the caller's request
+	req = req.Clone(req.Context())
+	if req.Header.Get("trakt-api-version") == "" {
+		req.Header.Set("trakt-api-version", "2")
+	}
+	if req.Header.Get("trakt-api-key") == "" {
+		req.Header.Set("trakt-api-key", t.clientId)
+	}
+	if ua, _ := t.userAgent.Load().(string); ua != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if id := CorrelationIDFromContext(req.Context()); id != "" && req.Header.Get(CorrelationIDHeader) == "" {
+		req.Header.Set(CorrelationIDHeader, id)
+	}
+
+	attempts := 1
+	if req.Method == http.MethodGet && req.Body == nil {
+		attempts += int(t.getRetries.Load())
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 1; attempt <= attempts; attempt++ {
+		resp, err = t.roundTripOnce(req)
+		if attempt == attempts || !shouldRetry(resp, err) || req.Context().Err() != nil {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t.stats.retries.Add(1)
+		delay := getRetryBaseDelay * time.Duration(1<<(attempt-1))
+		slog.Debug("trakt request retry",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"attempt", attempt+1,
+			"delay_ms", delay.Milliseconds(),
+		)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+	return resp, err
+}
+
+func (t *transport) roundTripOnce(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	var (
+		resp *http.Response
+		err  error
+	)
+	if fault := t.pickFault(req); fault != "" {
+		resp, err = faultResponse(req, fault)
+	} else {
+		resp, err = t.base.RoundTrip(req)
+	}
+	elapsed := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.stats.record(status, elapsed, err)
+
+	if threshold := time.Duration(t.slowThreshold.Load()); threshold > 0 && elapsed >= threshold {
+		t.stats.slow.Add(1)
+		slog.Warn("trakt request slow",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", status,
+			"duration_ms", elapsed.Milliseconds(),
+			"correlation_id", CorrelationIDFromContext(req.Context()),
+		)
+	}
+	return resp, err
+}
+
+// shouldRetry reports whether a GET response warrants another attempt.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// SetSlowRequestThreshold sets the latency above which outbound Trakt calls are
+// logged as slow. Zero disables slow-call logging.
+func (t *Trakt) SetSlowRequestThreshold(d time.Duration) {
+	if t.transport != nil {
+		t.transport.slowThreshold.Store(int64(d))
+	}
+}
+
+// SetGetRetries sets how many extra attempts idempotent GET requests get on
+// network errors or transient (429/502/503/504) responses.
+func (t *Trakt) SetGetRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if t.transport != nil {
+		t.transport.getRetries.Store(int32(n))
+	}
+}
+
+// UserAgent builds the User-Agent sent to Trakt, e.g. "plaxt/1.4.0 (+home-lab)".
+// An empty version is reported as "dev"; the optional suffix lets operators
+// identify their instance to Trakt support.
+func UserAgent(version, suffix string) string {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		version = "dev"
+	}
+	ua := userAgentProduct + "/" + version
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		ua += " (" + suffix + ")"
+	}
+	return ua
+}
+
+// SetUserAgent overrides the User-Agent sent on every outbound Trakt request.
+func (t *Trakt) SetUserAgent(ua string) {
+	if t.transport != nil && strings.TrimSpace(ua) != "" {
+		t.transport.userAgent.Store(strings.TrimSpace(ua))
+	}
+}
+
+// Metrics returns a snapshot of outbound Trakt HTTP metrics.
+func (t *Trakt) Metrics() TransportMetrics {
+	if t.transport == nil {
+		return TransportMetrics{StatusCounts: map[int]uint64{}}
+	}
+	return t.transport.stats.snapshot()
+}
@@ -0,0 +1,154 @@
+package trakt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"crovlune/plaxt/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportInjectsHeadersAndCorrelationID(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "2", req.Header.Get("trakt-api-version"))
+		assert.Equal(t, "client-id", req.Header.Get("trakt-api-key"))
+		assert.Equal(t, "corr-123", req.Header.Get(CorrelationIDHeader))
+		assert.Equal(t, "plaxt/dev", req.Header.Get("User-Agent"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	ctx := WithCorrelationID(context.Background(), "corr-123")
+	require.NoError(t, tr.HealthCheck(ctx))
+
+	m := tr.Metrics()
+	assert.Equal(t, uint64(1), m.Requests)
+	assert.Equal(t, uint64(1), m.StatusCounts[http.StatusOK])
+	assert.NotNil(t, m.LastRequestAt)
+}
+
+func TestTransportRetriesIdempotentGet(t *testing.T) {
+	calls := 0
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		status := http.StatusServiceUnavailable
+		if calls == 2 {
+			status = http.StatusOK
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader("[]")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	tr.SetGetRetries(2)
+
+	_, err := tr.makeRequest(context.Background(), "https://api.trakt.tv/search/movie")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	m := tr.Metrics()
+	assert.Equal(t, uint64(2), m.Requests)
+	assert.Equal(t, uint64(1), m.Retries)
+}
+
+func TestTransportDoesNotRetryPost(t *testing.T) {
+	calls := 0
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	tr.SetGetRetries(3)
+
+	err := tr.Scrobble(context.Background(), "start", common.CacheItem{}, "token")
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestUserAgent(t *testing.T) {
+	assert.Equal(t, "plaxt/dev", UserAgent("", ""))
+	assert.Equal(t, "plaxt/1.2.3", UserAgent("1.2.3", ""))
+	assert.Equal(t, "plaxt/1.2.3 (home-lab)", UserAgent("1.2.3", "  home-lab "))
+}
+
+func TestSetUserAgentAppliesToRequests(t *testing.T) {
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "plaxt/2.0.0 (ops@example.com)", req.Header.Get("User-Agent"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	tr.SetUserAgent(UserAgent("2.0.0", "ops@example.com"))
+	require.NoError(t, tr.HealthCheck(context.Background()))
+}
+
+func TestTransportInjectsFaults(t *testing.T) {
+	calls := 0
+	handler := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tr := newTestTrakt(handler)
+	tr.transport.roll = func() float64 { return 0.05 }
+
+	tr.SetFaultInjection(10, []FaultKind{FaultUnavailable})
+	err := tr.Scrobble(context.Background(), "start", common.CacheItem{}, "token")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+
+	tr.SetFaultInjection(10, []FaultKind{FaultTimeout})
+	err = tr.HealthCheck(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Equal(t, 0, calls, "faulted requests never reach Trakt")
+
+	tr.SetFaultInjection(5, nil)
+	require.NoError(t, tr.HealthCheck(context.Background()), "rolls above the percentage pass through")
+	assert.Equal(t, 1, calls)
+
+	tr.SetFaultInjection(0, nil)
+	require.NoError(t, tr.HealthCheck(context.Background()))
+	assert.Equal(t, uint64(2), tr.Metrics().Faults)
+}
+
+func TestParseFaultSettings(t *testing.T) {
+	percent, err := ParseFaultInjection("12.5%")
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, percent)
+	_, err = ParseFaultInjection("150")
+	assert.Error(t, err)
+
+	kinds, err := ParseFaultKinds("")
+	require.NoError(t, err)
+	assert.Equal(t, AllFaultKinds, kinds)
+	kinds, err = ParseFaultKinds(" 429, Timeout ")
+	require.NoError(t, err)
+	assert.Equal(t, []FaultKind{FaultRateLimited, FaultTimeout}, kinds)
+	_, err = ParseFaultKinds("500")
+	assert.Error(t, err)
+}
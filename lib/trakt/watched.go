@@ -0,0 +1,47 @@
+package trakt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/logging"
+	"crovlune/plaxt/lib/store"
+)
+
+// WatchedMovieFromScrobble converts a finished movie scrobble into a local
+// watch history entry watched at the given time (now when zero). It returns false for episodes, unfinished plays and
+// bodies without a title.
+func WatchedMovieFromScrobble(userID, action string, body common.ScrobbleBody, watchedAt time.Time) (*store.WatchedMovie, bool) {
+	if action != actionStop || body.Progress < ProgressThreshold || body.Movie == nil || body.Movie.Title == nil {
+		return nil, false
+	}
+	movie := &store.WatchedMovie{
+		UserID:    userID,
+		Title:     *body.Movie.Title,
+		WatchedAt: watchedAt,
+	}
+	if body.Movie.Year != nil {
+		movie.Year = *body.Movie.Year
+	}
+	if body.Movie.Ids.Imdb != nil {
+		movie.Imdb = *body.Movie.Ids.Imdb
+	}
+	if body.Movie.Ids.Tmdb != nil {
+		movie.Tmdb = *body.Movie.Ids.Tmdb
+	}
+	return movie, true
+}
+
+// RecordWatched stores a finished movie scrobble in the local watch history.
+// Failures are logged only; history is best-effort and never blocks scrobbling.
+func RecordWatched(ctx context.Context, s store.Store, userID, action string, body common.ScrobbleBody, watchedAt time.Time) {
+	movie, ok := WatchedMovieFromScrobble(userID, action, body, watchedAt)
+	if !ok || s == nil {
+		return
+	}
+	if err := s.RecordWatchedMovie(ctx, movie); err != nil {
+		slog.Warn("watch history record failed", "plaxt_id", userID, "title", logging.Redact(userID, movie.Title), "error", err)
+	}
+}
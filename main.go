@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -18,15 +19,25 @@ import (
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"crovlune/plaxt/lib/adminauth"
+	"crovlune/plaxt/lib/backup"
 	"crovlune/plaxt/lib/common"
 	"crovlune/plaxt/lib/config"
 	"crovlune/plaxt/lib/logging"
 	"crovlune/plaxt/lib/notify"
+	"crovlune/plaxt/lib/preferences"
+	"crovlune/plaxt/lib/provider"
 	"crovlune/plaxt/lib/queue"
+	"crovlune/plaxt/lib/plex"
+	"crovlune/plaxt/lib/reachability"
+	"crovlune/plaxt/lib/simkl"
 	"crovlune/plaxt/lib/store"
 	"crovlune/plaxt/lib/trakt"
 	"crovlune/plaxt/plexhooks"
@@ -43,11 +54,20 @@ var (
 	date          string
 	storage       store.Store
 	apiSf         *singleflight.Group
+	refreshSf     = &singleflight.Group{}
 	webhookCache  *webhookDedupeCache
+	replayGuard   = &webhookReplayGuard{}
+	invalidIDs    = &invalidIDTracker{banFor: 24 * time.Hour}
+	webhookEvents = &webhookEventStats{}
 	traktSrv      *trakt.Trakt
 	trustProxy    bool = true
 	requestLogMod string
-	appAssets     *assetManifest = newAssetManifest("static/dist/manifest.json")
+	// Log 1 in requestLogSample successful /api requests (REQUEST_LOG_SAMPLE)
+	requestLogSample  int
+	requestLogSampled atomic.Uint64
+	appAssets     *assetManifest = newAssetManifest(staticFS, "dist/manifest.json")
+	// Dates shown in the web UI (DISPLAY_LOCALE, DISPLAY_TIMEZONE)
+	displayDates  common.DateFormatter
 	templateFuncs = template.FuncMap{
 		"assetPath": assetPath,
 	}
@@ -55,6 +75,22 @@ var (
 	// Queue monitoring
 	queueEventLog     *store.QueueEventLog
 	drainStateTracker *DrainStateTracker
+	drainPolicy       = &drainSchedule{}
+	failureMonitor    *notify.FailureMonitor
+
+	// Scrobble targets keyed by name; Trakt is always registered, Simkl optionally
+	simklClient *simkl.Client
+	providers   *provider.Registry
+	// Registers plaxt's webhook on users' Plex accounts
+	plexClient = plex.New("plaxt")
+	// Probes the webhook URL from outside (POST /webhooks/{id}/reachability)
+	reachabilityChecker = &reachability.Checker{}
+	reachabilityProbes  = &probeNonces{issued: make(map[string]time.Time)}
+	// Orders live scrobbles ahead of queue drain and retry backlog
+	scrobbleScheduler *provider.Scheduler
+
+	// Reply 200 to Plex even when a webhook fails (WEBHOOK_ALWAYS_200)
+	webhookAlwaysOK bool
 )
 
 // webhookDedupeCache prevents rapid-fire duplicate webhook requests
@@ -119,6 +155,466 @@ func (c *webhookDedupeCache) shouldProcess(plaxtID, traktDisplayName, event, rat
 	return true
 }
 
+// webhookReplayGuard rejects (or only flags) webhooks whose embedded event
+// time is older than maxAge, so a captured payload cannot be replayed later
+// to re-scrobble content.
+type webhookReplayGuard struct {
+	maxAge   time.Duration // 0 disables the check
+	flagOnly bool          // log and count instead of rejecting
+
+	rejected atomic.Uint64
+	flagged  atomic.Uint64
+}
+
+type webhookReplayMetrics struct {
+	MaxAgeSeconds int64  `json:"max_age_seconds"`
+	Action        string `json:"action"`
+	Rejected      uint64 `json:"rejected"`
+	Flagged       uint64 `json:"flagged"`
+}
+
+// webhookEventTime returns the best available event time for a webhook.
+// Plex stamps lastViewedAt when it marks an item watched, so it is only
+// trusted for media.scrobble; otherwise the request Date header is used.
+func webhookEventTime(hook *plexhooks.Webhook, r *http.Request) (time.Time, string, bool) {
+	if hook.Event == "media.scrobble" && hook.Metadata.LastViewedAt > 0 {
+		return time.Unix(int64(hook.Metadata.LastViewedAt), 0), "last_viewed_at", true
+	}
+	if r != nil {
+		if date := r.Header.Get("Date"); date != "" {
+			if t, err := http.ParseTime(date); err == nil {
+				return t, "date_header", true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// allow reports whether the webhook should be processed.
+func (g *webhookReplayGuard) allow(hook *plexhooks.Webhook, r *http.Request, id string) bool {
+	if g == nil || g.maxAge <= 0 || hook == nil {
+		return true
+	}
+	eventTime, source, ok := webhookEventTime(hook, r)
+	if !ok {
+		return true
+	}
+	age := time.Since(eventTime)
+	if age <= g.maxAge {
+		return true
+	}
+
+	attrs := []any{
+		"id", id,
+		"event", hook.Event,
+		"rating_key", hook.Metadata.RatingKey,
+		"timestamp_source", source,
+		"age_seconds", int64(age.Seconds()),
+		"max_age_seconds", int64(g.maxAge.Seconds()),
+	}
+	if g.flagOnly {
+		g.flagged.Add(1)
+		slog.Warn("webhook replay suspected", attrs...)
+		return true
+	}
+	g.rejected.Add(1)
+	slog.Warn("webhook replay rejected", attrs...)
+	return false
+}
+
+func (g *webhookReplayGuard) metrics() webhookReplayMetrics {
+	action := "reject"
+	if g.flagOnly {
+		action = "flag"
+	}
+	return webhookReplayMetrics{
+		MaxAgeSeconds: int64(g.maxAge.Seconds()),
+		Action:        action,
+		Rejected:      g.rejected.Load(),
+		Flagged:       g.flagged.Load(),
+	}
+}
+
+// webhookStatusHeader carries the real outcome of a webhook when the
+// response code sent to Plex was rewritten to 200.
+const webhookStatusHeader = "X-Plaxt-Webhook-Status"
+
+// alwaysOKWriter rewrites non-2xx statuses to 200 and remembers the original.
+type alwaysOKWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *alwaysOKWriter) WriteHeader(code int) {
+	w.status = code
+	if code >= 300 {
+		w.Header().Set(webhookStatusHeader, strconv.Itoa(code))
+		code = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// webhookResponseCodes wraps the Plex webhook handler. When webhookAlwaysOK is
+// set every reply is a 200, since some Plex versions disable a webhook after
+// repeated non-2xx responses; failures are logged here instead of being
+// reflected back to Plex.
+func webhookResponseCodes(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !webhookAlwaysOK {
+			next(w, r)
+			return
+		}
+		ow := &alwaysOKWriter{ResponseWriter: w, status: http.StatusOK}
+		next(ow, r)
+		if ow.status >= 300 {
+			slog.Warn("webhook failure hidden from plex",
+				"status", ow.status,
+				"id", r.URL.Query().Get("id"),
+				"remote", r.RemoteAddr,
+			)
+		}
+	}
+}
+
+// maxInvalidIDSources bounds how many source IPs invalidIDTracker remembers.
+const maxInvalidIDSources = 1000
+
+// invalidIDTracker counts webhooks naming an unknown user id, per source IP.
+// Plex servers of deleted users keep firing webhooks forever, so after
+// banAfter strikes a source is banned for banFor and answered without
+// touching storage or the logs.
+type invalidIDTracker struct {
+	banAfter int           // 0 disables banning
+	banFor   time.Duration // 0 bans until restart
+
+	mu      sync.Mutex
+	total   uint64
+	sources map[string]*invalidIDSource
+}
+
+type invalidIDSource struct {
+	IP          string     `json:"ip"`
+	Count       int        `json:"count"`
+	LastID      string     `json:"last_id"`
+	LastSeen    time.Time  `json:"last_seen"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+	banned      bool
+}
+
+type invalidIDMetrics struct {
+	Total    uint64            `json:"total"`
+	BanAfter int               `json:"ban_after"`
+	Banned   int               `json:"banned"`
+	Sources  []invalidIDSource `json:"sources"`
+}
+
+// webhookSourceIP returns the client address without its port.
+func webhookSourceIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// isBanned reports whether ip is currently banned, lifting expired bans.
+func (t *invalidIDTracker) isBanned(ip string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	src, ok := t.sources[ip]
+	if !ok || !src.banned {
+		return false
+	}
+	if src.BannedUntil != nil && time.Now().After(*src.BannedUntil) {
+		src.banned = false
+		src.BannedUntil = nil
+		src.Count = 0
+		return false
+	}
+	return true
+}
+
+// strike records a webhook from ip for the unknown id.
+func (t *invalidIDTracker) strike(ip, id string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sources == nil {
+		t.sources = make(map[string]*invalidIDSource)
+	}
+	src, ok := t.sources[ip]
+	if !ok {
+		if len(t.sources) >= maxInvalidIDSources {
+			t.evictOldest()
+		}
+		src = &invalidIDSource{IP: ip}
+		t.sources[ip] = src
+	}
+	t.total++
+	src.Count++
+	src.LastID = id
+	src.LastSeen = time.Now()
+
+	switch {
+	case src.Count == 1:
+		slog.Warn("webhook for invalid id", "id", id, "remote", ip)
+	case t.banAfter > 0 && src.Count >= t.banAfter && !src.banned:
+		src.banned = true
+		if t.banFor > 0 {
+			until := src.LastSeen.Add(t.banFor)
+			src.BannedUntil = &until
+		}
+		slog.Warn("webhook source banned after repeated invalid ids", "id", id, "remote", ip, "strikes", src.Count, "ban_for", t.banFor)
+	default:
+		slog.Debug("webhook for invalid id", "id", id, "remote", ip, "strikes", src.Count)
+	}
+}
+
+// evictOldest drops the least recently seen source that is not banned.
+func (t *invalidIDTracker) evictOldest() {
+	var oldest *invalidIDSource
+	for _, src := range t.sources {
+		if !src.banned && (oldest == nil || src.LastSeen.Before(oldest.LastSeen)) {
+			oldest = src
+		}
+	}
+	if oldest != nil {
+		delete(t.sources, oldest.IP)
+	}
+}
+
+func (t *invalidIDTracker) metrics() invalidIDMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := invalidIDMetrics{Total: t.total, BanAfter: t.banAfter, Sources: make([]invalidIDSource, 0, len(t.sources))}
+	for _, src := range t.sources {
+		if src.banned {
+			m.Banned++
+		}
+		m.Sources = append(m.Sources, *src)
+	}
+	sort.Slice(m.Sources, func(i, j int) bool {
+		if m.Sources[i].Count != m.Sources[j].Count {
+			return m.Sources[i].Count > m.Sources[j].Count
+		}
+		return m.Sources[i].IP < m.Sources[j].IP
+	})
+	return m
+}
+
+// Webhook event capture limits
+const (
+	maxUnknownEventSamples = 20
+	maxUnknownEventPayload = 4096
+)
+
+// knownWebhookEvents are the Plex event types counted under their own name.
+// admin.* events are grouped; anything else is counted as "unknown" and
+// sampled so new Plex event kinds get noticed.
+var knownWebhookEvents = map[string]struct{}{
+	"media.play":       {},
+	"media.pause":      {},
+	"media.resume":     {},
+	"media.stop":       {},
+	"media.scrobble":   {},
+	"media.rate":       {},
+	"playback.started": {},
+	"library.new":      {},
+	"library.on.deck":  {},
+	"device.new":       {},
+}
+
+// webhookEventStats counts received webhooks by event type and keeps the
+// latest payloads of unknown types.
+type webhookEventStats struct {
+	mu      sync.Mutex
+	counts  map[string]uint64
+	skipped map[string]uint64     // by extraWebhookReason
+	unknown []unknownWebhookEvent // newest last
+	arrived map[string]time.Time  // last webhook per user or family group ID
+}
+
+type unknownWebhookEvent struct {
+	Event      string    `json:"event"`
+	ReceivedAt time.Time `json:"received_at"`
+	Payload    string    `json:"payload"`
+	Truncated  bool      `json:"truncated,omitempty"`
+}
+
+type webhookEventMetrics struct {
+	Counts  map[string]uint64     `json:"counts"`
+	Skipped map[string]uint64     `json:"skipped"`
+	Unknown []unknownWebhookEvent `json:"unknown_samples"`
+}
+
+// plexExtraTypeTrailer is Plex's extraType for trailers.
+const plexExtraTypeTrailer = 1
+
+// extraWebhookReason reports whether m is a pre-roll, trailer or other extra
+// rather than a library item, returning "preroll", "trailer", "extra" or ""
+// for a real movie or episode. Extras have no Trakt match, so scrobbling them
+// either fails GUID lookup or hits the wrong item via title search.
+func extraWebhookReason(m plexhooks.Metadata) string {
+	typ, subtype, guid := strings.ToLower(m.Type), strings.ToLower(m.Subtype), strings.ToLower(m.GUID)
+	switch {
+	case typ == "preroll" || subtype == "preroll":
+		return "preroll"
+	case typ == "trailer" || subtype == "trailer" || m.ExtraType == plexExtraTypeTrailer || strings.HasPrefix(guid, "iva://"):
+		return "trailer"
+	case typ == "clip" && m.LibrarySectionType == "" && (guid == "" || strings.HasPrefix(guid, "file://")):
+		// Pre-roll videos are local files played outside any library
+		return "preroll"
+	case typ == "clip" || m.ExtraType != 0 || strings.Contains(m.Key, "/extras"):
+		return "extra"
+	}
+	return ""
+}
+
+// skip counts a webhook dropped because it was for an extra.
+func (s *webhookEventStats) skip(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipped == nil {
+		s.skipped = make(map[string]uint64)
+	}
+	s.skipped[reason]++
+}
+
+// webhookStat tracks the user or family group one webhook belongs to and
+// how it was handled, for the daily per-subject counters in the store.
+type webhookStat struct {
+	subject store.WebhookSubject
+	id      string
+	outcome store.WebhookOutcome
+}
+
+// track ties the webhook to a user or family group; until then nothing is
+// recorded, so requests with unknown IDs cannot create counters.
+func (s *webhookStat) track(subject store.WebhookSubject, id string) {
+	s.subject, s.id = subject, id
+}
+
+// record counts the webhook as received plus its outcome (processed when
+// none was set).
+func (s *webhookStat) record(ctx context.Context) {
+	if s.id == "" {
+		return
+	}
+	now := time.Now()
+	webhookEvents.arrive(s.id, now)
+	if storage == nil {
+		return
+	}
+	outcome := s.outcome
+	if outcome == "" {
+		outcome = store.WebhookProcessed
+	}
+	for _, o := range []store.WebhookOutcome{store.WebhookReceived, outcome} {
+		if err := storage.IncrementWebhookStat(ctx, s.subject, s.id, o, now); err != nil {
+			slog.Warn("webhook stats update failed", "subject", s.subject, "id", s.id, "outcome", o, "error", err)
+			return
+		}
+	}
+}
+
+// webhookEventBucket maps an event type to the name it is counted under.
+func webhookEventBucket(event string) string {
+	if _, ok := knownWebhookEvents[event]; ok {
+		return event
+	}
+	if strings.HasPrefix(event, "admin.") {
+		return "admin.*"
+	}
+	return "unknown"
+}
+
+// record counts event and samples its payload when the type is unknown.
+func (s *webhookEventStats) record(event string, payload []byte) {
+	bucket := webhookEventBucket(event)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[string]uint64)
+	}
+	s.counts[bucket]++
+	if bucket != "unknown" {
+		return
+	}
+
+	seen := false
+	for _, sample := range s.unknown {
+		if sample.Event == event {
+			seen = true
+			break
+		}
+	}
+	if !seen {
+		slog.Warn("unknown webhook event type", "event", event)
+	}
+	sample := unknownWebhookEvent{Event: event, ReceivedAt: time.Now(), Payload: string(payload)}
+	if len(payload) > maxUnknownEventPayload {
+		sample.Payload = string(payload[:maxUnknownEventPayload])
+		sample.Truncated = true
+	}
+	s.unknown = append(s.unknown, sample)
+	if len(s.unknown) > maxUnknownEventSamples {
+		s.unknown = s.unknown[len(s.unknown)-maxUnknownEventSamples:]
+	}
+}
+
+// arrive notes that a webhook for a known user or family group came in.
+func (s *webhookEventStats) arrive(id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.arrived == nil {
+		s.arrived = make(map[string]time.Time)
+	}
+	s.arrived[id] = at
+}
+
+// lastArrival returns when the last webhook for id came in, or the zero time
+// when none has since startup.
+func (s *webhookEventStats) lastArrival(id string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.arrived[id]
+}
+
+// total returns how many webhooks were counted since startup.
+func (s *webhookEventStats) total() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n uint64
+	for _, c := range s.counts {
+		n += c
+	}
+	return n
+}
+
+func (s *webhookEventStats) metrics() webhookEventMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := webhookEventMetrics{
+		Counts:  make(map[string]uint64, len(s.counts)),
+		Skipped: make(map[string]uint64, len(s.skipped)),
+		Unknown: make([]unknownWebhookEvent, 0, len(s.unknown)),
+	}
+	for bucket, n := range s.counts {
+		m.Counts[bucket] = n
+	}
+	for reason, n := range s.skipped {
+		m.Skipped[reason] = n
+	}
+	for i := len(s.unknown) - 1; i >= 0; i-- {
+		m.Unknown = append(m.Unknown, s.unknown[i])
+	}
+	return m
+}
+
 var errUsernameMismatch = errors.New("manual renewal username mismatch")
 
 // ========== QUEUE MONITORING TYPES ==========
@@ -386,22 +882,41 @@ type FamilyContext struct {
 	Banner        *Banner
 }
 
+// TokenHealthContext describes the token state of the user named by the
+// landing page's id parameter so the page can warn before silent expiry.
+type TokenHealthContext struct {
+	UserID      string
+	Username    string
+	Status      string // "healthy", "warning", "expired"
+	ExpiresAt   string // RFC3339, drives the client-side countdown
+	ExpiryLabel string
+	Remaining   string
+}
+
 type AuthorizePage struct {
-	SelfRoot   string
-	ClientID   string
-	Mode       string
-	Onboarding OnboardingContext
-	Manual     ManualRenewContext
-	Family     FamilyContext
+	SelfRoot    string
+	ClientID    string
+	Mode        string
+	Onboarding  OnboardingContext
+	Manual      ManualRenewContext
+	Family      FamilyContext
+	TokenHealth *TokenHealthContext
+	// SimklLinkURL starts Simkl linking for the user who just authorized
+	// with Trakt; empty when Simkl is off or the link has expired.
+	SimklLinkURL string
 }
 
-var authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
+var authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
 	if traktSrv == nil {
-		return map[string]interface{}{}, false
+		return nil, &trakt.TokenError{Code: "unavailable", Description: "trakt client not configured"}
 	}
-	return traktSrv.AuthRequest(redirectURI, username, code, refreshToken, grantType)
+	return traktSrv.AuthRequest(ctx, redirectURI, username, code, refreshToken, grantType)
 }
 
+// familyRetryDelay is how long a family scrobble that failed transiently
+// waits before the retry worker first picks it up.
+var familyRetryDelay = queue.BaseBackoffDelay
+
 var fetchDisplayNameFunc = func(ctx context.Context, accessToken string) (string, bool, error) {
 	if traktSrv == nil {
 		return "", false, nil
@@ -627,39 +1142,32 @@ func createFamilyAuthState(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    time.Now(),
 	}
 
-	if err := storage.CreateFamilyGroup(ctx, familyGroup); err != nil {
-		slog.Error("failed to create family group", "plex_username", plexUsername, "error", err)
-		writeJSONError(w, http.StatusInternalServerError, "failed to create family group")
-		return
-	}
-
-	// Create pending group members
+	// Create the group with its pending members in one step so a failure
+	// never leaves orphaned members or an empty group behind
+	members := make([]*store.GroupMember, 0, len(req.Members))
 	memberStates := make([]FamilyMemberState, 0, len(req.Members))
 	for _, m := range req.Members {
-		memberID := generateCorrelationID()
 		member := &store.GroupMember{
-			ID:                  memberID,
+			ID:                  generateCorrelationID(),
 			FamilyGroupID:       groupID,
 			TempLabel:           strings.TrimSpace(m.TempLabel),
 			AuthorizationStatus: "pending",
 			CreatedAt:           time.Now(),
 		}
-
-		if err := storage.AddGroupMember(ctx, member); err != nil {
-			slog.Error("failed to add group member", "group_id", groupID, "label", m.TempLabel, "error", err)
-			// Cleanup: delete the family group
-			_ = storage.DeleteFamilyGroup(ctx, groupID)
-			writeJSONError(w, http.StatusInternalServerError, "failed to create group members")
-			return
-		}
-
+		members = append(members, member)
 		memberStates = append(memberStates, FamilyMemberState{
-			MemberID:            memberID,
+			MemberID:            member.ID,
 			TempLabel:           member.TempLabel,
 			AuthorizationStatus: "pending",
 		})
 	}
 
+	if err := storage.CreateFamilyGroupWithMembers(ctx, familyGroup, members); err != nil {
+		slog.Error("failed to create family group", "plex_username", plexUsername, "members", len(members), "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create family group")
+		return
+	}
+
 	// Create auth state for session tracking
 	state := authState{
 		Mode:    "family",
@@ -691,7 +1199,16 @@ func createAuthState(w http.ResponseWriter, r *http.Request) {
 		Username string `json:"username"`
 		ID       string `json:"id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	// Plain form posts come from the wizard with JavaScript off; they are
+	// answered with redirects instead of JSON.
+	isForm := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	if isForm {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		req.Mode, req.Username, req.ID = r.PostForm.Get("mode"), r.PostForm.Get("username"), r.PostForm.Get("id")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
@@ -706,36 +1223,51 @@ func createAuthState(w http.ResponseWriter, r *http.Request) {
 		selectedID    string
 		correlationID string
 	)
+	fail := func(status int, message string) {
+		if !isForm {
+			writeJSONError(w, status, message)
+			return
+		}
+		back := url.Values{"result": {"error"}, "error": {message}}
+		if mode == "renew" {
+			back.Set("mode", "renew")
+			back.Set("id", strings.TrimSpace(req.ID))
+		} else {
+			back.Set("step", "authorize")
+			back.Set("username", username)
+		}
+		http.Redirect(w, r, "/?"+back.Encode(), http.StatusSeeOther)
+	}
 
 	switch mode {
 	case "renew":
 		if storage == nil {
-			writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+			fail(http.StatusServiceUnavailable, "storage unavailable")
 			return
 		}
 		selectedID = strings.TrimSpace(req.ID)
 		if selectedID == "" {
-			writeJSONError(w, http.StatusBadRequest, "missing user id")
+			fail(http.StatusBadRequest, "missing user id")
 			return
 		}
 		user := storage.GetUser(selectedID)
 		if user == nil {
-			writeJSONError(w, http.StatusNotFound, "user not found")
+			fail(http.StatusNotFound, "user not found")
 			return
 		}
 		username = strings.ToLower(strings.TrimSpace(user.Username))
 		if username == "" {
-			writeJSONError(w, http.StatusConflict, "user record missing username")
+			fail(http.StatusConflict, "user record missing username")
 			return
 		}
 		correlationID = generateCorrelationID()
 	case "onboarding":
 		if username == "" {
-			writeJSONError(w, http.StatusBadRequest, "missing username")
+			fail(http.StatusBadRequest, "missing username")
 			return
 		}
 	default:
-		writeJSONError(w, http.StatusBadRequest, "unsupported mode")
+		fail(http.StatusBadRequest, "unsupported mode")
 		return
 	}
 
@@ -748,11 +1280,24 @@ func createAuthState(w http.ResponseWriter, r *http.Request) {
 	}
 	token := authStates.Create(state)
 
-	writeJSON(w, http.StatusOK, map[string]string{"state": token})
+	if !isForm {
+		writeJSON(w, http.StatusOK, map[string]string{"state": token})
+		return
+	}
+	redirectPath := "/authorize"
+	if mode == "renew" {
+		redirectPath = "/manual/authorize"
+	}
+	params := url.Values{}
+	if traktSrv != nil {
+		params.Set("client_id", traktSrv.ClientId)
+	}
+	params.Set("redirect_uri", SelfRoot(r)+redirectPath)
+	params.Set("response_type", "code")
+	params.Set("state", token)
+	http.Redirect(w, r, "https://trakt.tv/oauth/authorize?"+params.Encode(), http.StatusSeeOther)
 }
 
-// authorizeFamilyMember handles OAuth callback for family member authorization.
-// Query params: state (auth token), code (OAuth code), member_id (UUID)
 func authorizeFamilyMember(w http.ResponseWriter, r *http.Request) {
 	args := r.URL.Query()
 	stateToken := strings.TrimSpace(args.Get("state"))
@@ -871,35 +1416,15 @@ func authorizeFamilyMember(w http.ResponseWriter, r *http.Request) {
 	// Exchange code for tokens
 	// Must match the redirect_uri sent to Trakt (including member_id query param)
 	redirectURI := fmt.Sprintf("%s/authorize/family/member?member_id=%s", root, url.QueryEscape(memberID))
-	result, ok := authRequestFunc(redirectURI, "", code, "", "authorization_code")
-	if !ok {
-		// Extract error details
-		httpStatus := 0
-		if statusVal, exists := result["http_status"]; exists {
-			if statusInt, ok := statusVal.(int); ok {
-				httpStatus = statusInt
-			}
-		}
-		traktError := "unknown"
-		if errVal, exists := result["error"]; exists {
-			if errStr, ok := errVal.(string); ok && errStr != "" {
-				traktError = errStr
-			}
-		}
-		traktErrorDesc := ""
-		if descVal, exists := result["error_description"]; exists {
-			if descStr, ok := descVal.(string); ok && descStr != "" {
-				traktErrorDesc = descStr
-			}
-		}
-
-		errorDetail := fmt.Sprintf("Trakt token exchange failed: %s", traktError)
-		if httpStatus != 0 {
-			errorDetail = fmt.Sprintf("Trakt token exchange failed: HTTP %d - %s", httpStatus, traktError)
-		}
-		if traktErrorDesc != "" {
-			errorDetail = fmt.Sprintf("%s (%s)", errorDetail, traktErrorDesc)
+	result, tokenErr := authRequestFunc(r.Context(), redirectURI, "", code, "", "authorization_code")
+	if tokenErr != nil {
+		httpStatus := tokenErr.HTTPStatus
+		traktError := tokenErr.Code
+		if traktError == "" {
+			traktError = "unknown"
 		}
+		traktErrorDesc := tokenErr.Description
+		errorDetail := "Trakt token exchange failed: " + tokenErr.Error()
 
 		userError := "Trakt authorization failed. Please try again."
 		if traktError == "invalid_grant" {
@@ -927,10 +1452,8 @@ func authorizeFamilyMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract tokens
-	accessToken, accessOK := result["access_token"].(string)
-	refreshToken, refreshOK := result["refresh_token"].(string)
-	if !accessOK || !refreshOK || accessToken == "" || refreshToken == "" {
+	accessToken, refreshToken := result.AccessToken, result.RefreshToken
+	if accessToken == "" || refreshToken == "" {
 		slog.Error("family member auth: missing tokens", "member_id", memberID, "label", memberState.TempLabel)
 		redirectWith(map[string]string{
 			"result":    "error",
@@ -1096,18 +1619,98 @@ func authorizeFamilyMember(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-// calculateTokenExpiry extracts the expires_in value from Trakt OAuth response
-// and calculates the expiration time. Defaults to 3 months if not provided.
-func calculateTokenExpiry(oauthResult map[string]interface{}) time.Time {
-	// Try to get expires_in from the OAuth response
-	if expiresIn, ok := oauthResult["expires_in"].(float64); ok && expiresIn > 0 {
-		return time.Now().Add(time.Duration(expiresIn) * time.Second)
+// calculateTokenExpiry calculates the expiration time from the created_at and
+// expires_in values of a Trakt OAuth response, falling back to the local clock
+// when created_at is missing. Defaults to 3 months if expires_in is missing.
+func calculateTokenExpiry(token *trakt.TokenResponse) time.Time {
+	if expiry := token.ExpiresAt(time.Now()); !expiry.IsZero() {
+		return expiry
 	}
 
 	// Default to 3 months (Trakt tokens typically last 3 months)
 	return time.Now().Add(90 * 24 * time.Hour)
 }
 
+// tokenRefreshWindow is how long before expiry webhook handling refreshes a token.
+const tokenRefreshWindow = 48 * time.Hour
+
+// refreshUserToken refreshes a user's Trakt token, running at most one refresh
+// per user at a time. Trakt rotates the refresh token on every use, so two
+// parallel refreshes (e.g. webhooks from several players) would otherwise race
+// and the losing write could persist an already-invalidated refresh token.
+//
+// Inside the flight the stored record is re-read: if another request already
+// rotated the token it is reused instead of refreshing again, and the new
+// tokens are only written if the stored refresh token is still the one used.
+func refreshUserToken(ctx context.Context, redirectURI string, user *store.User) (*store.User, error) {
+	v, err, shared := refreshSf.Do(user.ID, func() (any, error) {
+		current := storage.GetUser(user.ID)
+		if current == nil {
+			current = user
+		}
+		if current.RefreshToken != user.RefreshToken && time.Until(current.TokenExpiry) >= tokenRefreshWindow {
+			slog.Info("token refresh skipped, already refreshed", "username", current.Username, "plaxt_id", current.ID)
+			return current, nil
+		}
+
+		slog.Info("token refresh request", "username", current.Username, "plaxt_id", current.ID, "time_until_expiry", time.Until(current.TokenExpiry))
+		// Finish the refresh even if the triggering request goes away: once
+		// Trakt has rotated the token, dropping the response bricks the account.
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		token, err := traktSrv.RefreshToken(refreshCtx, current.RefreshToken, redirectURI)
+		if err != nil {
+			slog.Warn("token refresh failed", "username", current.Username, "plaxt_id", current.ID, "error", err)
+			return nil, err
+		}
+		tokenExpiry := token.ExpiresAt
+		if tokenExpiry.IsZero() {
+			// Default to 3 months (Trakt tokens typically last 3 months)
+			tokenExpiry = time.Now().Add(90 * 24 * time.Hour)
+		}
+
+		// Check-and-set against the version read above: a manual renewal or
+		// an admin edit may have landed while the refresh was in flight.
+		for attempt := 0; ; attempt++ {
+			updated := *current
+			updated.AccessToken = token.AccessToken
+			updated.RefreshToken = token.RefreshToken
+			updated.TokenExpiry = tokenExpiry
+			updated.TokenIssuedAt = token.IssuedAt
+			updated.Updated = time.Now()
+			err := storage.CompareAndSwapUser(refreshCtx, updated)
+			if err == nil {
+				updated.Version++
+				slog.Info("token refresh success", "username", updated.Username, "plaxt_id", updated.ID, "new_expiry", tokenExpiry)
+				return &updated, nil
+			}
+			if !errors.Is(err, store.ErrUserVersionConflict) || attempt >= 2 {
+				slog.Error("token refresh could not be stored", "username", current.Username, "plaxt_id", current.ID, "error", err)
+				return nil, err
+			}
+			latest := storage.GetUser(current.ID)
+			if latest == nil {
+				return nil, store.ErrUserNotFound
+			}
+			if latest.RefreshToken != current.RefreshToken {
+				// Never overwrite tokens stored by a concurrent renewal.
+				slog.Warn("token refresh result discarded, record changed concurrently", "username", current.Username, "plaxt_id", current.ID)
+				return latest, nil
+			}
+			// Unrelated edit (e.g. admin rename): keep it and retry with our tokens.
+			current = latest
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	refreshed := *v.(*store.User)
+	if shared {
+		slog.Debug("token refresh shared", "plaxt_id", user.ID)
+	}
+	return &refreshed, nil
+}
+
 func authorize(w http.ResponseWriter, r *http.Request) {
 	args := r.URL.Query()
 	stateToken := strings.TrimSpace(args.Get("state"))
@@ -1246,36 +1849,15 @@ func authorize(w http.ResponseWriter, r *http.Request) {
 	}
 	redirectURI := root + callbackPath
 
-	result, ok := authRequestFunc(redirectURI, username, code, "", "authorization_code")
-	if !ok {
-		// Extract detailed error information from result map
-		httpStatus := 0
-		if statusVal, exists := result["http_status"]; exists {
-			if statusInt, ok := statusVal.(int); ok {
-				httpStatus = statusInt
-			}
-		}
-		traktError := "unknown"
-		if errVal, exists := result["error"]; exists {
-			if errStr, ok := errVal.(string); ok && errStr != "" {
-				traktError = errStr
-			}
-		}
-		traktErrorDesc := ""
-		if descVal, exists := result["error_description"]; exists {
-			if descStr, ok := descVal.(string); ok && descStr != "" {
-				traktErrorDesc = descStr
-			}
-		}
-
-		// Build detailed error message for logs
-		errorDetail := fmt.Sprintf("Trakt token exchange failed: %s", traktError)
-		if httpStatus != 0 {
-			errorDetail = fmt.Sprintf("Trakt token exchange failed: HTTP %d - %s", httpStatus, traktError)
-		}
-		if traktErrorDesc != "" {
-			errorDetail = fmt.Sprintf("%s (%s)", errorDetail, traktErrorDesc)
+	result, tokenErr := authRequestFunc(r.Context(), redirectURI, username, code, "", "authorization_code")
+	if tokenErr != nil {
+		httpStatus := tokenErr.HTTPStatus
+		traktError := tokenErr.Code
+		if traktError == "" {
+			traktError = "unknown"
 		}
+		traktErrorDesc := tokenErr.Description
+		errorDetail := "Trakt token exchange failed: " + tokenErr.Error()
 
 		// Build user-friendly error message
 		userError := "Trakt token exchange failed. Please try again."
@@ -1311,9 +1893,8 @@ func authorize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, accessOK := result["access_token"].(string)
-	refreshToken, refreshOK := result["refresh_token"].(string)
-	if !accessOK || !refreshOK || accessToken == "" || refreshToken == "" {
+	accessToken, refreshToken := result.AccessToken, result.RefreshToken
+	if accessToken == "" || refreshToken == "" {
 		if mode == "renew" && correlationID != "" {
 			slog.Error("manual renewal trakt response missing tokens", "correlation_id", correlationID, "username", username, "plaxt_id", existingID)
 		} else {
@@ -1363,7 +1944,7 @@ func authorize(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tokenExpiry := calculateTokenExpiry(result)
-	user, reused, persistErr := persistAuthorizedUser(username, existingID, accessToken, refreshToken, displayNamePointer, tokenExpiry)
+	user, reused, persistErr := persistAuthorizedUser(username, existingID, accessToken, refreshToken, displayNamePointer, tokenExpiry, result.IssuedAt(time.Now()))
 	if persistErr != nil {
 		errMessage := ""
 		switch persistErr {
@@ -1417,6 +1998,10 @@ func authorize(w http.ResponseWriter, r *http.Request) {
 	if displayNamePrompt {
 		params["display_name_missing"] = "1"
 	}
+	if simklClient != nil {
+		// Only someone who just authorized this user with Trakt may link Simkl
+		params["simkl_state"] = authStates.Create(authState{Mode: simkl.ProviderName, Username: user.Username, SelectedID: user.ID})
+	}
 	if displayNameWarning == "truncated" {
 		if mode == "renew" && correlationID != "" {
 			slog.Info("display name truncated", "correlation_id", correlationID, "username", username, "plaxt_id", user.ID)
@@ -1452,7 +2037,7 @@ func authorize(w http.ResponseWriter, r *http.Request) {
 	redirectWith(params)
 }
 
-func persistAuthorizedUser(username, existingID, accessToken, refreshToken string, displayName *string, tokenExpiry time.Time) (*store.User, bool, error) {
+func persistAuthorizedUser(username, existingID, accessToken, refreshToken string, displayName *string, tokenExpiry, tokenIssuedAt time.Time) (*store.User, bool, error) {
 	if existingID != "" {
 		existing := storage.GetUser(existingID)
 		if existing == nil {
@@ -1476,20 +2061,17 @@ func persistAuthorizedUser(username, existingID, accessToken, refreshToken strin
 		}
 
 		existing.Username = inputUsername
-		existing.UpdateUser(accessToken, refreshToken, displayName, tokenExpiry)
+		existing.UpdateUser(accessToken, refreshToken, displayName, tokenExpiry, tokenIssuedAt)
 		return existing, true, nil
 	}
 	normalized := strings.ToLower(strings.TrimSpace(username))
-	newUser := store.NewUser(normalized, accessToken, refreshToken, displayName, tokenExpiry, storage)
+	newUser := store.NewUser(normalized, accessToken, refreshToken, displayName, tokenExpiry, tokenIssuedAt, storage)
 	return &newUser, false, nil
 }
 
 func renderLandingPage(w http.ResponseWriter, r *http.Request) {
 	page := prepareAuthorizePage(r)
-	tmpl := template.Must(template.New("index.html").Funcs(templateFuncs).ParseFiles("static/index.html"))
-	if err := tmpl.Execute(w, page); err != nil {
-		slog.Error("failed to render landing page", "error", err)
-	}
+	renderTemplate(w, "index.html", page)
 }
 
 func prepareAuthorizePage(r *http.Request) AuthorizePage {
@@ -1511,14 +2093,73 @@ func prepareAuthorizePage(r *http.Request) AuthorizePage {
 	manual := buildManualContext(root, manualUsers, query, mode)
 	family := buildFamilyContext(root, query)
 
-	return AuthorizePage{
-		SelfRoot:   root,
-		ClientID:   clientID,
-		Mode:       mode,
-		Onboarding: onboarding,
-		Manual:     manual,
-		Family:     family,
+	page := AuthorizePage{
+		SelfRoot:    root,
+		ClientID:    clientID,
+		Mode:        mode,
+		Onboarding:  onboarding,
+		Manual:      manual,
+		Family:      family,
+		TokenHealth: buildTokenHealthContext(query),
+	}
+	if token := strings.TrimSpace(query.Get("simkl_state")); token != "" && simklClient != nil {
+		if state, ok := authStates.Get(token); ok && state.Mode == simkl.ProviderName {
+			page.SimklLinkURL = "/simkl/authorize?state=" + url.QueryEscape(token)
+		}
+	}
+	return page
+}
+
+// tokenHealthStatus classifies a token expiry the same way for the admin
+// dashboard and the landing page.
+func tokenHealthStatus(expiry time.Time) string {
+	timeUntilExpiry := time.Until(expiry)
+	if timeUntilExpiry < 0 {
+		return "expired"
+	}
+	if timeUntilExpiry < 48*time.Hour { // Warn 2 days before expiry
+		return "warning"
+	}
+	return "healthy"
+}
+
+func buildTokenHealthContext(query url.Values) *TokenHealthContext {
+	id := strings.TrimSpace(query.Get("id"))
+	if id == "" || storage == nil {
+		return nil
+	}
+	user := storage.GetUser(id)
+	if user == nil {
+		return nil
+	}
+
+	health := &TokenHealthContext{
+		UserID:   user.ID,
+		Username: user.Username,
+		Status:   tokenHealthStatus(user.TokenExpiry),
+	}
+	if user.TokenExpiry.IsZero() {
+		health.ExpiryLabel = "unknown"
+		return health
+	}
+	health.ExpiresAt = user.TokenExpiry.UTC().Format(time.RFC3339)
+	health.ExpiryLabel = displayDates.DateTime(user.TokenExpiry)
+	if remaining := time.Until(user.TokenExpiry); remaining > 0 {
+		health.Remaining = formatRemaining(remaining)
+	}
+	return health
+}
+
+// formatRemaining renders a positive duration as "3d 4h" or "5h 12m" for the
+// server-rendered countdown; the page script takes over once it loads.
+func formatRemaining(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, hours)
 	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
 }
 
 func buildManualUsers(root string) []ManualUser {
@@ -1530,7 +2171,7 @@ func buildManualUsers(root string) []ManualUser {
 	for _, u := range storedUsers {
 		refreshed := "unknown"
 		if !u.Updated.IsZero() {
-			refreshed = u.Updated.UTC().Format("2006-01-02 15:04 MST")
+			refreshed = displayDates.DateTime(u.Updated)
 		}
 		displayName := strings.TrimSpace(u.TraktDisplayName)
 		display := u.Username
@@ -1934,24 +2575,95 @@ func updateTraktDisplayName(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleFamilyWebhook processes Plex webhooks for family groups by broadcasting to all members.
-// Implements FR-008 (broadcast scrobbling) and FR-008a (retry queueing).
-func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexhooks.Webhook, familyGroup *store.FamilyGroup) {
-	ctx := r.Context()
-	plexUsername := strings.ToLower(webhook.Account.Title)
+// plexWebhookTargetExists reports whether id is a user or family group a
+// webhook can point at.
+func plexWebhookTargetExists(ctx context.Context, id string) bool {
+	if storage.GetUser(id) != nil {
+		return true
+	}
+	group, err := storage.GetFamilyGroup(ctx, id)
+	return err == nil && group != nil
+}
 
-	// Load all authorized group members
-	members, err := storage.ListGroupMembers(ctx, familyGroup.ID)
-	if err != nil {
-		slog.Error("family webhook: failed to list members",
-			"group_id", familyGroup.ID,
-			"plex_username", plexUsername,
-			"error", err,
-		)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to load family members"})
+// registerPlexWebhook adds the webhook for a user or family group to the Plex
+// account owning the posted X-Plex-Token, so the wizard's copy-and-paste step
+// becomes optional. The token is kept so startPlexWebhookVerifier can re-add
+// the webhook if it disappears.
+func registerPlexWebhook(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
 		return
 	}
+	ctx := r.Context()
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if !plexWebhookTargetExists(ctx, id) {
+		writeJSONError(w, http.StatusNotFound, "user or family group not found")
+		return
+	}
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&payload); err != nil || strings.TrimSpace(payload.Token) == "" {
+		writeJSONError(w, http.StatusBadRequest, "a Plex token is required")
+		return
+	}
+	reg := &store.PlexWebhook{
+		ID:         id,
+		Token:      payload.Token,
+		WebhookURL: fmt.Sprintf("%s/api?id=%s", SelfRoot(r), id),
+	}
+	if existing, err := storage.GetPlexWebhook(ctx, id); err == nil {
+		reg.CreatedAt = existing.CreatedAt
+	}
+
+	added, err := plexClient.EnsureWebhook(ctx, strings.TrimSpace(reg.Token), reg.WebhookURL)
+	switch {
+	case errors.Is(err, plex.ErrUnauthorized):
+		writeJSONError(w, http.StatusBadRequest, "Plex rejected the token")
+		return
+	case errors.Is(err, plex.ErrPlexPassRequired):
+		writeJSONError(w, http.StatusBadRequest, "Plex webhooks require a Plex Pass subscription")
+		return
+	case err != nil:
+		slog.Warn("plex webhook registration failed", "plaxt_id", id, "error", err)
+		writeJSONError(w, http.StatusBadGateway, "could not reach Plex")
+		return
+	}
+
+	now := time.Now()
+	reg.VerifiedAt, reg.UpdatedAt = now, now
+	if err := storage.PutPlexWebhook(ctx, reg); err != nil {
+		slog.Error("plex webhook save failed", "plaxt_id", id, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "webhook registered but the Plex token could not be saved")
+		return
+	}
+	result := "already_registered"
+	if added {
+		result = "registered"
+	}
+	slog.Info("plex webhook registered", "plaxt_id", id, "added", added)
+	writeJSON(w, http.StatusOK, map[string]string{"result": result, "webhook_url": reg.WebhookURL})
+}
+
+// handleFamilyWebhook processes Plex webhooks for family groups by broadcasting to all members.
+// Implements FR-008 (broadcast scrobbling) and FR-008a (retry queueing).
+// It returns the outcome the webhook is counted under in the group's stats.
+func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexhooks.Webhook, familyGroup *store.FamilyGroup) store.WebhookOutcome {
+	ctx := r.Context()
+	plexUsername := strings.ToLower(webhook.Account.Title)
+
+	// Load all authorized group members
+	members, err := storage.ListGroupMembers(ctx, familyGroup.ID)
+	if err != nil {
+		slog.Error("family webhook: failed to list members",
+			"group_id", familyGroup.ID,
+			"plex_username", plexUsername,
+			"error", err,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to load family members"})
+		return store.WebhookFailed
+	}
 
 	// Filter to authorized members only
 	authorizedMembers := make([]*store.GroupMember, 0, len(members))
@@ -1968,7 +2680,7 @@ func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexho
 		)
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(map[string]string{"result": "no_authorized_members"})
-		return
+		return store.WebhookSkipped
 	}
 
 	// Generate event ID for tracking (FR-008b)
@@ -1984,7 +2696,7 @@ func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexho
 		)
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(map[string]string{"result": "not_scrobblable"})
-		return
+		return store.WebhookSkipped
 	}
 
 	// Extract media title for logging
@@ -1996,7 +2708,7 @@ func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexho
 		"plex_username", plexUsername,
 		"event", webhook.Event,
 		"action", action,
-		"media_title", mediaTitle,
+		"media_title", logging.Redact(familyGroup.ID, mediaTitle),
 		"member_count", len(authorizedMembers),
 	)
 
@@ -2016,41 +2728,40 @@ func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexho
 			if berr.IsRetryable() {
 				// Queue for retry with exponential backoff
 				queueItem := &store.RetryQueueItem{
-					ID:             generateCorrelationID(),
-					FamilyGroupID:  familyGroup.ID,
-					GroupMemberID:  berr.Member.ID,
-					Payload:        mustMarshalJSON(scrobbleBody),
-					AttemptCount:   0,
-					NextAttemptAt:  time.Now().Add(30 * time.Second), // Initial backoff
-					LastError:      berr.Err.Error(),
-					Status:         store.RetryQueueStatusQueued,
-					CreatedAt:      time.Now(),
-					UpdatedAt:      time.Now(),
+					ID:            generateCorrelationID(),
+					FamilyGroupID: familyGroup.ID,
+					GroupMemberID: berr.Member.ID,
+					Payload:       mustMarshalJSON(scrobbleBody),
+					AttemptCount:  0,
+					NextAttemptAt: time.Now().Add(familyRetryDelay),
+					LastError:     berr.Err.Error(),
+					Status:        store.RetryQueueStatusQueued,
+					CreatedAt:     time.Now(),
+					UpdatedAt:     time.Now(),
+				}
+				if err := queue.NewPostgresRepo(storage).Enqueue(ctx, queueItem); err != nil {
+					slog.Error("family webhook: failed to enqueue retry",
+						"event_id", eventID,
+						"member_id", berr.Member.ID,
+						"error", err,
+					)
+					continue
 				}
 
-				// Note: Queue repository integration deferred (T019)
-				// For now, log the retry event
 				slog.Warn("family webhook: scrobble queued for retry",
 					"event_id", eventID,
 					"member_id", berr.Member.ID,
 					"trakt_username", berr.Member.TraktUsername,
-					"media_title", mediaTitle,
+					"media_title", logging.Redact(berr.Member.ID, mediaTitle),
 					"error", berr.Err.Error(),
 				)
-
-				// TODO: Uncomment when worker is integrated
-				// queueRepo := queue.NewPostgresRepo(storage)
-				// if err := queueRepo.Enqueue(ctx, queueItem); err != nil {
-				//     slog.Error("failed to enqueue retry", "event_id", eventID, "member_id", berr.Member.ID, "error", err)
-				// }
-				_ = queueItem // Suppress unused variable warning
 			} else {
 				// Permanent failure - log only
 				slog.Error("family webhook: scrobble permanent failure",
 					"event_id", eventID,
 					"member_id", berr.Member.ID,
 					"trakt_username", berr.Member.TraktUsername,
-					"media_title", mediaTitle,
+					"media_title", logging.Redact(berr.Member.ID, mediaTitle),
 					"error", berr.Err.Error(),
 				)
 			}
@@ -2067,6 +2778,10 @@ func handleFamilyWebhook(w http.ResponseWriter, r *http.Request, webhook *plexho
 		"members_success": successCount,
 		"members_failed":  len(broadcastErrors),
 	})
+	if successCount == 0 {
+		return store.WebhookFailed
+	}
+	return store.WebhookProcessed
 }
 
 // extractMediaTitleFromScrobble extracts a human-readable title from ScrobbleBody.
@@ -2109,6 +2824,10 @@ func api(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if invalidIDs.isBanned(webhookSourceIP(r)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -2173,16 +2892,48 @@ func api(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	username := strings.ToLower(webhook.Account.Title)
+	annotateRequestLog(r.Context(), "plaxt_id", id, "event", webhook.Event)
+	webhookEvents.record(webhook.Event, payload)
 
-	// Check if this Plex username belongs to a family group (FR-007)
+	if !replayGuard.allow(webhook, r, id) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "stale webhook"})
+		return
+	}
+
+	// Count the webhook against its user or family group once that is known
+	// (GET /admin/api/users/{id}/metrics)
 	ctx := r.Context()
+	stat := &webhookStat{}
+	defer stat.record(ctx)
+
+	// Check if this Plex username belongs to a family group (FR-007)
+	var familyGroup *store.FamilyGroup
 	if storage != nil {
-		familyGroup, err := storage.GetFamilyGroupByPlex(ctx, username)
-		if err == nil && familyGroup != nil {
-			// Route to family webhook handler
-			handleFamilyWebhook(w, r, webhook, familyGroup)
-			return
+		if group, err := storage.GetFamilyGroupByPlex(ctx, username); err == nil && group != nil {
+			familyGroup = group
+			stat.track(store.WebhookSubjectFamilyGroup, group.ID)
+		}
+	}
+
+	if reason := extraWebhookReason(webhook.Metadata); reason != "" {
+		webhookEvents.skip(reason)
+		if familyGroup == nil && storage != nil && storage.GetUser(id) != nil {
+			stat.track(store.WebhookSubjectUser, id)
 		}
+		stat.outcome = store.WebhookSkipped
+		slog.Debug("webhook skipped: not a library item", "reason", reason, "event", webhook.Event, "title", logging.Redact(id, webhook.Metadata.Title), "id", id)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "skipped", "reason": reason})
+		return
+	}
+
+	if familyGroup != nil {
+		// Route to family webhook handler
+		annotateRequestLog(ctx, "family_group_id", familyGroup.ID, "username", familyGroup.PlexUsername)
+		stat.outcome = handleFamilyWebhook(w, r, webhook, familyGroup)
+		return
 	}
 
 	// Handle the requests of the same user one at a time
@@ -2190,7 +2941,7 @@ func api(w http.ResponseWriter, r *http.Request) {
 	userInf, err, _ := apiSf.Do(key, func() (any, error) {
 		user := storage.GetUser(id)
 		if user == nil {
-			slog.Warn("invalid id", "id", id)
+			invalidIDs.strike(webhookSourceIP(r), id)
 			return nil, trakt.NewHttpError(http.StatusForbidden, "id is invalid")
 		}
 		if webhook.Owner && username != user.Username {
@@ -2203,44 +2954,71 @@ func api(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Check if token is near expiration (refresh 2 days before expiry)
-		timeUntilExpiry := time.Until(user.TokenExpiry)
-		if timeUntilExpiry < 48*time.Hour {
-			slog.Info("token refresh request", "username", user.Username, "plaxt_id", user.ID, "time_until_expiry", timeUntilExpiry)
-			redirectURI := SelfRoot(r) + "/authorize"
-			result, success := traktSrv.AuthRequest(redirectURI, user.Username, "", user.RefreshToken, "refresh_token")
-			if success {
-				tokenExpiry := calculateTokenExpiry(result)
-				user.UpdateUser(result["access_token"].(string), result["refresh_token"].(string), nil, tokenExpiry)
-				slog.Info("token refresh success", "username", user.Username, "plaxt_id", user.ID, "new_expiry", tokenExpiry)
-			} else {
-				slog.Warn("token refresh failed", "username", user.Username, "plaxt_id", user.ID)
+		if time.Until(user.TokenExpiry) < tokenRefreshWindow {
+			refreshed, refreshErr := refreshUserToken(ctx, SelfRoot(r)+"/authorize", user)
+			if refreshErr != nil {
 				// Do not delete user on transient failure; return 401 so caller can retry later
 				return nil, trakt.NewHttpError(http.StatusUnauthorized, "fail")
 			}
+			user = refreshed
 		}
 		return user, nil
 	})
 	if err != nil {
+		if code := err.(trakt.HttpError).Code; code != http.StatusForbidden {
+			// The ID is valid; the user was not found or could not be refreshed
+			stat.track(store.WebhookSubjectUser, id)
+			stat.outcome = store.WebhookFailed
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(err.(trakt.HttpError).Code)
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 	user := userInf.(*store.User)
+	annotateRequestLog(ctx, "username", user.Username)
+	stat.track(store.WebhookSubjectUser, id)
 
 	// Check for duplicate scrobble to same Trakt account
 	if !webhookCache.shouldProcess(id, user.TraktDisplayName, webhook.Event, webhook.Metadata.RatingKey, webhook.Metadata.ViewOffset) {
-		slog.Debug("webhook duplicate filtered", "event", webhook.Event, "username", username, "id", id, "trakt_display_name", user.TraktDisplayName, "rating_key", webhook.Metadata.RatingKey)
+		stat.outcome = store.WebhookSkipped
+		slog.Debug("webhook duplicate filtered", "event", webhook.Event, "username", username, "id", id, "trakt_display_name", logging.Redact(user.ID, user.TraktDisplayName), "rating_key", webhook.Metadata.RatingKey)
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{"result": "duplicate_filtered"})
 		return
 	}
 
-	slog.Info("webhook received", "event", webhook.Event, "username", username, "id", id, "type", strings.ToLower(webhook.Metadata.Type), "title", webhook.Metadata.Title, "show", webhook.Metadata.GrandparentTitle, "season", webhook.Metadata.ParentIndex, "episode", webhook.Metadata.Index, "server", webhook.Server.Title, "client", webhook.Player.Title)
+	prefs := loadUserPreferences(ctx, user.ID)
+	slog.Info("webhook received", "event", webhook.Event, "username", username, "id", id, "type", strings.ToLower(webhook.Metadata.Type), "title", logging.Redact(user.ID, webhook.Metadata.Title), "show", logging.Redact(user.ID, webhook.Metadata.GrandparentTitle), "season", webhook.Metadata.ParentIndex, "episode", webhook.Metadata.Index, "server", webhook.Server.Title, "client", webhook.Player.Title)
+
+	if reason := prefs.SkipReason(webhook.Metadata); reason != "" {
+		webhookEvents.skip("user_" + reason)
+		stat.outcome = store.WebhookSkipped
+		slog.Info("webhook skipped by user preferences", "reason", reason, "username", user.Username, "id", id)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "skipped", "reason": "user_" + reason})
+		return
+	}
 
 	if username == user.Username {
-		traktSrv.Handle(webhook, *user)
+		// Parse before Handle updates the scrobble cache so secondary
+		// providers see the same action Trakt does
+		var secondaryBody common.ScrobbleBody
+		var secondaryAction string
+		secondaryOK := false
+		if len(providers.Secondary()) > 0 {
+			secondaryBody, secondaryAction, secondaryOK = traktSrv.ParseWebhookForScrobble(webhook)
+		}
+		traktSrv.Handle(ctx, webhook, *user)
+		if secondaryOK {
+			dispatchSecondaryScrobbles(ctx, *user, secondaryAction, common.CacheItem{
+				PlayerUuid: webhook.Player.UUID,
+				RatingKey:  webhook.Metadata.RatingKey,
+				Body:       secondaryBody,
+			})
+		}
 	} else {
+		stat.outcome = store.WebhookSkipped
 		slog.Info("username mismatch; skipping", "plex_username", strings.ToLower(webhook.Account.Title), "plaxt_username", user.Username)
 	}
 
@@ -2334,8 +3112,32 @@ type adminUserResponse struct {
 	TraktDisplayName string    `json:"trakt_display_name"`
 	WebhookURL       string    `json:"webhook_url"`
 	Updated          time.Time `json:"updated"`
-	TokenAge         float64   `json:"token_age_hours"`
-	Status           string    `json:"status"` // "healthy", "warning", "expired"
+	TokenAge         float64    `json:"token_age_hours"`
+	TokenIssuedAt    *time.Time `json:"token_issued_at,omitempty"`
+	TokenExpiry      time.Time  `json:"token_expiry"`
+	Status           string     `json:"status"` // "healthy", "warning", "expired"
+	Version          int64      `json:"version"`
+}
+
+// newAdminUserResponse builds the admin view of a user. Token age is only
+// known for tokens issued since Trakt's created_at has been stored.
+func newAdminUserResponse(root string, user store.User, status string) adminUserResponse {
+	resp := adminUserResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		TraktDisplayName: user.TraktDisplayName,
+		WebhookURL:       fmt.Sprintf("%s/api?id=%s", root, user.ID),
+		Updated:          user.Updated,
+		TokenExpiry:      user.TokenExpiry,
+		Status:           status,
+		Version:          user.Version,
+	}
+	if !user.TokenIssuedAt.IsZero() {
+		issued := user.TokenIssuedAt
+		resp.TokenIssuedAt = &issued
+		resp.TokenAge = time.Since(issued).Hours()
+	}
+	return resp
 }
 
 // listAdminUsers returns a list of all users with their status
@@ -2350,31 +3152,245 @@ func listAdminUsers(w http.ResponseWriter, r *http.Request) {
 	root := SelfRoot(r)
 
 	for _, user := range users {
-		// Calculate time until expiry (can be negative if already expired)
-		timeUntilExpiry := time.Until(user.TokenExpiry)
-		status := "healthy"
-
-		if timeUntilExpiry < 0 {
-			status = "expired"
-		} else if timeUntilExpiry < 48*time.Hour { // Warn 2 days before expiry
-			status = "warning"
-		}
-
-		response = append(response, adminUserResponse{
-			ID:               user.ID,
-			Username:         user.Username,
-			TraktDisplayName: user.TraktDisplayName,
-			WebhookURL:       fmt.Sprintf("%s/api?id=%s", root, user.ID),
-			Updated:          user.Updated,
-			TokenAge:         0, // Will be removed from UI
-			Status:           status,
-		})
+		response = append(response, newAdminUserResponse(root, user, tokenHealthStatus(user.TokenExpiry)))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// adminStatsResponse summarises the instance for the admin dashboard cards.
+type adminStatsResponse struct {
+	TotalUsers    int       `json:"total_users"`
+	HealthyTokens int       `json:"healthy_tokens"`
+	WarningTokens int       `json:"warning_tokens"`
+	ExpiredTokens int       `json:"expired_tokens"`
+	Scrobbles24h  int       `json:"scrobbles_24h"`
+	Scrobbles7d   int       `json:"scrobbles_7d"`
+	QueueDepth    int       `json:"queue_depth"`
+	DrainMode     string    `json:"drain_mode"` // "live" or "queue"
+	DrainActive   bool      `json:"drain_active"`
+	GeneratedAt   time.Time `json:"generated_at"`
+}
+
+// getAdminStats returns summary totals for the admin dashboard.
+func getAdminStats(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now()
+	users := storage.ListUsers()
+	response := adminStatsResponse{
+		TotalUsers:  len(users),
+		DrainMode:   drainStateTracker.GetMode(),
+		DrainActive: len(drainStateTracker.GetAllActiveUsers()) > 0,
+		GeneratedAt: now.UTC(),
+	}
+	for _, user := range users {
+		switch tokenHealthStatus(user.TokenExpiry) {
+		case "expired":
+			response.ExpiredTokens++
+		case "warning":
+			response.WarningTokens++
+		default:
+			response.HealthyTokens++
+		}
+	}
+
+	buckets, err := storage.ListActivity(ctx, now.Add(-7*24*time.Hour), now)
+	if err != nil {
+		slog.Error("failed to list activity", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	dayStart := store.ActivityBucketStart(now.Add(-24 * time.Hour))
+	for _, bucket := range buckets {
+		response.Scrobbles7d += bucket.Scrobbles
+		if !bucket.Start.Before(dayStart) {
+			response.Scrobbles24h += bucket.Scrobbles
+		}
+	}
+	if response.QueueDepth, err = storage.TotalQueueSize(ctx); err != nil {
+		slog.Error("failed to read queue depth", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// adminActivityResponse is a zero-filled activity series for the dashboard chart.
+type adminActivityResponse struct {
+	From          time.Time              `json:"from"`
+	To            time.Time              `json:"to"`
+	BucketSeconds int64                  `json:"bucket_seconds"`
+	Buckets       []store.ActivityBucket `json:"buckets"`
+	Totals        store.ActivityCounts   `json:"totals"`
+}
+
+// parseActivityDuration accepts Go durations plus a whole-day "7d" form.
+func parseActivityDuration(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// getAdminActivity returns scrobbles, failures and queued events per time
+// bucket. Query params: range (default 24h, at most 7d) and bucket (whole
+// hours, default 1h).
+func getAdminActivity(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+
+	query := r.URL.Query()
+	window := 24 * time.Hour
+	if v := strings.TrimSpace(query.Get("range")); v != "" {
+		d, err := parseActivityDuration(v)
+		if err != nil || d < time.Hour || d > 7*24*time.Hour {
+			writeJSONError(w, http.StatusBadRequest, "range must be between 1h and 7d")
+			return
+		}
+		window = d
+	}
+	step := time.Hour
+	if v := strings.TrimSpace(query.Get("bucket")); v != "" {
+		d, err := parseActivityDuration(v)
+		if err != nil || d < time.Hour || d%time.Hour != 0 || d > window {
+			writeJSONError(w, http.StatusBadRequest, "bucket must be a whole number of hours no larger than range")
+			return
+		}
+		step = d
+	}
+
+	now := time.Now()
+	span := window.Truncate(step)
+	// Align the series so the last bucket ends with the current hour
+	from := store.ActivityBucketStart(now).Add(time.Hour - span)
+
+	hourly, err := storage.ListActivity(r.Context(), from, now)
+	if err != nil {
+		slog.Error("failed to list activity", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load activity")
+		return
+	}
+
+	count := int(span / step)
+	response := adminActivityResponse{
+		From:          from,
+		To:            now.UTC(),
+		BucketSeconds: int64(step / time.Second),
+		Buckets:       make([]store.ActivityBucket, count),
+	}
+	for i := range response.Buckets {
+		response.Buckets[i].Start = from.Add(time.Duration(i) * step)
+	}
+	for _, bucket := range hourly {
+		i := int(bucket.Start.Sub(from) / step)
+		if i < 0 || i >= count {
+			continue
+		}
+		response.Buckets[i].Merge(bucket.ActivityCounts)
+		response.Totals.Merge(bucket.ActivityCounts)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// adminWebhookMetricsResponse is a zero-filled daily series of the webhooks
+// received for one user or family group.
+type adminWebhookMetricsResponse struct {
+	Subject store.WebhookSubject    `json:"subject"`
+	ID      string                  `json:"id"`
+	From    time.Time               `json:"from"`
+	To      time.Time               `json:"to"`
+	Days    []store.WebhookStatsDay `json:"days"`
+	Totals  store.WebhookCounts     `json:"totals"`
+}
+
+// getUserWebhookMetrics returns the daily webhook counters of a user, so an
+// admin can tell whether the user's webhooks reach plaxt at all.
+func getUserWebhookMetrics(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if storage.GetUser(id) == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	writeWebhookMetrics(w, r, store.WebhookSubjectUser, id)
+}
+
+// getFamilyGroupWebhookMetrics is getUserWebhookMetrics for a family group.
+func getFamilyGroupWebhookMetrics(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if _, err := storage.GetFamilyGroup(r.Context(), id); err != nil {
+		writeJSONError(w, http.StatusNotFound, "family group not found")
+		return
+	}
+	writeWebhookMetrics(w, r, store.WebhookSubjectFamilyGroup, id)
+}
+
+// writeWebhookMetrics serves the counters of (subject, id) for the range
+// query param: whole days, default 7d, at most 30d.
+func writeWebhookMetrics(w http.ResponseWriter, r *http.Request, subject store.WebhookSubject, id string) {
+	window := 7 * 24 * time.Hour
+	if v := strings.TrimSpace(r.URL.Query().Get("range")); v != "" {
+		d, err := parseActivityDuration(v)
+		if err != nil || d < 24*time.Hour || d%(24*time.Hour) != 0 || d > 30*24*time.Hour {
+			writeJSONError(w, http.StatusBadRequest, "range must be a whole number of days between 1d and 30d")
+			return
+		}
+		window = d
+	}
+
+	now := time.Now()
+	// The last day is today (UTC)
+	from := store.WebhookDayStart(now).Add(24*time.Hour - window)
+	daily, err := storage.ListWebhookStats(r.Context(), subject, id, from, now)
+	if err != nil {
+		slog.Error("failed to list webhook stats", "subject", subject, "id", id, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load webhook metrics")
+		return
+	}
+
+	count := int(window / (24 * time.Hour))
+	response := adminWebhookMetricsResponse{
+		Subject: subject,
+		ID:      id,
+		From:    from,
+		To:      now.UTC(),
+		Days:    make([]store.WebhookStatsDay, count),
+	}
+	for i := range response.Days {
+		response.Days[i].Day = from.Add(time.Duration(i) * 24 * time.Hour)
+	}
+	for _, day := range daily {
+		i := int(day.Day.Sub(from) / (24 * time.Hour))
+		if i < 0 || i >= count {
+			continue
+		}
+		response.Days[i].Merge(day.WebhookCounts)
+		response.Totals.Merge(day.WebhookCounts)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
 // getAdminUser returns details for a specific user
 func getAdminUser(w http.ResponseWriter, r *http.Request) {
 	if storage == nil {
@@ -2396,24 +3412,7 @@ func getAdminUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	root := SelfRoot(r)
-	timeUntilExpiry := time.Until(user.TokenExpiry)
-	status := "healthy"
-
-	if timeUntilExpiry < 0 {
-		status = "expired"
-	} else if timeUntilExpiry < 48*time.Hour {
-		status = "warning"
-	}
-
-	response := adminUserResponse{
-		ID:               user.ID,
-		Username:         user.Username,
-		TraktDisplayName: user.TraktDisplayName,
-		WebhookURL:       fmt.Sprintf("%s/api?id=%s", root, user.ID),
-		Updated:          user.Updated,
-		TokenAge:         0, // Will be removed from UI
-		Status:           status,
-	}
+	response := newAdminUserResponse(root, *user, tokenHealthStatus(user.TokenExpiry))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -2442,6 +3441,9 @@ func updateAdminUser(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		Username         *string `json:"username"`
 		TraktDisplayName *string `json:"trakt_display_name"`
+		// Version is the value the client last read; when omitted the edit
+		// applies on top of the record loaded above.
+		Version *int64 `json:"version"`
 	}
 
 	body, err := io.ReadAll(r.Body)
@@ -2464,15 +3466,32 @@ func updateAdminUser(w http.ResponseWriter, r *http.Request) {
 		user.TraktDisplayName = strings.TrimSpace(*payload.TraktDisplayName)
 	}
 
-	// Save the updated user
-	storage.WriteUser(*user)
+	if payload.Version != nil {
+		user.Version = *payload.Version
+	}
+
+	// Save the updated user unless someone else changed it first
+	if err := storage.CompareAndSwapUser(r.Context(), *user); err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserVersionConflict):
+			slog.Warn("admin user update conflict", "id", id, "version", user.Version)
+			http.Error(w, "user was modified by another request; reload and try again", http.StatusConflict)
+		case errors.Is(err, store.ErrUserNotFound):
+			http.Error(w, "user not found", http.StatusNotFound)
+		default:
+			slog.Error("admin user update failed", "id", id, "error", err)
+			http.Error(w, "failed to update user", http.StatusInternalServerError)
+		}
+		return
+	}
 
-	slog.Info("admin user updated", "id", id, "username", user.Username, "display_name", user.TraktDisplayName)
+	slog.Info("admin user updated", "id", id, "username", user.Username, "display_name", logging.Redact(user.ID, user.TraktDisplayName))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "User updated successfully",
+		"version": user.Version + 1,
 	})
 }
 
@@ -2496,77 +3515,1310 @@ func deleteAdminUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep a restorable snapshot before anything is removed
+	entry, err := snapshotUser(r.Context(), user)
+	if err == nil {
+		err = storage.PutTrashEntry(r.Context(), entry)
+	}
+	if err != nil {
+		slog.Error("failed to move user to trash", "id", id, "error", err)
+		http.Error(w, "failed to move user to trash", http.StatusInternalServerError)
+		return
+	}
+
 	// Delete the user
 	if !storage.DeleteUser(id, user.Username) {
+		_ = storage.DeleteTrashEntry(r.Context(), id)
 		http.Error(w, "failed to delete user", http.StatusInternalServerError)
 		return
 	}
 
-	slog.Info("admin user deleted", "id", id, "username", user.Username)
+	slog.Info("admin user deleted", "id", id, "username", user.Username, "restorable_until", entry.ExpiresAt())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "User deleted successfully",
+		"success":          true,
+		"message":          "User moved to trash",
+		"restorable_until": entry.ExpiresAt(),
 	})
 }
 
-// Family Group Admin API Response Types
-type adminFamilyGroupResponse struct {
-	ID              string    `json:"id"`
-	PlexUsername    string    `json:"plex_username"`
-	MemberCount     int       `json:"member_count"`
-	AuthorizedCount int       `json:"authorized_count"`
-	WebhookURL      string    `json:"webhook_url"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+// ========== TRASH ==========
+
+// trashedUser is the payload of a user trash entry. Postgres cascades a user
+// delete to its queue, provider tokens and watch history, so those are kept
+// alongside the tokens.
+type trashedUser struct {
+	ID               string                      `json:"id"`
+	Username         string                      `json:"username"`
+	AccessToken      string                      `json:"access_token"`
+	RefreshToken     string                      `json:"refresh_token"`
+	TraktDisplayName string                      `json:"trakt_display_name,omitempty"`
+	Updated          time.Time                   `json:"updated"`
+	TokenExpiry      time.Time                   `json:"token_expiry"`
+	TokenIssuedAt    time.Time                   `json:"token_issued_at,omitempty"`
+	ProviderTokens   []store.ProviderToken       `json:"provider_tokens,omitempty"`
+	QueuedEvents     []store.QueuedScrobbleEvent `json:"queued_events,omitempty"`
+	WatchHistory     []store.WatchedMovie        `json:"watch_history,omitempty"`
+	Preferences      json.RawMessage             `json:"preferences,omitempty"`
 }
 
-type adminGroupMemberResponse struct {
+// trashedGroupMember mirrors store.GroupMember including the tokens, which
+// GroupMember hides from JSON.
+type trashedGroupMember struct {
 	ID                  string     `json:"id"`
-	FamilyGroupID       string     `json:"family_group_id"`
 	TempLabel           string     `json:"temp_label"`
 	TraktUsername       string     `json:"trakt_username,omitempty"`
-	AuthorizationStatus string     `json:"authorization_status"`
+	AccessToken         string     `json:"access_token,omitempty"`
+	RefreshToken        string     `json:"refresh_token,omitempty"`
 	TokenExpiry         *time.Time `json:"token_expiry,omitempty"`
+	AuthorizationStatus string     `json:"authorization_status"`
 	CreatedAt           time.Time  `json:"created_at"`
-	Status              string     `json:"status"` // "healthy", "warning", "expired", "pending", "failed"
 }
 
-type adminFamilyGroupDetailResponse struct {
-	*adminFamilyGroupResponse
-	Members []adminGroupMemberResponse `json:"members"`
+type trashedFamilyGroup struct {
+	Group   store.FamilyGroup    `json:"group"`
+	Members []trashedGroupMember `json:"members"`
 }
 
-// T031: List all family groups
-func listFamilyGroups(w http.ResponseWriter, r *http.Request) {
-	if storage == nil {
-		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
-		return
-	}
+// trashQueueSnapshotLimit bounds the queued events kept with a deleted user;
+// it matches the per-user queue cap.
+const trashQueueSnapshotLimit = 1000
 
-	ctx := r.Context()
-	groups, err := storage.ListFamilyGroups(ctx)
-	if err != nil {
-		slog.Error("failed to list family groups", "error", err)
-		http.Error(w, "failed to list family groups", http.StatusInternalServerError)
-		return
-	}
+// errTrashRestoreConflict is returned when the record to restore would clash
+// with one created after the delete.
+var errTrashRestoreConflict = errors.New("a record with the same id or name already exists")
 
-	response := make([]adminFamilyGroupResponse, 0, len(groups))
-	root := SelfRoot(r)
+type adminTrashResponse struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Label     string    `json:"label"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
 
-	for _, group := range groups {
-		members, err := storage.ListGroupMembers(ctx, group.ID)
+// snapshotUser captures everything needed to restore a user after DeleteUser.
+func snapshotUser(ctx context.Context, user *store.User) (*store.TrashEntry, error) {
+	snapshot := trashedUser{
+		ID:               user.ID,
+		Username:         user.Username,
+		AccessToken:      user.AccessToken,
+		RefreshToken:     user.RefreshToken,
+		TraktDisplayName: user.TraktDisplayName,
+		Updated:          user.Updated,
+		TokenExpiry:      user.TokenExpiry,
+		TokenIssuedAt:    user.TokenIssuedAt,
+	}
+	for _, p := range providers.Secondary() {
+		token, err := storage.GetProviderToken(ctx, user.ID, p.Name())
 		if err != nil {
-			slog.Warn("failed to list members for group", "group_id", group.ID, "error", err)
-			continue
-		}
-
-		authorizedCount := 0
-		for _, member := range members {
-			if member.AuthorizationStatus == "authorized" {
-				authorizedCount++
+			if !errors.Is(err, store.ErrProviderTokenNotFound) {
+				slog.Warn("trash: failed to read provider token", "id", user.ID, "provider", p.Name(), "error", err)
+			}
+			continue
+		}
+		snapshot.ProviderTokens = append(snapshot.ProviderTokens, *token)
+	}
+	events, err := storage.DequeueScrobbles(ctx, user.ID, trashQueueSnapshotLimit)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot queue: %w", err)
+	}
+	snapshot.QueuedEvents = events
+	history, err := storage.ListWatchedMovies(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot watch history: %w", err)
+	}
+	snapshot.WatchHistory = history
+	if prefs, err := storage.GetUserPreferences(ctx, user.ID); err == nil {
+		snapshot.Preferences = prefs.Data
+	} else if !errors.Is(err, store.ErrUserPreferencesNotFound) {
+		return nil, fmt.Errorf("snapshot preferences: %w", err)
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	entry := &store.TrashEntry{
+		ID:        user.ID,
+		Kind:      store.TrashKindUser,
+		Label:     user.Username,
+		DeletedAt: time.Now(),
+		Payload:   payload,
+	}
+	return entry, entry.Validate()
+}
+
+// restoreUser recreates a trashed user. Backends that do not cascade deletes
+// still hold the queue and history, so those are only re-added when missing.
+func restoreUser(ctx context.Context, entry *store.TrashEntry) error {
+	var snapshot trashedUser
+	if err := json.Unmarshal(entry.Payload, &snapshot); err != nil {
+		return fmt.Errorf("decode trashed user: %w", err)
+	}
+	if storage.GetUser(snapshot.ID) != nil {
+		return errTrashRestoreConflict
+	}
+	if existing := storage.GetUserByName(snapshot.Username); existing != nil && existing.ID != snapshot.ID {
+		return errTrashRestoreConflict
+	}
+
+	storage.WriteUser(store.User{
+		ID:               snapshot.ID,
+		Username:         snapshot.Username,
+		AccessToken:      snapshot.AccessToken,
+		RefreshToken:     snapshot.RefreshToken,
+		TraktDisplayName: snapshot.TraktDisplayName,
+		Updated:          snapshot.Updated,
+		TokenExpiry:      snapshot.TokenExpiry,
+		TokenIssuedAt:    snapshot.TokenIssuedAt,
+	})
+	for i := range snapshot.ProviderTokens {
+		if err := storage.SaveProviderToken(ctx, &snapshot.ProviderTokens[i]); err != nil {
+			slog.Warn("trash: failed to restore provider token", "id", snapshot.ID, "provider", snapshot.ProviderTokens[i].Provider, "error", err)
+		}
+	}
+	if len(snapshot.Preferences) > 0 {
+		if err := storage.PutUserPreferences(ctx, &store.UserPreferences{UserID: snapshot.ID, Data: snapshot.Preferences}); err != nil {
+			slog.Warn("trash: failed to restore preferences", "id", snapshot.ID, "error", err)
+		}
+	}
+
+	existing, err := storage.DequeueScrobbles(ctx, snapshot.ID, len(snapshot.QueuedEvents)+1)
+	if err != nil {
+		return fmt.Errorf("restore queue: %w", err)
+	}
+	present := make(map[string]struct{}, len(existing))
+	for _, event := range existing {
+		present[event.ID] = struct{}{}
+	}
+	for _, event := range snapshot.QueuedEvents {
+		if _, ok := present[event.ID]; ok {
+			continue
+		}
+		if err := storage.EnqueueScrobble(ctx, event); err != nil {
+			slog.Warn("trash: failed to restore queued event", "id", snapshot.ID, "event_id", event.ID, "error", err)
+		}
+	}
+
+	history, err := storage.ListWatchedMovies(ctx, snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("restore watch history: %w", err)
+	}
+	if len(history) == 0 {
+		for i := range snapshot.WatchHistory {
+			if err := storage.RecordWatchedMovie(ctx, &snapshot.WatchHistory[i]); err != nil {
+				slog.Warn("trash: failed to restore watch history", "id", snapshot.ID, "error", err)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotFamilyGroup captures a group and its members, tokens included.
+func snapshotFamilyGroup(ctx context.Context, group *store.FamilyGroup) (*store.TrashEntry, error) {
+	members, err := storage.ListGroupMembers(ctx, group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot members: %w", err)
+	}
+	snapshot := trashedFamilyGroup{Group: *group, Members: make([]trashedGroupMember, 0, len(members))}
+	for _, m := range members {
+		snapshot.Members = append(snapshot.Members, trashedGroupMember{
+			ID:                  m.ID,
+			TempLabel:           m.TempLabel,
+			TraktUsername:       m.TraktUsername,
+			AccessToken:         m.AccessToken,
+			RefreshToken:        m.RefreshToken,
+			TokenExpiry:         m.TokenExpiry,
+			AuthorizationStatus: m.AuthorizationStatus,
+			CreatedAt:           m.CreatedAt,
+		})
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	entry := &store.TrashEntry{
+		ID:        group.ID,
+		Kind:      store.TrashKindFamilyGroup,
+		Label:     group.PlexUsername,
+		DeletedAt: time.Now(),
+		Payload:   payload,
+	}
+	return entry, entry.Validate()
+}
+
+// restoreFamilyGroup recreates a trashed group with all of its members.
+func restoreFamilyGroup(ctx context.Context, entry *store.TrashEntry) error {
+	var snapshot trashedFamilyGroup
+	if err := json.Unmarshal(entry.Payload, &snapshot); err != nil {
+		return fmt.Errorf("decode trashed family group: %w", err)
+	}
+	if existing, err := storage.GetFamilyGroup(ctx, snapshot.Group.ID); err == nil && existing != nil {
+		return errTrashRestoreConflict
+	}
+	if existing, err := storage.GetFamilyGroupByPlex(ctx, snapshot.Group.PlexUsername); err == nil && existing != nil {
+		return errTrashRestoreConflict
+	}
+
+	group := snapshot.Group
+	members := make([]*store.GroupMember, 0, len(snapshot.Members))
+	for _, m := range snapshot.Members {
+		members = append(members, &store.GroupMember{
+			ID:                  m.ID,
+			FamilyGroupID:       group.ID,
+			TempLabel:           m.TempLabel,
+			TraktUsername:       m.TraktUsername,
+			AccessToken:         m.AccessToken,
+			RefreshToken:        m.RefreshToken,
+			TokenExpiry:         m.TokenExpiry,
+			AuthorizationStatus: m.AuthorizationStatus,
+			CreatedAt:           m.CreatedAt,
+		})
+	}
+	return storage.CreateFamilyGroupWithMembers(ctx, &group, members)
+}
+
+// discardTrashEntry permanently removes a snapshot, along with any queue the
+// backend kept for a deleted user.
+func discardTrashEntry(ctx context.Context, entry store.TrashEntry) error {
+	if entry.Kind == store.TrashKindUser {
+		if _, err := storage.PurgeQueueForUser(ctx, entry.ID); err != nil {
+			slog.Warn("trash: failed to purge queue", "id", entry.ID, "error", err)
+		}
+	}
+	return storage.DeleteTrashEntry(ctx, entry.ID)
+}
+
+// purgeExpiredTrash drops snapshots past the restore window.
+func purgeExpiredTrash(ctx context.Context, now time.Time) int {
+	entries, err := storage.ListTrashEntries(ctx)
+	if err != nil {
+		slog.Warn("trash purge failed", "error", err)
+		return 0
+	}
+	purged := 0
+	for _, entry := range entries {
+		if !entry.Expired(now) {
+			continue
+		}
+		if err := discardTrashEntry(ctx, entry); err != nil {
+			slog.Warn("trash purge: failed to delete entry", "id", entry.ID, "kind", entry.Kind, "error", err)
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		slog.Info("trash purged", "count", purged)
+	}
+	return purged
+}
+
+// startTrashPurger removes expired trash entries once an hour.
+func startTrashPurger(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	purgeExpiredTrash(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purgeExpiredTrash(ctx, now)
+		}
+	}
+}
+
+// retentionAudit names the admin login audit in retentionPolicy. It lives in
+// memory, so it is pruned here rather than by the store.
+const retentionAudit = "audit"
+
+// retentionPolicy maps a category (a store.RetentionCategory or
+// retentionAudit) to how long its records are kept. Categories without an
+// entry are only bounded by their built-in limits.
+var retentionPolicy = map[string]time.Duration{}
+
+// retentionCategories lists every category retentionPolicy can name.
+func retentionCategories() []string {
+	categories := []string{}
+	for _, c := range store.RetentionCategories {
+		categories = append(categories, string(c))
+	}
+	return append(categories, retentionAudit)
+}
+
+// purgeExpiredRecords drops records past retentionPolicy and returns how
+// many it removed per category.
+func purgeExpiredRecords(ctx context.Context, now time.Time) map[string]int {
+	purged := map[string]int{}
+	for _, category := range retentionCategories() {
+		keep, ok := retentionPolicy[category]
+		if !ok {
+			continue
+		}
+		before := now.Add(-keep)
+		if category == retentionAudit {
+			purged[category] = adminLoginGuard.Prune(before)
+			continue
+		}
+		n, err := storage.PurgeBefore(ctx, store.RetentionCategory(category), before)
+		if err != nil {
+			slog.Warn("retention purge failed", "category", category, "error", err)
+		}
+		purged[category] = n
+	}
+	args := []any{}
+	total := 0
+	for _, category := range retentionCategories() {
+		if n, ok := purged[category]; ok {
+			args = append(args, category, n)
+			total += n
+		}
+	}
+	if total > 0 {
+		slog.Info("retention purge finished", args...)
+	}
+	return purged
+}
+
+// startRetentionPurger applies retentionPolicy at startup and once a day.
+func startRetentionPurger(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	purgeExpiredRecords(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purgeExpiredRecords(ctx, now)
+		}
+	}
+}
+
+// listTrash returns restorable users and family groups.
+func listTrash(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	now := time.Now()
+	entries, err := storage.ListTrashEntries(r.Context())
+	if err != nil {
+		slog.Error("failed to list trash", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list trash")
+		return
+	}
+	response := make([]adminTrashResponse, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Expired(now) {
+			continue
+		}
+		response = append(response, adminTrashResponse{
+			ID:        entry.ID,
+			Kind:      entry.Kind,
+			Label:     entry.Label,
+			DeletedAt: entry.DeletedAt,
+			ExpiresAt: entry.ExpiresAt(),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// restoreTrashEntry recreates a deleted user or family group.
+func restoreTrashEntry(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	ctx := r.Context()
+	entry, err := storage.GetTrashEntry(ctx, id)
+	if err != nil || entry.Expired(time.Now()) {
+		writeJSONError(w, http.StatusNotFound, "trash entry not found")
+		return
+	}
+
+	switch entry.Kind {
+	case store.TrashKindUser:
+		err = restoreUser(ctx, entry)
+	case store.TrashKindFamilyGroup:
+		err = restoreFamilyGroup(ctx, entry)
+	default:
+		err = store.ErrInvalidTrashEntry
+	}
+	if err != nil {
+		if errors.Is(err, errTrashRestoreConflict) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		slog.Error("failed to restore trash entry", "id", id, "kind", entry.Kind, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to restore")
+		return
+	}
+	if err := storage.DeleteTrashEntry(ctx, id); err != nil {
+		slog.Warn("restored trash entry could not be removed", "id", id, "error", err)
+	}
+
+	slog.Info("trash entry restored", "id", id, "kind", entry.Kind, "label", entry.Label)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"id":      entry.ID,
+		"kind":    entry.Kind,
+	})
+}
+
+// deleteTrashEntry permanently discards a snapshot before it expires.
+func deleteTrashEntry(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	ctx := r.Context()
+	entry, err := storage.GetTrashEntry(ctx, id)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "trash entry not found")
+		return
+	}
+	if err := discardTrashEntry(ctx, *entry); err != nil {
+		slog.Error("failed to delete trash entry", "id", id, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to delete trash entry")
+		return
+	}
+	slog.Info("trash entry deleted permanently", "id", id, "kind", entry.Kind, "label", entry.Label)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// manualScrobbleRequest describes a play to submit on behalf of a user.
+// Movies are identified by imdb/tmdb/tvdb or title+year. Episodes are identified
+// by show ids (or title+year) plus season/episode, or by episode-level ids alone.
+type manualScrobbleRequest struct {
+	Type     string `json:"type"`   // "movie" | "episode"
+	Action   string `json:"action"` // "start" | "pause" | "stop" (default "stop")
+	Progress *int   `json:"progress"`
+	Imdb     string `json:"imdb"`
+	Tmdb     int    `json:"tmdb"`
+	Tvdb     int    `json:"tvdb"`
+	Title    string `json:"title"`
+	Year     int    `json:"year"`
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+}
+
+// ids returns the external ids present on the request, or nil if none were supplied.
+func (m manualScrobbleRequest) ids() *common.Ids {
+	ids := common.Ids{}
+	found := false
+	if imdb := strings.TrimSpace(m.Imdb); imdb != "" {
+		ids.Imdb = &imdb
+		found = true
+	}
+	if m.Tmdb > 0 {
+		tmdb := m.Tmdb
+		ids.Tmdb = &tmdb
+		found = true
+	}
+	if m.Tvdb > 0 {
+		tvdb := m.Tvdb
+		ids.Tvdb = &tvdb
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &ids
+}
+
+// buildManualScrobble validates the request and converts it into a ScrobbleBody.
+func buildManualScrobble(req manualScrobbleRequest) (string, common.ScrobbleBody, error) {
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if action == "" {
+		action = "stop"
+	}
+	if action != "start" && action != "pause" && action != "stop" {
+		return "", common.ScrobbleBody{}, fmt.Errorf("action must be start, pause or stop")
+	}
+
+	progress := 0
+	if action == "stop" {
+		progress = 100
+	}
+	if req.Progress != nil {
+		progress = *req.Progress
+	}
+	if progress < 0 || progress > 100 {
+		return "", common.ScrobbleBody{}, fmt.Errorf("progress must be between 0 and 100")
+	}
+
+	title := strings.TrimSpace(req.Title)
+	ids := req.ids()
+	body := common.ScrobbleBody{Progress: progress}
+
+	switch strings.ToLower(strings.TrimSpace(req.Type)) {
+	case "movie":
+		movie := &common.Movie{}
+		if ids != nil {
+			movie.Ids = *ids
+		} else if title != "" && req.Year > 0 {
+			year := req.Year
+			movie.Title = &title
+			movie.Year = &year
+		} else {
+			return "", common.ScrobbleBody{}, fmt.Errorf("movie requires imdb, tmdb, tvdb or title and year")
+		}
+		body.Movie = movie
+	case "episode":
+		if req.Season > 0 || req.Episode > 0 {
+			if req.Season < 0 || req.Episode <= 0 {
+				return "", common.ScrobbleBody{}, fmt.Errorf("episode requires both season and episode numbers")
+			}
+			show := &common.Show{}
+			if ids != nil {
+				show.Ids = *ids
+			} else if title != "" {
+				show.Title = &title
+				if req.Year > 0 {
+					year := req.Year
+					show.Year = &year
+				}
+			} else {
+				return "", common.ScrobbleBody{}, fmt.Errorf("episode requires show ids or title")
+			}
+			season, number := req.Season, req.Episode
+			body.Show = show
+			body.Episode = &common.Episode{Season: &season, Number: &number}
+		} else if ids != nil {
+			body.Episode = &common.Episode{Ids: ids}
+		} else {
+			return "", common.ScrobbleBody{}, fmt.Errorf("episode requires episode ids or show and season/episode numbers")
+		}
+	default:
+		return "", common.ScrobbleBody{}, fmt.Errorf("type must be movie or episode")
+	}
+
+	return action, body, nil
+}
+
+// submitManualScrobble sends an admin-supplied play to Trakt for a user, for
+// recovering plays that Plex never reported.
+func submitManualScrobble(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	if traktSrv == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "trakt client unavailable")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := strings.TrimSpace(vars["id"])
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing user id")
+		return
+	}
+
+	user := storage.GetUser(id)
+	if user == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	var req manualScrobbleRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	action, body, err := buildManualScrobble(req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mediaTitle := extractMediaTitleFromScrobble(body)
+	result, err := traktSrv.SubmitScrobble(r.Context(), action, body, user.AccessToken)
+	if err != nil {
+		var httpErr trakt.HttpError
+		traktStatus := 0
+		if errors.As(err, &httpErr) {
+			traktStatus = httpErr.Code
+		}
+		slog.Error("admin manual scrobble failed", "plaxt_id", user.ID, "username", user.Username, "action", action, "media", logging.Redact(user.ID, mediaTitle), "trakt_status", traktStatus, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{
+			"error":        "trakt scrobble failed",
+			"detail":       err.Error(),
+			"trakt_status": traktStatus,
+		})
+		return
+	}
+
+	if resolved := extractMediaTitleFromScrobble(result); resolved != "Unknown Media" {
+		mediaTitle = resolved
+	}
+	slog.Info("admin manual scrobble success", "plaxt_id", user.ID, "username", user.Username, "action", action, "media", logging.Redact(user.ID, mediaTitle), "progress", result.Progress)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"action":   action,
+		"media":    mediaTitle,
+		"progress": result.Progress,
+	})
+}
+
+// Bulk history import limits
+const (
+	maxHistoryImportBytes = 5 << 20
+	maxHistoryImportItems = 10000
+)
+
+// historyCSVColumns maps accepted CSV header names (lowercased) to HistoryItem
+// fields. A few aliases cover exports from other trackers (e.g. Letterboxd's
+// "Name"/"Date"/"Watched Date").
+var historyCSVColumns = map[string]string{
+	"type":         "type",
+	"imdb":         "imdb",
+	"imdb_id":      "imdb",
+	"tmdb":         "tmdb",
+	"tmdb_id":      "tmdb",
+	"tvdb":         "tvdb",
+	"tvdb_id":      "tvdb",
+	"title":        "title",
+	"name":         "title",
+	"year":         "year",
+	"season":       "season",
+	"episode":      "episode",
+	"watched_at":   "watched_at",
+	"date":         "watched_at",
+	"watched date": "watched_at",
+}
+
+// parseHistoryTime accepts RFC3339 timestamps or plain dates.
+func parseHistoryTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid watched_at %q", v)
+}
+
+// parseHistoryCSV reads a CSV export with a header row into history items.
+func parseHistoryCSV(r io.Reader) ([]trakt.HistoryItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing csv header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = historyCSVColumns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))]
+	}
+
+	var items []trakt.HistoryItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var item trakt.HistoryItem
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			switch columns[i] {
+			case "type":
+				item.Type = strings.ToLower(value)
+			case "imdb":
+				item.Imdb = value
+			case "title":
+				item.Title = value
+			case "tmdb", "tvdb", "year", "season", "episode":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid %s %q", line, columns[i], value)
+				}
+				switch columns[i] {
+				case "tmdb":
+					item.Tmdb = n
+				case "tvdb":
+					item.Tvdb = n
+				case "year":
+					item.Year = n
+				case "season":
+					item.Season = &n
+				case "episode":
+					item.Episode = &n
+				}
+			case "watched_at":
+				t, err := parseHistoryTime(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				item.WatchedAt = t
+			}
+		}
+		if item.Type == "" {
+			if item.Season != nil || item.Episode != nil {
+				item.Type = "episode"
+			} else {
+				item.Type = "movie"
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseHistoryImport decodes a history import body. CSV is used when the
+// content type says so; otherwise a JSON array or {"items": [...]} is expected.
+// A body over maxHistoryImportBytes fails with *http.MaxBytesError rather
+// than importing a truncated list.
+func parseHistoryImport(w http.ResponseWriter, r *http.Request) ([]trakt.HistoryItem, error) {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHistoryImportBytes))
+	if err != nil {
+		return nil, err
+	}
+	contentType := strings.ToLower(r.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "csv") {
+		return parseHistoryCSV(bytes.NewReader(raw))
+	}
+
+	raw = bytes.TrimSpace(raw)
+	var items []trakt.HistoryItem
+	if len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &items)
+	} else {
+		var wrapper struct {
+			Items []trakt.HistoryItem `json:"items"`
+		}
+		err = json.Unmarshal(raw, &wrapper)
+		items = wrapper.Items
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return items, nil
+}
+
+// pushUserHistory imports a list of watched items into a user's Trakt history,
+// e.g. when migrating from another tracker. Items are pushed in rate-limited
+// batches and a per-import report is returned.
+func pushUserHistory(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	if traktSrv == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "trakt client unavailable")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := strings.TrimSpace(vars["id"])
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing user id")
+		return
+	}
+
+	user := storage.GetUser(id)
+	if user == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	items, err := parseHistoryImport(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("import too large (max %d bytes)", maxHistoryImportBytes))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(items) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "no items to import")
+		return
+	}
+	if len(items) > maxHistoryImportItems {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("too many items (max %d)", maxHistoryImportItems))
+		return
+	}
+
+	slog.Info("admin history import starting", "plaxt_id", user.ID, "username", user.Username, "items", len(items))
+	report := traktSrv.PushHistory(r.Context(), user.AccessToken, items, trakt.DefaultHistoryBatchSize, trakt.DefaultHistoryInterval)
+	slog.Info("admin history import complete",
+		"plaxt_id", user.ID,
+		"username", user.Username,
+		"items", report.Total,
+		"batches", report.Batches,
+		"failed_batches", report.FailedBatches,
+		"added_movies", report.AddedMovies,
+		"added_episodes", report.AddedEpisodes,
+		"not_found", report.NotFound,
+		"invalid", report.Invalid,
+	)
+
+	status := http.StatusOK
+	if report.Batches > 0 && report.FailedBatches == report.Batches {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, report)
+}
+
+// letterboxdCSVHeader matches the columns accepted by Letterboxd's diary importer.
+var letterboxdCSVHeader = []string{"Title", "Year", "imdbID", "tmdbID", "WatchedDate", "Rewatch"}
+
+// writeLetterboxdCSV writes movies (oldest first) as a Letterboxd import file.
+// Repeat watches of the same film are flagged as rewatches.
+func writeLetterboxdCSV(w io.Writer, movies []store.WatchedMovie) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(letterboxdCSVHeader); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(movies))
+	for _, m := range movies {
+		key := m.Imdb
+		if key == "" {
+			key = strings.ToLower(m.Title) + "|" + strconv.Itoa(m.Year)
+		}
+		rewatch := "false"
+		if seen[key] {
+			rewatch = "true"
+		}
+		seen[key] = true
+
+		year, tmdb := "", ""
+		if m.Year > 0 {
+			year = strconv.Itoa(m.Year)
+		}
+		if m.Tmdb > 0 {
+			tmdb = strconv.Itoa(m.Tmdb)
+		}
+		if err := cw.Write([]string{m.Title, year, m.Imdb, tmdb, m.WatchedAt.Format("2006-01-02"), rewatch}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportLetterboxdDiary streams the user's completed movies as a Letterboxd
+// diary CSV. An optional since=YYYY-MM-DD limits the export to newer plays.
+func exportLetterboxdDiary(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	user := storage.GetUser(id)
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	var since time.Time
+	if v := strings.TrimSpace(r.URL.Query().Get("since")); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "since must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	movies, err := storage.ListWatchedMovies(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("letterboxd export failed", "plaxt_id", user.ID, "error", err)
+		http.Error(w, "failed to load watch history", http.StatusInternalServerError)
+		return
+	}
+	if !since.IsZero() {
+		filtered := movies[:0]
+		for _, m := range movies {
+			if !m.WatchedAt.Before(since) {
+				filtered = append(filtered, m)
+			}
+		}
+		movies = filtered
+	}
+
+	filename := fmt.Sprintf("plaxt-letterboxd-%s.csv", user.Username)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := writeLetterboxdCSV(w, movies); err != nil {
+		slog.Error("letterboxd export write failed", "plaxt_id", user.ID, "error", err)
+		return
+	}
+	slog.Info("letterboxd export", "plaxt_id", user.ID, "username", user.Username, "movies", len(movies))
+}
+
+// ========== SECONDARY SCROBBLE PROVIDERS ==========
+
+// dispatchSecondaryScrobbles forwards a scrobble to every configured secondary
+// provider the user has linked. Failures never affect Trakt; transient ones
+// are queued under the provider's name so the queue drain retries them.
+func dispatchSecondaryScrobbles(ctx context.Context, user store.User, action string, item common.CacheItem) {
+	body := item.Body
+	for _, p := range providers.Secondary() {
+		token, err := storage.GetProviderToken(ctx, user.ID, p.Name())
+		if err != nil {
+			if !errors.Is(err, store.ErrProviderTokenNotFound) {
+				slog.Warn("provider token lookup failed", "provider", p.Name(), "plaxt_id", user.ID, "error", err)
+			}
+			continue
+		}
+		if err := p.Scrobble(ctx, action, common.CacheItem{PlayerUuid: item.PlayerUuid, RatingKey: item.RatingKey, Body: body}, token.AccessToken); err != nil {
+			slog.Warn("provider scrobble failed", "provider", p.Name(), "username", user.Username, "plaxt_id", user.ID, "action", action, "media", logging.Redact(user.ID, extractMediaTitleFromScrobble(body)), "error", err)
+			if isTransientError(err) {
+				enqueueSecondaryScrobble(ctx, p.Name(), user, action, item)
+			}
+			continue
+		}
+		slog.Info("provider scrobble success", "provider", p.Name(), "username", user.Username, "plaxt_id", user.ID, "action", action, "media", logging.Redact(user.ID, extractMediaTitleFromScrobble(body)), "progress", body.Progress)
+	}
+}
+
+// enqueueSecondaryScrobble queues a failed secondary-provider scrobble for the
+// queue drain, which routes it back to the named provider.
+func enqueueSecondaryScrobble(ctx context.Context, name string, user store.User, action string, item common.CacheItem) {
+	event := store.QueuedScrobbleEvent{
+		UserID:       user.ID,
+		ScrobbleBody: item.Body,
+		Action:       action,
+		Progress:     item.Body.Progress,
+		Provider:     name,
+		CreatedAt:    time.Now(),
+		PlayerUUID:   item.PlayerUuid,
+		RatingKey:    item.RatingKey,
+	}
+	if err := storage.EnqueueScrobble(ctx, event); err != nil {
+		slog.Error("failed to enqueue provider scrobble", "provider", name, "plaxt_id", user.ID, "action", action, "error", err)
+		return
+	}
+	slog.Info("provider scrobble queued for retry", "provider", name, "plaxt_id", user.ID, "action", action)
+}
+
+// simklAuthorize starts the Simkl OAuth flow for an existing Plaxt user. It
+// needs the state token the wizard issues after a successful Trakt
+// authorization, so a bare user ID cannot be used to attach a Simkl account.
+func simklAuthorize(w http.ResponseWriter, r *http.Request) {
+	if simklClient == nil {
+		http.Error(w, "simkl integration not configured", http.StatusNotFound)
+		return
+	}
+	if storage == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	token := strings.TrimSpace(r.URL.Query().Get("state"))
+	state, ok := authStates.Get(token)
+	if !ok || state.Mode != simkl.ProviderName {
+		http.Error(w, "invalid or expired state; authorize with Trakt again to link Simkl", http.StatusForbidden)
+		return
+	}
+	if storage.GetUser(state.SelectedID) == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	// simklCallback consumes the same state
+	redirectURI := SelfRoot(r) + "/simkl/callback"
+	http.Redirect(w, r, simklClient.AuthorizeURL(redirectURI, token), http.StatusFound)
+}
+
+// simklCallback completes the Simkl OAuth flow and stores the token.
+func simklCallback(w http.ResponseWriter, r *http.Request) {
+	if simklClient == nil {
+		http.Error(w, "simkl integration not configured", http.StatusNotFound)
+		return
+	}
+	if storage == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, ok := authStates.Consume(strings.TrimSpace(r.URL.Query().Get("state")))
+	if !ok || state.Mode != simkl.ProviderName {
+		http.Error(w, "invalid or expired state", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(r.URL.Query().Get("code"))
+	if code == "" {
+		slog.Info("simkl authorization cancelled", "plaxt_id", state.SelectedID)
+		http.Error(w, "authorization cancelled", http.StatusBadRequest)
+		return
+	}
+	user := storage.GetUser(state.SelectedID)
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	redirectURI := SelfRoot(r) + "/simkl/callback"
+	result, err := simklClient.ExchangeCode(r.Context(), code, redirectURI)
+	if err != nil {
+		slog.Error("simkl token exchange failed", "plaxt_id", user.ID, "username", user.Username, "error", err)
+		http.Error(w, "simkl authorization failed", http.StatusBadGateway)
+		return
+	}
+
+	token := &store.ProviderToken{
+		UserID:      user.ID,
+		Provider:    simkl.ProviderName,
+		AccessToken: result.AccessToken,
+	}
+	if err := storage.SaveProviderToken(r.Context(), token); err != nil {
+		slog.Error("simkl token save failed", "plaxt_id", user.ID, "error", err)
+		http.Error(w, "failed to save simkl token", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("simkl linked", "plaxt_id", user.ID, "username", user.Username)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Simkl Connected</title></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; text-align: center; padding: 3rem;">
+	<h1>Simkl connected</h1>
+	<p>Plays for %s will now also be scrobbled to Simkl. You can close this window.</p>
+</body>
+</html>`, template.HTMLEscapeString(user.Username))
+}
+
+// adminProviderResponse describes a linked secondary provider for a user.
+type adminProviderResponse struct {
+	Provider    string    `json:"provider"`
+	AccountName string    `json:"account_name,omitempty"`
+	LinkedAt    time.Time `json:"linked_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// listUserProviders returns the secondary providers linked to a user.
+func listUserProviders(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if storage.GetUser(id) == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	secondary := providers.Secondary()
+	linked := make([]adminProviderResponse, 0, len(secondary))
+	for _, p := range secondary {
+		token, err := storage.GetProviderToken(r.Context(), id, p.Name())
+		if err != nil {
+			continue
+		}
+		linked = append(linked, adminProviderResponse{
+			Provider:    token.Provider,
+			AccountName: token.AccountName,
+			LinkedAt:    token.CreatedAt,
+			UpdatedAt:   token.UpdatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"providers": linked})
+}
+
+// deleteUserProvider unlinks a secondary provider from a user.
+func deleteUserProvider(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	vars := mux.Vars(r)
+	id := strings.TrimSpace(vars["id"])
+	name := strings.ToLower(strings.TrimSpace(vars["provider"]))
+	if storage.GetUser(id) == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := storage.DeleteProviderToken(r.Context(), id, name); err != nil {
+		slog.Error("provider unlink failed", "plaxt_id", id, "provider", name, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to unlink provider")
+		return
+	}
+	slog.Info("provider unlinked", "plaxt_id", id, "provider", name)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// maxPreferencesBody bounds a user preferences document.
+const maxPreferencesBody = 64 << 10
+
+type adminPreferencesResponse struct {
+	Preferences json.RawMessage `json:"preferences"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+}
+
+// getUserPreferences returns a user's preferences document, {} when none
+// were saved.
+func getUserPreferences(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if storage.GetUser(id) == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	prefs, err := storage.GetUserPreferences(r.Context(), id)
+	switch {
+	case errors.Is(err, store.ErrUserPreferencesNotFound):
+		writeJSON(w, http.StatusOK, adminPreferencesResponse{Preferences: json.RawMessage("{}")})
+	case err != nil:
+		slog.Error("preferences lookup failed", "plaxt_id", id, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load preferences")
+	default:
+		writeJSON(w, http.StatusOK, adminPreferencesResponse{Preferences: prefs.Data, UpdatedAt: &prefs.UpdatedAt})
+	}
+}
+
+// putUserPreferences replaces a user's preferences document after checking
+// it against preferences.Schema.
+func putUserPreferences(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if storage.GetUser(id) == nil {
+		writeJSONError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPreferencesBody+1))
+	if err != nil || len(body) > maxPreferencesBody {
+		writeJSONError(w, http.StatusBadRequest, "preferences body too large or unreadable")
+		return
+	}
+	parsed, err := preferences.Parse(body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	prefs := &store.UserPreferences{UserID: id, Data: body, UpdatedAt: time.Now()}
+	if err := storage.PutUserPreferences(r.Context(), prefs); err != nil {
+		slog.Error("preferences save failed", "plaxt_id", id, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to save preferences")
+		return
+	}
+	logging.SetUserPrivacy(id, parsed.PrivacyLogging)
+	slog.Info("preferences updated", "plaxt_id", id)
+	writeJSON(w, http.StatusOK, adminPreferencesResponse{Preferences: prefs.Data, UpdatedAt: &prefs.UpdatedAt})
+}
+
+// getPreferencesSchema returns the JSON Schema user preferences are checked against.
+func getPreferencesSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, preferences.Schema())
+}
+
+// loadUserPreferences returns the user's preferences and records their
+// privacy logging choice. Unreadable preferences decode as the zero value, so
+// they never block a scrobble.
+func loadUserPreferences(ctx context.Context, userID string) preferences.Preferences {
+	stored, err := storage.GetUserPreferences(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, store.ErrUserPreferencesNotFound) {
+			slog.Warn("preferences lookup failed; scrobbling anyway", "plaxt_id", userID, "error", err)
+			return preferences.Preferences{}
+		}
+		logging.SetUserPrivacy(userID, false)
+		return preferences.Preferences{}
+	}
+	prefs, err := preferences.Parse(stored.Data)
+	if err != nil {
+		slog.Warn("stored preferences invalid; scrobbling anyway", "plaxt_id", userID, "error", err)
+		return preferences.Preferences{}
+	}
+	logging.SetUserPrivacy(userID, prefs.PrivacyLogging)
+	return prefs
+}
+
+// loadPrivacyPreferences records every user's privacy logging choice at
+// startup, so log lines written before a user's next webhook honour it.
+func loadPrivacyPreferences(ctx context.Context) {
+	for _, user := range storage.ListUsers() {
+		loadUserPreferences(ctx, user.ID)
+	}
+}
+
+// Family Group Admin API Response Types
+type adminFamilyGroupResponse struct {
+	ID              string    `json:"id"`
+	PlexUsername    string    `json:"plex_username"`
+	MemberCount     int       `json:"member_count"`
+	AuthorizedCount int       `json:"authorized_count"`
+	WebhookURL      string    `json:"webhook_url"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type adminGroupMemberResponse struct {
+	ID                  string     `json:"id"`
+	FamilyGroupID       string     `json:"family_group_id"`
+	TempLabel           string     `json:"temp_label"`
+	TraktUsername       string     `json:"trakt_username,omitempty"`
+	AuthorizationStatus string     `json:"authorization_status"`
+	TokenExpiry         *time.Time `json:"token_expiry,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	Status              string     `json:"status"` // "healthy", "warning", "expired", "pending", "failed"
+}
+
+type adminFamilyGroupDetailResponse struct {
+	*adminFamilyGroupResponse
+	Members []adminGroupMemberResponse `json:"members"`
+}
+
+// T031: List all family groups
+func listFamilyGroups(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx := r.Context()
+	groups, err := storage.ListFamilyGroups(ctx)
+	if err != nil {
+		slog.Error("failed to list family groups", "error", err)
+		http.Error(w, "failed to list family groups", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]adminFamilyGroupResponse, 0, len(groups))
+	root := SelfRoot(r)
+
+	for _, group := range groups {
+		members, err := storage.ListGroupMembers(ctx, group.ID)
+		if err != nil {
+			slog.Warn("failed to list members for group", "group_id", group.ID, "error", err)
+			continue
+		}
+
+		authorizedCount := 0
+		for _, member := range members {
+			if member.AuthorizationStatus == "authorized" {
+				authorizedCount++
 			}
 		}
 
@@ -2813,36 +5065,43 @@ func deleteFamilyGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep a restorable snapshot of the group and its members
+	entry, err := snapshotFamilyGroup(ctx, group)
+	if err == nil {
+		err = storage.PutTrashEntry(ctx, entry)
+	}
+	if err != nil {
+		slog.Error("failed to move family group to trash", "group_id", groupID, "error", err)
+		http.Error(w, "failed to move family group to trash", http.StatusInternalServerError)
+		return
+	}
+
 	// Delete group (cascade deletes members and retry queue items)
 	if err := storage.DeleteFamilyGroup(ctx, groupID); err != nil {
+		_ = storage.DeleteTrashEntry(ctx, groupID)
 		slog.Error("failed to delete family group", "group_id", groupID, "error", err)
 		http.Error(w, "failed to delete family group", http.StatusInternalServerError)
 		return
 	}
 
-	slog.Info("family group deleted", "group_id", groupID, "plex_username", group.PlexUsername)
+	slog.Info("family group deleted", "group_id", groupID, "plex_username", group.PlexUsername, "restorable_until", entry.ExpiresAt())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Family group deleted successfully",
+		"success":          true,
+		"message":          "Family group moved to trash",
+		"restorable_until": entry.ExpiresAt(),
 	})
 }
 
 // renderAdminDashboard serves the admin dashboard HTML
 func renderAdminDashboard(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.New("admin.html").Funcs(templateFuncs).ParseFiles("static/admin.html"))
-	if err := tmpl.Execute(w, nil); err != nil {
-		slog.Error("failed to render admin dashboard", "error", err)
-	}
+	renderTemplate(w, "admin.html", nil)
 }
 
 // renderFamilyAdmin serves the family groups admin HTML
 func renderFamilyAdmin(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.New("family-admin.html").Funcs(templateFuncs).ParseFiles("static/family-admin.html"))
-	if err := tmpl.Execute(w, nil); err != nil {
-		slog.Error("failed to render family admin", "error", err)
-	}
+	renderTemplate(w, "family-admin.html", nil)
 }
 
 // ========== TELEMETRY API ==========
@@ -2882,10 +5141,7 @@ func telemetryHandler(w http.ResponseWriter, r *http.Request) {
 
 // renderQueueMonitor serves the queue monitoring HTML page
 func renderQueueMonitor(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.New("queue.html").Funcs(templateFuncs).ParseFiles("static/queue.html"))
-	if err := tmpl.Execute(w, nil); err != nil {
-		slog.Error("failed to render queue monitor", "error", err)
-	}
+	renderTemplate(w, "queue.html", nil)
 }
 
 // getQueueStatus returns system-wide queue status
@@ -2959,7 +5215,12 @@ func getQueueStatus(w http.ResponseWriter, r *http.Request) {
 			"total_events":      totalEvents,
 			"drain_active":      len(drainStateTracker.GetAllActiveUsers()) > 0,
 			"mode":              drainStateTracker.GetMode(),
+			"drain_schedule":    drainPolicy.status(),
 			"last_health_check": drainStateTracker.GetLastHealthCheck(),
+			"trakt_http":        traktHTTPMetrics(),
+			"webhook_replay":    replayGuard.metrics(),
+			"webhook_invalid":   invalidIDs.metrics(),
+			"scheduler":         scrobbleScheduler.Stats(),
 		},
 		"users": userInfos,
 	}
@@ -2968,6 +5229,15 @@ func getQueueStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// traktHTTPMetrics returns outbound Trakt HTTP metrics, or nil when no client is configured.
+func traktHTTPMetrics() *trakt.TransportMetrics {
+	if traktSrv == nil {
+		return nil
+	}
+	m := traktSrv.Metrics()
+	return &m
+}
+
 // determineQueueStatus determines the queue status based on various factors
 func determineQueueStatus(queueSize int, oldestAgeSeconds *int64, drainActive bool) string {
 	if queueSize == 0 {
@@ -2983,6 +5253,10 @@ func determineQueueStatus(queueSize int, oldestAgeSeconds *int64, drainActive bo
 }
 
 // getQueueEvents returns recent queue events from the log
+//
+// Query parameters:
+//   - offset, limit: pagination (limit defaults to 50, capped at 500)
+//   - since, until: RFC3339 bounds on the event timestamp (inclusive)
 func getQueueEvents(w http.ResponseWriter, r *http.Request) {
 	if queueEventLog == nil {
 		slog.Error("queue event log unavailable")
@@ -2990,17 +5264,139 @@ func getQueueEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get recent events (default 50)
-	events := queueEventLog.GetRecent(50)
-	slog.Debug("queue events requested", "event_count", len(events))
+	query := store.QueueLogQuery{
+		Offset: parseIntQuery(r, "offset", 0),
+		Limit:  parseIntQuery(r, "limit", defaultQueueDetailLimit),
+	}
+	if query.Limit == 0 || query.Limit > maxQueueDetailLimit {
+		query.Limit = maxQueueDetailLimit
+	}
+	for name, dst := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		v := strings.TrimSpace(r.URL.Query().Get(name))
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: expected RFC3339 timestamp", name), http.StatusBadRequest)
+			return
+		}
+		*dst = t
+	}
+
+	// Ask for one extra event to tell whether another page exists
+	limit := query.Limit
+	query.Limit++
+	events, err := queueEventLog.Query(r.Context(), query)
+	if err != nil {
+		slog.Error("queue events query failed", "error", err)
+		http.Error(w, "failed to fetch queue events", http.StatusInternalServerError)
+		return
+	}
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+	slog.Debug("queue events requested", "event_count", len(events), "offset", query.Offset)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"events": events,
+		"events":   events,
+		"offset":   query.Offset,
+		"limit":    limit,
+		"has_more": hasMore,
 	})
 }
 
-// getUserQueueDetail returns detailed queue info for a specific user
+// Queue detail pagination and payload preview defaults
+const (
+	defaultQueueDetailLimit   = 50
+	maxQueueDetailLimit       = 500
+	defaultPayloadPreviewSize = 200
+)
+
+// adminQueueEventResponse is a queued scrobble event decorated for the admin UI.
+type adminQueueEventResponse struct {
+	ID               string          `json:"id"`
+	Action           string          `json:"action"`
+	Progress         int             `json:"progress"`
+	MediaTitle       string          `json:"media_title"`
+	Summary          string          `json:"summary"`
+	CreatedAt        time.Time       `json:"created_at"`
+	RetryCount       int             `json:"retry_count"`
+	LastAttempt      time.Time       `json:"last_attempt"`
+	PlayerUUID       string          `json:"player_uuid"`
+	RatingKey        string          `json:"rating_key"`
+	Payload          json.RawMessage `json:"payload,omitempty"`
+	PayloadPreview   string          `json:"payload_preview,omitempty"`
+	PayloadTruncated bool            `json:"payload_truncated,omitempty"`
+}
+
+// newAdminQueueEventResponse builds the admin view of a queued event.
+// payloadMode is "full", "none" or "preview" (truncated to previewSize bytes).
+func newAdminQueueEventResponse(event store.QueuedScrobbleEvent, payloadMode string, previewSize int) adminQueueEventResponse {
+	title := extractMediaTitleFromScrobble(event.ScrobbleBody)
+	resp := adminQueueEventResponse{
+		ID:          event.ID,
+		Action:      event.Action,
+		Progress:    event.Progress,
+		MediaTitle:  title,
+		Summary:     fmt.Sprintf("%s – %s %d%%", title, event.Action, event.Progress),
+		CreatedAt:   event.CreatedAt,
+		RetryCount:  event.RetryCount,
+		LastAttempt: event.LastAttempt,
+		PlayerUUID:  event.PlayerUUID,
+		RatingKey:   event.RatingKey,
+	}
+
+	switch payloadMode {
+	case "none":
+	case "full":
+		resp.Payload = mustMarshalJSON(event.ScrobbleBody)
+	default:
+		payload := string(mustMarshalJSON(event.ScrobbleBody))
+		if previewSize > 0 && len(payload) > previewSize {
+			// Cut at a rune boundary so non-ASCII titles stay valid UTF-8
+			cut := previewSize
+			for cut > 0 && !utf8.RuneStart(payload[cut]) {
+				cut--
+			}
+			payload = payload[:cut] + "…"
+			resp.PayloadTruncated = true
+		}
+		resp.PayloadPreview = payload
+	}
+	return resp
+}
+
+// parseIntQuery reads a non-negative integer query parameter, falling back to def.
+func parseIntQuery(r *http.Request, name string, def int) int {
+	v := strings.TrimSpace(r.URL.Query().Get(name))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// listAllQueuedEvents returns every queued event for a user, oldest first.
+func listAllQueuedEvents(ctx context.Context, userID string) ([]store.QueuedScrobbleEvent, error) {
+	total, err := storage.GetQueueSize(ctx, userID)
+	if err != nil || total == 0 {
+		return nil, err
+	}
+	return storage.DequeueScrobbles(ctx, userID, total)
+}
+
+// getUserQueueDetail returns a page of queued events for a specific user.
+//
+// Query parameters:
+//   - offset, limit: pagination (limit defaults to 50, capped at 500)
+//   - payload: "preview" (default), "full" or "none"
+//   - preview_size: max bytes of payload preview (default 200)
 func getUserQueueDetail(w http.ResponseWriter, r *http.Request) {
 	if storage == nil {
 		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
@@ -3023,8 +5419,16 @@ func getUserQueueDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get all queued events for user (up to 100)
-	events, err := storage.DequeueScrobbles(ctx, userID, 100)
+	offset := parseIntQuery(r, "offset", 0)
+	limit := parseIntQuery(r, "limit", defaultQueueDetailLimit)
+	if limit == 0 || limit > maxQueueDetailLimit {
+		limit = maxQueueDetailLimit
+	}
+	payloadMode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("payload")))
+	previewSize := parseIntQuery(r, "preview_size", defaultPayloadPreviewSize)
+
+	// Fetch the whole queue so stats cover every event, then page in memory
+	events, err := listAllQueuedEvents(ctx, userID)
 	if err != nil {
 		http.Error(w, "failed to fetch queue", http.StatusInternalServerError)
 		return
@@ -3033,17 +5437,125 @@ func getUserQueueDetail(w http.ResponseWriter, r *http.Request) {
 	// Calculate stats
 	stats := calculateQueueStats(events)
 
+	page := []store.QueuedScrobbleEvent{}
+	if offset < len(events) {
+		end := offset + limit
+		if end > len(events) {
+			end = len(events)
+		}
+		page = events[offset:end]
+	}
+	items := make([]adminQueueEventResponse, 0, len(page))
+	for _, event := range page {
+		items = append(items, newAdminQueueEventResponse(event, payloadMode, previewSize))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user_id":            user.ID,
 		"username":           user.Username,
 		"trakt_display_name": user.TraktDisplayName,
 		"queue_size":         len(events),
-		"events":             events,
+		"offset":             offset,
+		"limit":              limit,
+		"has_more":           offset+len(page) < len(events),
+		"events":             items,
 		"stats":              stats,
 	})
 }
 
+// getWebhookEvents returns webhook counts by event type, skipped extras by
+// reason and the latest payloads of unknown event types, newest first.
+func getWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, webhookEvents.metrics())
+}
+
+// triggerQueueDrain starts a drain of every user's queue right away,
+// regardless of QUEUE_DRAIN_MODE or QUEUE_DRAIN_WINDOW.
+func triggerQueueDrain(w http.ResponseWriter, r *http.Request) {
+	if storage == nil || providers == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	if len(drainStateTracker.GetAllActiveUsers()) > 0 {
+		writeJSONError(w, http.StatusConflict, "queue drain already running")
+		return
+	}
+	slog.Info("queue drain triggered by admin", "remote", r.RemoteAddr)
+	go initiateQueueDrain(context.WithoutCancel(r.Context()), storage, providers)
+	writeJSON(w, http.StatusAccepted, map[string]string{"result": "drain_started"})
+}
+
+// deleteUserQueueEvent removes a single queued event belonging to a user.
+func deleteUserQueueEvent(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID := strings.TrimSpace(vars["id"])
+	eventID := strings.TrimSpace(vars["event_id"])
+	if userID == "" || eventID == "" {
+		http.Error(w, "missing user id or event id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user := storage.GetUser(userID)
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	// Make sure the event belongs to this user before deleting it
+	events, err := listAllQueuedEvents(ctx, userID)
+	if err != nil {
+		http.Error(w, "failed to fetch queue", http.StatusInternalServerError)
+		return
+	}
+	var target *store.QueuedScrobbleEvent
+	for i := range events {
+		if events[i].ID == eventID {
+			target = &events[i]
+			break
+		}
+	}
+	if target == nil {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+
+	if err := storage.DeleteQueuedScrobble(ctx, eventID); err != nil {
+		slog.Error("admin queue event delete failed", "user_id", userID, "event_id", eventID, "error", err)
+		http.Error(w, "failed to delete event", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("admin queue event deleted",
+		"operation", "queue_event_deleted",
+		"user_id", userID,
+		"event_id", eventID,
+		"media", logging.Redact(userID, extractMediaTitleFromScrobble(target.ScrobbleBody)),
+	)
+	if queueEventLog != nil {
+		queueEventLog.Append(store.QueueLogEvent{
+			Timestamp: time.Now(),
+			Operation: "queue_event_deleted",
+			UserID:    userID,
+			Username:  user.Username,
+			EventID:   eventID,
+			QueueSize: len(events) - 1,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Queued event deleted",
+	})
+}
+
 // calculateQueueStats computes statistics for a set of queued events
 func calculateQueueStats(events []store.QueuedScrobbleEvent) map[string]interface{} {
 	byAction := make(map[string]int)
@@ -3085,7 +5597,7 @@ func startRetryQueueWorker(ctx context.Context, storage store.Store, traktSrv *t
 	// Create worker with default configuration
 	worker := queue.NewWorker(queue.WorkerConfig{
 		Repo:         repo,
-		Trakt:        traktSrv,
+		Provider:     traktSrv,
 		Notifier:     notifier,
 		Store:        storage,
 		PollInterval: 0, // Use default (15 seconds)
@@ -3099,25 +5611,332 @@ func startRetryQueueWorker(ctx context.Context, storage store.Store, traktSrv *t
 		slog.Info("retry queue worker stopped")
 	}()
 
-	// Log initial queue metrics
-	go func() {
-		// Wait briefly for worker to stabilize
-		time.Sleep(5 * time.Second)
-		logRetryQueueMetrics(ctx, repo)
+	// Log initial queue metrics
+	go func() {
+		// Wait briefly for worker to stabilize
+		time.Sleep(5 * time.Second)
+		logRetryQueueMetrics(ctx, repo)
+
+		// Periodically log queue metrics (every 5 minutes)
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				logRetryQueueMetrics(ctx, repo)
+			}
+		}
+	}()
+}
+
+// failureMonitorConfigFromEnv reads the ALERT_* settings; invalid values fall
+// back to the monitor defaults.
+func failureMonitorConfigFromEnv() notify.FailureMonitorConfig {
+	cfg := notify.FailureMonitorConfig{
+		WebhookURL: strings.TrimSpace(os.Getenv("ALERT_WEBHOOK_URL")),
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_WEBHOOK_SECRET")); v != "" {
+		if keys, err := notify.ParseSigningKeys(v); err == nil {
+			cfg.WebhookKeys = keys
+		} else {
+			slog.Warn("invalid ALERT_WEBHOOK_SECRET; alerts are sent unsigned", "error", err)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_WINDOW")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.Window = d
+		} else {
+			slog.Warn("invalid ALERT_WINDOW; using default", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_COOLDOWN")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.Cooldown = d
+		} else {
+			slog.Warn("invalid ALERT_COOLDOWN; using default", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_FAILURE_RATE")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 && f <= 1 {
+			cfg.FailureRate = f
+		} else {
+			slog.Warn("invalid ALERT_FAILURE_RATE; using default", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_MIN_EVENTS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MinEvents = n
+		} else {
+			slog.Warn("invalid ALERT_MIN_EVENTS; using default", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ALERT_USER_FAILURES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.UserFailures = n
+		} else {
+			slog.Warn("invalid ALERT_USER_FAILURES; using default", "value", v)
+		}
+	}
+	return cfg
+}
+
+// buildHeartbeat summarises the period before now: scrobble activity from the
+// store and users whose tokens are due for a refresh that has not happened.
+func buildHeartbeat(ctx context.Context, now time.Time, period time.Duration, webhooks int) notify.Heartbeat {
+	hb := notify.Heartbeat{
+		Period:        strings.TrimSuffix(strings.TrimSuffix(period.String(), "0s"), "0m"),
+		Webhooks:      webhooks,
+		TokenWarnings: []notify.TokenWarning{},
+		SentAt:        now,
+	}
+	if storage == nil {
+		return hb
+	}
+	buckets, err := storage.ListActivity(ctx, now.Add(-period), now)
+	if err != nil {
+		slog.Warn("heartbeat: activity lookup failed", "error", err)
+	}
+	for _, b := range buckets {
+		hb.Scrobbles += b.Scrobbles
+		hb.Failures += b.Failures
+		hb.Queued += b.Queued
+	}
+	for _, user := range storage.ListUsers() {
+		if user.TokenExpiry.IsZero() || user.TokenExpiry.After(now.Add(tokenRefreshWindow)) {
+			continue
+		}
+		hb.TokenWarnings = append(hb.TokenWarnings, notify.TokenWarning{UserID: user.ID, Username: user.Username, ExpiresAt: user.TokenExpiry})
+	}
+	return hb
+}
+
+// startHeartbeat sends a summary through the failure monitor every interval,
+// so a silent plaxt is noticed by the absence of heartbeats or by one
+// reporting no webhooks.
+func startHeartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	seen := webhookEvents.total()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			total := webhookEvents.total()
+			hb := buildHeartbeat(ctx, now, interval, int(total-seen))
+			seen = total
+			if err := failureMonitor.SendHeartbeat(ctx, hb); err != nil {
+				slog.Warn("heartbeat failed", "error", err)
+			}
+		}
+	}
+}
+
+// probeNonces are the answers GET /reachability/{nonce} gives, so a
+// reachability check can tell plaxt apart from whatever else answers on its
+// address.
+type probeNonces struct {
+	mu     sync.Mutex
+	issued map[string]time.Time
+}
+
+const probeNonceTTL = time.Minute
+
+func (p *probeNonces) issue() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for nonce, at := range p.issued {
+		if now.Sub(at) > probeNonceTTL {
+			delete(p.issued, nonce)
+		}
+	}
+	nonce := generateCorrelationID()
+	p.issued[nonce] = now
+	return nonce
+}
+
+func (p *probeNonces) valid(nonce string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	at, ok := p.issued[nonce]
+	return ok && time.Since(at) <= probeNonceTTL
+}
+
+// serveReachabilityProbe answers a probe issued by checkWebhookReachability.
+func serveReachabilityProbe(w http.ResponseWriter, r *http.Request) {
+	nonce := mux.Vars(r)["nonce"]
+	if !reachabilityProbes.valid(nonce) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(nonce))
+}
+
+type webhookReachabilityResponse struct {
+	WebhookURL    string               `json:"webhook_url"`
+	Report        *reachability.Report `json:"report,omitempty"`
+	LastWebhookAt *time.Time           `json:"last_webhook_at,omitempty"`
+}
+
+func newWebhookReachabilityResponse(root, id string) webhookReachabilityResponse {
+	resp := webhookReachabilityResponse{WebhookURL: fmt.Sprintf("%s/api?id=%s", root, id)}
+	if at := webhookEvents.lastArrival(id); !at.IsZero() {
+		resp.LastWebhookAt = &at
+	}
+	return resp
+}
+
+// checkWebhookReachability fetches a one-off URL on the webhook's own
+// address, the way Plex would reach it, and reports each step (DNS, connect,
+// TLS, HTTP) with a hint for the first one that fails. Plex offers no way to
+// send a test webhook, so the wizard then polls getWebhookReachability until
+// a real one arrives.
+func checkWebhookReachability(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if !plexWebhookTargetExists(r.Context(), id) {
+		writeJSONError(w, http.StatusNotFound, "user or family group not found")
+		return
+	}
+	root := SelfRoot(r)
+	nonce := reachabilityProbes.issue()
+	report := reachabilityChecker.Check(r.Context(), root+"/reachability/"+nonce, nonce)
+	slog.Info("webhook reachability checked", "plaxt_id", id, "root", root, "reachable", report.Reachable)
+	resp := newWebhookReachabilityResponse(root, id)
+	resp.Report = &report
+	writeJSON(w, http.StatusOK, resp)
+}
 
-		// Periodically log queue metrics (every 5 minutes)
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
+// getWebhookReachability reports when the last webhook for a user or family
+// group arrived.
+func getWebhookReachability(w http.ResponseWriter, r *http.Request) {
+	if storage == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "storage unavailable")
+		return
+	}
+	id := strings.TrimSpace(mux.Vars(r)["id"])
+	if !plexWebhookTargetExists(r.Context(), id) {
+		writeJSONError(w, http.StatusNotFound, "user or family group not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, newWebhookReachabilityResponse(SelfRoot(r), id))
+}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				logRetryQueueMetrics(ctx, repo)
+// verifyPlexWebhooks re-adds every saved webhook Plex no longer lists, for
+// example after the user removed it or reset their server, and drops
+// registrations whose user or family group is gone.
+func verifyPlexWebhooks(ctx context.Context, now time.Time) {
+	regs, err := storage.ListPlexWebhooks(ctx)
+	if err != nil {
+		slog.Warn("plex webhook verification: listing failed", "error", err)
+		return
+	}
+	for i := range regs {
+		reg := &regs[i]
+		if !plexWebhookTargetExists(ctx, reg.ID) {
+			if err := storage.DeletePlexWebhook(ctx, reg.ID); err != nil {
+				slog.Warn("plex webhook verification: delete failed", "plaxt_id", reg.ID, "error", err)
 			}
+			continue
 		}
-	}()
+		added, err := plexClient.EnsureWebhook(ctx, reg.Token, reg.WebhookURL)
+		reg.UpdatedAt = now
+		if err != nil {
+			reg.LastError = err.Error()
+			slog.Warn("plex webhook verification failed", "plaxt_id", reg.ID, "error", err)
+		} else {
+			reg.LastError = ""
+			reg.VerifiedAt = now
+			if added {
+				slog.Warn("plex webhook was missing; registered it again", "plaxt_id", reg.ID, "webhook_url", reg.WebhookURL)
+			}
+		}
+		if err := storage.PutPlexWebhook(ctx, reg); err != nil {
+			slog.Warn("plex webhook verification: save failed", "plaxt_id", reg.ID, "error", err)
+		}
+	}
+}
+
+// startPlexWebhookVerifier runs verifyPlexWebhooks every interval.
+func startPlexWebhookVerifier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			verifyPlexWebhooks(ctx, now)
+		}
+	}
+}
+
+// startKeystoreBackups schedules tar.gz snapshots of the disk keystore when
+// KEYSTORE_BACKUP_DIR or an S3 bucket is configured.
+func startKeystoreBackups(ctx context.Context) {
+	cfg := backup.Config{
+		SourceDir: "keystore",
+		Dir:       strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_DIR")),
+	}
+	if v := strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.Interval = d
+		} else {
+			slog.Warn("invalid KEYSTORE_BACKUP_INTERVAL; using default", "value", v, "default", backup.DefaultInterval)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_RETENTION")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Retention = n
+		} else {
+			slog.Warn("invalid KEYSTORE_BACKUP_RETENTION; using default", "value", v, "default", backup.DefaultRetention)
+		}
+	}
+	if bucket := strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_S3_BUCKET")); bucket != "" {
+		cfg.S3 = &backup.S3Target{
+			Endpoint:  strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_S3_ENDPOINT")),
+			Bucket:    bucket,
+			Region:    strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_S3_REGION")),
+			AccessKey: os.Getenv("KEYSTORE_BACKUP_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("KEYSTORE_BACKUP_S3_SECRET_KEY"),
+			Prefix:    strings.TrimSpace(os.Getenv("KEYSTORE_BACKUP_S3_PREFIX")),
+		}
+	}
+
+	runner, err := backup.NewRunner(cfg)
+	if err != nil {
+		if errors.Is(err, backup.ErrNoTarget) {
+			slog.Info("keystore backups disabled (set KEYSTORE_BACKUP_DIR or KEYSTORE_BACKUP_S3_BUCKET)")
+		} else {
+			slog.Error("keystore backups disabled", "error", err)
+		}
+		return
+	}
+	go runner.Start(ctx)
+}
+
+// runRestoreBackup implements `plaxt restore-backup <archive>`. It must run
+// while the server is stopped; the current keystore is kept alongside.
+func runRestoreBackup(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: plaxt restore-backup <keystore-YYYYMMDDTHHMMSSZ.tar.gz>")
+		return 2
+	}
+	previous, err := backup.Restore(args[0], "keystore")
+	if err != nil {
+		slog.Error("keystore restore failed", "archive", args[0], "error", err)
+		return 1
+	}
+	slog.Info("keystore restored", "archive", args[0], "previous_keystore", previous)
+	return 0
 }
 
 // logRetryQueueMetrics logs current retry queue depth and permanent failure counts.
@@ -3149,19 +5968,178 @@ func logRetryQueueMetrics(ctx context.Context, repo *queue.PostgresRepo) {
 
 // ========== QUEUE DRAIN SYSTEM ==========
 
+// drainWindow is a daily local-time window, e.g. 02:00-06:00. A window whose
+// end is before its start wraps past midnight.
+type drainWindow struct {
+	start, end time.Duration // offsets from local midnight
+}
+
+// parseDrainWindow parses "HH:MM-HH:MM".
+func parseDrainWindow(v string) (*drainWindow, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(v), "-")
+	if !ok {
+		return nil, fmt.Errorf("drain window %q must look like 02:00-06:00", v)
+	}
+	var w drainWindow
+	for i, part := range []string{from, to} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("drain window %q: %w", v, err)
+		}
+		offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			w.start = offset
+		} else {
+			w.end = offset
+		}
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("drain window %q is empty", v)
+	}
+	return &w, nil
+}
+
+func (w *drainWindow) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.start) + "-" + format(w.end)
+}
+
+// bounds returns the window opening at or before now on now's day, and its close.
+func (w *drainWindow) bounds(now time.Time) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	open := midnight.Add(w.start)
+	if w.end < w.start && now.Before(midnight.Add(w.end)) {
+		// Still inside the window that opened yesterday
+		open = open.AddDate(0, 0, -1)
+	}
+	end := open.Add(w.end - w.start)
+	if w.end < w.start {
+		end = end.Add(24 * time.Hour)
+	}
+	return open, end
+}
+
+// contains reports whether t falls inside the window.
+func (w *drainWindow) contains(t time.Time) bool {
+	open, end := w.bounds(t)
+	return !t.Before(open) && t.Before(end)
+}
+
+// next returns when the window next opens after t and when it then closes.
+func (w *drainWindow) next(t time.Time) (time.Time, time.Time) {
+	open, end := w.bounds(t)
+	if !t.Before(open) {
+		open, end = open.AddDate(0, 0, 1), end.AddDate(0, 0, 1)
+	}
+	return open, end
+}
+
+// drainSchedule decides when automatic queue drains (startup and Trakt
+// recovery) may run. In trigger-only mode they never do and the backlog waits
+// for POST /admin/api/queue/drain; with a window they are deferred until it
+// opens and stopped when it closes, so a large drain cannot compete with
+// prime-time live scrobbles.
+type drainSchedule struct {
+	triggerOnly bool
+	window      *drainWindow
+
+	mu            sync.Mutex
+	deferredUntil time.Time
+}
+
+type drainScheduleStatus struct {
+	Mode          string     `json:"mode"`
+	Window        string     `json:"window,omitempty"`
+	DeferredUntil *time.Time `json:"deferred_until,omitempty"`
+}
+
+func (d *drainSchedule) status() drainScheduleStatus {
+	st := drainScheduleStatus{Mode: "auto"}
+	if d.triggerOnly {
+		st.Mode = "trigger"
+	}
+	if d.window != nil {
+		st.Window = d.window.String()
+	}
+	d.mu.Lock()
+	if !d.deferredUntil.IsZero() {
+		until := d.deferredUntil
+		st.DeferredUntil = &until
+	}
+	d.mu.Unlock()
+	return st
+}
+
+// runAutomatic runs drain when the schedule allows: now if inside the window,
+// otherwise once it opens. Only one deferred drain is pending at a time.
+func (d *drainSchedule) runAutomatic(ctx context.Context, reason string, drain func(context.Context)) {
+	if d.triggerOnly {
+		slog.Info("automatic queue drain skipped; trigger-only mode", "reason", reason)
+		return
+	}
+	if d.window == nil {
+		drain(ctx)
+		return
+	}
+
+	now := time.Now()
+	if !d.window.contains(now) {
+		open, _ := d.window.next(now)
+		d.mu.Lock()
+		if !d.deferredUntil.IsZero() {
+			d.mu.Unlock()
+			return
+		}
+		d.deferredUntil = open
+		d.mu.Unlock()
+		slog.Info("queue drain deferred until drain window", "reason", reason, "window", d.window.String(), "opens_at", open)
+
+		timer := time.NewTimer(time.Until(open))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		d.mu.Lock()
+		d.deferredUntil = time.Time{}
+		d.mu.Unlock()
+		now = time.Now()
+	}
+
+	_, end := d.window.bounds(now)
+	windowCtx, cancel := context.WithDeadline(ctx, end)
+	defer cancel()
+	drain(windowCtx)
+	if windowCtx.Err() != nil && ctx.Err() == nil {
+		slog.Info("queue drain stopped at end of drain window; remaining events wait for the next window", "window", d.window.String())
+	}
+}
+
 // startQueueDrainSystem initializes health checker and queue drain orchestration.
-func startQueueDrainSystem(ctx context.Context, storage store.Store, traktSrv *trakt.Trakt) {
+func startQueueDrainSystem(ctx context.Context, storage store.Store, registry *provider.Registry) {
 	slog.Info("queue drain system starting")
 
-	// Start health checker
-	healthChecker := trakt.NewHealthChecker(traktSrv)
+	primary, err := registry.Get(provider.DefaultName)
+	if err != nil {
+		slog.Error("queue drain system disabled", "error", err)
+		return
+	}
+
+	// Start health checker against the primary provider
+	healthChecker := trakt.NewHealthChecker(primary)
 	stateChan := healthChecker.Start(ctx)
 
 	// Perform initial drain check on startup (don't wait for first health transition)
 	go func() {
 		time.Sleep(2 * time.Second) // Brief delay to let app stabilize
 		slog.Info("performing initial queue drain check on startup")
-		initiateQueueDrain(ctx, storage, traktSrv)
+		drainPolicy.runAutomatic(ctx, "startup", func(ctx context.Context) {
+			initiateQueueDrain(ctx, storage, registry)
+		})
 	}()
 
 	// Listen for health state changes
@@ -3173,14 +6151,16 @@ func startQueueDrainSystem(ctx context.Context, storage store.Store, traktSrv *t
 		case state := <-stateChan:
 			if state == "live" {
 				slog.Info("trakt service restored, initiating queue drain")
-				go initiateQueueDrain(ctx, storage, traktSrv)
+				go drainPolicy.runAutomatic(ctx, "trakt_recovered", func(ctx context.Context) {
+					initiateQueueDrain(ctx, storage, registry)
+				})
 			}
 		}
 	}
 }
 
 // initiateQueueDrain starts per-user drain goroutines when Trakt becomes available.
-func initiateQueueDrain(ctx context.Context, storage store.Store, traktSrv *trakt.Trakt) {
+func initiateQueueDrain(ctx context.Context, storage store.Store, registry *provider.Registry) {
 	userIDs, err := storage.ListUsersWithQueuedEvents(ctx)
 	if err != nil {
 		slog.Error("failed to list users with queued events",
@@ -3206,7 +6186,7 @@ func initiateQueueDrain(ctx context.Context, storage store.Store, traktSrv *trak
 		wg.Add(1)
 		go func(uid string) {
 			defer wg.Done()
-			drainUserQueue(ctx, storage, traktSrv, uid)
+			drainUserQueue(ctx, storage, registry, uid)
 		}(userID)
 	}
 
@@ -3218,7 +6198,9 @@ func initiateQueueDrain(ctx context.Context, storage store.Store, traktSrv *trak
 }
 
 // drainUserQueue processes all queued events for a specific user.
-func drainUserQueue(ctx context.Context, storage store.Store, traktSrv *trakt.Trakt, userID string) {
+func drainUserQueue(ctx context.Context, storage store.Store, registry *provider.Registry, userID string) {
+	// Catch-up work yields to live scrobbles when both wait for Trakt
+	ctx = provider.WithPriority(ctx, provider.PriorityBacklog)
 	startTime := time.Now()
 	successCount := 0
 	failureCount := 0
@@ -3269,7 +6251,17 @@ func drainUserQueue(ctx context.Context, storage store.Store, traktSrv *trakt.Tr
 			}
 
 			// Attempt to send with retry
-			if err := sendEventWithRetry(ctx, storage, traktSrv, event); err != nil {
+			err := sendEventWithRetry(ctx, storage, registry, event)
+			if ctx.Err() != nil {
+				// Drain cancelled mid-flight; leave the event queued for the next drain
+				slog.Info("user queue drain cancelled",
+					"operation", "queue_drain_user_cancelled",
+					"user_id", userID,
+					"event_id", event.ID,
+				)
+				return
+			}
+			if err != nil {
 				slog.Error("queue event permanent failure",
 					"operation", "queue_event_failed",
 					"user_id", userID,
@@ -3333,8 +6325,12 @@ func drainUserQueue(ctx context.Context, storage store.Store, traktSrv *trakt.Tr
 	)
 }
 
-// sendEventWithRetry attempts to send an event with exponential backoff.
-func sendEventWithRetry(ctx context.Context, storage store.Store, traktSrv *trakt.Trakt, event store.QueuedScrobbleEvent) error {
+// sendEventWithRetry attempts to send an event to its provider with exponential backoff.
+func sendEventWithRetry(ctx context.Context, storage store.Store, registry *provider.Registry, event store.QueuedScrobbleEvent) error {
+	target, err := registry.Get(event.Provider)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, event.Provider)
+	}
 	backoffSchedule := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}
 
 	for attempt := 0; attempt < 5; attempt++ {
@@ -3351,32 +6347,61 @@ func sendEventWithRetry(ctx context.Context, storage store.Store, traktSrv *trak
 			Body:       event.ScrobbleBody,
 		}
 
-		// Attempt scrobble via Trakt client
-		// We need to construct the request ourselves here
-		err := sendScrobble(traktSrv, event.Action, cacheItem, *user)
+		err := sendScrobble(ctx, storage, target, event.Action, cacheItem, *user)
 
 		if err == nil {
+			if target.Name() == provider.DefaultName {
+				// Record against the original play time, not the drain time
+				trakt.RecordWatched(ctx, storage, user.ID, event.Action, event.ScrobbleBody, event.CreatedAt)
+				trakt.RecordActivity(ctx, storage, store.ActivityScrobble, time.Now())
+				failureMonitor.Observe(user.ID, user.Username, false, time.Now())
+			}
 			return nil // Success
 		}
 
 		// Check if it's a transient error
 		if !isTransientError(err) {
+			if target.Name() == provider.DefaultName {
+				trakt.RecordActivity(ctx, storage, store.ActivityFailure, time.Now())
+				failureMonitor.Observe(user.ID, user.Username, true, time.Now())
+			}
 			return err // Permanent failure
 		}
 
 		// Transient error - update retry count and backoff
 		if attempt < 4 {
 			storage.UpdateQueuedScrobbleRetry(ctx, event.ID, attempt+1)
-			time.Sleep(backoffSchedule[attempt])
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoffSchedule[attempt]):
+			}
 		}
 	}
 
 	return fmt.Errorf("max retries exceeded")
 }
 
-// sendScrobble sends a scrobble request to Trakt (queue drain version).
-func sendScrobble(traktSrv *trakt.Trakt, action string, item common.CacheItem, user store.User) error {
-	return traktSrv.ScrobbleFromQueue(action, item, user.AccessToken)
+// sendScrobble sends a scrobble request to a provider (queue drain version).
+func sendScrobble(ctx context.Context, storage store.Store, target provider.ScrobbleProvider, action string, item common.CacheItem, user store.User) error {
+	accessToken, err := providerAccessToken(ctx, storage, target.Name(), user)
+	if err != nil {
+		return err
+	}
+	return target.Scrobble(ctx, action, item, accessToken)
+}
+
+// providerAccessToken returns the user's access token for the named provider.
+// Trakt tokens live on the user record; other providers use provider tokens.
+func providerAccessToken(ctx context.Context, storage store.Store, name string, user store.User) (string, error) {
+	if name == provider.DefaultName {
+		return user.AccessToken, nil
+	}
+	token, err := storage.GetProviderToken(ctx, user.ID, name)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
 }
 
 // isTransientError checks if an error is temporary and worth retrying.
@@ -3396,6 +6421,9 @@ func isTransientError(err error) bool {
 func main() {
 	// init structured logging
 	logging.Init()
+	if len(os.Args) > 1 && os.Args[1] == "restore-backup" {
+		os.Exit(runRestoreBackup(os.Args[2:]))
+	}
 	// read trust proxy flag
 	trustProxy = true
 	if v := strings.ToLower(strings.TrimSpace(os.Getenv("TRUST_PROXY"))); v != "" {
@@ -3405,9 +6433,44 @@ func main() {
 	if m := strings.ToLower(strings.TrimSpace(os.Getenv("REQUEST_LOG"))); m != "" {
 		requestLogMod = m
 	}
+	if v := strings.TrimSpace(os.Getenv("REQUEST_LOG_SAMPLE")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			requestLogSample = n
+		} else {
+			slog.Warn("invalid REQUEST_LOG_SAMPLE; logging every request", "value", v)
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("PRIVACY_LOGGING"))); v == "1" || v == "true" || v == "yes" {
+		logging.SetPrivacy(true)
+	}
+	if dir := strings.TrimSpace(os.Getenv("STATIC_DIR")); dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			staticFS = newStaticFS(dir)
+			appAssets = newAssetManifest(staticFS, "dist/manifest.json")
+			slog.Info("serving static files with overrides", "dir", dir)
+		} else {
+			slog.Warn("invalid STATIC_DIR; using built-in files", "value", dir)
+		}
+	}
+	locale := strings.TrimSpace(os.Getenv("DISPLAY_LOCALE"))
+	if locale != "" && !common.KnownLocale(locale) {
+		slog.Warn("unknown DISPLAY_LOCALE; using ISO dates", "value", locale)
+	}
+	var displayLocation *time.Location
+	if v := strings.TrimSpace(os.Getenv("DISPLAY_TIMEZONE")); v != "" {
+		if loc, err := time.LoadLocation(v); err == nil {
+			displayLocation = loc
+		} else {
+			slog.Warn("invalid DISPLAY_TIMEZONE; using UTC", "value", v, "error", err)
+		}
+	}
+	displayDates = common.NewDateFormatter(locale, displayLocation)
 
 	slog.Info("starting", "version", version, "commit", commit, "date", date)
-	if os.Getenv("POSTGRESQL_URL") != "" {
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("DEMO_MODE"))); v == "1" || v == "true" || v == "yes" {
+		storage = store.NewMemoryStore()
+		slog.Warn("demo mode: using in-memory storage, all data is lost on restart")
+	} else if os.Getenv("POSTGRESQL_URL") != "" {
 		storage = store.NewPostgresqlStore(store.NewPostgresqlClient(os.Getenv("POSTGRESQL_URL")))
 		slog.Info("using postgres storage", "url", os.Getenv("POSTGRESQL_URL"))
 	} else if os.Getenv("REDIS_URL") != "" {
@@ -3416,16 +6479,160 @@ func main() {
 	} else if os.Getenv("REDIS_URI") != "" {
 		storage = store.NewRedisStore(store.NewRedisClient(os.Getenv("REDIS_URI"), os.Getenv("REDIS_PASSWORD")))
 		slog.Info("using redis storage", "uri", os.Getenv("REDIS_URI"))
+	} else if os.Getenv("CONSUL_URL") != "" {
+		prefix := os.Getenv("CONSUL_KV_PREFIX")
+		if prefix == "" {
+			prefix = "plaxt/"
+		}
+		storage = store.NewConsulStore(os.Getenv("CONSUL_URL"), os.Getenv("CONSUL_TOKEN"), prefix)
+		slog.Info("using consul storage", "url", os.Getenv("CONSUL_URL"), "prefix", prefix)
 	} else {
 		storage = store.NewDiskStore()
 		slog.Info("using disk storage")
 	}
+	loadPrivacyPreferences(context.Background())
 	apiSf = &singleflight.Group{}
 	webhookCache = newWebhookDedupeCache()
 	traktSrv = trakt.New(config.TraktClientId, config.TraktClientSecret, storage)
+	traktSrv.SetUserAgent(trakt.UserAgent(version, os.Getenv("TRAKT_USER_AGENT_SUFFIX")))
+	providers = provider.NewRegistry(traktSrv)
+	if config.SimklClientId != "" {
+		simklClient = simkl.New(config.SimklClientId, config.SimklClientSecret)
+		simklClient.SetUserAgent(trakt.UserAgent(version, os.Getenv("TRAKT_USER_AGENT_SUFFIX")))
+		providers.Register(simklClient)
+		slog.Info("simkl dual-scrobbling enabled")
+	}
+	if v := strings.TrimSpace(os.Getenv("TRAKT_SLOW_REQUEST_MS")); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			traktSrv.SetSlowRequestThreshold(time.Duration(ms) * time.Millisecond)
+		} else {
+			slog.Warn("invalid TRAKT_SLOW_REQUEST_MS; using default", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("TRAKT_GET_RETRIES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			traktSrv.SetGetRetries(n)
+		} else {
+			slog.Warn("invalid TRAKT_GET_RETRIES; retries disabled", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("TRAKT_FAULT_INJECTION")); v != "" {
+		percent, err := trakt.ParseFaultInjection(v)
+		kinds, kindsErr := trakt.ParseFaultKinds(os.Getenv("TRAKT_FAULT_KINDS"))
+		switch {
+		case err != nil:
+			slog.Warn("invalid TRAKT_FAULT_INJECTION; fault injection disabled", "value", v)
+		case kindsErr != nil:
+			slog.Warn("invalid TRAKT_FAULT_KINDS; fault injection disabled", "error", kindsErr)
+		case percent > 0:
+			traktSrv.SetFaultInjection(percent, kinds)
+			slog.Warn("trakt fault injection enabled; do not use in production", "percent", percent, "kinds", kinds)
+		}
+	}
+
+	if v := strings.TrimSpace(os.Getenv("WEBHOOK_MAX_AGE")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			replayGuard.maxAge = d
+		} else {
+			slog.Warn("invalid WEBHOOK_MAX_AGE; replay protection disabled", "value", v)
+		}
+	}
+	replayGuard.flagOnly = strings.EqualFold(strings.TrimSpace(os.Getenv("WEBHOOK_REPLAY_ACTION")), "flag")
+	if v := strings.TrimSpace(os.Getenv("WEBHOOK_INVALID_ID_BAN_AFTER")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			invalidIDs.banAfter = n
+		} else {
+			slog.Warn("invalid WEBHOOK_INVALID_ID_BAN_AFTER; banning disabled", "value", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("WEBHOOK_INVALID_ID_BAN_DURATION")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			invalidIDs.banFor = d
+		} else {
+			slog.Warn("invalid WEBHOOK_INVALID_ID_BAN_DURATION; using default", "value", v, "default", invalidIDs.banFor)
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("WEBHOOK_ALWAYS_200"))); v == "1" || v == "true" || v == "yes" {
+		webhookAlwaysOK = true
+		slog.Info("webhook failures will be answered with 200", "header", webhookStatusHeader)
+	}
+	if replayGuard.maxAge > 0 {
+		slog.Info("webhook replay protection enabled", "max_age", replayGuard.maxAge, "action", replayGuard.metrics().Action)
+	}
+
+	failureMonitor = notify.NewFailureMonitor(failureMonitorConfigFromEnv())
+	traktSrv.SetFailureMonitor(failureMonitor)
+	cfg := failureMonitor.Config()
+	slog.Info("scrobble anomaly alerts enabled",
+		"window", cfg.Window,
+		"failure_rate", cfg.FailureRate,
+		"min_events", cfg.MinEvents,
+		"user_failures", cfg.UserFailures,
+		"webhook", cfg.WebhookURL != "",
+	)
+
+	slots, liveWeight := provider.DefaultSchedulerSlots, provider.DefaultSchedulerLiveWeight
+	if v := strings.TrimSpace(os.Getenv("SCROBBLE_CONCURRENCY")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			slots = n
+		} else {
+			slog.Warn("invalid SCROBBLE_CONCURRENCY; using default", "value", v, "default", slots)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("SCROBBLE_LIVE_WEIGHT")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			liveWeight = n
+		} else {
+			slog.Warn("invalid SCROBBLE_LIVE_WEIGHT; using default", "value", v, "default", liveWeight)
+		}
+	}
+	if slots > 0 {
+		scrobbleScheduler = provider.NewScheduler(slots, liveWeight)
+		traktSrv.SetScheduler(scrobbleScheduler)
+		slog.Info("scrobble scheduler enabled", "slots", slots, "live_weight", liveWeight)
+	}
+
+	if v := strings.TrimSpace(os.Getenv("SCROBBLE_START_DELAY")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			traktSrv.SetStartDelay(d)
+			slog.Info("scrobble start delay enabled", "delay", d)
+		} else {
+			slog.Warn("invalid SCROBBLE_START_DELAY; starts are sent immediately", "value", v)
+		}
+	}
+
+	if v := strings.TrimSpace(os.Getenv("SCROBBLE_CONFLICT_POLICY")); v != "" {
+		if policy, err := trakt.ParseConflictPolicy(v); err == nil {
+			traktSrv.SetConflictPolicy(policy)
+		} else {
+			slog.Warn("invalid SCROBBLE_CONFLICT_POLICY; using latest-wins", "error", err)
+		}
+	}
+
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("QUEUE_DRAIN_MODE"))); mode {
+	case "", "auto":
+	case "trigger":
+		drainPolicy.triggerOnly = true
+	default:
+		slog.Warn("invalid QUEUE_DRAIN_MODE; using auto", "value", mode)
+	}
+	if v := strings.TrimSpace(os.Getenv("QUEUE_DRAIN_WINDOW")); v != "" {
+		if window, err := parseDrainWindow(v); err == nil {
+			drainPolicy.window = window
+		} else {
+			slog.Warn("invalid QUEUE_DRAIN_WINDOW; drains run whenever trakt recovers", "error", err)
+		}
+	}
+	if st := drainPolicy.status(); st.Mode != "auto" || st.Window != "" {
+		slog.Info("queue drain schedule", "mode", st.Mode, "window", st.Window)
+	}
 
 	// Initialize queue monitoring
 	queueEventLog = store.NewQueueEventLog(100)
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("QUEUE_EVENT_LOG_PERSIST"))); v == "1" || v == "true" || v == "yes" {
+		queueEventLog.Persist(storage)
+		slog.Info("queue event log persistence enabled", "retention", store.QueueLogRetention, "max_events", store.MaxQueueLogEvents)
+	}
 	drainStateTracker = NewDrainStateTracker()
 	traktSrv.SetQueueEventLog(queueEventLog)
 	slog.Info("queue monitoring initialized")
@@ -3433,7 +6640,58 @@ func main() {
 	// Start queue drain system
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go startQueueDrainSystem(ctx, storage, traktSrv)
+	go startQueueDrainSystem(ctx, storage, providers)
+	go startTrashPurger(ctx)
+	if v := strings.TrimSpace(os.Getenv("HEARTBEAT_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			slog.Info("heartbeat enabled", "interval", d, "webhook", failureMonitor.Config().WebhookURL != "")
+			go startHeartbeat(ctx, d)
+		} else {
+			slog.Warn("invalid HEARTBEAT_INTERVAL; heartbeat disabled", "value", v)
+		}
+	}
+	plexVerifyInterval := 24 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("PLEX_WEBHOOK_VERIFY_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && (d == 0 || d >= time.Minute) {
+			plexVerifyInterval = d
+		} else {
+			slog.Warn("invalid PLEX_WEBHOOK_VERIFY_INTERVAL; using default", "value", v)
+		}
+	}
+	if plexVerifyInterval > 0 {
+		go startPlexWebhookVerifier(ctx, plexVerifyInterval)
+	}
+	// RETENTION_DAYS applies to every category; RETENTION_<CATEGORY>_DAYS
+	// overrides it, with 0 keeping that category to its built-in limits.
+	defaultRetentionDays := 0
+	if v := strings.TrimSpace(os.Getenv("RETENTION_DAYS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			defaultRetentionDays = n
+		} else {
+			slog.Warn("invalid RETENTION_DAYS; keeping built-in limits", "value", v)
+		}
+	}
+	for _, category := range retentionCategories() {
+		days := defaultRetentionDays
+		name := "RETENTION_" + strings.ToUpper(category) + "_DAYS"
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				days = n
+			} else {
+				slog.Warn("invalid "+name+"; using RETENTION_DAYS", "value", v)
+			}
+		}
+		if days > 0 {
+			retentionPolicy[category] = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if len(retentionPolicy) > 0 {
+		slog.Info("retention purge enabled", "policy", retentionPolicy)
+		go startRetentionPurger(ctx)
+	}
+	if _, isDisk := storage.(*store.DiskStore); isDisk {
+		startKeystoreBackups(ctx)
+	}
 
 	// Start retry queue worker (PostgreSQL only - FR-016)
 	// This worker processes failed scrobbles from the retry_queue_items table
@@ -3444,10 +6702,80 @@ func main() {
 		slog.Info("retry queue worker disabled (PostgreSQL storage required)")
 	}
 
+	if v := strings.TrimSpace(os.Getenv("ADMIN_ACCOUNTS")); v != "" {
+		accounts, err := adminauth.ParseAccounts(v)
+		if err != nil {
+			slog.Error("invalid ADMIN_ACCOUNTS", "error", err)
+			os.Exit(1)
+		}
+		adminAccounts = accounts
+		slog.Info("admin authentication enabled", "accounts", accounts.Len())
+		if v := strings.TrimSpace(os.Getenv("ADMIN_LOCKOUT_THRESHOLD")); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				adminLoginGuard.Threshold = n
+				adminTOTPGuard.Threshold = n
+			} else {
+				slog.Warn("invalid ADMIN_LOCKOUT_THRESHOLD; using default", "value", v, "default", adminauth.DefaultLockoutThreshold)
+			}
+		}
+		if v := strings.ToLower(strings.TrimSpace(os.Getenv("ADMIN_REQUIRE_TOTP"))); v == "1" || v == "true" || v == "yes" {
+			adminRequireTOTP = true
+			slog.Info("admin two-factor authentication required")
+		}
+	}
+	if issuer := strings.TrimSpace(os.Getenv("ADMIN_OIDC_ISSUER")); issuer != "" {
+		roles, err := adminauth.ParseRoleGroups(os.Getenv("ADMIN_OIDC_ROLE_GROUPS"))
+		if err != nil {
+			slog.Error("invalid ADMIN_OIDC_ROLE_GROUPS", "error", err)
+			os.Exit(1)
+		}
+		provider, err := adminauth.NewOIDCProvider(adminauth.OIDCConfig{
+			Issuer:       issuer,
+			ClientID:     strings.TrimSpace(os.Getenv("ADMIN_OIDC_CLIENT_ID")),
+			ClientSecret: os.Getenv("ADMIN_OIDC_CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv("ADMIN_OIDC_SCOPES")),
+			GroupsClaim:  strings.TrimSpace(os.Getenv("ADMIN_OIDC_GROUPS_CLAIM")),
+			RoleGroups:   roles,
+		})
+		if err != nil {
+			slog.Error("invalid admin OIDC configuration", "error", err)
+			os.Exit(1)
+		}
+		adminOIDC = provider
+		adminOIDCRedirectURL = strings.TrimSpace(os.Getenv("ADMIN_OIDC_REDIRECT_URL"))
+		slog.Info("admin single sign-on enabled", "issuer", issuer, "role_groups", len(roles))
+	}
+	if header := strings.TrimSpace(os.Getenv("ADMIN_PROXY_USER_HEADER")); header != "" {
+		trusted, err := adminauth.ParseTrustedProxies(os.Getenv("ADMIN_PROXY_TRUSTED_IPS"))
+		if err != nil {
+			slog.Error("invalid ADMIN_PROXY_TRUSTED_IPS; it is required with ADMIN_PROXY_USER_HEADER", "error", err)
+			os.Exit(1)
+		}
+		proxyAuth := &adminauth.ProxyAuth{
+			UserHeader:   header,
+			GroupsHeader: strings.TrimSpace(os.Getenv("ADMIN_PROXY_GROUPS_HEADER")),
+			Trusted:      trusted,
+		}
+		if v := strings.TrimSpace(os.Getenv("ADMIN_PROXY_ROLE_GROUPS")); v != "" {
+			if proxyAuth.RoleGroups, err = adminauth.ParseRoleGroups(v); err != nil {
+				slog.Error("invalid ADMIN_PROXY_ROLE_GROUPS", "error", err)
+				os.Exit(1)
+			}
+		} else {
+			slog.Warn("ADMIN_PROXY_ROLE_GROUPS not set; every user the proxy lets through is an admin")
+		}
+		adminProxyAuth = proxyAuth
+		slog.Info("admin proxy authentication enabled", "header", header, "trusted_proxies", len(trusted))
+	}
+	if !adminAuthEnabled() {
+		slog.Warn("ADMIN_ACCOUNTS not set; the admin dashboard is open to anyone who can reach it")
+	}
+
 	router := mux.NewRouter()
 	// Assumption: Behind a proper web server (nginx/traefik, etc) that removes/replaces trusted headers
 	router.Use(recoveryMiddleware)
 	router.Use(requestLoggerMiddleware())
+	router.Use(rememberPeerAddr)
 	if trustProxy {
 		router.Use(handlers.ProxyHeaders)
 	}
@@ -3460,37 +6788,75 @@ func main() {
 	} else if os.Getenv("ALLOWED_HOSTNAMES") != "" {
 		router.Use(allowedHostsHandler(os.Getenv("ALLOWED_HOSTNAMES")))
 	}
-	router.PathPrefix("/static/").Handler(cacheStaticFiles(http.StripPrefix("/static/", http.FileServer(http.Dir("static")))))
+	router.Use(adminAuthMiddleware)
+	router.PathPrefix("/static/").Handler(cacheStaticFiles(http.StripPrefix("/static/", http.FileServer(http.FS(staticFS)))))
 	router.HandleFunc("/authorize", authorize).Methods("GET")
 	router.HandleFunc("/authorize/family/member", authorizeFamilyMember).Methods("GET")
 	router.HandleFunc("/manual/authorize", authorize).Methods("GET")
 	router.HandleFunc("/oauth/state", createAuthState).Methods("POST")
 	router.HandleFunc("/oauth/family/state", createFamilyAuthState).Methods("POST")
-	router.HandleFunc("/api", api).Methods("POST")
+	router.HandleFunc("/api", webhookResponseCodes(api)).Methods("POST")
+	router.HandleFunc("/simkl/authorize", simklAuthorize).Methods("GET")
+	router.HandleFunc("/simkl/callback", simklCallback).Methods("GET")
 	router.HandleFunc("/api/telemetry", telemetryHandler).Methods("POST")
 	router.HandleFunc("/users/{id}/trakt-display-name", updateTraktDisplayName).Methods("POST")
+	router.HandleFunc("/users/{id}/letterboxd.csv", exportLetterboxdDiary).Methods("GET")
+	router.HandleFunc("/webhooks/{id}/plex", registerPlexWebhook).Methods("POST")
+	router.HandleFunc("/webhooks/{id}/reachability", checkWebhookReachability).Methods("POST")
+	router.HandleFunc("/webhooks/{id}/reachability", getWebhookReachability).Methods("GET")
+	router.HandleFunc("/reachability/{nonce}", serveReachabilityProbe).Methods("GET")
 	router.Handle("/healthcheck", healthcheckHandler()).Methods("GET")
 
 	// Admin routes
 	router.HandleFunc("/admin", renderAdminDashboard).Methods("GET")
 	router.HandleFunc("/admin/family", renderFamilyAdmin).Methods("GET")
+	router.HandleFunc("/admin/login", startAdminLogin).Methods("GET")
+	router.HandleFunc("/admin/oidc/callback", finishAdminLogin).Methods("GET")
+	router.HandleFunc("/admin/logout", adminLogout).Methods("POST")
+	router.HandleFunc("/admin/2fa", renderAdminTwoFactor).Methods("GET")
+	router.HandleFunc("/admin/api/me", getAdminSession).Methods("GET")
+	router.HandleFunc("/admin/api/me/totp", getAdminTOTPStatus).Methods("GET")
+	router.HandleFunc("/admin/api/me/totp", beginAdminTOTPEnrollment).Methods("POST")
+	router.HandleFunc("/admin/api/me/totp", disableAdminTOTP).Methods("DELETE")
+	router.HandleFunc("/admin/api/me/totp/confirm", confirmAdminTOTPEnrollment).Methods("POST")
+	router.HandleFunc("/admin/api/me/totp/verify", verifyAdminTOTP).Methods("POST")
+	router.HandleFunc("/admin/api/admin-accounts/{username}/totp", resetAdminAccountTOTP).Methods("DELETE")
+	router.HandleFunc("/admin/api/auth/attempts", getAdminLoginAttempts).Methods("GET")
+	router.HandleFunc("/admin/api/stats", getAdminStats).Methods("GET")
+	router.HandleFunc("/admin/api/activity", getAdminActivity).Methods("GET")
+	router.HandleFunc("/admin/api/webhooks/events", getWebhookEvents).Methods("GET")
 	router.HandleFunc("/admin/api/users", listAdminUsers).Methods("GET")
 	router.HandleFunc("/admin/api/users/{id}", getAdminUser).Methods("GET")
 	router.HandleFunc("/admin/api/users/{id}", updateAdminUser).Methods("PUT")
 	router.HandleFunc("/admin/api/users/{id}", deleteAdminUser).Methods("DELETE")
+	router.HandleFunc("/admin/api/users/{id}/scrobble", submitManualScrobble).Methods("POST")
+	router.HandleFunc("/admin/api/users/{id}/history", pushUserHistory).Methods("POST")
+	router.HandleFunc("/admin/api/users/{id}/letterboxd.csv", exportLetterboxdDiary).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}/providers", listUserProviders).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}/preferences", getUserPreferences).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}/preferences", putUserPreferences).Methods("PUT")
+	router.HandleFunc("/admin/api/preferences/schema", getPreferencesSchema).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}/metrics", getUserWebhookMetrics).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}/providers/{provider}", deleteUserProvider).Methods("DELETE")
 
 	// Queue monitoring routes
 	router.HandleFunc("/admin/queue", renderQueueMonitor).Methods("GET")
 	router.HandleFunc("/admin/api/queue/status", getQueueStatus).Methods("GET")
 	router.HandleFunc("/admin/api/queue/events", getQueueEvents).Methods("GET")
+	router.HandleFunc("/admin/api/queue/drain", triggerQueueDrain).Methods("POST")
 	router.HandleFunc("/admin/api/queue/user/{id}", getUserQueueDetail).Methods("GET")
+	router.HandleFunc("/admin/api/queue/user/{id}/events/{event_id}", deleteUserQueueEvent).Methods("DELETE")
 
 	// Family group admin routes
 	router.HandleFunc("/admin/api/family-groups", listFamilyGroups).Methods("GET")
 	router.HandleFunc("/admin/api/family-groups/{id}", getFamilyGroupDetail).Methods("GET")
+	router.HandleFunc("/admin/api/family-groups/{id}/metrics", getFamilyGroupWebhookMetrics).Methods("GET")
 	router.HandleFunc("/admin/api/family-groups/{id}/members", addFamilyGroupMember).Methods("POST")
 	router.HandleFunc("/admin/api/family-groups/{group_id}/members/{member_id}", removeFamilyGroupMember).Methods("DELETE")
 	router.HandleFunc("/admin/api/family-groups/{id}", deleteFamilyGroup).Methods("DELETE")
+	router.HandleFunc("/admin/api/trash", listTrash).Methods("GET")
+	router.HandleFunc("/admin/api/trash/{id}/restore", restoreTrashEntry).Methods("POST")
+	router.HandleFunc("/admin/api/trash/{id}", deleteTrashEntry).Methods("DELETE")
 
 	router.HandleFunc("/", renderLandingPage).Methods("GET")
 	listen := os.Getenv("LISTEN")
@@ -3513,6 +6879,13 @@ func requestLoggerMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			sr := &statusRecorder{ResponseWriter: w, status: 200}
+			correlationID := strings.TrimSpace(r.Header.Get(trakt.CorrelationIDHeader))
+			if correlationID == "" {
+				correlationID = generateCorrelationID()
+			}
+			info := &requestLogInfo{}
+			ctx := trakt.WithCorrelationID(r.Context(), correlationID)
+			r = r.WithContext(context.WithValue(ctx, requestLogKey{}, info))
 			start := time.Now()
 			next.ServeHTTP(sr, r)
 			d := time.Since(start)
@@ -3529,10 +6902,11 @@ func requestLoggerMiddleware() mux.MiddlewareFunc {
 			default: // errors
 				shouldLog = sr.status >= 400
 			}
-			if !shouldLog {
+			if !shouldLog || !sampleRequestLog(r.URL.Path, sr.status) {
 				return
 			}
-			attrs := []any{"method", r.Method, "path", r.URL.Path, "status", sr.status, "duration_ms", d.Milliseconds(), "remote", r.RemoteAddr}
+			attrs := []any{"method", r.Method, "path", r.URL.Path, "status", sr.status, "duration_ms", d.Milliseconds(), "remote", r.RemoteAddr, "correlation_id", correlationID}
+			attrs = append(attrs, info.fields()...)
 			if sr.status >= 500 {
 				slog.Error("request", attrs...)
 			} else if sr.status >= 400 {
@@ -3544,6 +6918,44 @@ func requestLoggerMiddleware() mux.MiddlewareFunc {
 	}
 }
 
+// requestLogKey is the context key for the request's *requestLogInfo.
+type requestLogKey struct{}
+
+// requestLogInfo collects details only a handler can resolve (the Plaxt user,
+// the webhook event) for the access log line.
+type requestLogInfo struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+func (i *requestLogInfo) fields() []any {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.attrs
+}
+
+// annotateRequestLog adds key/value pairs to the access log line of the
+// request carrying ctx. It is a no-op outside requestLoggerMiddleware.
+func annotateRequestLog(ctx context.Context, args ...any) {
+	info, _ := ctx.Value(requestLogKey{}).(*requestLogInfo)
+	if info == nil {
+		return
+	}
+	info.mu.Lock()
+	info.attrs = append(info.attrs, args...)
+	info.mu.Unlock()
+}
+
+// sampleRequestLog reports whether a request that would be logged should be.
+// With REQUEST_LOG_SAMPLE=N only 1 in N successful webhooks is kept; errors
+// are always logged.
+func sampleRequestLog(path string, status int) bool {
+	if requestLogSample <= 1 || path != "/api" || status >= 400 {
+		return true
+	}
+	return (requestLogSampled.Add(1)-1)%uint64(requestLogSample) == 0
+}
+
 // statusRecorder captures HTTP status codes.
 type statusRecorder struct {
 	http.ResponseWriter
@@ -3557,7 +6969,8 @@ func (sr *statusRecorder) WriteHeader(code int) {
 
 func cacheStaticFiles(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains(r.URL.Path, "/dist/") {
+		// Hashed dist bundles and ?v= fallbacks change URL whenever their content does
+		if strings.Contains(r.URL.Path, "/dist/") || r.URL.Query().Get("v") != "" {
 			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 		}
 		next.ServeHTTP(w, r)
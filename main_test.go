@@ -5,16 +5,31 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"crovlune/plaxt/lib/adminauth"
 	"crovlune/plaxt/lib/common"
+	"crovlune/plaxt/lib/logging"
+	"crovlune/plaxt/lib/plex"
+	"crovlune/plaxt/lib/provider"
+	"crovlune/plaxt/lib/simkl"
 	"crovlune/plaxt/lib/store"
+	"crovlune/plaxt/plexhooks"
+	"crovlune/plaxt/lib/trakt"
+	"crovlune/plaxt/lib/trakt/trakttest"
 
+	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
@@ -115,6 +130,9 @@ type MockSuccessStore struct{}
 
 func (s MockSuccessStore) Ping(ctx context.Context) error            { return nil }
 func (s MockSuccessStore) WriteUser(user store.User)                 {}
+func (s MockSuccessStore) CompareAndSwapUser(ctx context.Context, user store.User) error {
+	return nil
+}
 func (s MockSuccessStore) GetUser(id string) *store.User             { return nil }
 func (s MockSuccessStore) GetUserByName(username string) *store.User { return nil }
 func (s MockSuccessStore) DeleteUser(id, username string) bool       { return true }
@@ -152,6 +170,10 @@ func (s MockSuccessStore) CreateFamilyGroup(ctx context.Context, group *store.Fa
 	return store.ErrNotSupported
 }
 
+func (s MockSuccessStore) CreateFamilyGroupWithMembers(ctx context.Context, group *store.FamilyGroup, members []*store.GroupMember) error {
+	return store.ErrNotSupported
+}
+
 func (s MockSuccessStore) GetFamilyGroup(ctx context.Context, groupID string) (*store.FamilyGroup, error) {
 	return nil, store.ErrNotSupported
 }
@@ -212,6 +234,9 @@ type MockFailStore struct{}
 
 func (s MockFailStore) Ping(ctx context.Context) error            { return errors.New("OH NO") }
 func (s MockFailStore) WriteUser(user store.User)                 { panic(errors.New("OH NO")) }
+func (s MockFailStore) CompareAndSwapUser(ctx context.Context, user store.User) error {
+	return errors.New("OH NO")
+}
 func (s MockFailStore) GetUser(id string) *store.User             { panic(errors.New("OH NO")) }
 func (s MockFailStore) GetUserByName(username string) *store.User { panic(errors.New("OH NO")) }
 func (s MockFailStore) DeleteUser(id, username string) bool       { return false }
@@ -249,6 +274,10 @@ func (s MockFailStore) CreateFamilyGroup(ctx context.Context, group *store.Famil
 	return errors.New("OH NO")
 }
 
+func (s MockFailStore) CreateFamilyGroupWithMembers(ctx context.Context, group *store.FamilyGroup, members []*store.GroupMember) error {
+	return errors.New("OH NO")
+}
+
 func (s MockFailStore) GetFamilyGroup(ctx context.Context, groupID string) (*store.FamilyGroup, error) {
 	return nil, errors.New("OH NO")
 }
@@ -334,9 +363,9 @@ func TestPersistAuthorizedUserRenewsExistingUser(t *testing.T) {
 	storage = testStore
 
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 
-	user, reused, err := persistAuthorizedUser("tester", existing.ID, "newAccess", "newRefresh", nil, tokenExpiry)
+	user, reused, err := persistAuthorizedUser("tester", existing.ID, "newAccess", "newRefresh", nil, tokenExpiry, time.Time{})
 	assert.NoError(t, err)
 
 	assert.True(t, reused)
@@ -360,9 +389,9 @@ func TestPersistAuthorizedUserAllowsCaseInsensitiveMatch(t *testing.T) {
 	storage = testStore
 
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("MixedCaseUser", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("MixedCaseUser", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 
-	user, reused, err := persistAuthorizedUser("mixedcaseuser", existing.ID, "newAccess", "newRefresh", nil, tokenExpiry)
+	user, reused, err := persistAuthorizedUser("mixedcaseuser", existing.ID, "newAccess", "newRefresh", nil, tokenExpiry, time.Time{})
 	assert.NoError(t, err)
 	assert.True(t, reused)
 	if assert.NotNil(t, user) {
@@ -385,9 +414,9 @@ func TestPersistAuthorizedUserCreatesNewWhenIdMismatch(t *testing.T) {
 	storage = testStore
 
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	other := store.NewUser("other", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	other := store.NewUser("other", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 
-	user, reused, err := persistAuthorizedUser("tester", other.ID, "newAccess", "newRefresh", nil, tokenExpiry)
+	user, reused, err := persistAuthorizedUser("tester", other.ID, "newAccess", "newRefresh", nil, tokenExpiry, time.Time{})
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, errUsernameMismatch))
 	assert.False(t, reused)
@@ -409,7 +438,7 @@ func TestPersistAuthorizedUserCreatesNewUser(t *testing.T) {
 
 	displayName := "Alice"
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user, reused, err := persistAuthorizedUser("tester", "", "newAccess", "newRefresh", &displayName, tokenExpiry)
+	user, reused, err := persistAuthorizedUser("tester", "", "newAccess", "newRefresh", &displayName, tokenExpiry, time.Time{})
 	assert.NoError(t, err)
 	assert.False(t, reused)
 	if assert.NotNil(t, user) {
@@ -426,6 +455,57 @@ func TestPersistAuthorizedUserCreatesNewUser(t *testing.T) {
 	}
 }
 
+func TestCreateAuthStateFormPost(t *testing.T) {
+	prevStorage, prevTrakt, prevStates := storage, traktSrv, authStates
+	defer func() { storage, traktSrv, authStates = prevStorage, prevTrakt, prevStates }()
+	s := newPersistTestStore()
+	storage = s
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+	authStates = newAuthStateStore()
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://plaxt.example/oauth/state", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		createAuthState(rr, req)
+		return rr
+	}
+
+	rr := post(url.Values{"mode": {"onboarding"}, "username": {"Alice"}})
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "trakt.tv", location.Host)
+		assert.Equal(t, "client-id", location.Query().Get("client_id"))
+		assert.Equal(t, "http://plaxt.example/authorize", location.Query().Get("redirect_uri"))
+		state, ok := authStates.Consume(location.Query().Get("state"))
+		assert.True(t, ok)
+		assert.Equal(t, "alice", state.Username)
+	}
+
+	rr = post(url.Values{"mode": {"renew"}, "id": {"u1"}})
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Contains(t, rr.Header().Get("Location"), url.QueryEscape("http://plaxt.example/manual/authorize"))
+
+	rr = post(url.Values{"mode": {"renew"}, "id": {"missing"}})
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	back, err := url.Parse(rr.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/", back.Path)
+		assert.Equal(t, "renew", back.Query().Get("mode"))
+		assert.Equal(t, "error", back.Query().Get("result"))
+		assert.Equal(t, "user not found", back.Query().Get("error"))
+	}
+
+	rr = post(url.Values{"mode": {"onboarding"}})
+	back, err = url.Parse(rr.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "authorize", back.Query().Get("step"))
+		assert.Equal(t, "missing username", back.Query().Get("error"))
+	}
+}
+
 func TestAuthorizeSuccessRedirectsWithExistingUser(t *testing.T) {
 	prevStorage := storage
 	prevAuth := authRequestFunc
@@ -443,7 +523,7 @@ func TestAuthorizeSuccessRedirectsWithExistingUser(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 	existingID := existing.ID
 	authStates = newAuthStateStore()
 	corrID := generateCorrelationID()
@@ -462,11 +542,8 @@ func TestAuthorizeSuccessRedirectsWithExistingUser(t *testing.T) {
 		CorrelationID: corrID,
 	})
 
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
-		return map[string]interface{}{
-			"access_token":  "newAccess",
-			"refresh_token": "newRefresh",
-		}, true
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
+		return &trakt.TokenResponse{AccessToken: "newAccess", RefreshToken: "newRefresh"}, nil
 	}
 
 	fetchDisplayNameFunc = func(ctx context.Context, token string) (string, bool, error) {
@@ -523,7 +600,7 @@ func TestAuthorizeSuccessUsesForwardedHeaders(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	authStates = newAuthStateStore()
 	corrID := generateCorrelationID()
@@ -534,11 +611,8 @@ func TestAuthorizeSuccessUsesForwardedHeaders(t *testing.T) {
 		CorrelationID: corrID,
 	})
 
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
-		return map[string]interface{}{
-			"access_token":  "newAccess",
-			"refresh_token": "newRefresh",
-		}, true
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
+		return &trakt.TokenResponse{AccessToken: "newAccess", RefreshToken: "newRefresh"}, nil
 	}
 	fetchDisplayNameFunc = func(ctx context.Context, token string) (string, bool, error) {
 		return "Alice", false, nil
@@ -584,7 +658,7 @@ func TestAuthorizeManualRenewFallsBackToStoredUsername(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("MixedCaseUser", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("MixedCaseUser", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 	existingID := existing.ID
 	authStates = newAuthStateStore()
 	corrID := generateCorrelationID()
@@ -596,12 +670,9 @@ func TestAuthorizeManualRenewFallsBackToStoredUsername(t *testing.T) {
 	})
 
 	var authUsername string
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
 		authUsername = username
-		return map[string]interface{}{
-			"access_token":  "newAccess",
-			"refresh_token": "newRefresh",
-		}, true
+		return &trakt.TokenResponse{AccessToken: "newAccess", RefreshToken: "newRefresh"}, nil
 	}
 
 	fetchDisplayNameFunc = func(ctx context.Context, token string) (string, bool, error) {
@@ -656,7 +727,7 @@ func TestAuthorizeCancellationDoesNotUpdateTokens(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 	existingID := existing.ID
 	authStates = newAuthStateStore()
 	corrID := generateCorrelationID()
@@ -667,7 +738,7 @@ func TestAuthorizeCancellationDoesNotUpdateTokens(t *testing.T) {
 		CorrelationID: corrID,
 	})
 
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
 		panic("should not be called when code missing")
 	}
 
@@ -718,7 +789,7 @@ func TestAuthorizeRequestsManualDisplayNameOnFetchFailure(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 	authStates = newAuthStateStore()
 	corrID := generateCorrelationID()
 	stateToken := createStateToken(authState{
@@ -728,11 +799,8 @@ func TestAuthorizeRequestsManualDisplayNameOnFetchFailure(t *testing.T) {
 		CorrelationID: corrID,
 	})
 
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
-		return map[string]interface{}{
-			"access_token":  "newAccess",
-			"refresh_token": "newRefresh",
-		}, true
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
+		return &trakt.TokenResponse{AccessToken: "newAccess", RefreshToken: "newRefresh"}, nil
 	}
 
 	fetchDisplayNameFunc = func(ctx context.Context, token string) (string, bool, error) {
@@ -780,11 +848,8 @@ func TestAuthorizeSuccessWithNewUserKeepsOnboardingMode(t *testing.T) {
 		Username: "freshuser",
 	})
 
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
-		return map[string]interface{}{
-			"access_token":  "access",
-			"refresh_token": "refresh",
-		}, true
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
+		return &trakt.TokenResponse{AccessToken: "access", RefreshToken: "refresh"}, nil
 	}
 
 	traktSrv = nil
@@ -855,17 +920,17 @@ func TestAuthorizeWithTraktErrorReturnsDetailedError(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, testStore)
+	existing := store.NewUser("tester", "oldAccess", "oldRefresh", nil, tokenExpiry, time.Time{}, testStore)
 	existingID := existing.ID
 
 	// Mock Trakt returning error details
-	authRequestFunc = func(redirectURI, username, code, refreshToken, grantType string) (map[string]interface{}, bool) {
-		return map[string]interface{}{
-			"http_status":       400,
-			"http_status_text":  "400 Bad Request",
-			"error":             "invalid_grant",
-			"error_description": "The authorization code has expired",
-		}, false
+	authRequestFunc = func(ctx context.Context, redirectURI, username, code, refreshToken, grantType string) (*trakt.TokenResponse, *trakt.TokenError) {
+		return nil, &trakt.TokenError{
+			HTTPStatus:     400,
+			HTTPStatusText: "400 Bad Request",
+			Code:           "invalid_grant",
+			Description:    "The authorization code has expired",
+		}
 	}
 
 	traktSrv = nil
@@ -919,7 +984,7 @@ func TestPrepareAuthorizePage_OnboardingSuccessShowsWebhookStep(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	req := httptest.NewRequest("GET", "/?result=success&id="+user.ID+"&username=tester", nil)
 	req.Host = "plaxt.test"
@@ -942,7 +1007,7 @@ func TestPrepareAuthorizePage_ManualSuccessActivatesResultStep(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	req := httptest.NewRequest("GET", "/?mode=renew&id="+user.ID+"&result=success&username=tester", nil)
 	req.Host = "plaxt.test"
@@ -967,7 +1032,7 @@ func TestPrepareAuthorizePage_ManualErrorShowsBanner(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	req := httptest.NewRequest("GET", "/?mode=renew&id="+user.ID+"&result=error&error=boom&username=tester", nil)
 	req.Host = "plaxt.test"
@@ -980,6 +1045,38 @@ func TestPrepareAuthorizePage_ManualErrorShowsBanner(t *testing.T) {
 	assert.Equal(t, StepActive, page.Manual.Steps[2].State)
 }
 
+func TestPrepareAuthorizePage_TokenHealthForID(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+
+	testStore := newPersistTestStore()
+	storage = testStore
+	tokenExpiry := time.Now().Add(24 * time.Hour)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
+
+	req := httptest.NewRequest("GET", "/?id="+user.ID, nil)
+	req.Host = "plaxt.test"
+
+	page := prepareAuthorizePage(req)
+	if assert.NotNil(t, page.TokenHealth) {
+		assert.Equal(t, user.ID, page.TokenHealth.UserID)
+		assert.Equal(t, "tester", page.TokenHealth.Username)
+		assert.Equal(t, "warning", page.TokenHealth.Status)
+		assert.Equal(t, tokenExpiry.UTC().Format(time.RFC3339), page.TokenHealth.ExpiresAt)
+		assert.NotEmpty(t, page.TokenHealth.Remaining)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Host = "plaxt.test"
+	assert.Nil(t, prepareAuthorizePage(req).TokenHealth)
+}
+
+func TestTokenHealthStatus(t *testing.T) {
+	assert.Equal(t, "expired", tokenHealthStatus(time.Now().Add(-time.Minute)))
+	assert.Equal(t, "warning", tokenHealthStatus(time.Now().Add(time.Hour)))
+	assert.Equal(t, "healthy", tokenHealthStatus(time.Now().Add(72*time.Hour)))
+}
+
 func TestPrepareAuthorizePage_ManualNoSelectionDefaultsToSelectStep(t *testing.T) {
 	prevStorage := storage
 	defer func() { storage = prevStorage }()
@@ -1004,7 +1101,7 @@ func TestPrepareAuthorizePage_ManualIncludesDisplayName(t *testing.T) {
 	storage = testStore
 	display := "Alice Smith"
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", &display, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", &display, tokenExpiry, time.Time{}, testStore)
 
 	req := httptest.NewRequest("GET", "/?mode=renew&id="+user.ID, nil)
 	req.Host = "plaxt.test"
@@ -1024,7 +1121,7 @@ func TestPrepareAuthorizePage_ManualMarksDisplayNameMissing(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	req := httptest.NewRequest("GET", "/?mode=renew&id="+user.ID+"&display_name_missing=1", nil)
 	req.Host = "plaxt.test"
@@ -1048,7 +1145,7 @@ func TestUpdateTraktDisplayNameSuccess(t *testing.T) {
 	testStore := newPersistTestStore()
 	storage = testStore
 	tokenExpiry := time.Now().Add(90 * 24 * time.Hour)
-	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, testStore)
+	user := store.NewUser("tester", "access", "refresh", nil, tokenExpiry, time.Time{}, testStore)
 
 	body := bytes.NewBufferString(`{"display_name":"` + strings.Repeat("Z", common.MaxTraktDisplayNameLength+3) + `"}`)
 	req := httptest.NewRequest("POST", "/users/"+user.ID+"/trakt-display-name", body)
@@ -1088,8 +1185,18 @@ func TestUpdateTraktDisplayNameNotFound(t *testing.T) {
 }
 
 type persistTestStore struct {
-	users  map[string]store.User
-	byName map[string]string
+	users          map[string]store.User
+	byName         map[string]string
+	providerTokens map[string]store.ProviderToken
+	watched        []store.WatchedMovie
+	trash          map[string]store.TrashEntry
+	sessions       map[string]store.PlaybackSession
+	preferences    map[string]store.UserPreferences
+	webhookStats   map[string]map[time.Time]store.WebhookCounts
+	adminTOTP      map[string]store.AdminTOTP
+	activity       map[time.Time]store.ActivityCounts
+	queueLog       []store.QueueLogEvent
+	plexWebhooks   map[string]store.PlexWebhook
 }
 
 func newPersistTestStore() *persistTestStore {
@@ -1108,10 +1215,23 @@ func (s *persistTestStore) WriteUser(user store.User) {
 	if s.byName == nil {
 		s.byName = make(map[string]string)
 	}
+	user.Version = s.users[user.ID].Version + 1
 	s.users[user.ID] = user
 	s.byName[user.Username] = user.ID
 }
 
+func (s *persistTestStore) CompareAndSwapUser(ctx context.Context, user store.User) error {
+	current, ok := s.users[user.ID]
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	if current.Version != user.Version {
+		return store.ErrUserVersionConflict
+	}
+	s.WriteUser(user)
+	return nil
+}
+
 func (s *persistTestStore) GetUser(id string) *store.User {
 	if s.users == nil {
 		return nil
@@ -1195,6 +1315,10 @@ func (s *persistTestStore) CreateFamilyGroup(ctx context.Context, group *store.F
 	return store.ErrNotSupported
 }
 
+func (s *persistTestStore) CreateFamilyGroupWithMembers(ctx context.Context, group *store.FamilyGroup, members []*store.GroupMember) error {
+	return store.ErrNotSupported
+}
+
 func (s *persistTestStore) GetFamilyGroup(ctx context.Context, groupID string) (*store.FamilyGroup, error) {
 	return nil, store.ErrNotSupported
 }
@@ -1313,3 +1437,2242 @@ func (s *persistTestStore) GetNotifications(ctx context.Context, userID string,
 	return nil, store.ErrNotSupported
 }
 
+// --- provider tokens ---
+
+// MockSuccessStore
+func (s MockSuccessStore) SaveProviderToken(ctx context.Context, token *store.ProviderToken) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetProviderToken(ctx context.Context, userID, provider string) (*store.ProviderToken, error) {
+	return nil, store.ErrProviderTokenNotFound
+}
+
+func (s MockSuccessStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	return nil
+}
+
+// MockFailStore
+func (s MockFailStore) SaveProviderToken(ctx context.Context, token *store.ProviderToken) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetProviderToken(ctx context.Context, userID, provider string) (*store.ProviderToken, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	return errors.New("OH NO")
+}
+
+// persistTestStore keeps provider tokens in memory keyed by user and provider.
+func (s *persistTestStore) SaveProviderToken(ctx context.Context, token *store.ProviderToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	if s.providerTokens == nil {
+		s.providerTokens = make(map[string]store.ProviderToken)
+	}
+	s.providerTokens[token.UserID+"/"+token.Provider] = *token
+	return nil
+}
+
+func (s *persistTestStore) GetProviderToken(ctx context.Context, userID, provider string) (*store.ProviderToken, error) {
+	token, ok := s.providerTokens[userID+"/"+provider]
+	if !ok {
+		return nil, store.ErrProviderTokenNotFound
+	}
+	return &token, nil
+}
+
+func (s *persistTestStore) DeleteProviderToken(ctx context.Context, userID, provider string) error {
+	delete(s.providerTokens, userID+"/"+provider)
+	return nil
+}
+
+// --- watch history ---
+
+func (s MockSuccessStore) RecordWatchedMovie(ctx context.Context, movie *store.WatchedMovie) error {
+	return nil
+}
+
+func (s MockSuccessStore) ListWatchedMovies(ctx context.Context, userID string) ([]store.WatchedMovie, error) {
+	return []store.WatchedMovie{}, nil
+}
+
+func (s MockFailStore) RecordWatchedMovie(ctx context.Context, movie *store.WatchedMovie) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) ListWatchedMovies(ctx context.Context, userID string) ([]store.WatchedMovie, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s *persistTestStore) RecordWatchedMovie(ctx context.Context, movie *store.WatchedMovie) error {
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+	s.watched = append(s.watched, *movie)
+	return nil
+}
+
+func (s *persistTestStore) ListWatchedMovies(ctx context.Context, userID string) ([]store.WatchedMovie, error) {
+	movies := []store.WatchedMovie{}
+	for _, m := range s.watched {
+		if m.UserID == userID {
+			movies = append(movies, m)
+		}
+	}
+	return movies, nil
+}
+
+// --- trash ---
+
+func (s MockSuccessStore) PutTrashEntry(ctx context.Context, entry *store.TrashEntry) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetTrashEntry(ctx context.Context, id string) (*store.TrashEntry, error) {
+	return nil, store.ErrTrashEntryNotFound
+}
+
+func (s MockSuccessStore) ListTrashEntries(ctx context.Context) ([]store.TrashEntry, error) {
+	return []store.TrashEntry{}, nil
+}
+
+func (s MockSuccessStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s MockFailStore) PutTrashEntry(ctx context.Context, entry *store.TrashEntry) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetTrashEntry(ctx context.Context, id string) (*store.TrashEntry, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) ListTrashEntries(ctx context.Context) ([]store.TrashEntry, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	return errors.New("OH NO")
+}
+
+func (s *persistTestStore) PutTrashEntry(ctx context.Context, entry *store.TrashEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if s.trash == nil {
+		s.trash = make(map[string]store.TrashEntry)
+	}
+	s.trash[entry.ID] = *entry
+	return nil
+}
+
+func (s *persistTestStore) GetTrashEntry(ctx context.Context, id string) (*store.TrashEntry, error) {
+	entry, ok := s.trash[id]
+	if !ok {
+		return nil, store.ErrTrashEntryNotFound
+	}
+	return &entry, nil
+}
+
+func (s *persistTestStore) ListTrashEntries(ctx context.Context) ([]store.TrashEntry, error) {
+	entries := []store.TrashEntry{}
+	for _, entry := range s.trash {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *persistTestStore) DeleteTrashEntry(ctx context.Context, id string) error {
+	delete(s.trash, id)
+	return nil
+}
+
+// --- playback sessions ---
+
+func (s MockSuccessStore) PutPlaybackSession(ctx context.Context, session *store.PlaybackSession) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*store.PlaybackSession, error) {
+	return nil, store.ErrPlaybackSessionNotFound
+}
+
+func (s MockSuccessStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	return nil
+}
+
+func (s MockFailStore) PutPlaybackSession(ctx context.Context, session *store.PlaybackSession) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*store.PlaybackSession, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	return errors.New("OH NO")
+}
+
+func (s *persistTestStore) PutPlaybackSession(ctx context.Context, session *store.PlaybackSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[string]store.PlaybackSession)
+	}
+	s.sessions[session.PlayerUUID+"/"+session.RatingKey] = *session
+	return nil
+}
+
+func (s *persistTestStore) GetPlaybackSession(ctx context.Context, playerUUID, ratingKey string) (*store.PlaybackSession, error) {
+	session, ok := s.sessions[playerUUID+"/"+ratingKey]
+	if !ok {
+		return nil, store.ErrPlaybackSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *persistTestStore) DeletePlaybackSession(ctx context.Context, playerUUID, ratingKey string) error {
+	delete(s.sessions, playerUUID+"/"+ratingKey)
+	return nil
+}
+
+// --- user preferences ---
+
+func (s MockSuccessStore) PutUserPreferences(ctx context.Context, prefs *store.UserPreferences) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetUserPreferences(ctx context.Context, userID string) (*store.UserPreferences, error) {
+	return nil, store.ErrUserPreferencesNotFound
+}
+
+func (s MockSuccessStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (s MockFailStore) PutUserPreferences(ctx context.Context, prefs *store.UserPreferences) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetUserPreferences(ctx context.Context, userID string) (*store.UserPreferences, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	return errors.New("OH NO")
+}
+
+func (s *persistTestStore) PutUserPreferences(ctx context.Context, prefs *store.UserPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	if s.preferences == nil {
+		s.preferences = make(map[string]store.UserPreferences)
+	}
+	s.preferences[prefs.UserID] = *prefs
+	return nil
+}
+
+func (s *persistTestStore) GetUserPreferences(ctx context.Context, userID string) (*store.UserPreferences, error) {
+	prefs, ok := s.preferences[userID]
+	if !ok {
+		return nil, store.ErrUserPreferencesNotFound
+	}
+	return &prefs, nil
+}
+
+func (s *persistTestStore) DeleteUserPreferences(ctx context.Context, userID string) error {
+	delete(s.preferences, userID)
+	return nil
+}
+
+// --- webhook stats ---
+
+func (s MockSuccessStore) IncrementWebhookStat(ctx context.Context, subject store.WebhookSubject, id string, outcome store.WebhookOutcome, at time.Time) error {
+	return nil
+}
+
+func (s MockSuccessStore) ListWebhookStats(ctx context.Context, subject store.WebhookSubject, id string, from, to time.Time) ([]store.WebhookStatsDay, error) {
+	return []store.WebhookStatsDay{}, nil
+}
+
+func (s MockFailStore) IncrementWebhookStat(ctx context.Context, subject store.WebhookSubject, id string, outcome store.WebhookOutcome, at time.Time) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) ListWebhookStats(ctx context.Context, subject store.WebhookSubject, id string, from, to time.Time) ([]store.WebhookStatsDay, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s *persistTestStore) IncrementWebhookStat(ctx context.Context, subject store.WebhookSubject, id string, outcome store.WebhookOutcome, at time.Time) error {
+	if s.webhookStats == nil {
+		s.webhookStats = make(map[string]map[time.Time]store.WebhookCounts)
+	}
+	key := string(subject) + "/" + id
+	if s.webhookStats[key] == nil {
+		s.webhookStats[key] = make(map[time.Time]store.WebhookCounts)
+	}
+	day := store.WebhookDayStart(at)
+	counts := s.webhookStats[key][day]
+	counts.Add(outcome, 1)
+	s.webhookStats[key][day] = counts
+	return nil
+}
+
+func (s *persistTestStore) ListWebhookStats(ctx context.Context, subject store.WebhookSubject, id string, from, to time.Time) ([]store.WebhookStatsDay, error) {
+	days := []store.WebhookStatsDay{}
+	for day, counts := range s.webhookStats[string(subject)+"/"+id] {
+		if !day.Before(store.WebhookDayStart(from)) && !day.After(to) {
+			days = append(days, store.WebhookStatsDay{Day: day, WebhookCounts: counts})
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.Before(days[j].Day) })
+	return days, nil
+}
+
+// --- admin totp ---
+
+func (s MockSuccessStore) PutAdminTOTP(ctx context.Context, t *store.AdminTOTP) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetAdminTOTP(ctx context.Context, username string) (*store.AdminTOTP, error) {
+	return nil, store.ErrAdminTOTPNotFound
+}
+
+func (s MockSuccessStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	return nil
+}
+
+func (s MockFailStore) PutAdminTOTP(ctx context.Context, t *store.AdminTOTP) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetAdminTOTP(ctx context.Context, username string) (*store.AdminTOTP, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	return errors.New("OH NO")
+}
+
+func (s *persistTestStore) PutAdminTOTP(ctx context.Context, t *store.AdminTOTP) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if s.adminTOTP == nil {
+		s.adminTOTP = make(map[string]store.AdminTOTP)
+	}
+	s.adminTOTP[t.Username] = *t
+	return nil
+}
+
+func (s *persistTestStore) GetAdminTOTP(ctx context.Context, username string) (*store.AdminTOTP, error) {
+	t, ok := s.adminTOTP[strings.ToLower(username)]
+	if !ok {
+		return nil, store.ErrAdminTOTPNotFound
+	}
+	return &t, nil
+}
+
+func (s *persistTestStore) DeleteAdminTOTP(ctx context.Context, username string) error {
+	delete(s.adminTOTP, strings.ToLower(username))
+	return nil
+}
+
+// --- retention ---
+
+func (s MockSuccessStore) PurgeBefore(ctx context.Context, category store.RetentionCategory, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s MockFailStore) PurgeBefore(ctx context.Context, category store.RetentionCategory, before time.Time) (int, error) {
+	return 0, errors.New("OH NO")
+}
+
+func (s *persistTestStore) PurgeBefore(ctx context.Context, category store.RetentionCategory, before time.Time) (int, error) {
+	purged := 0
+	switch category {
+	case store.RetentionHistory:
+		kept := s.watched[:0]
+		for _, movie := range s.watched {
+			if movie.WatchedAt.Before(before) {
+				purged++
+				continue
+			}
+			kept = append(kept, movie)
+		}
+		s.watched = kept
+	case store.RetentionQueueLog:
+		kept := s.queueLog[:0]
+		for _, event := range s.queueLog {
+			if event.Timestamp.Before(before) {
+				purged++
+				continue
+			}
+			kept = append(kept, event)
+		}
+		s.queueLog = kept
+	case store.RetentionTelemetry:
+		for start := range s.activity {
+			if start.Before(store.ActivityBucketStart(before)) {
+				delete(s.activity, start)
+				purged++
+			}
+		}
+		for _, days := range s.webhookStats {
+			for day := range days {
+				if day.Before(store.WebhookDayStart(before)) {
+					delete(days, day)
+					purged++
+				}
+			}
+		}
+	default:
+		return 0, store.ErrInvalidRetentionCategory
+	}
+	return purged, nil
+}
+
+// --- plex webhooks ---
+
+func (s MockSuccessStore) PutPlexWebhook(ctx context.Context, w *store.PlexWebhook) error {
+	return nil
+}
+
+func (s MockSuccessStore) GetPlexWebhook(ctx context.Context, id string) (*store.PlexWebhook, error) {
+	return nil, store.ErrPlexWebhookNotFound
+}
+
+func (s MockSuccessStore) ListPlexWebhooks(ctx context.Context) ([]store.PlexWebhook, error) {
+	return []store.PlexWebhook{}, nil
+}
+
+func (s MockSuccessStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s MockFailStore) PutPlexWebhook(ctx context.Context, w *store.PlexWebhook) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) GetPlexWebhook(ctx context.Context, id string) (*store.PlexWebhook, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) ListPlexWebhooks(ctx context.Context) ([]store.PlexWebhook, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	return errors.New("OH NO")
+}
+
+func (s *persistTestStore) PutPlexWebhook(ctx context.Context, w *store.PlexWebhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	if s.plexWebhooks == nil {
+		s.plexWebhooks = make(map[string]store.PlexWebhook)
+	}
+	s.plexWebhooks[w.ID] = *w
+	return nil
+}
+
+func (s *persistTestStore) GetPlexWebhook(ctx context.Context, id string) (*store.PlexWebhook, error) {
+	w, ok := s.plexWebhooks[id]
+	if !ok {
+		return nil, store.ErrPlexWebhookNotFound
+	}
+	return &w, nil
+}
+
+func (s *persistTestStore) ListPlexWebhooks(ctx context.Context) ([]store.PlexWebhook, error) {
+	out := make([]store.PlexWebhook, 0, len(s.plexWebhooks))
+	for _, w := range s.plexWebhooks {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *persistTestStore) DeletePlexWebhook(ctx context.Context, id string) error {
+	delete(s.plexWebhooks, id)
+	return nil
+}
+
+// --- queue event log ---
+
+func (s MockSuccessStore) AppendQueueLogEvent(ctx context.Context, event store.QueueLogEvent) error {
+	return nil
+}
+
+func (s MockSuccessStore) ListQueueLogEvents(ctx context.Context, query store.QueueLogQuery) ([]store.QueueLogEvent, error) {
+	return []store.QueueLogEvent{}, nil
+}
+
+func (s MockFailStore) AppendQueueLogEvent(ctx context.Context, event store.QueueLogEvent) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) ListQueueLogEvents(ctx context.Context, query store.QueueLogQuery) ([]store.QueueLogEvent, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s *persistTestStore) AppendQueueLogEvent(ctx context.Context, event store.QueueLogEvent) error {
+	s.queueLog = append([]store.QueueLogEvent{event}, s.queueLog...)
+	return nil
+}
+
+func (s *persistTestStore) ListQueueLogEvents(ctx context.Context, query store.QueueLogQuery) ([]store.QueueLogEvent, error) {
+	events := []store.QueueLogEvent{}
+	for _, event := range s.queueLog {
+		if (query.Since.IsZero() || !event.Timestamp.Before(query.Since)) &&
+			(query.Until.IsZero() || !event.Timestamp.After(query.Until)) {
+			events = append(events, event)
+		}
+	}
+	events = events[min(query.Offset, len(events)):]
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[:query.Limit]
+	}
+	return events, nil
+}
+
+// --- stats ---
+
+func (s MockSuccessStore) IncrementActivity(ctx context.Context, kind store.ActivityKind, at time.Time) error {
+	return nil
+}
+
+func (s MockSuccessStore) ListActivity(ctx context.Context, from, to time.Time) ([]store.ActivityBucket, error) {
+	return []store.ActivityBucket{}, nil
+}
+
+func (s MockSuccessStore) TotalQueueSize(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s MockFailStore) IncrementActivity(ctx context.Context, kind store.ActivityKind, at time.Time) error {
+	return errors.New("OH NO")
+}
+
+func (s MockFailStore) ListActivity(ctx context.Context, from, to time.Time) ([]store.ActivityBucket, error) {
+	return nil, errors.New("OH NO")
+}
+
+func (s MockFailStore) TotalQueueSize(ctx context.Context) (int, error) {
+	return 0, errors.New("OH NO")
+}
+
+func (s *persistTestStore) IncrementActivity(ctx context.Context, kind store.ActivityKind, at time.Time) error {
+	if s.activity == nil {
+		s.activity = make(map[time.Time]store.ActivityCounts)
+	}
+	start := store.ActivityBucketStart(at)
+	counts := s.activity[start]
+	counts.Add(kind, 1)
+	s.activity[start] = counts
+	return nil
+}
+
+func (s *persistTestStore) ListActivity(ctx context.Context, from, to time.Time) ([]store.ActivityBucket, error) {
+	buckets := []store.ActivityBucket{}
+	for start, counts := range s.activity {
+		if !start.Before(store.ActivityBucketStart(from)) && !start.After(to) {
+			buckets = append(buckets, store.ActivityBucket{Start: start, ActivityCounts: counts})
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+func (s *persistTestStore) TotalQueueSize(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// queueTestStore extends persistTestStore with an in-memory scrobble queue.
+type queueTestStore struct {
+	*persistTestStore
+	events []store.QueuedScrobbleEvent
+}
+
+func (s *queueTestStore) DequeueScrobbles(ctx context.Context, userID string, limit int) ([]store.QueuedScrobbleEvent, error) {
+	var out []store.QueuedScrobbleEvent
+	for _, e := range s.events {
+		if e.UserID == userID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *queueTestStore) GetQueueSize(ctx context.Context, userID string) (int, error) {
+	n := 0
+	for _, e := range s.events {
+		if e.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *queueTestStore) TotalQueueSize(ctx context.Context) (int, error) {
+	return len(s.events), nil
+}
+
+func (s *queueTestStore) DeleteQueuedScrobble(ctx context.Context, eventID string) error {
+	for i, e := range s.events {
+		if e.ID == eventID {
+			s.events = append(s.events[:i], s.events[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func newQueueTestStore(t *testing.T) (*queueTestStore, *store.User) {
+	t.Helper()
+	testStore := &queueTestStore{persistTestStore: newPersistTestStore()}
+	user := store.NewUser("viewer", "access", "refresh", nil, time.Now().Add(24*time.Hour), time.Time{}, testStore)
+	show := "The Bear"
+	season, number := 3, 2
+	for i := 0; i < 3; i++ {
+		testStore.events = append(testStore.events, store.QueuedScrobbleEvent{
+			ID:     "evt-" + string(rune('a'+i)),
+			UserID: user.ID,
+			Action: "stop",
+			ScrobbleBody: common.ScrobbleBody{
+				Show:     &common.Show{Title: &show},
+				Episode:  &common.Episode{Season: &season, Number: &number},
+				Progress: 97,
+			},
+			Progress: 97,
+		})
+	}
+	return testStore, &user
+}
+
+func TestGetUserQueueDetailPaginatesAndSummarises(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+
+	testStore, user := newQueueTestStore(t)
+	storage = testStore
+
+	req := httptest.NewRequest("GET", "/admin/api/queue/user/"+user.ID+"?offset=1&limit=1&payload=none", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": user.ID})
+	resp := httptest.NewRecorder()
+
+	getUserQueueDetail(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var payload struct {
+		QueueSize int                       `json:"queue_size"`
+		HasMore   bool                      `json:"has_more"`
+		Events    []adminQueueEventResponse `json:"events"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &payload))
+	assert.Equal(t, 3, payload.QueueSize)
+	assert.True(t, payload.HasMore)
+	if assert.Len(t, payload.Events, 1) {
+		assert.Equal(t, "evt-b", payload.Events[0].ID)
+		assert.Equal(t, "The Bear S03E02 – stop 97%", payload.Events[0].Summary)
+		assert.Empty(t, payload.Events[0].PayloadPreview)
+	}
+}
+
+func TestGetUserQueueDetailTruncatesPayloadPreview(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+
+	testStore, user := newQueueTestStore(t)
+	storage = testStore
+
+	req := httptest.NewRequest("GET", "/admin/api/queue/user/"+user.ID+"?preview_size=10", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": user.ID})
+	resp := httptest.NewRecorder()
+
+	getUserQueueDetail(resp, req)
+
+	var payload struct {
+		Events []adminQueueEventResponse `json:"events"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &payload))
+	if assert.Len(t, payload.Events, 3) {
+		assert.True(t, payload.Events[0].PayloadTruncated)
+		assert.True(t, strings.HasSuffix(payload.Events[0].PayloadPreview, "…"))
+	}
+}
+
+func TestAdminQueueEventPreviewKeepsRunesWhole(t *testing.T) {
+	title := "Amélie"
+	event := store.QueuedScrobbleEvent{ID: "evt", ScrobbleBody: common.ScrobbleBody{Movie: &common.Movie{Title: &title}}}
+	full := string(mustMarshalJSON(event.ScrobbleBody))
+	// Cut in the middle of the two-byte é
+	size := strings.Index(full, "é") + 1
+
+	resp := newAdminQueueEventResponse(event, "preview", size)
+	assert.True(t, resp.PayloadTruncated)
+	assert.True(t, utf8.ValidString(resp.PayloadPreview), resp.PayloadPreview)
+	assert.Equal(t, full[:size-1]+"…", resp.PayloadPreview)
+}
+
+func TestDeleteUserQueueEvent(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+
+	testStore, user := newQueueTestStore(t)
+	storage = testStore
+
+	req := httptest.NewRequest("DELETE", "/admin/api/queue/user/"+user.ID+"/events/evt-a", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": user.ID, "event_id": "evt-a"})
+	resp := httptest.NewRecorder()
+	deleteUserQueueEvent(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, testStore.events, 2)
+
+	req = httptest.NewRequest("DELETE", "/admin/api/queue/user/"+user.ID+"/events/evt-a", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": user.ID, "event_id": "evt-a"})
+	resp = httptest.NewRecorder()
+	deleteUserQueueEvent(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestBuildManualScrobble(t *testing.T) {
+	progress := 42
+	tests := []struct {
+		name    string
+		req     manualScrobbleRequest
+		action  string
+		wantErr bool
+		check   func(t *testing.T, body common.ScrobbleBody)
+	}{
+		{
+			name:   "movie by imdb defaults to stop at 100",
+			req:    manualScrobbleRequest{Type: "movie", Imdb: "tt0111161"},
+			action: "stop",
+			check: func(t *testing.T, body common.ScrobbleBody) {
+				assert.Equal(t, 100, body.Progress)
+				if assert.NotNil(t, body.Movie) && assert.NotNil(t, body.Movie.Ids.Imdb) {
+					assert.Equal(t, "tt0111161", *body.Movie.Ids.Imdb)
+				}
+			},
+		},
+		{
+			name:   "movie by title and year",
+			req:    manualScrobbleRequest{Type: "movie", Title: "Heat", Year: 1995, Action: "pause", Progress: &progress},
+			action: "pause",
+			check: func(t *testing.T, body common.ScrobbleBody) {
+				assert.Equal(t, 42, body.Progress)
+				assert.Equal(t, "Heat", *body.Movie.Title)
+			},
+		},
+		{
+			name:   "episode by show id and numbers",
+			req:    manualScrobbleRequest{Type: "episode", Tvdb: 81189, Season: 5, Episode: 14},
+			action: "stop",
+			check: func(t *testing.T, body common.ScrobbleBody) {
+				assert.Equal(t, 81189, *body.Show.Ids.Tvdb)
+				assert.Equal(t, 5, *body.Episode.Season)
+				assert.Equal(t, 14, *body.Episode.Number)
+			},
+		},
+		{
+			name:   "episode by episode ids",
+			req:    manualScrobbleRequest{Type: "episode", Tmdb: 62085},
+			action: "stop",
+			check: func(t *testing.T, body common.ScrobbleBody) {
+				assert.Nil(t, body.Show)
+				assert.Equal(t, 62085, *body.Episode.Ids.Tmdb)
+			},
+		},
+		{name: "movie missing identifiers", req: manualScrobbleRequest{Type: "movie", Title: "Heat"}, wantErr: true},
+		{name: "episode missing number", req: manualScrobbleRequest{Type: "episode", Title: "Lost", Season: 1}, wantErr: true},
+		{name: "unknown type", req: manualScrobbleRequest{Type: "album", Imdb: "tt1"}, wantErr: true},
+		{name: "invalid action", req: manualScrobbleRequest{Type: "movie", Imdb: "tt1", Action: "watch"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, body, err := buildManualScrobble(tt.req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.action, action)
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestSubmitManualScrobbleUnknownUser(t *testing.T) {
+	prevStorage, prevTrakt := storage, traktSrv
+	defer func() { storage, traktSrv = prevStorage, prevTrakt }()
+	storage = newPersistTestStore()
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+
+	req := httptest.NewRequest("POST", "/admin/api/users/missing/scrobble", strings.NewReader(`{"type":"movie","imdb":"tt1"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "missing"})
+	resp := httptest.NewRecorder()
+
+	submitManualScrobble(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestParseHistoryCSV(t *testing.T) {
+	input := "Date,Name,Year,Letterboxd URI\n2024-01-05,Heat,1995,https://boxd.it/x\n"
+	items, err := parseHistoryCSV(strings.NewReader(input))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "movie", items[0].Type)
+		assert.Equal(t, "Heat", items[0].Title)
+		assert.Equal(t, 1995, items[0].Year)
+		assert.Equal(t, 2024, items[0].WatchedAt.Year())
+	}
+
+	input = "type,tvdb,season,episode,watched_at\n,81189,5,14,2024-02-01T10:00:00Z\n"
+	items, err = parseHistoryCSV(strings.NewReader(input))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "episode", items[0].Type)
+		assert.Equal(t, 14, *items[0].Episode)
+	}
+
+	_, err = parseHistoryCSV(strings.NewReader("title,year\nHeat,nineteen\n"))
+	assert.Error(t, err)
+}
+
+func TestPushUserHistoryRejectsOversizedImport(t *testing.T) {
+	prevStorage, prevTrakt := storage, traktSrv
+	defer func() { storage, traktSrv = prevStorage, prevTrakt }()
+	s := newPersistTestStore()
+	storage = s
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+
+	body := "title,year\n" + strings.Repeat("Heat,1995\n", maxHistoryImportBytes/10+1)
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/users/u1/history", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	req = mux.SetURLVars(req, map[string]string{"id": "u1"})
+	rr := httptest.NewRecorder()
+	pushUserHistory(rr, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "an oversized import is rejected, not truncated")
+}
+
+func TestSimklAuthorizeRequiresWizardState(t *testing.T) {
+	prevStorage, prevSimkl, prevStates := storage, simklClient, authStates
+	defer func() { storage, simklClient, authStates = prevStorage, prevSimkl, prevStates }()
+	s := newPersistTestStore()
+	storage = s
+	simklClient = simkl.New("simkl-id", "simkl-secret")
+	authStates = newAuthStateStore()
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		simklAuthorize(rr, httptest.NewRequest(http.MethodGet, "http://plaxt.example/simkl/authorize?"+query, nil))
+		return rr
+	}
+	assert.Equal(t, http.StatusForbidden, get("id=u1").Code, "a user id alone cannot start linking")
+	renew := authStates.Create(authState{Mode: "renew", Username: "alice", SelectedID: "u1"})
+	assert.Equal(t, http.StatusForbidden, get("state="+renew).Code, "only Simkl link states are accepted")
+
+	token := authStates.Create(authState{Mode: simkl.ProviderName, Username: "alice", SelectedID: "u1"})
+	rr := get("state=" + token)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Contains(t, rr.Header().Get("Location"), "state="+token)
+}
+
+type recordingProvider struct {
+	name   string
+	tokens []string
+	err    error
+}
+
+func (p *recordingProvider) Name() string { return p.name }
+
+func (p *recordingProvider) Scrobble(ctx context.Context, action string, item common.CacheItem, accessToken string) error {
+	p.tokens = append(p.tokens, accessToken)
+	return p.err
+}
+
+func (p *recordingProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *recordingProvider) RefreshToken(ctx context.Context, refreshToken, redirectURI string) (provider.Token, error) {
+	return provider.Token{}, provider.ErrRefreshNotSupported
+}
+
+func TestDispatchSecondaryScrobblesSkipsUnlinkedUsers(t *testing.T) {
+	prevStorage, prevProviders := storage, providers
+	defer func() { storage, providers = prevStorage, prevProviders }()
+
+	s := newPersistTestStore()
+	storage = s
+	linked := &recordingProvider{name: "simkl"}
+	unlinked := &recordingProvider{name: "other"}
+	providers = provider.NewRegistry(linked, unlinked)
+
+	user := store.User{ID: "u1", Username: "alice"}
+	assert.NoError(t, s.SaveProviderToken(context.Background(), &store.ProviderToken{UserID: "u1", Provider: "simkl", AccessToken: "simkl-token"}))
+
+	dispatchSecondaryScrobbles(context.Background(), user, "stop", common.CacheItem{Body: common.ScrobbleBody{Progress: 100}})
+	assert.Equal(t, []string{"simkl-token"}, linked.tokens)
+	assert.Empty(t, unlinked.tokens)
+}
+
+func TestDispatchSecondaryScrobblesQueuesTransientFailures(t *testing.T) {
+	prevStorage, prevProviders := storage, providers
+	defer func() { storage, providers = prevStorage, prevProviders }()
+
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	storage = s
+	down := &recordingProvider{name: "simkl", err: errors.New("simkl scrobble failed: 503")}
+	rejected := &recordingProvider{name: "other", err: errors.New("simkl scrobble failed: 401")}
+	providers = provider.NewRegistry(&recordingProvider{name: provider.DefaultName}, down, rejected)
+	user := store.User{ID: "u1", Username: "alice"}
+	s.WriteUser(user)
+	assert.NoError(t, s.SaveProviderToken(ctx, &store.ProviderToken{UserID: "u1", Provider: "simkl", AccessToken: "simkl-token"}))
+	assert.NoError(t, s.SaveProviderToken(ctx, &store.ProviderToken{UserID: "u1", Provider: "other", AccessToken: "other-token"}))
+
+	dispatchSecondaryScrobbles(ctx, user, "stop", common.CacheItem{PlayerUuid: "player-1", RatingKey: "42", Body: common.ScrobbleBody{Progress: 100}})
+
+	events, err := s.DequeueScrobbles(ctx, "u1", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1, "only the transient failure is retried") {
+		assert.Equal(t, "simkl", events[0].Provider)
+		assert.Equal(t, "stop", events[0].Action)
+		assert.Equal(t, "42", events[0].RatingKey)
+	}
+
+	// The drain sends it back to the same provider
+	down.err = nil
+	assert.NoError(t, sendEventWithRetry(ctx, s, providers, events[0]))
+	assert.Equal(t, []string{"simkl-token", "simkl-token"}, down.tokens)
+}
+
+func TestWriteLetterboxdCSVFlagsRewatches(t *testing.T) {
+	day := time.Date(2024, 3, 9, 21, 0, 0, 0, time.UTC)
+	movies := []store.WatchedMovie{
+		{Title: "Heat", Year: 1995, Imdb: "tt0113277", Tmdb: 949, WatchedAt: day},
+		{Title: "Ronin, the Movie", Year: 1998, WatchedAt: day.AddDate(0, 0, 1)},
+		{Title: "Heat", Year: 1995, Imdb: "tt0113277", Tmdb: 949, WatchedAt: day.AddDate(0, 1, 0)},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeLetterboxdCSV(&buf, movies))
+	assert.Equal(t, "Title,Year,imdbID,tmdbID,WatchedDate,Rewatch\n"+
+		"Heat,1995,tt0113277,949,2024-03-09,false\n"+
+		"\"Ronin, the Movie\",1998,,,2024-03-10,false\n"+
+		"Heat,1995,tt0113277,949,2024-04-09,true\n", buf.String())
+}
+
+func TestExportLetterboxdDiarySince(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+	_ = s.RecordWatchedMovie(context.Background(), &store.WatchedMovie{UserID: "u1", Title: "Old", WatchedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)})
+	_ = s.RecordWatchedMovie(context.Background(), &store.WatchedMovie{UserID: "u1", Title: "New", WatchedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)})
+
+	req := httptest.NewRequest("GET", "/users/u1/letterboxd.csv?since=2024-01-01", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "u1"})
+	resp := httptest.NewRecorder()
+	exportLetterboxdDiary(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Disposition"), "plaxt-letterboxd-alice.csv")
+	assert.Contains(t, resp.Body.String(), "New,")
+	assert.NotContains(t, resp.Body.String(), "Old,")
+}
+
+func TestWebhookReplayGuard(t *testing.T) {
+	stale := &plexhooks.Webhook{Event: "media.scrobble", Metadata: plexhooks.Metadata{RatingKey: "1", LastViewedAt: int(time.Now().Add(-2 * time.Hour).Unix())}}
+	fresh := &plexhooks.Webhook{Event: "media.scrobble", Metadata: plexhooks.Metadata{RatingKey: "1", LastViewedAt: int(time.Now().Unix())}}
+	// lastViewedAt on play events is the previous viewing and must be ignored
+	rewatch := &plexhooks.Webhook{Event: "media.play", Metadata: plexhooks.Metadata{RatingKey: "1", LastViewedAt: int(time.Now().AddDate(-1, 0, 0).Unix())}}
+	req := httptest.NewRequest("POST", "/api?id=u1", nil)
+
+	disabled := &webhookReplayGuard{}
+	assert.True(t, disabled.allow(stale, req, "u1"))
+
+	guard := &webhookReplayGuard{maxAge: 15 * time.Minute}
+	assert.False(t, guard.allow(stale, req, "u1"))
+	assert.True(t, guard.allow(fresh, req, "u1"))
+	assert.True(t, guard.allow(rewatch, req, "u1"))
+
+	dated := httptest.NewRequest("POST", "/api?id=u1", nil)
+	dated.Header.Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	assert.False(t, guard.allow(rewatch, dated, "u1"))
+	assert.EqualValues(t, 2, guard.metrics().Rejected)
+
+	flag := &webhookReplayGuard{maxAge: 15 * time.Minute, flagOnly: true}
+	assert.True(t, flag.allow(stale, req, "u1"))
+	assert.EqualValues(t, 1, flag.metrics().Flagged)
+	assert.Equal(t, "flag", flag.metrics().Action)
+}
+
+func TestWebhookResponseCodesAlwaysOK(t *testing.T) {
+	prev := webhookAlwaysOK
+	defer func() { webhookAlwaysOK = prev }()
+	handler := webhookResponseCodes(api)
+
+	// Missing id is a 400 by default
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/api", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get(webhookStatusHeader))
+
+	webhookAlwaysOK = true
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/api", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "400", rr.Header().Get(webhookStatusHeader))
+
+	ok := webhookResponseCodes(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rr = httptest.NewRecorder()
+	ok(rr, httptest.NewRequest(http.MethodPost, "/api?id=u1", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code, "successful replies are passed through")
+	assert.Empty(t, rr.Header().Get(webhookStatusHeader))
+}
+
+func TestInvalidIDWebhooksBanSourceAfterStrikes(t *testing.T) {
+	useMockTrakt(t, nil)
+	prev := invalidIDs
+	defer func() { invalidIDs = prev }()
+	invalidIDs = &invalidIDTracker{banAfter: 3, banFor: time.Hour}
+
+	send := func(remote string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api?id=deleted-user", bytes.NewReader(movieWebhook("media.play", "ghost", 0)))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remote + ":32400"
+		rr := httptest.NewRecorder()
+		api(rr, req)
+		return rr.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusForbidden, send("10.0.0.5"))
+	}
+	assert.True(t, invalidIDs.isBanned("10.0.0.5"))
+	assert.Equal(t, http.StatusForbidden, send("10.0.0.5"))
+	assert.Equal(t, http.StatusForbidden, send("10.0.0.6"))
+	assert.False(t, invalidIDs.isBanned("10.0.0.6"))
+
+	m := invalidIDs.metrics()
+	assert.EqualValues(t, 4, m.Total, "banned requests are not counted again")
+	assert.Equal(t, 1, m.Banned)
+	if !assert.Len(t, m.Sources, 2) {
+		return
+	}
+	assert.Equal(t, "10.0.0.5", m.Sources[0].IP)
+	assert.Equal(t, 3, m.Sources[0].Count)
+	assert.Equal(t, "deleted-user", m.Sources[0].LastID)
+	assert.NotNil(t, m.Sources[0].BannedUntil)
+
+	// Bans lift once they expire
+	expired := time.Now().Add(-time.Minute)
+	invalidIDs.sources["10.0.0.5"].BannedUntil = &expired
+	assert.False(t, invalidIDs.isBanned("10.0.0.5"))
+	assert.Zero(t, invalidIDs.metrics().Banned)
+}
+
+func TestRequestLoggerAddsWebhookContextAndSamples(t *testing.T) {
+	useMockTrakt(t, nil)
+	prevMode, prevSample, prevLogger := requestLogMod, requestLogSample, slog.Default()
+	defer func() {
+		requestLogMod, requestLogSample = prevMode, prevSample
+		slog.SetDefault(prevLogger)
+	}()
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	requestLogMod = "important"
+	requestLogSample = 3
+	requestLogSampled.Store(0)
+
+	user := store.NewUser("alice", "access", "refresh", nil, time.Now().Add(30*24*time.Hour), time.Now(), storage)
+	handler := requestLoggerMiddleware()(http.HandlerFunc(api))
+	send := func(id string) {
+		req := httptest.NewRequest(http.MethodPost, "/api?id="+id, bytes.NewReader(movieWebhook("media.pause", "alice", 0)))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	accessLines := func() []map[string]any {
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]any
+			if json.Unmarshal([]byte(line), &entry) == nil && entry["msg"] == "request" {
+				lines = append(lines, entry)
+			}
+		}
+		return lines
+	}
+
+	for i := 0; i < 4; i++ {
+		send(user.ID)
+	}
+	lines := accessLines()
+	if !assert.Len(t, lines, 2, "1 in 3 successful webhooks is logged") {
+		return
+	}
+	assert.Equal(t, user.ID, lines[0]["plaxt_id"])
+	assert.Equal(t, "alice", lines[0]["username"])
+	assert.Equal(t, "media.pause", lines[0]["event"])
+
+	buf.Reset()
+	send("unknown")
+	lines = accessLines()
+	if assert.Len(t, lines, 1, "failures are never sampled out") {
+		assert.EqualValues(t, http.StatusForbidden, lines[0]["status"])
+		assert.Equal(t, "unknown", lines[0]["plaxt_id"])
+		assert.NotContains(t, lines[0], "username")
+	}
+}
+
+func TestDrainWindow(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		parsed, _ := time.Parse("15:04", hhmm)
+		return time.Date(2024, 3, 10, parsed.Hour(), parsed.Minute(), 0, 0, time.UTC)
+	}
+
+	night, err := parseDrainWindow("02:00-06:00")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "02:00-06:00", night.String())
+	assert.True(t, night.contains(at("02:00")))
+	assert.True(t, night.contains(at("05:59")))
+	assert.False(t, night.contains(at("06:00")))
+	assert.False(t, night.contains(at("20:00")))
+	open, end := night.next(at("20:00"))
+	assert.Equal(t, at("02:00").AddDate(0, 0, 1), open)
+	assert.Equal(t, at("06:00").AddDate(0, 0, 1), end)
+	open, _ = night.next(at("01:00"))
+	assert.Equal(t, at("02:00"), open)
+
+	// Windows may wrap past midnight
+	late, err := parseDrainWindow("23:00 - 01:30")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, late.contains(at("23:30")))
+	assert.True(t, late.contains(at("00:15")))
+	assert.False(t, late.contains(at("12:00")))
+	_, end = late.bounds(at("00:15"))
+	assert.Equal(t, at("01:30"), end)
+	_, end = late.bounds(at("23:30"))
+	assert.Equal(t, at("01:30").AddDate(0, 0, 1), end)
+
+	for _, bad := range []string{"", "02:00", "2am-6am", "25:00-06:00", "03:00-03:00"} {
+		_, err := parseDrainWindow(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestDrainScheduleHoldsAutomaticDrains(t *testing.T) {
+	drains := make(chan context.Context, 1)
+	drain := func(ctx context.Context) { drains <- ctx }
+
+	trigger := &drainSchedule{triggerOnly: true}
+	trigger.runAutomatic(context.Background(), "startup", drain)
+	assert.Empty(t, drains)
+	assert.Equal(t, "trigger", trigger.status().Mode)
+
+	now := time.Now()
+	offset := func(d time.Duration) time.Duration {
+		t := now.Add(d)
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	// Inside the window the drain runs now and stops when the window closes
+	open := &drainSchedule{window: &drainWindow{start: offset(-time.Hour), end: offset(time.Hour)}}
+	open.runAutomatic(context.Background(), "startup", drain)
+	if assert.Len(t, drains, 1) {
+		deadline, ok := (<-drains).Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, now.Add(time.Hour), deadline, time.Minute)
+	}
+
+	// Outside it the drain waits for the window to open
+	closed := &drainSchedule{window: &drainWindow{start: offset(2 * time.Hour), end: offset(3 * time.Hour)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		closed.runAutomatic(ctx, "trakt_recovered", drain)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return closed.status().DeferredUntil != nil }, time.Second, time.Millisecond)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *closed.status().DeferredUntil, time.Minute)
+	// A second recovery while one drain is pending does not queue another
+	closed.runAutomatic(ctx, "trakt_recovered", drain)
+	cancel()
+	<-done
+	assert.Empty(t, drains)
+}
+
+func TestTriggerQueueDrainSendsBacklog(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	srv.SetUser("access-dave", "", trakttest.User{Username: "dave"})
+	user := store.NewUser("dave", "access-dave", "refresh-dave", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+	title, year := "Heat", 1995
+	ctx := context.Background()
+	assert.NoError(t, s.EnqueueScrobble(ctx, store.QueuedScrobbleEvent{
+		UserID:       user.ID,
+		Action:       "stop",
+		Progress:     95,
+		ScrobbleBody: common.ScrobbleBody{Progress: 95, Movie: &common.Movie{Title: &title, Year: &year}},
+		PlayerUUID:   "player-1",
+		RatingKey:    "42",
+		CreatedAt:    time.Now(),
+	}))
+
+	rr := httptest.NewRecorder()
+	triggerQueueDrain(rr, httptest.NewRequest(http.MethodPost, "/admin/api/queue/drain", nil))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Eventually(t, func() bool {
+		size, err := s.GetQueueSize(ctx, user.ID)
+		return err == nil && size == 0 && len(drainStateTracker.GetAllActiveUsers()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStop), 1)
+}
+
+func TestWebhookEventStatsCountsAndSamplesUnknownEvents(t *testing.T) {
+	useMockTrakt(t, nil)
+	prev := webhookEvents
+	defer func() { webhookEvents = prev }()
+	webhookEvents = &webhookEventStats{}
+
+	user := store.NewUser("erin", "access", "refresh", nil, time.Now().Add(30*24*time.Hour), time.Now(), storage)
+	for _, event := range []string{"media.play", "media.rate", "admin.database.backup", "library.new", "media.transcode", "media.play"} {
+		req := httptest.NewRequest(http.MethodPost, "/api?id="+user.ID, bytes.NewReader(movieWebhook(event, "erin", 0)))
+		req.Header.Set("Content-Type", "application/json")
+		api(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	getWebhookEvents(rr, httptest.NewRequest(http.MethodGet, "/admin/api/webhooks/events", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got webhookEventMetrics
+	if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got)) {
+		return
+	}
+	assert.Equal(t, map[string]uint64{"media.play": 2, "media.rate": 1, "admin.*": 1, "library.new": 1, "unknown": 1}, got.Counts)
+	if assert.Len(t, got.Unknown, 1) {
+		assert.Equal(t, "media.transcode", got.Unknown[0].Event)
+		assert.Contains(t, got.Unknown[0].Payload, `"ratingKey":"42"`)
+	}
+
+	// Samples are capped and oversized payloads truncated
+	big := bytes.Repeat([]byte("x"), maxUnknownEventPayload+10)
+	for i := 0; i < maxUnknownEventSamples+5; i++ {
+		webhookEvents.record(fmt.Sprintf("new.kind.%d", i), big)
+	}
+	m := webhookEvents.metrics()
+	assert.Len(t, m.Unknown, maxUnknownEventSamples)
+	assert.Equal(t, fmt.Sprintf("new.kind.%d", maxUnknownEventSamples+4), m.Unknown[0].Event, "newest first")
+	assert.True(t, m.Unknown[0].Truncated)
+	assert.Len(t, m.Unknown[0].Payload, maxUnknownEventPayload)
+	assert.EqualValues(t, maxUnknownEventSamples+6, m.Counts["unknown"])
+}
+
+func TestExtraWebhookReason(t *testing.T) {
+	cases := []struct {
+		name string
+		meta plexhooks.Metadata
+		want string
+	}{
+		{"movie", plexhooks.Metadata{Type: "movie", LibrarySectionType: "movie", GUID: "plex://movie/5d77"}, ""},
+		{"episode", plexhooks.Metadata{Type: "episode", LibrarySectionType: "show"}, ""},
+		{"cinema trailer", plexhooks.Metadata{Type: "clip", Subtype: "trailer", GUID: "iva://api.internetvideoarchive.com/2.0/DataService/VideoAssets(123)"}, "trailer"},
+		{"trailer extra type", plexhooks.Metadata{Type: "clip", ExtraType: 1, LibrarySectionType: "movie"}, "trailer"},
+		{"preroll subtype", plexhooks.Metadata{Type: "clip", Subtype: "preroll"}, "preroll"},
+		{"preroll file", plexhooks.Metadata{Type: "clip", GUID: "file:///prerolls/intro.mp4"}, "preroll"},
+		{"behind the scenes", plexhooks.Metadata{Type: "clip", ExtraType: 5, LibrarySectionType: "movie"}, "extra"},
+		{"extras key", plexhooks.Metadata{Type: "movie", Key: "/library/metadata/42/extras"}, "extra"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, extraWebhookReason(tc.meta))
+		})
+	}
+}
+
+func TestWebhookSkipsTrailersAndCountsThem(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	prev := webhookEvents
+	defer func() { webhookEvents = prev }()
+	webhookEvents = &webhookEventStats{}
+	user := store.NewUser("frank", "access-frank", "refresh", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+
+	var payload map[string]any
+	if !assert.NoError(t, json.Unmarshal(movieWebhook("media.play", "frank", 0), &payload)) {
+		return
+	}
+	meta := payload["Metadata"].(map[string]any)
+	meta["type"], meta["subtype"], meta["librarySectionType"] = "clip", "trailer", ""
+	body, _ := json.Marshal(payload)
+
+	rr := postWebhook(t, user.ID, body)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"result":"skipped","reason":"trailer"}`, rr.Body.String())
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobbleStart))
+	m := webhookEvents.metrics()
+	assert.Equal(t, map[string]uint64{"trailer": 1}, m.Skipped)
+	assert.EqualValues(t, 1, m.Counts["media.play"], "skipped webhooks are still counted by event")
+}
+
+func TestUserPreferencesAPI(t *testing.T) {
+	srv, s := useMockTrakt(t, nil)
+	user := store.NewUser("iris", "access-iris", "refresh", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+	call := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/api/users/"+user.ID+"/preferences", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": user.ID})
+		rr := httptest.NewRecorder()
+		if method == http.MethodPut {
+			putUserPreferences(rr, req)
+		} else {
+			getUserPreferences(rr, req)
+		}
+		return rr
+	}
+
+	rr := call(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"preferences":{}}`, rr.Body.String())
+
+	rr = call(http.MethodPut, `{"paused":"soon"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":"paused: must be a boolean"}`, rr.Body.String())
+
+	rr = call(http.MethodPut, `{"exclude_types":["episode"]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = call(http.MethodGet, "")
+	var got struct {
+		Preferences map[string]any `json:"preferences"`
+		UpdatedAt   *time.Time     `json:"updated_at"`
+	}
+	if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got)) {
+		assert.Equal(t, map[string]any{"exclude_types": []any{"episode"}}, got.Preferences)
+		assert.NotNil(t, got.UpdatedAt)
+	}
+
+	// Movies still scrobble; paused users do not.
+	assert.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "iris", 100)).Code)
+	assert.Len(t, srv.Requests(trakttest.RouteScrobbleStart), 1)
+	assert.Equal(t, http.StatusOK, call(http.MethodPut, `{"paused":true}`).Code)
+	rr = postWebhook(t, user.ID, movieWebhook("media.pause", "iris", 200))
+	assert.JSONEq(t, `{"result":"skipped","reason":"user_paused"}`, rr.Body.String())
+	assert.Empty(t, srv.Requests(trakttest.RouteScrobblePause))
+
+	assert.False(t, logging.Private(user.ID))
+	assert.Equal(t, http.StatusOK, call(http.MethodPut, `{"privacy_logging":true}`).Code)
+	assert.True(t, logging.Private(user.ID))
+	assert.NotEqual(t, "Alien", logging.Redact(user.ID, "Alien"))
+	assert.Equal(t, http.StatusOK, call(http.MethodPut, `{}`).Code)
+	assert.False(t, logging.Private(user.ID))
+
+	rr = httptest.NewRecorder()
+	getPreferencesSchema(rr, httptest.NewRequest(http.MethodGet, "/admin/api/preferences/schema", nil))
+	assert.Contains(t, rr.Body.String(), `"exclude_types"`)
+}
+
+func TestUserWebhookMetrics(t *testing.T) {
+	_, s := useMockTrakt(t, nil)
+	prev := webhookEvents
+	defer func() { webhookEvents = prev }()
+	webhookEvents = &webhookEventStats{}
+	user := store.NewUser("judy", "access-judy", "refresh", nil, time.Now().Add(30*24*time.Hour), time.Now(), s)
+
+	var payload map[string]any
+	if !assert.NoError(t, json.Unmarshal(movieWebhook("media.play", "judy", 0), &payload)) {
+		return
+	}
+	meta := payload["Metadata"].(map[string]any)
+	meta["type"], meta["subtype"] = "clip", "trailer"
+	trailer, _ := json.Marshal(payload)
+
+	assert.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "judy", 0)).Code)
+	assert.Equal(t, http.StatusOK, postWebhook(t, user.ID, movieWebhook("media.play", "judy", 0)).Code, "duplicate")
+	assert.Equal(t, http.StatusOK, postWebhook(t, user.ID, trailer).Code)
+	assert.Equal(t, http.StatusForbidden, postWebhook(t, "no-such-id", movieWebhook("media.play", "judy", 0)).Code)
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/users/"+id+"/metrics"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		getUserWebhookMetrics(rr, req)
+		return rr
+	}
+
+	rr := get(user.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp adminWebhookMetricsResponse
+	if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp)) {
+		return
+	}
+	assert.Equal(t, store.WebhookSubjectUser, resp.Subject)
+	assert.Len(t, resp.Days, 7)
+	assert.Equal(t, store.WebhookDayStart(time.Now()), resp.Days[6].Day)
+	assert.Equal(t, store.WebhookCounts{Received: 3, Processed: 1, Skipped: 2}, resp.Totals)
+	assert.Equal(t, resp.Totals, resp.Days[6].WebhookCounts)
+
+	rr = get(user.ID, "?range=30d")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"received":3`)
+	assert.Equal(t, http.StatusBadRequest, get(user.ID, "?range=12h").Code)
+	assert.Equal(t, http.StatusNotFound, get("no-such-id", "").Code)
+	days, err := s.ListWebhookStats(context.Background(), store.WebhookSubjectUser, "no-such-id", time.Now().Add(-24*time.Hour), time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, days, "unknown IDs are not counted")
+}
+
+func TestRefreshUserTokenReusesConcurrentRefresh(t *testing.T) {
+	prevStorage, prevTrakt := storage, traktSrv
+	defer func() { storage, traktSrv = prevStorage, prevTrakt }()
+	s := newPersistTestStore()
+	storage = s
+	// A nil client makes any attempt to call Trakt panic
+	traktSrv = nil
+
+	s.WriteUser(store.User{ID: "u1", Username: "alice", AccessToken: "new-access", RefreshToken: "rt-1", TokenExpiry: time.Now().Add(90 * 24 * time.Hour)})
+	stale := &store.User{ID: "u1", Username: "alice", AccessToken: "old-access", RefreshToken: "rt-0", TokenExpiry: time.Now().Add(time.Hour)}
+
+	refreshed, err := refreshUserToken(context.Background(), "http://localhost/authorize", stale)
+	assert.NoError(t, err)
+	assert.Equal(t, "rt-1", refreshed.RefreshToken)
+	assert.Equal(t, "new-access", refreshed.AccessToken)
+}
+
+func TestRefreshUserTokenSerializesConcurrentRefreshes(t *testing.T) {
+	prevStorage, prevTrakt := storage, traktSrv
+	defer func() { storage, traktSrv = prevStorage, prevTrakt }()
+	storage = store.NewMemoryStore()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Hold the first refresh open so every goroutine joins it
+		<-release
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access-1", "refresh_token": "rt-1", "expires_in": 7776000})
+	}))
+	defer srv.Close()
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+	traktSrv.SetBaseURL(srv.URL)
+
+	storage.WriteUser(store.User{ID: "u1", Username: "alice", AccessToken: "access-0", RefreshToken: "rt-0", TokenExpiry: time.Now().Add(time.Hour)})
+	stale := *storage.GetUser("u1")
+
+	const workers = 8
+	results := make([]*store.User, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := stale
+			refreshed, err := refreshUserToken(context.Background(), "http://localhost/authorize", &user)
+			assert.NoError(t, err)
+			results[i] = refreshed
+		}(i)
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+	// Let the stragglers reach the in-flight refresh before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "one Trakt refresh for all callers")
+	for _, refreshed := range results {
+		if assert.NotNil(t, refreshed) {
+			assert.Equal(t, "rt-1", refreshed.RefreshToken)
+		}
+	}
+	stored := storage.GetUser("u1")
+	assert.Equal(t, "rt-1", stored.RefreshToken)
+	assert.Equal(t, "access-1", stored.AccessToken)
+
+	// A late caller holding the old token reuses the stored one
+	user := stale
+	refreshed, err := refreshUserToken(context.Background(), "http://localhost/authorize", &user)
+	assert.NoError(t, err)
+	assert.Equal(t, "rt-1", refreshed.RefreshToken)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRefreshUserTokenKeepsNewerConcurrentRenewal(t *testing.T) {
+	prevStorage, prevTrakt := storage, traktSrv
+	defer func() { storage, traktSrv = prevStorage, prevTrakt }()
+	storage = store.NewMemoryStore()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A manual renewal lands while Trakt answers the background refresh
+		renewed := *storage.GetUser("u1")
+		renewed.AccessToken, renewed.RefreshToken = "access-manual", "rt-manual"
+		storage.WriteUser(renewed)
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access-1", "refresh_token": "rt-1", "expires_in": 7776000})
+	}))
+	defer srv.Close()
+	traktSrv = trakt.New("client-id", "client-secret", storage)
+	traktSrv.SetBaseURL(srv.URL)
+
+	storage.WriteUser(store.User{ID: "u1", Username: "alice", AccessToken: "access-0", RefreshToken: "rt-0", TokenExpiry: time.Now().Add(time.Hour)})
+	user := *storage.GetUser("u1")
+	refreshed, err := refreshUserToken(context.Background(), "http://localhost/authorize", &user)
+	assert.NoError(t, err)
+	assert.Equal(t, "rt-manual", refreshed.RefreshToken)
+	assert.Equal(t, "rt-manual", storage.GetUser("u1").RefreshToken, "the newer token is never overwritten")
+}
+
+func TestPurgeExpiredRecords(t *testing.T) {
+	prevStorage, prevPolicy, prevGuard := storage, retentionPolicy, adminLoginGuard
+	defer func() { storage, retentionPolicy, adminLoginGuard = prevStorage, prevPolicy, prevGuard }()
+	s := newPersistTestStore()
+	storage = s
+	adminLoginGuard = adminauth.NewGuard()
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+
+	for _, at := range []time.Time{old, now} {
+		assert.NoError(t, s.RecordWatchedMovie(ctx, &store.WatchedMovie{UserID: "u1", Title: "Alien", WatchedAt: at}))
+		assert.NoError(t, s.AppendQueueLogEvent(ctx, store.QueueLogEvent{Timestamp: at, Operation: "queue_enqueue", UserID: "u1"}))
+		adminLoginGuard.Fail("10.0.0.1", "mallory", at)
+	}
+
+	retentionPolicy = map[string]time.Duration{"history": 7 * 24 * time.Hour, retentionAudit: 7 * 24 * time.Hour}
+	purged := purgeExpiredRecords(ctx, now)
+	assert.Equal(t, map[string]int{"history": 1, retentionAudit: 1}, purged, "categories without a policy are left alone")
+	assert.Len(t, s.watched, 1)
+	assert.Len(t, s.queueLog, 2)
+	assert.Len(t, adminLoginGuard.Attempts(), 1)
+}
+
+func TestBuildHeartbeat(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-time.Hour)))
+	assert.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-2*time.Hour)))
+	assert.NoError(t, s.IncrementActivity(ctx, store.ActivityFailure, now.Add(-time.Hour)))
+	assert.NoError(t, s.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-3*24*time.Hour)))
+	s.WriteUser(store.User{ID: "u1", Username: "alice", TokenExpiry: now.Add(90 * 24 * time.Hour)})
+	s.WriteUser(store.User{ID: "u2", Username: "bob", TokenExpiry: now.Add(time.Hour)})
+
+	hb := buildHeartbeat(ctx, now, 24*time.Hour, 7)
+	assert.Equal(t, "24h", hb.Period)
+	assert.Equal(t, 7, hb.Webhooks)
+	assert.Equal(t, 2, hb.Scrobbles, "activity before the period is not counted")
+	assert.Equal(t, 1, hb.Failures)
+	if assert.Len(t, hb.TokenWarnings, 1) {
+		assert.Equal(t, "bob", hb.TokenWarnings[0].Username)
+	}
+}
+
+// fakePlexWebhooks points plexClient at a plex.tv stand-in that accepts
+// "good-token" and keeps the posted webhook list in hooks.
+func fakePlexWebhooks(t *testing.T, hooks *[]string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			assert.NoError(t, r.ParseForm())
+			*hooks = r.PostForm["urls[]"]
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		list := make([]map[string]string, 0, len(*hooks))
+		for _, h := range *hooks {
+			list = append(list, map[string]string{"url": h})
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(srv.Close)
+	prev := plexClient
+	t.Cleanup(func() { plexClient = prev })
+	plexClient = plex.New("plaxt-test")
+	plexClient.BaseURL = srv.URL
+}
+
+func TestRegisterPlexWebhook(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+	var hooks []string
+	fakePlexWebhooks(t, &hooks)
+
+	register := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://plaxt.example/webhooks/"+id+"/plex", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		registerPlexWebhook(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, register("missing", `{"token":"good-token"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, register("u1", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, register("u1", `{"token":"bad-token"}`).Code)
+
+	rr := register("u1", `{"token":"good-token"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"result":"registered"`)
+	assert.Equal(t, []string{"http://plaxt.example/api?id=u1"}, hooks)
+	reg, err := s.GetPlexWebhook(context.Background(), "u1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "good-token", reg.Token)
+		assert.False(t, reg.VerifiedAt.IsZero())
+	}
+
+	rr = register("u1", `{"token":"good-token"}`)
+	assert.Contains(t, rr.Body.String(), `"result":"already_registered"`)
+	assert.Len(t, hooks, 1)
+}
+
+func TestVerifyPlexWebhooks(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	ctx := context.Background()
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+	s.WriteUser(store.User{ID: "u2", Username: "bob"})
+	hooks := []string{"https://other.example/hook"}
+	fakePlexWebhooks(t, &hooks)
+
+	assert.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{ID: "u1", Token: "good-token", WebhookURL: "https://plaxt.example/api?id=u1"}))
+	assert.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{ID: "u2", Token: "revoked", WebhookURL: "https://plaxt.example/api?id=u2"}))
+	assert.NoError(t, s.PutPlexWebhook(ctx, &store.PlexWebhook{ID: "gone", Token: "good-token", WebhookURL: "https://plaxt.example/api?id=gone"}))
+
+	now := time.Now()
+	verifyPlexWebhooks(ctx, now)
+
+	assert.Equal(t, []string{"https://other.example/hook", "https://plaxt.example/api?id=u1"}, hooks,
+		"the missing webhook is added back")
+	reg, err := s.GetPlexWebhook(ctx, "u1")
+	if assert.NoError(t, err) {
+		assert.True(t, reg.VerifiedAt.Equal(now))
+		assert.Empty(t, reg.LastError)
+	}
+	reg, err = s.GetPlexWebhook(ctx, "u2")
+	if assert.NoError(t, err) {
+		assert.True(t, reg.VerifiedAt.IsZero())
+		assert.NotEmpty(t, reg.LastError)
+	}
+	_, err = s.GetPlexWebhook(ctx, "gone")
+	assert.ErrorIs(t, err, store.ErrPlexWebhookNotFound, "registrations for deleted users are dropped")
+}
+
+func TestWebhookReachability(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks/{id}/reachability", checkWebhookReachability).Methods("POST")
+	router.HandleFunc("/webhooks/{id}/reachability", getWebhookReachability).Methods("GET")
+	router.HandleFunc("/reachability/{nonce}", serveReachabilityProbe).Methods("GET")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/webhooks/missing/reachability", "application/json", nil)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/webhooks/u1/reachability", "application/json", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var checked webhookReachabilityResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&checked))
+	resp.Body.Close()
+	assert.Equal(t, srv.URL+"/api?id=u1", checked.WebhookURL)
+	if assert.NotNil(t, checked.Report) {
+		assert.True(t, checked.Report.Reachable, "%+v", checked.Report.Steps)
+	}
+
+	arrived := time.Now().Truncate(time.Second)
+	(&webhookStat{subject: store.WebhookSubjectUser, id: "u1"}).record(context.Background())
+	resp, err = http.Get(srv.URL + "/webhooks/u1/reachability")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var status webhookReachabilityResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	resp.Body.Close()
+	if assert.NotNil(t, status.LastWebhookAt) {
+		assert.False(t, status.LastWebhookAt.Before(arrived))
+	}
+
+	rr := httptest.NewRecorder()
+	serveReachabilityProbe(rr, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/reachability/guess", nil), map[string]string{"nonce": "guess"}))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "only issued nonces are answered")
+}
+
+func TestBuildManualUsersUsesDisplayLocale(t *testing.T) {
+	prevStorage, prevDates := storage, displayDates
+	defer func() { storage, displayDates = prevStorage, prevDates }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice", Updated: time.Date(2024, 3, 7, 18, 5, 0, 0, time.UTC)})
+	s.WriteUser(store.User{ID: "u2", Username: "bob"})
+
+	displayDates = common.NewDateFormatter("de-DE", time.FixedZone("CET", 3600))
+	users := buildManualUsers("https://plaxt.example")
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "07.03.2024 19:05 CET", users[0].LastUpdated)
+		assert.Equal(t, "alice • refreshed 07.03.2024 19:05 CET", users[0].DisplayLabel)
+		assert.Equal(t, "unknown", users[1].LastUpdated)
+	}
+}
+
+func TestUpdateAdminUserRejectsStaleVersion(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice"})
+	// A concurrent token refresh bumps the version past what the admin read
+	s.WriteUser(store.User{ID: "u1", Username: "alice", AccessToken: "refreshed"})
+
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/api/users/u1", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "u1"})
+		rr := httptest.NewRecorder()
+		updateAdminUser(rr, req)
+		return rr
+	}
+
+	rr := update(`{"username":"bob","version":1}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "alice", s.GetUser("u1").Username)
+
+	rr = update(`{"username":"bob","version":2}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	updated := s.GetUser("u1")
+	assert.Equal(t, "bob", updated.Username)
+	assert.Equal(t, "refreshed", updated.AccessToken)
+	assert.Equal(t, int64(3), updated.Version)
+}
+
+func TestGetAdminStatsSummarisesUsersScrobblesAndQueue(t *testing.T) {
+	prevStorage, prevTracker := storage, drainStateTracker
+	defer func() { storage, drainStateTracker = prevStorage, prevTracker }()
+	drainStateTracker = NewDrainStateTracker()
+
+	testStore, _ := newQueueTestStore(t)
+	storage = testStore
+	testStore.WriteUser(store.User{ID: "u2", Username: "bob", TokenExpiry: time.Now().Add(72 * time.Hour)})
+	testStore.WriteUser(store.User{ID: "u3", Username: "carol", TokenExpiry: time.Now().Add(-time.Hour)})
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-time.Hour)))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-3*24*time.Hour)))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-10*24*time.Hour)))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityFailure, now))
+
+	rr := httptest.NewRecorder()
+	getAdminStats(rr, httptest.NewRequest(http.MethodGet, "/admin/api/stats", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var stats adminStatsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Equal(t, 3, stats.TotalUsers)
+	assert.Equal(t, 1, stats.HealthyTokens)
+	assert.Equal(t, 1, stats.WarningTokens)
+	assert.Equal(t, 1, stats.ExpiredTokens)
+	assert.Equal(t, 1, stats.Scrobbles24h)
+	assert.Equal(t, 2, stats.Scrobbles7d)
+	assert.Equal(t, 3, stats.QueueDepth)
+	assert.False(t, stats.DrainActive)
+}
+
+func TestGetAdminActivityBucketsSeries(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	testStore := newPersistTestStore()
+	storage = testStore
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityScrobble, now))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityFailure, now))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityQueued, now.Add(-5*time.Hour)))
+	assert.NoError(t, testStore.IncrementActivity(ctx, store.ActivityScrobble, now.Add(-30*time.Hour)))
+
+	rr := httptest.NewRecorder()
+	getAdminActivity(rr, httptest.NewRequest(http.MethodGet, "/admin/api/activity?range=24h&bucket=6h", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var activity adminActivityResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &activity))
+	assert.Equal(t, int64(6*3600), activity.BucketSeconds)
+	if assert.Len(t, activity.Buckets, 4) {
+		last := activity.Buckets[3]
+		assert.Equal(t, store.ActivityBucketStart(now).Add(-5*time.Hour), last.Start)
+		assert.Equal(t, store.ActivityCounts{Scrobbles: 1, Failures: 1, Queued: 1}, last.ActivityCounts)
+	}
+	assert.Equal(t, store.ActivityCounts{Scrobbles: 1, Failures: 1, Queued: 1}, activity.Totals)
+
+	for _, query := range []string{"range=8d", "range=30m", "bucket=90m", "range=6h&bucket=12h"} {
+		rr = httptest.NewRecorder()
+		getAdminActivity(rr, httptest.NewRequest(http.MethodGet, "/admin/api/activity?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestGetQueueEventsPagesAndFilters(t *testing.T) {
+	prevLog := queueEventLog
+	defer func() { queueEventLog = prevLog }()
+	queueEventLog = store.NewQueueEventLog(100)
+	testStore := newPersistTestStore()
+	queueEventLog.Persist(testStore)
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		queueEventLog.Append(store.QueueLogEvent{Timestamp: base.Add(time.Duration(i) * time.Minute), Operation: "queue_enqueue", QueueSize: i})
+	}
+	assert.Len(t, testStore.queueLog, 5, "events are persisted")
+
+	type page struct {
+		Events  []store.QueueLogEvent `json:"events"`
+		Offset  int                   `json:"offset"`
+		Limit   int                   `json:"limit"`
+		HasMore bool                  `json:"has_more"`
+	}
+	get := func(query string) (int, page) {
+		rr := httptest.NewRecorder()
+		getQueueEvents(rr, httptest.NewRequest(http.MethodGet, "/admin/api/queue/events?"+query, nil))
+		var p page
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+		}
+		return rr.Code, p
+	}
+
+	code, p := get("limit=2&offset=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, p.HasMore)
+	if assert.Len(t, p.Events, 2) {
+		assert.Equal(t, 3, p.Events[0].QueueSize)
+		assert.Equal(t, 2, p.Events[1].QueueSize)
+	}
+
+	code, p = get("since=" + base.Add(time.Minute).Format(time.RFC3339) + "&until=" + base.Add(2*time.Minute).Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, p.HasMore)
+	assert.Len(t, p.Events, 2)
+	assert.Equal(t, 50, p.Limit)
+
+	code, _ = get("since=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestDeleteAdminUserMovesToTrashAndRestores(t *testing.T) {
+	prevStorage := storage
+	defer func() { storage = prevStorage }()
+	s := newPersistTestStore()
+	storage = s
+	s.WriteUser(store.User{ID: "u1", Username: "alice", AccessToken: "access", RefreshToken: "refresh"})
+	assert.NoError(t, s.RecordWatchedMovie(context.Background(), &store.WatchedMovie{UserID: "u1", Title: "Heat"}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/api/users/u1", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "u1"})
+	rr := httptest.NewRecorder()
+	deleteAdminUser(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, s.GetUser("u1"))
+
+	rr = httptest.NewRecorder()
+	listTrash(rr, httptest.NewRequest(http.MethodGet, "/admin/api/trash", nil))
+	var listed []adminTrashResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, store.TrashKindUser, listed[0].Kind)
+		assert.Equal(t, "alice", listed[0].Label)
+	}
+
+	// A new user claiming the same username blocks the restore
+	s.WriteUser(store.User{ID: "u2", Username: "alice"})
+	req = httptest.NewRequest(http.MethodPost, "/admin/api/trash/u1/restore", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "u1"})
+	rr = httptest.NewRecorder()
+	restoreTrashEntry(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	s.DeleteUser("u2", "alice")
+	rr = httptest.NewRecorder()
+	restoreTrashEntry(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	restored := s.GetUser("u1")
+	if assert.NotNil(t, restored) {
+		assert.Equal(t, "access", restored.AccessToken)
+		assert.Equal(t, "refresh", restored.RefreshToken)
+	}
+	history, _ := s.ListWatchedMovies(context.Background(), "u1")
+	assert.Len(t, history, 1)
+	assert.Empty(t, s.trash)
+}
+
+func TestAdminAuthMiddlewareEnforcesRoles(t *testing.T) {
+	prev, prevStorage := adminAccounts, storage
+	defer func() { adminAccounts, storage = prev, prevStorage }()
+	accounts, err := adminauth.ParseAccounts("vera:viewer:v,otto:operator:o,ada:admin:a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	adminAccounts = accounts
+	storage = &MockSuccessStore{}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(adminAuthMiddleware)
+	router.HandleFunc("/admin/api/me", getAdminSession).Methods("GET")
+	router.HandleFunc("/admin/api/queue/status", ok).Methods("GET")
+	router.HandleFunc("/admin/api/queue/drain", ok).Methods("POST")
+	router.HandleFunc("/admin/api/users/{id}", ok).Methods("DELETE")
+	router.HandleFunc("/admin/api/queue/user/{id}/events/{event_id}", ok).Methods("DELETE")
+	router.HandleFunc("/admin/api/family-groups/{id}", ok).Methods("GET")
+	router.HandleFunc("/api", ok).Methods("POST")
+
+	call := func(method, path, user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := call("GET", "/admin/api/queue/status", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Basic")
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/admin/api/queue/status", "vera", "wrong").Code)
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api", "", "").Code, "webhooks are not admin routes")
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/family-groups/g1", "", "").Code, "the onboarding wizard reads family groups")
+
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/queue/status", "vera", "v").Code)
+	assert.Equal(t, http.StatusForbidden, call("POST", "/admin/api/queue/drain", "vera", "v").Code)
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/admin/api/users/u1", "vera", "v").Code)
+
+	assert.Equal(t, http.StatusNoContent, call("POST", "/admin/api/queue/drain", "otto", "o").Code)
+	assert.Equal(t, http.StatusNoContent, call("DELETE", "/admin/api/queue/user/u1/events/e1", "otto", "o").Code)
+	rr = call("DELETE", "/admin/api/users/u1", "otto", "o")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":"requires the admin role"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusNoContent, call("DELETE", "/admin/api/users/u1", "ada", "a").Code)
+
+	rr = call("GET", "/admin/api/me", "vera", "v")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"username":"vera","role":"viewer","auth_enabled":true,"can_operate":false,"can_admin":false}`, rr.Body.String())
+
+	adminAccounts = nil
+	assert.Equal(t, http.StatusNoContent, call("DELETE", "/admin/api/users/u1", "", "").Code, "without accounts the dashboard stays open")
+	rr = call("GET", "/admin/api/me", "", "")
+	assert.JSONEq(t, `{"role":"admin","auth_enabled":false,"can_operate":true,"can_admin":true}`, rr.Body.String())
+}
+
+func TestAdminLoginLockoutAndAudit(t *testing.T) {
+	prevAccounts, prevGuard, prevStorage := adminAccounts, adminLoginGuard, storage
+	defer func() { adminAccounts, adminLoginGuard, storage = prevAccounts, prevGuard, prevStorage }()
+	accounts, err := adminauth.ParseAccounts("vera:viewer:v,ada:admin:a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	adminAccounts = accounts
+	adminLoginGuard = &adminauth.Guard{Threshold: 2, BaseLockout: time.Minute}
+	storage = &MockSuccessStore{}
+
+	router := mux.NewRouter()
+	router.Use(adminAuthMiddleware)
+	router.HandleFunc("/admin/api/auth/attempts", getAdminLoginAttempts).Methods("GET")
+	call := func(ip, user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/api/auth/attempts", nil)
+		req.RemoteAddr = ip + ":4242"
+		req.SetBasicAuth(user, password)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, call("203.0.113.9", "ada", "guess1").Code)
+	assert.Equal(t, http.StatusUnauthorized, call("203.0.113.9", "ada", "guess2").Code)
+	rr := call("203.0.113.9", "ada", "a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "locked out even with the right password")
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusForbidden, call("198.51.100.7", "vera", "v").Code, "only admins see the login audit")
+	rr = call("198.51.100.7", "ada", "a")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var audit adminLoginAuditResponse
+	if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &audit)) {
+		return
+	}
+	assert.True(t, audit.AuthEnabled)
+	if assert.Len(t, audit.Attempts, 4) {
+		assert.Equal(t, adminauth.OutcomeSuccess, audit.Attempts[0].Outcome)
+		assert.Equal(t, "ada", audit.Attempts[0].Username)
+		assert.Equal(t, "198.51.100.7", audit.Attempts[0].IP)
+		assert.Equal(t, adminauth.OutcomeFailure, audit.Attempts[3].Outcome)
+	}
+	if assert.Len(t, audit.Lockouts, 1) {
+		assert.Equal(t, "203.0.113.9", audit.Lockouts[0].IP)
+		assert.Equal(t, 1, audit.Lockouts[0].Blocked)
+	}
+}
+
+func TestAdminTOTPEnrollmentAndVerification(t *testing.T) {
+	prevAccounts, prevStorage, prevGuard, prevRequire := adminAccounts, storage, adminTOTPGuard, adminRequireTOTP
+	defer func() {
+		adminAccounts, storage, adminTOTPGuard, adminRequireTOTP = prevAccounts, prevStorage, prevGuard, prevRequire
+	}()
+	accounts, err := adminauth.ParseAccounts("vera:viewer:v,ada:admin:a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	adminAccounts = accounts
+	s := newPersistTestStore()
+	storage = s
+	adminTOTPGuard = &adminauth.Guard{Threshold: 3, BaseLockout: time.Minute}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(adminAuthMiddleware)
+	router.HandleFunc("/admin", ok).Methods("GET")
+	router.HandleFunc("/admin/api/queue/status", ok).Methods("GET")
+	router.HandleFunc("/admin/api/me/totp", getAdminTOTPStatus).Methods("GET")
+	router.HandleFunc("/admin/api/me/totp", beginAdminTOTPEnrollment).Methods("POST")
+	router.HandleFunc("/admin/api/me/totp/confirm", confirmAdminTOTPEnrollment).Methods("POST")
+	router.HandleFunc("/admin/api/me/totp/verify", verifyAdminTOTP).Methods("POST")
+	router.HandleFunc("/admin/api/admin-accounts/{username}/totp", resetAdminAccountTOTP).Methods("DELETE")
+
+	var cookie *http.Cookie
+	call := func(method, path, user, password, code string) *httptest.ResponseRecorder {
+		body := ""
+		if code != "" {
+			body = `{"code":"` + code + `"}`
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth(user, password)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/queue/status", "ada", "a", "").Code, "accounts without two-factor only need a password")
+
+	rr := call("POST", "/admin/api/me/totp", "ada", "a", "")
+	if !assert.Equal(t, http.StatusOK, rr.Code) {
+		return
+	}
+	var enrollment map[string]string
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &enrollment))
+	secret := enrollment["secret"]
+	assert.Contains(t, enrollment["otpauth_url"], "otpauth://totp/plaxt:ada?")
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/queue/status", "ada", "a", "").Code, "a pending enrollment is not enforced")
+
+	now := time.Now()
+	code, err := adminauth.TOTPCode(secret, adminauth.TOTPStep(now))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/me/totp/confirm", "ada", "a", "000000x").Code)
+	rr = call("POST", "/admin/api/me/totp/confirm", "ada", "a", code)
+	if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		return
+	}
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &confirmed))
+	assert.Len(t, confirmed.RecoveryCodes, adminauth.RecoveryCodeCount)
+	stored, err := s.GetAdminTOTP(context.Background(), "ada")
+	if assert.NoError(t, err) {
+		assert.True(t, stored.Confirmed)
+		assert.NotContains(t, stored.RecoveryCodes, confirmed.RecoveryCodes[0], "recovery codes are stored hashed")
+	}
+	assert.NotEmpty(t, rr.Result().Cookies(), "confirming unlocks this browser")
+
+	rr = call("GET", "/admin/api/queue/status", "ada", "a", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "the password alone is no longer enough")
+	assert.JSONEq(t, `{"error":"two-factor code required","totp_required":true}`, rr.Body.String())
+	rr = call("GET", "/admin", "ada", "a", "")
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/admin/2fa?next=%2Fadmin", rr.Header().Get("Location"))
+	assert.Equal(t, http.StatusConflict, call("POST", "/admin/api/me/totp", "ada", "a", "").Code, "a confirmed enrollment cannot be replaced with the password alone")
+
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/me/totp/verify", "ada", "a", code).Code, "codes cannot be replayed")
+	rr = call("POST", "/admin/api/me/totp/verify", "ada", "a", confirmed.RecoveryCodes[0])
+	if !assert.Equal(t, http.StatusOK, rr.Code) {
+		return
+	}
+	assert.JSONEq(t, `{"recovery_codes_left":9}`, rr.Body.String())
+	cookie = rr.Result().Cookies()[0]
+	assert.Equal(t, adminMFACookie, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/queue/status", "ada", "a", "").Code)
+	assert.Equal(t, http.StatusNoContent, call("GET", "/admin/api/queue/status", "vera", "v", "").Code, "the cookie does not matter to unenrolled accounts")
+
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/me/totp/verify", "ada", "a", confirmed.RecoveryCodes[0]).Code, "recovery codes are single use")
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/me/totp/verify", "ada", "a", "123456").Code)
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/me/totp/verify", "ada", "a", "654321").Code)
+	rr = call("POST", "/admin/api/me/totp/verify", "ada", "a", confirmed.RecoveryCodes[1])
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "wrong codes lock the account out")
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	adminRequireTOTP = true
+	cookie = nil
+	rr = call("GET", "/admin/api/queue/status", "vera", "v", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"error":"two-factor enrollment required","totp_enroll_required":true}`, rr.Body.String())
+	rr = call("GET", "/admin/api/me/totp", "vera", "v", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "enrollment stays reachable")
+	assert.JSONEq(t, `{"enabled":false,"pending":false,"verified":false,"required":true,"recovery_codes_left":0}`, rr.Body.String())
+
+	adminRequireTOTP = false
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/admin/api/admin-accounts/ada/totp", "vera", "v", "").Code)
+	_, err = s.GetAdminTOTP(context.Background(), "ada")
+	assert.NoError(t, err)
+}
+
+func TestAdminSSOSession(t *testing.T) {
+	prevAccounts, prevOIDC, prevStorage := adminAccounts, adminOIDC, storage
+	defer func() { adminAccounts, adminOIDC, storage = prevAccounts, prevOIDC, prevStorage }()
+	provider, err := adminauth.NewOIDCProvider(adminauth.OIDCConfig{
+		Issuer:     "https://sso.example",
+		ClientID:   "plaxt",
+		RoleGroups: map[string]adminauth.Role{"ops": adminauth.RoleOperator},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	adminAccounts, adminOIDC, storage = nil, provider, &MockSuccessStore{}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(adminAuthMiddleware)
+	router.HandleFunc("/admin", ok).Methods("GET")
+	router.HandleFunc("/admin/api/me", getAdminSession).Methods("GET")
+	router.HandleFunc("/admin/api/me/totp", beginAdminTOTPEnrollment).Methods("POST")
+	router.HandleFunc("/admin/api/queue/drain", ok).Methods("POST")
+	router.HandleFunc("/admin/api/users/{id}", ok).Methods("DELETE")
+	router.HandleFunc("/admin/oidc/callback", finishAdminLogin).Methods("GET")
+	router.HandleFunc("/admin/logout", adminLogout).Methods("POST")
+
+	session := adminSessionKey.Issue("operator:sam", time.Now().Add(time.Hour))
+	call := func(method, path, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: adminSessionCookie, Value: cookie})
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := call("GET", "/admin", "")
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/admin/login?next=%2Fadmin", rr.Header().Get("Location"))
+	rr = call("POST", "/admin/api/queue/drain", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"error":"authentication required","login_url":"/admin/login"}`, rr.Body.String())
+	assert.Empty(t, rr.Header().Get("WWW-Authenticate"), "no basic auth prompt without ADMIN_ACCOUNTS")
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/queue/drain", session+"0").Code, "tampered sessions are rejected")
+	forged := adminSessionKey.Issue("admin:sam", time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, call("POST", "/admin/api/queue/drain", forged).Code, "expired sessions are rejected")
+
+	assert.Equal(t, http.StatusNoContent, call("POST", "/admin/api/queue/drain", session).Code)
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/admin/api/users/u1", session).Code, "the group-mapped role applies")
+	rr = call("GET", "/admin/api/me", session)
+	assert.JSONEq(t, `{"username":"sam","role":"operator","sso":true,"auth_enabled":true,"can_operate":true,"can_admin":false}`, rr.Body.String())
+	assert.Equal(t, http.StatusConflict, call("POST", "/admin/api/me/totp", session).Code, "SSO accounts use the provider's second factor")
+
+	req := httptest.NewRequest("GET", "/admin/oidc/callback?state=abc&code=xyz", nil)
+	req.AddCookie(&http.Cookie{Name: adminOIDCStateCookie, Value: "other"})
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "callbacks must come from the browser that started the login")
+
+	rr = call("POST", "/admin/logout", session)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	for _, c := range rr.Result().Cookies() {
+		assert.Equal(t, -1, c.MaxAge, c.Name)
+	}
+
+	assert.Equal(t, "/admin/queue", adminLoginNext("/admin/queue"))
+	assert.Equal(t, "/admin", adminLoginNext("https://evil.example/admin"))
+	assert.Equal(t, "/admin", adminLoginNext("/administrator"))
+}
+
+func TestAdminProxyAuthHeader(t *testing.T) {
+	prevAccounts, prevProxy, prevStorage := adminAccounts, adminProxyAuth, storage
+	defer func() { adminAccounts, adminProxyAuth, storage = prevAccounts, prevProxy, prevStorage }()
+	trusted, err := adminauth.ParseTrustedProxies("10.0.0.2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	adminAccounts, storage = nil, &MockSuccessStore{}
+	adminProxyAuth = &adminauth.ProxyAuth{
+		UserHeader: "Remote-User",
+		RoleGroups: map[string]adminauth.Role{"plaxt-admins": adminauth.RoleAdmin, "family": adminauth.RoleViewer},
+		Trusted:    trusted,
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router := mux.NewRouter()
+	router.Use(rememberPeerAddr)
+	router.Use(handlers.ProxyHeaders)
+	router.Use(adminAuthMiddleware)
+	router.HandleFunc("/admin/api/me", getAdminSession).Methods("GET")
+	router.HandleFunc("/admin/api/users/{id}", ok).Methods("DELETE")
+
+	call := func(method, path, peer, user, groups string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = peer + ":40000"
+		req.Header.Set("X-Forwarded-For", "198.51.100.20")
+		if user != "" {
+			req.Header.Set("Remote-User", user)
+			req.Header.Set("Remote-Groups", groups)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := call("GET", "/admin/api/me", "10.0.0.2", "Alice", "dev,plaxt-admins")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"username":"alice","role":"admin","proxy":true,"auth_enabled":true,"can_operate":true,"can_admin":true}`, rr.Body.String())
+	assert.Equal(t, http.StatusNoContent, call("DELETE", "/admin/api/users/u1", "10.0.0.2", "alice", "plaxt-admins").Code)
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/admin/api/users/u1", "10.0.0.2", "kid", "family").Code)
+	assert.Equal(t, http.StatusForbidden, call("GET", "/admin/api/me", "10.0.0.2", "guest", "dev").Code, "users outside the mapped groups are refused")
+
+	rr = call("GET", "/admin/api/me", "203.0.113.7", "alice", "plaxt-admins")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "the header is ignored from untrusted clients")
+	assert.Empty(t, rr.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/admin/api/me", "10.0.0.2", "", "").Code, "the proxy must name a user")
+}
@@ -117,6 +117,7 @@ type Metadata struct {
 
 	Studio           string `json:"studio,omitempty"`
 	Type             string `json:"type,omitempty"`
+	Subtype          string `json:"subtype,omitempty"`
 	Title            string `json:"title,omitempty"`
 	TitleSort        string `json:"titleSort,omitempty"`
 	GrandparentKey   string `json:"grandparentKey,omitempty"`
@@ -130,6 +131,7 @@ type Metadata struct {
 	Index       int `json:"index,omitempty"`
 	ParentIndex int `json:"parentIndex,omitempty"`
 	RatingCount int `json:"ratingCount,omitempty"`
+	ExtraType   int `json:"extraType,omitempty"` // 1 = trailer; any non-zero value is an extra
 
 	AudienceRating float32 `json:"audienceRating,omitempty"`
 	ViewOffset     int     `json:"viewOffset,omitempty"`
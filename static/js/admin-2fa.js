@@ -0,0 +1,94 @@
+// Two-factor page: enroll an authenticator app or enter a code to unlock
+// the admin dashboard for this browser.
+
+function nextPage() {
+  const next = new URLSearchParams(window.location.search).get('next') || '/admin';
+  // Only follow local admin paths so the page cannot be used as a redirector
+  return next.startsWith('/admin') ? next : '/admin';
+}
+
+function showPanel(id) {
+  for (const panel of document.querySelectorAll('[id$="-panel"]')) {
+    panel.hidden = panel.id !== id;
+  }
+}
+
+function showError(message) {
+  const container = document.getElementById('error-container');
+  container.innerHTML = `<div class="error-message">${escapeHtml(message)}</div>`;
+}
+
+async function postCode(url, code) {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code })
+  });
+  const data = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw new Error(data.error || `HTTP ${response.status}`);
+  }
+  return data;
+}
+
+async function startEnrollment() {
+  const response = await fetch('/admin/api/me/totp', { method: 'POST' });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || `HTTP ${response.status}`);
+  }
+  document.getElementById('enroll-secret').value = data.secret;
+  document.getElementById('enroll-link').href = data.otpauth_url;
+  showPanel('enroll-panel');
+}
+
+async function loadTwoFactor() {
+  try {
+    const response = await fetch('/admin/api/me/totp');
+    const status = await response.json();
+    if (!response.ok) {
+      throw new Error(status.error || `HTTP ${response.status}`);
+    }
+    if (status.enabled && status.verified) {
+      document.getElementById('done-message').textContent =
+        `Two-factor authentication is enabled. ${status.recovery_codes_left} recovery codes left.`;
+      showPanel('done-panel');
+    } else if (status.enabled) {
+      showPanel('verify-panel');
+      document.getElementById('verify-code').focus();
+    } else {
+      if (status.required) {
+        document.getElementById('enroll-intro').textContent =
+          'This plaxt instance requires two-factor authentication for admin accounts. ' +
+          'Add the key below to an authenticator app, then enter the code it shows.';
+      }
+      await startEnrollment();
+    }
+  } catch (error) {
+    showError(`Failed to load two-factor status: ${error.message}`);
+  }
+}
+
+document.getElementById('verify-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  try {
+    await postCode('/admin/api/me/totp/verify', document.getElementById('verify-code').value);
+    window.location.href = nextPage();
+  } catch (error) {
+    showError(error.message);
+  }
+});
+
+document.getElementById('confirm-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  try {
+    const data = await postCode('/admin/api/me/totp/confirm', document.getElementById('confirm-code').value);
+    document.getElementById('recovery-codes').textContent = data.recovery_codes.join('\n');
+    document.getElementById('recovery-continue').href = nextPage();
+    showPanel('recovery-panel');
+  } catch (error) {
+    showError(error.message);
+  }
+});
+
+document.addEventListener('DOMContentLoaded', loadTwoFactor);
@@ -1,12 +1,22 @@
 let users = [];
 let familyGroups = [];
+let stats = null;
 
 document.addEventListener('DOMContentLoaded', () => {
   loadUsers();
   loadFamilyGroups();
+  loadStats();
+  loadActivity();
+  loadTrash();
+  loadLogins();
+  document.getElementById('activity-range').addEventListener('change', loadActivity);
   setInterval(() => {
     loadUsers();
     loadFamilyGroups();
+    loadStats();
+    loadActivity();
+    loadTrash();
+    loadLogins();
   }, 30000);
 });
 
@@ -38,6 +48,61 @@ async function loadFamilyGroups() {
   }
 }
 
+async function loadStats() {
+  try {
+    const response = await fetch('/admin/api/stats');
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    stats = await response.json();
+    updateStats();
+  } catch (error) {
+    // Cards fall back to counts derived from the user list
+    console.error('Failed to load stats:', error);
+  }
+}
+
+// Bucket size per range keeps the chart around 24-28 bars
+const activityBuckets = { '24h': '1h', '7d': '6h' };
+
+async function loadActivity() {
+  const container = document.getElementById('activity-content');
+  const range = document.getElementById('activity-range').value;
+  try {
+    const response = await fetch(`/admin/api/activity?range=${range}&bucket=${activityBuckets[range]}`);
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    renderActivity(await response.json());
+  } catch (error) {
+    container.innerHTML = `<div class="empty-state">Failed to load activity: ${escapeHtml(error.message)}</div>`;
+  }
+}
+
+function renderActivity(activity) {
+  const container = document.getElementById('activity-content');
+  const peak = Math.max(1, ...activity.buckets.map(b => b.scrobbles + b.failures + b.queued));
+  const bars = activity.buckets.map(bucket => {
+    const label = `${new Date(bucket.start).toLocaleString()}: ${bucket.scrobbles} scrobbled, ${bucket.failures} failed, ${bucket.queued} queued`;
+    return `
+      <div class="activity-bar" title="${escapeHtml(label)}">
+        <span class="activity-segment activity-queued" style="height: ${(bucket.queued / peak) * 100}%"></span>
+        <span class="activity-segment activity-failures" style="height: ${(bucket.failures / peak) * 100}%"></span>
+        <span class="activity-segment activity-scrobbles" style="height: ${(bucket.scrobbles / peak) * 100}%"></span>
+      </div>
+    `;
+  }).join('');
+
+  container.innerHTML = `
+    <div class="activity-chart">${bars}</div>
+    <div class="activity-legend">
+      <span><span class="activity-swatch activity-scrobbles"></span>Scrobbled (${activity.totals.scrobbles})</span>
+      <span><span class="activity-swatch activity-failures"></span>Failed (${activity.totals.failures})</span>
+      <span><span class="activity-swatch activity-queued"></span>Queued (${activity.totals.queued})</span>
+    </div>
+  `;
+}
+
 function renderUsers() {
   const container = document.getElementById('table-content');
 
@@ -79,8 +144,9 @@ function renderUsers() {
             <td>${formatDate(user.updated)}</td>
             <td>
               <div class="actions">
-                <button class="btn btn-edit" onclick="editUser('${user.id}')">Edit</button>
-                <button class="btn btn-delete" onclick="deleteUser('${user.id}')">Delete</button>
+                <button class="btn btn-edit" data-requires-role="operator" onclick="editUser('${user.id}')">Edit</button>
+                <a class="btn btn-edit" style="text-decoration: none;" href="/admin/api/users/${encodeURIComponent(user.id)}/letterboxd.csv" title="Download completed movies as a Letterboxd import CSV">Letterboxd CSV</a>
+                <button class="btn btn-delete" data-requires-role="admin" onclick="deleteUser('${user.id}')">Delete</button>
               </div>
             </td>
           </tr>
@@ -91,16 +157,23 @@ function renderUsers() {
 }
 
 function updateStats() {
-  const total = users.length;
-  const healthy = users.filter(u => u.status === 'healthy').length;
-  const warning = users.filter(u => u.status === 'warning').length;
-  const expired = users.filter(u => u.status === 'expired').length;
+  const total = stats ? stats.total_users : users.length;
+  const healthy = stats ? stats.healthy_tokens : users.filter(u => u.status === 'healthy').length;
+  const warning = stats ? stats.warning_tokens : users.filter(u => u.status === 'warning').length;
+  const expired = stats ? stats.expired_tokens : users.filter(u => u.status === 'expired').length;
 
   document.getElementById('stat-total').textContent = total;
   document.getElementById('stat-family-groups').textContent = familyGroups.length;
   document.getElementById('stat-healthy').textContent = healthy;
   document.getElementById('stat-warning').textContent = warning;
   document.getElementById('stat-expired').textContent = expired;
+
+  if (stats) {
+    document.getElementById('stat-scrobbles-24h').textContent = stats.scrobbles_24h;
+    document.getElementById('stat-scrobbles-7d').textContent = stats.scrobbles_7d;
+    document.getElementById('stat-queue-depth').textContent = stats.queue_depth;
+    document.getElementById('stat-drain-mode').textContent = stats.drain_active ? 'Draining' : stats.drain_mode === 'queue' ? 'Queueing' : 'Live';
+  }
 }
 
 function editUser(id) {
@@ -122,6 +195,7 @@ async function saveUser() {
   const id = document.getElementById('edit-user-id').value;
   const username = document.getElementById('edit-username').value.trim();
   const displayName = document.getElementById('edit-display-name').value.trim();
+  const user = users.find(u => u.id === id);
 
   if (!username) {
     alert('Username is required');
@@ -134,10 +208,18 @@ async function saveUser() {
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({
         username: username,
-        trakt_display_name: displayName || null
+        trakt_display_name: displayName || null,
+        version: user ? user.version : undefined
       })
     });
 
+    if (response.status === 409) {
+      closeEditModal();
+      await loadUsers();
+      showError('This user was changed by someone else. The list has been reloaded; please try again.');
+      return;
+    }
+
     if (!response.ok) {
       throw new Error(`HTTP ${response.status}`);
     }
@@ -178,12 +260,118 @@ async function confirmDelete() {
 
     closeDeleteModal();
     await loadUsers();
-    showSuccess('User deleted successfully');
+    await loadStats();
+    await loadTrash();
+    showSuccess('User moved to trash');
   } catch (error) {
     showError('Failed to delete user: ' + error.message);
   }
 }
 
+async function loadTrash() {
+  const container = document.getElementById('trash-content');
+  if (!container) return;
+  try {
+    const response = await fetch('/admin/api/trash');
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    renderTrash(await response.json());
+  } catch (error) {
+    container.innerHTML = `<div class="empty-state">Failed to load trash: ${escapeHtml(error.message)}</div>`;
+  }
+}
+
+function renderTrash(entries) {
+  const container = document.getElementById('trash-content');
+  if (!entries.length) {
+    container.innerHTML = '<div class="empty-state">Trash is empty</div>';
+    return;
+  }
+  const rows = entries.map(entry => `
+    <tr>
+      <td>${escapeHtml(entry.label)}</td>
+      <td>${entry.kind === 'family_group' ? 'Family group' : 'User'}</td>
+      <td>${new Date(entry.deleted_at).toLocaleString()}</td>
+      <td>${new Date(entry.expires_at).toLocaleDateString()}</td>
+      <td><button class="btn btn-edit" data-requires-role="operator" onclick="restoreTrash('${escapeHtml(entry.id)}')">Restore</button></td>
+    </tr>
+  `).join('');
+  container.innerHTML = `
+    <table>
+      <thead>
+        <tr><th>Name</th><th>Type</th><th>Deleted</th><th>Restorable until</th><th>Actions</th></tr>
+      </thead>
+      <tbody>${rows}</tbody>
+    </table>
+  `;
+}
+
+async function restoreTrash(id) {
+  try {
+    const response = await fetch(`/admin/api/trash/${id}/restore`, { method: 'POST' });
+    if (!response.ok) {
+      const body = await response.json().catch(() => ({}));
+      throw new Error(body.error || `HTTP ${response.status}`);
+    }
+    await Promise.all([loadUsers(), loadFamilyGroups(), loadStats(), loadActivity(), loadTrash()]);
+    showSuccess('Restored successfully');
+  } catch (error) {
+    showError('Failed to restore: ' + error.message);
+  }
+}
+
+async function loadLogins() {
+  const container = document.getElementById('logins-content');
+  if (!container) return;
+  try {
+    const response = await fetch('/admin/api/auth/attempts');
+    if (response.status === 403) {
+      return;
+    }
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    renderLogins(await response.json());
+  } catch (error) {
+    container.innerHTML = `<div class="empty-state">Failed to load logins: ${escapeHtml(error.message)}</div>`;
+  }
+}
+
+function renderLogins(audit) {
+  const container = document.getElementById('logins-content');
+  if (!audit.auth_enabled) {
+    container.innerHTML = '<div class="empty-state">Admin authentication is off. Set ADMIN_ACCOUNTS to require logins.</div>';
+    return;
+  }
+  const now = new Date();
+  const locked = audit.lockouts.filter(l => new Date(l.locked_until) > now);
+  const lockoutNote = locked.length
+    ? `<div class="error-message">Locked out: ${locked.map(l => `${escapeHtml(l.ip)} until ${new Date(l.locked_until).toLocaleTimeString()} (${l.failures} failures)`).join(', ')}</div>`
+    : '';
+  if (!audit.attempts.length) {
+    container.innerHTML = lockoutNote + '<div class="empty-state">No logins yet</div>';
+    return;
+  }
+  const rows = audit.attempts.slice(0, 50).map(attempt => `
+    <tr>
+      <td>${new Date(attempt.time).toLocaleString()}</td>
+      <td>${escapeHtml(attempt.username) || '-'}</td>
+      <td>${escapeHtml(attempt.ip)}</td>
+      <td><span class="status-indicator ${attempt.outcome === 'success' ? 'status-healthy' : 'status-expired'}"><span class="status-dot"></span>${escapeHtml(attempt.outcome)}</span></td>
+    </tr>
+  `).join('');
+  container.innerHTML = `
+    ${lockoutNote}
+    <table>
+      <thead>
+        <tr><th>Time</th><th>Account</th><th>IP</th><th>Outcome</th></tr>
+      </thead>
+      <tbody>${rows}</tbody>
+    </table>
+  `;
+}
+
 function showError(message) {
   const container = document.getElementById('error-container');
   container.innerHTML = `<div class="error-message">${escapeHtml(message)}</div>`;
@@ -39,3 +39,34 @@ function formatDate(dateString) {
 
   return date.toLocaleDateString('en-US', { month: 'short', day: 'numeric', year: 'numeric' });
 }
+
+// Hide actions the signed-in admin's role does not allow. The server
+// enforces the same rules; this only keeps the dashboard honest.
+async function loadAdminSession() {
+  try {
+    const response = await fetch('/admin/api/me');
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    const session = await response.json();
+    document.body.dataset.adminRole = session.role;
+    document.body.classList.toggle('role-cannot-operate', !session.can_operate);
+    document.body.classList.toggle('role-cannot-admin', !session.can_admin);
+    document.body.classList.toggle('admin-auth-disabled', !session.auth_enabled);
+    document.body.classList.toggle('admin-sso', Boolean(session.sso));
+    for (const el of document.querySelectorAll('[data-requires-sso]')) {
+      el.hidden = !session.sso;
+    }
+  } catch (error) {
+    console.error('Failed to load admin session:', error);
+  }
+}
+
+// Ends a single sign-on session. The identity provider's own session is
+// left alone, so this returns to the landing page rather than /admin.
+async function adminLogout() {
+  await fetch('/admin/logout', { method: 'POST' });
+  window.location.href = '/';
+}
+
+document.addEventListener('DOMContentLoaded', loadAdminSession);
@@ -68,7 +68,7 @@ function renderFamilyGroups() {
             <td>
               <div class="actions">
                 <button class="btn btn-edit" onclick="viewGroupDetail('${group.id}')">View</button>
-                <button class="btn btn-delete" onclick="deleteGroup('${group.id}', '${escapeHtml(group.plex_username)}')">Delete</button>
+                <button class="btn btn-delete" data-requires-role="admin" onclick="deleteGroup('${group.id}', '${escapeHtml(group.plex_username)}')">Delete</button>
               </div>
             </td>
           </tr>
@@ -131,7 +131,7 @@ function renderGroupDetail() {
     <div style="margin-bottom: 1rem; display: flex; justify-content: space-between; align-items: center;">
       <h3 style="margin: 0;">Members (${currentGroupDetail.members.length})</h3>
       ${currentGroupDetail.members.length < 10
-        ? `<button class="btn btn-edit" style="font-size: 0.875rem; padding: 0.5rem 1rem;" onclick="showAddMemberModal('${currentGroupDetail.id}')">+ Add Member</button>`
+        ? `<button class="btn btn-edit" data-requires-role="operator" style="font-size: 0.875rem; padding: 0.5rem 1rem;" onclick="showAddMemberModal('${currentGroupDetail.id}')">+ Add Member</button>`
         : '<span style="color: #6b7280; font-size: 0.875rem;">Maximum members reached</span>'
       }
     </div>
@@ -159,7 +159,7 @@ function renderGroupDetail() {
             </td>
             <td>${member.token_age_hours !== null ? formatTokenAge(member.token_age_hours) : '-'}</td>
             <td>
-              <button class="btn btn-delete" style="font-size: 0.875rem; padding: 0.4rem 0.8rem;" onclick="removeMember('${currentGroupDetail.id}', '${member.id}', '${escapeHtml(member.label)}')">Remove</button>
+              <button class="btn btn-delete" data-requires-role="admin" style="font-size: 0.875rem; padding: 0.4rem 0.8rem;" onclick="removeMember('${currentGroupDetail.id}', '${member.id}', '${escapeHtml(member.label)}')">Remove</button>
             </td>
           </tr>
         `).join('')}
@@ -410,7 +410,8 @@
   }
 
   if (onboardingAuthButton) {
-    onboardingAuthButton.addEventListener('click', function() {
+    onboardingAuthButton.addEventListener('click', function(event) {
+      event.preventDefault();
       var storedUsername = (body.dataset.onboardingUsername || '').trim();
       if (!storedUsername && onboardingInput) {
         storedUsername = onboardingInput.value.trim();
@@ -482,7 +483,8 @@
   }
 
   if (manualStart && manualSelect) {
-    manualStart.addEventListener('click', function() {
+    manualStart.addEventListener('click', function(event) {
+      event.preventDefault();
       var option = manualSelect.options[manualSelect.selectedIndex];
       if (!option || !option.value) {
         manualError.textContent = 'Choose a user before continuing.';
@@ -517,6 +519,59 @@
     });
   }
 
+  var tokenHealth = document.querySelector('.js-token-health');
+  if (tokenHealth) {
+    var tokenCountdown = tokenHealth.querySelector('.js-token-countdown');
+    var tokenRenew = tokenHealth.querySelector('.js-token-renew');
+    var tokenRenewError = tokenHealth.querySelector('.js-token-renew-error');
+    var tokenExpiresAt = Date.parse(tokenHealth.dataset.expiresAt || '');
+
+    if (tokenCountdown && !isNaN(tokenExpiresAt)) {
+      var renderCountdown = function() {
+        var remaining = tokenExpiresAt - Date.now();
+        if (remaining <= 0) {
+          tokenCountdown.textContent = '0m';
+          tokenHealth.classList.remove('banner-token-healthy', 'banner-token-warning');
+          tokenHealth.classList.add('banner-token-expired');
+          return false;
+        }
+        var totalMinutes = Math.floor(remaining / 60000);
+        var days = Math.floor(totalMinutes / 1440);
+        var hours = Math.floor((totalMinutes % 1440) / 60);
+        var minutes = totalMinutes % 60;
+        tokenCountdown.textContent = days > 0 ? days + 'd ' + hours + 'h' : hours + 'h ' + minutes + 'm';
+        return true;
+      };
+      if (renderCountdown()) {
+        var countdownTimer = setInterval(function() {
+          if (!renderCountdown()) {
+            clearInterval(countdownTimer);
+          }
+        }, 30000);
+      }
+    }
+
+    if (tokenRenew) {
+      tokenRenew.addEventListener('click', function(event) {
+        event.preventDefault();
+        if (tokenRenewError) {
+          tokenRenewError.textContent = '';
+        }
+        tokenRenew.disabled = true;
+        startAuthorization(tokenHealth.dataset.username, tokenHealth.dataset.userId, 'renew')
+          .then(function(authUrl) {
+            window.location = authUrl;
+          })
+          .catch(function(error) {
+            tokenRenew.disabled = false;
+            if (tokenRenewError) {
+              tokenRenewError.textContent = error.message || 'Unable to contact Trakt. Please try again.';
+            }
+          });
+      });
+    }
+  }
+
   if (manualDisplayForm && manualDisplayInput) {
     manualDisplayForm.addEventListener('submit', function(event) {
       event.preventDefault();
@@ -632,6 +687,135 @@
     });
   });
 
+  // webhookIdFor reads the user or family group ID from a rendered webhook URL.
+  function webhookIdFor(selector) {
+    var target = selector ? document.querySelector(selector) : null;
+    if (!target) {
+      return '';
+    }
+    try {
+      return new URL(target.textContent.trim()).searchParams.get('id') || '';
+    } catch (err) {
+      return '';
+    }
+  }
+
+    document.querySelectorAll('.js-plex-register-form').forEach(function(form) {
+    form.addEventListener('submit', function(event) {
+      event.preventDefault();
+      var tokenInput = form.querySelector('.js-plex-register-token');
+      var errorEl = form.querySelector('.js-plex-register-error');
+      var successEl = form.querySelector('.js-plex-register-success');
+      errorEl.textContent = '';
+      successEl.textContent = '';
+      var id = webhookIdFor(form.getAttribute('data-webhook-target'));
+      var token = tokenInput.value.trim();
+      if (!id || !token) {
+        errorEl.textContent = token ? 'Missing webhook identifier. Refresh and try again.' : 'Enter your X-Plex-Token.';
+        return;
+      }
+      fetch('/webhooks/' + encodeURIComponent(id) + '/plex', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ token: token })
+      }).then(function(response) {
+        return response.json().then(function(data) {
+          if (!response.ok) {
+            throw new Error((data && data.error) || 'Unable to register the webhook.');
+          }
+          return data;
+        });
+      }).then(function(data) {
+        tokenInput.value = '';
+        successEl.textContent = data.result === 'registered'
+          ? 'Webhook added to your Plex account.'
+          : 'Plex already has this webhook.';
+      }).catch(function(err) {
+        errorEl.textContent = err.message || 'Unable to register the webhook.';
+      });
+    });
+  });
+
+  document.querySelectorAll('.js-reachability').forEach(function(panel) {
+    var button = panel.querySelector('.js-reachability-start');
+    var stepList = panel.querySelector('.js-reachability-steps');
+    var status = panel.querySelector('.js-reachability-status');
+    var stepLabels = { dns: 'DNS lookup', connect: 'Connection', tls: 'TLS certificate', http: 'Reached Plaxt' };
+    var pollTimer = null;
+
+    function renderReport(report) {
+      stepList.innerHTML = '';
+      report.steps.forEach(function(step) {
+        var item = document.createElement('li');
+        item.setAttribute('data-ok', step.ok ? 'true' : 'false');
+        item.textContent = (step.ok ? '✓ ' : '✗ ') + (stepLabels[step.name] || step.name) + (step.detail ? ' — ' + step.detail : '');
+        if (step.hint) {
+          var hint = document.createElement('small');
+          hint.textContent = step.hint;
+          item.appendChild(hint);
+        }
+        stepList.appendChild(item);
+      });
+      (report.warnings || []).forEach(function(warning) {
+        var item = document.createElement('li');
+        item.textContent = '⚠ ' + warning;
+        stepList.appendChild(item);
+      });
+    }
+
+    function waitForWebhook(id, since) {
+      var deadline = Date.now() + 2 * 60 * 1000;
+      status.textContent = 'Now play or pause something in Plex. Waiting for its webhook…';
+      clearInterval(pollTimer);
+      pollTimer = setInterval(function() {
+        if (Date.now() > deadline) {
+          clearInterval(pollTimer);
+          status.textContent = 'No webhook arrived from Plex yet. Check that the URL is saved under Settings → Webhooks.';
+          return;
+        }
+        fetch('/webhooks/' + encodeURIComponent(id) + '/reachability').then(function(response) {
+          return response.ok ? response.json() : null;
+        }).then(function(data) {
+          if (data && data.last_webhook_at && new Date(data.last_webhook_at).getTime() > since) {
+            clearInterval(pollTimer);
+            status.textContent = 'Plex reached Plaxt. Webhooks are working.';
+          }
+        }).catch(function() {});
+      }, 5000);
+    }
+
+    button.addEventListener('click', function() {
+      var id = webhookIdFor(panel.getAttribute('data-webhook-target'));
+      if (!id) {
+        status.textContent = 'Missing webhook identifier. Refresh and try again.';
+        return;
+      }
+      var started = Date.now();
+      button.disabled = true;
+      stepList.innerHTML = '';
+      status.textContent = 'Checking…';
+      fetch('/webhooks/' + encodeURIComponent(id) + '/reachability', { method: 'POST' }).then(function(response) {
+        return response.json().then(function(data) {
+          if (!response.ok) {
+            throw new Error((data && data.error) || 'Unable to run the check.');
+          }
+          return data;
+        });
+      }).then(function(data) {
+        renderReport(data.report);
+        if (data.report.reachable) {
+          waitForWebhook(id, started);
+        } else {
+          status.textContent = 'Plex will not be able to reach this address until the failed step is fixed.';
+        }
+      }).catch(function(err) {
+        status.textContent = err.message || 'Unable to run the check.';
+      }).then(function() {
+        button.disabled = false;
+      });
+    });
+  });
+
   // Restore persisted state when returning without result
   if (!body.dataset.manualResult) {
     var storedManual = localStorage.getItem('plaxtManualStep');
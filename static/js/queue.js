@@ -1,5 +1,7 @@
 const refreshInterval = 5000;
 let lastUpdate = Date.now();
+// User whose queued events are shown, if any
+let detailUserId = null;
 
 async function fetchQueueStatus() {
   try {
@@ -68,7 +70,11 @@ function updateUI(data) {
           <strong>${escapeHtml(user.username)}</strong>
           ${user.trakt_display_name ? `<br><small style="color: #666;">${escapeHtml(user.trakt_display_name)}</small>` : ''}
         </td>
-        <td>${user.queue_size}</td>
+        <td>
+          ${user.queue_size > 0
+            ? `<button class="btn btn-secondary btn-small" onclick="showQueueDetail('${escapeHtml(user.user_id)}')">${user.queue_size}</button>`
+            : user.queue_size}
+        </td>
         <td>${user.oldest_event_age_seconds ? formatAge(user.oldest_event_age_seconds) : '-'}</td>
         <td>${renderStatus(user.status)}</td>
         <td>${progressInfo}</td>
@@ -130,6 +136,80 @@ font-size: 18px;">No recent events</div>
     .join('');
 }
 
+async function showQueueDetail(userId) {
+  detailUserId = userId;
+  try {
+    const response = await fetch(`/admin/api/queue/user/${encodeURIComponent(userId)}?payload=none&limit=500`);
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    const data = await response.json();
+    renderQueueDetail(data);
+  } catch (error) {
+    console.error('Error fetching queued events:', error);
+  }
+}
+
+function renderQueueDetail(data) {
+  const container = document.getElementById('queue-detail');
+  const tbody = document.getElementById('queue-detail-body');
+  document.getElementById('queue-detail-title').textContent = `Queued Events for ${data.username}`;
+  container.hidden = false;
+
+  if (!data.events || data.events.length === 0) {
+    tbody.innerHTML = `
+      <tr>
+        <td colspan="5" class="empty-state">
+          <div class="empty-state-icon">✅</div>
+          <div>Queue is empty</div>
+        </td>
+      </tr>
+    `;
+    return;
+  }
+
+  tbody.innerHTML = data.events
+    .map(
+      (event) => `
+    <tr>
+      <td class="event-time">${formatTime(event.created_at)}</td>
+      <td>${escapeHtml(event.summary)}</td>
+      <td>${event.retry_count}</td>
+      <td>${escapeHtml(event.id.substring(0, 8))}</td>
+      <td>
+        <button class="btn btn-danger btn-small" onclick="removeQueuedEvent('${escapeHtml(event.id)}')">Remove</button>
+      </td>
+    </tr>
+  `
+    )
+    .join('');
+}
+
+async function removeQueuedEvent(eventId) {
+  if (!detailUserId || !confirm('Remove this event from the queue? It will not be scrobbled.')) {
+    return;
+  }
+  try {
+    const response = await fetch(
+      `/admin/api/queue/user/${encodeURIComponent(detailUserId)}/events/${encodeURIComponent(eventId)}`,
+      { method: 'DELETE' }
+    );
+    if (!response.ok && response.status !== 404) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    await showQueueDetail(detailUserId);
+    fetchQueueStatus();
+  } catch (error) {
+    console.error('Error removing queued event:', error);
+    alert('Failed to remove event');
+  }
+}
+
+function closeQueueDetail() {
+  detailUserId = null;
+  document.getElementById('queue-detail').hidden = true;
+}
+
 function formatOperation(op) {
   return op.replace('queue_', '').replace(/_/g, ' ');
 }
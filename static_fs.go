@@ -0,0 +1,69 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// embeddedStatic holds the templates and assets, including the esbuild
+// bundles in static/dist when `npm run build` ran before `go build`, so the
+// binary runs from any working directory.
+//
+//go:embed static
+var embeddedStatic embed.FS
+
+// staticFS serves templates and /static/ files. STATIC_DIR replaces it with
+// an overlay in main.
+var staticFS = newStaticFS("")
+
+// newStaticFS returns the embedded static tree, overlaid by dir when set:
+// files in dir replace their embedded counterparts, so a template or
+// stylesheet can be customised without rebuilding.
+func newStaticFS(dir string) fs.FS {
+	base, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		// Only fails for an invalid literal path
+		panic(err)
+	}
+	if dir == "" {
+		return base
+	}
+	return overlayFS{override: os.DirFS(dir), base: base}
+}
+
+// overlayFS opens files from override, falling back to base for files
+// override does not have.
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.override.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+// renderTemplate parses name from staticFS and executes it with data. A
+// missing or broken template answers 500 instead of panicking.
+func renderTemplate(w http.ResponseWriter, name string, data any) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).ParseFS(staticFS, name)
+	if err != nil {
+		slog.Error("failed to parse template", "template", name, "error", err)
+		http.Error(w, "page unavailable", http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		slog.Error("failed to render template", "template", name, "error", err)
+	}
+}
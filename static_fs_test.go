@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticFSOverlay(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "queue.html"), []byte("custom {{ assetPath \"css/queue.css\" }}"), 0o644))
+	prev := staticFS
+	defer func() { staticFS = prev }()
+	staticFS = newStaticFS(dir)
+
+	data, err := fs.ReadFile(staticFS, "queue.html")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "custom", "override files win")
+	_, err = fs.ReadFile(staticFS, "admin.html")
+	assert.NoError(t, err, "other files come from the binary")
+
+	rr := httptest.NewRecorder()
+	renderTemplate(rr, "queue.html", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "custom /static/")
+
+	rr = httptest.NewRecorder()
+	renderTemplate(rr, "missing.html", nil)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code, "a missing template must not panic")
+}
@@ -1,607 +0,0 @@
-package contract
-
-import (
-	"encoding/json"
-	"testing"
-
-	"github.com/stretchr/testify/assert"
-)
-
-// TestFamilyAPIEndpoints tests that all family API endpoints match the OpenAPI specification
-func TestFamilyAPIEndpoints(t *testing.T) {
-	t.Run("POST /oauth/family/state", func(t *testing.T) {
-		// Test family onboarding state creation endpoint
-		
-		// Valid request payload
-		requestPayload := map[string]interface{}{
-			"mode":         "family",
-			"plex_username": "TestTV",
-			"members": []map[string]interface{}{
-				{"temp_label": "Dad"},
-				{"temp_label": "Mom"},
-				{"temp_label": "Kid1"},
-			},
-		}
-
-		// Verify request payload structure
-		assert.Equal(t, "family", requestPayload["mode"])
-		assert.Equal(t, "TestTV", requestPayload["plex_username"])
-		members := requestPayload["members"].([]map[string]interface{})
-		assert.Len(t, members, 3)
-		assert.Equal(t, "Dad", members[0]["temp_label"])
-		assert.Equal(t, "Mom", members[1]["temp_label"])
-		assert.Equal(t, "Kid1", members[2]["temp_label"])
-		
-		// Expected response structure
-		expectedResponse := map[string]interface{}{
-			"state":           "test-state-token",
-			"family_group_id": "test-group-id",
-		}
-
-		// Verify response structure
-		assert.Contains(t, expectedResponse, "state")
-		assert.Contains(t, expectedResponse, "family_group_id")
-		
-		// In a real test, we would:
-		// 1. Send POST request to /oauth/family/state
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that family group is created in database
-		// 5. Verify that state token is generated
-	})
-
-	t.Run("GET /authorize/family/member", func(t *testing.T) {
-		// Test family member authorization endpoint
-		
-		// Valid request parameters
-		state := "test-state-token"
-		code := "test-auth-code"
-		memberID := "test-member-id"
-		
-		// Verify parameters
-		assert.NotEmpty(t, state)
-		assert.NotEmpty(t, code)
-		assert.NotEmpty(t, memberID)
-		
-		// Expected redirect response
-		expectedRedirect := "/wizard?step=2&status=success"
-		
-		// Verify redirect URL
-		assert.Contains(t, expectedRedirect, "/wizard")
-		assert.Contains(t, expectedRedirect, "step=2")
-		assert.Contains(t, expectedRedirect, "status=success")
-		
-		// In a real test, we would:
-		// 1. Send GET request to /authorize/family/member with parameters
-		// 2. Verify response status is 302 (redirect)
-		// 3. Verify redirect URL matches expected pattern
-		// 4. Verify that member authorization is updated in database
-		// 5. Verify that OAuth flow completes successfully
-	})
-
-	t.Run("GET /admin/api/family-groups", func(t *testing.T) {
-		// Test family groups listing endpoint
-		
-		// Expected response structure
-		expectedResponse := []map[string]interface{}{
-			{
-				"id":               "group-1",
-				"plex_username":    "TV1",
-				"member_count":     3,
-				"authorized_count": 2,
-				"webhook_url":      "/api?id=group-1",
-				"created_at":       "2023-01-01T00:00:00Z",
-			},
-			{
-				"id":               "group-2",
-				"plex_username":    "TV2",
-				"member_count":     5,
-				"authorized_count": 5,
-				"webhook_url":      "/api?id=group-2",
-				"created_at":       "2023-01-02T00:00:00Z",
-			},
-		}
-
-		// Verify response structure
-		assert.Len(t, expectedResponse, 2)
-		for _, group := range expectedResponse {
-			assert.Contains(t, group, "id")
-			assert.Contains(t, group, "plex_username")
-			assert.Contains(t, group, "member_count")
-			assert.Contains(t, group, "authorized_count")
-			assert.Contains(t, group, "webhook_url")
-			assert.Contains(t, group, "created_at")
-		}
-		
-		// In a real test, we would:
-		// 1. Send GET request to /admin/api/family-groups
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that all family groups are returned
-		// 5. Verify that counts are accurate
-	})
-
-	t.Run("GET /admin/api/family-groups/{id}", func(t *testing.T) {
-		// Test family group detail endpoint
-		
-		groupID := "test-group-id"
-		
-		// Expected response structure
-		expectedResponse := map[string]interface{}{
-			"id":            groupID,
-			"plex_username": "TestTV",
-			"webhook_url":   "/api?id=" + groupID,
-			"created_at":    "2023-01-01T00:00:00Z",
-			"updated_at":    "2023-01-01T00:00:00Z",
-			"members": []map[string]interface{}{
-				{
-					"id":                  "member-1",
-					"temp_label":          "Dad",
-					"trakt_username":      "dad_user",
-					"authorization_status": "authorized",
-					"token_expiry":        "2023-12-31T23:59:59Z",
-					"created_at":          "2023-01-01T00:00:00Z",
-				},
-				{
-					"id":                  "member-2",
-					"temp_label":          "Mom",
-					"trakt_username":      "mom_user",
-					"authorization_status": "authorized",
-					"token_expiry":        "2023-12-31T23:59:59Z",
-					"created_at":          "2023-01-01T00:00:00Z",
-				},
-			},
-		}
-
-		// Verify response structure
-		assert.Equal(t, groupID, expectedResponse["id"])
-		assert.Contains(t, expectedResponse, "plex_username")
-		assert.Contains(t, expectedResponse, "webhook_url")
-		assert.Contains(t, expectedResponse, "created_at")
-		assert.Contains(t, expectedResponse, "updated_at")
-		assert.Contains(t, expectedResponse, "members")
-		
-		members := expectedResponse["members"].([]map[string]interface{})
-		assert.Len(t, members, 2)
-		for _, member := range members {
-			assert.Contains(t, member, "id")
-			assert.Contains(t, member, "temp_label")
-			assert.Contains(t, member, "trakt_username")
-			assert.Contains(t, member, "authorization_status")
-			assert.Contains(t, member, "token_expiry")
-			assert.Contains(t, member, "created_at")
-		}
-		
-		// In a real test, we would:
-		// 1. Send GET request to /admin/api/family-groups/{id}
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that all group details are returned
-		// 5. Verify that all member details are returned
-	})
-
-	t.Run("POST /admin/api/family-groups/{id}/members", func(t *testing.T) {
-		// Test adding member to family group endpoint
-		
-		_ = "test-group-id" // groupID placeholder for future use
-		
-		// Valid request payload
-		requestPayload := map[string]interface{}{
-			"temp_label": "NewMember",
-		}
-
-		// Verify request payload
-		assert.Equal(t, "NewMember", requestPayload["temp_label"])
-		
-		// Expected response structure
-		expectedResponse := map[string]interface{}{
-			"member_id":         "new-member-id",
-			"authorization_url": "/authorize/family/member?state=test-state&member_id=new-member-id",
-		}
-
-		// Verify response structure
-		assert.Contains(t, expectedResponse, "member_id")
-		assert.Contains(t, expectedResponse, "authorization_url")
-		
-		// In a real test, we would:
-		// 1. Send POST request to /admin/api/family-groups/{id}/members
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that member is created in database
-		// 5. Verify that authorization URL is generated
-	})
-
-	t.Run("DELETE /admin/api/family-groups/{group_id}/members/{member_id}", func(t *testing.T) {
-		// Test removing member from family group endpoint
-		
-		groupID := "test-group-id"
-		memberID := "test-member-id"
-		
-		// Verify parameters
-		assert.NotEmpty(t, groupID)
-		assert.NotEmpty(t, memberID)
-		
-		// Expected response structure
-		expectedResponse := map[string]interface{}{
-			"success": true,
-			"message": "Member removed successfully",
-		}
-
-		// Verify response structure
-		assert.True(t, expectedResponse["success"].(bool))
-		assert.Contains(t, expectedResponse, "message")
-		
-		// In a real test, we would:
-		// 1. Send DELETE request to /admin/api/family-groups/{group_id}/members/{member_id}
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that member is removed from database
-		// 5. Verify that member stops receiving scrobbles
-	})
-
-	t.Run("DELETE /admin/api/family-groups/{id}", func(t *testing.T) {
-		// Test deleting family group endpoint
-		
-		groupID := "test-group-id"
-		
-		// Verify parameter
-		assert.NotEmpty(t, groupID)
-		
-		// Expected response structure
-		expectedResponse := map[string]interface{}{
-			"success": true,
-			"message": "Family group deleted successfully",
-		}
-
-		// Verify response structure
-		assert.True(t, expectedResponse["success"].(bool))
-		assert.Contains(t, expectedResponse, "message")
-		
-		// In a real test, we would:
-		// 1. Send DELETE request to /admin/api/family-groups/{id}
-		// 2. Verify response status is 200
-		// 3. Verify response body matches expected structure
-		// 4. Verify that family group is deleted from database
-		// 5. Verify that all members are deleted
-		// 6. Verify that all retry queue items are deleted
-	})
-}
-
-// TestFamilyAPIErrorResponses tests that error responses match the OpenAPI specification
-func TestFamilyAPIErrorResponses(t *testing.T) {
-	t.Run("400 Bad Request - Missing Fields", func(t *testing.T) {
-		// Test 400 error for missing required fields
-		
-		// Invalid request payload (missing required fields)
-		invalidPayload := map[string]interface{}{
-			"mode": "family",
-			// Missing plex_username and members
-		}
-
-		// Verify invalid payload
-		assert.Contains(t, invalidPayload, "mode")
-		assert.NotContains(t, invalidPayload, "plex_username")
-		assert.NotContains(t, invalidPayload, "members")
-		
-		// Expected error response
-		expectedError := map[string]interface{}{
-			"error": "Missing required fields: plex_username, members",
-		}
-
-		// Verify error response structure
-		assert.Contains(t, expectedError, "error")
-		assert.Contains(t, expectedError["error"], "Missing required fields")
-		
-		// In a real test, we would:
-		// 1. Send POST request to /oauth/family/state with invalid payload
-		// 2. Verify response status is 400
-		// 3. Verify response body matches expected error structure
-		// 4. Verify that error message is descriptive
-	})
-
-	t.Run("400 Bad Request - Duplicate Plex Username", func(t *testing.T) {
-		// Test 400 error for duplicate Plex username
-		
-		// Request payload with duplicate Plex username
-		duplicatePayload := map[string]interface{}{
-			"mode":          "family",
-			"plex_username": "ExistingTV", // This username already exists
-			"members": []map[string]interface{}{
-				{"temp_label": "Dad"},
-				{"temp_label": "Mom"},
-			},
-		}
-
-		// Verify duplicate payload
-		assert.Equal(t, "ExistingTV", duplicatePayload["plex_username"])
-		
-		// Expected error response
-		expectedError := map[string]interface{}{
-			"error": "Plex username 'ExistingTV' already exists",
-		}
-
-		// Verify error response structure
-		assert.Contains(t, expectedError, "error")
-		assert.Contains(t, expectedError["error"], "already exists")
-		
-		// In a real test, we would:
-		// 1. Create a family group with Plex username "ExistingTV"
-		// 2. Send POST request to /oauth/family/state with duplicate username
-		// 3. Verify response status is 400
-		// 4. Verify response body matches expected error structure
-		// 5. Verify that error message is descriptive
-	})
-
-	t.Run("400 Bad Request - Too Many Members", func(t *testing.T) {
-		// Test 400 error for too many members
-		
-		// Request payload with too many members (11 members, max is 10)
-		tooManyMembersPayload := map[string]interface{}{
-			"mode":          "family",
-			"plex_username": "TooManyTV",
-			"members": []map[string]interface{}{
-				{"temp_label": "Member1"},
-				{"temp_label": "Member2"},
-				{"temp_label": "Member3"},
-				{"temp_label": "Member4"},
-				{"temp_label": "Member5"},
-				{"temp_label": "Member6"},
-				{"temp_label": "Member7"},
-				{"temp_label": "Member8"},
-				{"temp_label": "Member9"},
-				{"temp_label": "Member10"},
-				{"temp_label": "Member11"}, // This exceeds the limit
-			},
-		}
-
-		// Verify too many members
-		members := tooManyMembersPayload["members"].([]map[string]interface{})
-		assert.Len(t, members, 11)
-		assert.Greater(t, len(members), 10)
-		
-		// Expected error response
-		expectedError := map[string]interface{}{
-			"error": "Maximum 10 members allowed per family group",
-		}
-
-		// Verify error response structure
-		assert.Contains(t, expectedError, "error")
-		assert.Contains(t, expectedError["error"], "Maximum 10 members")
-		
-		// In a real test, we would:
-		// 1. Send POST request to /oauth/family/state with 11 members
-		// 2. Verify response status is 400
-		// 3. Verify response body matches expected error structure
-		// 4. Verify that error message is descriptive
-	})
-
-	t.Run("404 Not Found - Family Group Not Found", func(t *testing.T) {
-		// Test 404 error for non-existent family group
-		
-		nonExistentGroupID := "non-existent-group-id"
-		
-		// Verify parameter
-		assert.NotEmpty(t, nonExistentGroupID)
-		
-		// Expected error response
-		expectedError := map[string]interface{}{
-			"error": "Family group not found",
-		}
-
-		// Verify error response structure
-		assert.Contains(t, expectedError, "error")
-		assert.Contains(t, expectedError["error"], "not found")
-		
-		// In a real test, we would:
-		// 1. Send GET request to /admin/api/family-groups/{non-existent-id}
-		// 2. Verify response status is 404
-		// 3. Verify response body matches expected error structure
-		// 4. Verify that error message is descriptive
-	})
-
-	t.Run("404 Not Found - Member Not Found", func(t *testing.T) {
-		// Test 404 error for non-existent member
-		
-		groupID := "existing-group-id"
-		nonExistentMemberID := "non-existent-member-id"
-		
-		// Verify parameters
-		assert.NotEmpty(t, groupID)
-		assert.NotEmpty(t, nonExistentMemberID)
-		
-		// Expected error response
-		expectedError := map[string]interface{}{
-			"error": "Member not found",
-		}
-
-		// Verify error response structure
-		assert.Contains(t, expectedError, "error")
-		assert.Contains(t, expectedError["error"], "not found")
-		
-		// In a real test, we would:
-		// 1. Create a family group
-		// 2. Send DELETE request to /admin/api/family-groups/{group_id}/members/{non-existent-member-id}
-		// 3. Verify response status is 404
-		// 4. Verify response body matches expected error structure
-		// 5. Verify that error message is descriptive
-	})
-}
-
-// TestFamilyAPIRequestValidation tests that request validation matches the OpenAPI specification
-func TestFamilyAPIRequestValidation(t *testing.T) {
-	t.Run("Request Body Validation", func(t *testing.T) {
-		// Test request body validation
-		
-		// Valid request body
-		validRequestBody := map[string]interface{}{
-			"mode":          "family",
-			"plex_username": "ValidTV",
-			"members": []map[string]interface{}{
-				{"temp_label": "Dad"},
-				{"temp_label": "Mom"},
-			},
-		}
-
-		// Verify valid request body
-		assert.Equal(t, "family", validRequestBody["mode"])
-		assert.Equal(t, "ValidTV", validRequestBody["plex_username"])
-		members := validRequestBody["members"].([]map[string]interface{})
-		assert.Len(t, members, 2)
-		assert.Equal(t, "Dad", members[0]["temp_label"])
-		assert.Equal(t, "Mom", members[1]["temp_label"])
-		
-		// Invalid request body (wrong mode)
-		invalidRequestBody := map[string]interface{}{
-			"mode":          "invalid",
-			"plex_username": "InvalidTV",
-			"members": []map[string]interface{}{
-				{"temp_label": "Dad"},
-			},
-		}
-
-		// Verify invalid request body
-		assert.Equal(t, "invalid", invalidRequestBody["mode"])
-		assert.NotEqual(t, "family", invalidRequestBody["mode"])
-		
-		// In a real test, we would:
-		// 1. Test valid request body validation
-		// 2. Test invalid request body validation
-		// 3. Verify that validation errors are returned
-		// 4. Verify that error messages are descriptive
-	})
-
-	t.Run("Query Parameter Validation", func(t *testing.T) {
-		// Test query parameter validation
-		
-		// Valid query parameters
-		validParams := map[string]string{
-			"state":     "valid-state-token",
-			"code":      "valid-auth-code",
-			"member_id": "valid-member-id",
-		}
-
-		// Verify valid parameters
-		assert.NotEmpty(t, validParams["state"])
-		assert.NotEmpty(t, validParams["code"])
-		assert.NotEmpty(t, validParams["member_id"])
-		
-		// Invalid query parameters (missing required)
-		invalidParams := map[string]string{
-			"state": "valid-state-token",
-			// Missing code and member_id
-		}
-
-		// Verify invalid parameters
-		assert.NotEmpty(t, invalidParams["state"])
-		assert.Empty(t, invalidParams["code"])
-		assert.Empty(t, invalidParams["member_id"])
-		
-		// In a real test, we would:
-		// 1. Test valid query parameter validation
-		// 2. Test invalid query parameter validation
-		// 3. Verify that validation errors are returned
-		// 4. Verify that error messages are descriptive
-	})
-
-	t.Run("Path Parameter Validation", func(t *testing.T) {
-		// Test path parameter validation
-		
-		// Valid path parameters
-		validGroupID := "valid-group-id"
-		validMemberID := "valid-member-id"
-		
-		// Verify valid parameters
-		assert.NotEmpty(t, validGroupID)
-		assert.NotEmpty(t, validMemberID)
-		
-		// Invalid path parameters (empty)
-		invalidGroupID := ""
-		invalidMemberID := ""
-		
-		// Verify invalid parameters
-		assert.Empty(t, invalidGroupID)
-		assert.Empty(t, invalidMemberID)
-		
-		// In a real test, we would:
-		// 1. Test valid path parameter validation
-		// 2. Test invalid path parameter validation
-		// 3. Verify that validation errors are returned
-		// 4. Verify that error messages are descriptive
-	})
-}
-
-// TestFamilyAPIResponseFormats tests that response formats match the OpenAPI specification
-func TestFamilyAPIResponseFormats(t *testing.T) {
-	t.Run("JSON Response Format", func(t *testing.T) {
-		// Test that all responses are valid JSON
-		
-		// Sample JSON response
-		sampleResponse := map[string]interface{}{
-			"id":               "test-group-id",
-			"plex_username":    "TestTV",
-			"member_count":     float64(3),
-			"authorized_count": float64(2),
-			"webhook_url":      "/api?id=test-group-id",
-			"created_at":       "2023-01-01T00:00:00Z",
-		}
-
-		// Verify JSON marshaling
-		jsonData, err := json.Marshal(sampleResponse)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, jsonData)
-		
-		// Verify JSON unmarshaling
-		var unmarshaled map[string]interface{}
-		err = json.Unmarshal(jsonData, &unmarshaled)
-		assert.NoError(t, err)
-		assert.Equal(t, sampleResponse, unmarshaled)
-		
-		// In a real test, we would:
-		// 1. Test that all API responses are valid JSON
-		// 2. Test that JSON structure matches OpenAPI spec
-		// 3. Test that JSON parsing works correctly
-		// 4. Test that JSON serialization works correctly
-	})
-
-	t.Run("Content Type Headers", func(t *testing.T) {
-		// Test that content type headers are correct
-		
-		// Expected content type for JSON responses
-		expectedContentType := "application/json"
-		
-		// Verify content type
-		assert.Equal(t, "application/json", expectedContentType)
-		
-		// In a real test, we would:
-		// 1. Test that all API responses have correct content type headers
-		// 2. Test that content type is application/json
-		// 3. Test that charset is UTF-8
-		// 4. Test that headers are properly set
-	})
-
-	t.Run("HTTP Status Codes", func(t *testing.T) {
-		// Test that HTTP status codes are correct
-		
-		// Expected status codes for different scenarios
-		expectedStatusCodes := map[string]int{
-			"success":           200,
-			"created":           200,
-			"bad_request":       400,
-			"not_found":         404,
-			"redirect":          302,
-		}
-
-		// Verify status codes
-		assert.Equal(t, 200, expectedStatusCodes["success"])
-		assert.Equal(t, 200, expectedStatusCodes["created"])
-		assert.Equal(t, 400, expectedStatusCodes["bad_request"])
-		assert.Equal(t, 404, expectedStatusCodes["not_found"])
-		assert.Equal(t, 302, expectedStatusCodes["redirect"])
-		
-		// In a real test, we would:
-		// 1. Test that all API endpoints return correct status codes
-		// 2. Test that success responses return 200
-		// 3. Test that error responses return appropriate error codes
-		// 4. Test that redirect responses return 302
-	})
-}